@@ -6,6 +6,8 @@ import (
 
 	"github.com/spf13/cobra"
 	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/platform/metrics"
+	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 )
 
@@ -20,3 +22,14 @@ func Register(name string, f HookFunc) {
 	}
 	Registered[name] = f
 }
+
+// serveMetrics exposes the prometheus metrics until the context is done, if a port is set for them.
+func serveMetrics(ctx context.Context, logger *zap.Logger, cfg *config.Config) {
+	if cfg.MetricsPort == 0 {
+		return
+	}
+	err := metrics.Serve(ctx, logger, cfg.MetricsAddress, cfg.MetricsPort)
+	if err != nil {
+		utils.LogError(logger, err, "failed to serve the prometheus metrics")
+	}
+}
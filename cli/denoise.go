@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	replaySvc "go.keploy.io/server/v2/pkg/service/replay"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("denoise", Denoise)
+}
+
+// Denoise retrieves the command to detect the noisy fields of the recorded testcases
+func Denoise(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var denoiseCmd = &cobra.Command{
+		Use:     "denoise",
+		Short:   "replay the recorded testcases and add the fields which change between runs to their noise",
+		Example: `keploy denoise -c "/path/to/user/app" --delay 6 -t "test-set-1"`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var replay replaySvc.Service
+			var ok bool
+			if replay, ok = svc.(replaySvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy replay service interface")
+				return nil
+			}
+			// defering the stop function to stop keploy in case of any error in denoise or in case of context cancellation
+			defer func() {
+				select {
+				case <-ctx.Done():
+					break
+				default:
+					utils.ExecCancel()
+				}
+			}()
+			err = replay.Denoise(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to denoise the testcases")
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(denoiseCmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add denoise flags")
+		return nil
+	}
+
+	return denoiseCmd
+}
@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
+	"slices"
 	"strings"
 	"time"
 
@@ -19,6 +19,7 @@ import (
 	"github.com/spf13/viper"
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg/models"
+	reportSvc "go.keploy.io/server/v2/pkg/service/report"
 	"go.keploy.io/server/v2/pkg/service/tools"
 	"go.keploy.io/server/v2/utils"
 	"go.keploy.io/server/v2/utils/log"
@@ -186,7 +187,21 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 		}
 
 	case "update", "export":
+		if cmd.Parent() != nil && cmd.Parent().Name() == "report" {
+			cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks/reports are stored")
+			cmd.Flags().String("test-run", "", "Test run to export, the latest one by default")
+			cmd.Flags().String("format", reportSvc.FormatJUnit, "Format to export the test run to (junit/json/sarif)")
+			cmd.Flags().StringP("output", "o", "", "File to write the export to, by default a file in the directory of the test run")
+		}
 		return nil
+	case "list", "show", "diff", "compare":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks/reports are stored")
+		if cmd.Name() == "diff" {
+			cmd.Flags().String("test-run", "", "Test run of the testcases, the latest one by default")
+		}
+	case "quarantine":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks/reports are stored")
+		cmd.Flags().Int("runs", 10, "Number of latest test runs to look for the flaky testcases in, all of them if not positive")
 	case "normalize":
 		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks/reports are stored")
 		cmd.Flags().String("test-run", "", "Test Run to be normalized")
@@ -217,7 +232,7 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 			return errors.New(errMsg)
 		}
 
-	case "record", "test", "rerecord":
+	case "record", "test", "rerecord", "denoise":
 		if cmd.Parent() != nil && cmd.Parent().Name() == "contract" {
 			cmd.Flags().StringSliceP("services", "s", c.cfg.Contract.Services, "Specify the services for which to generate contracts")
 			cmd.Flags().StringP("path", "p", ".", "Specify the path to generate contracts")
@@ -230,6 +245,8 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
 		cmd.Flags().Uint32("proxy-port", c.cfg.ProxyPort, "Port used by the Keploy proxy server to intercept the outgoing dependency calls")
 		cmd.Flags().Uint32("dns-port", c.cfg.DNSPort, "Port used by the Keploy DNS server to intercept the DNS queries")
+		cmd.Flags().Uint32("metrics-port", c.cfg.MetricsPort, "Port to expose the prometheus metrics of the proxy and the test runs on, disabled if 0")
+		cmd.Flags().String("metrics-address", c.cfg.MetricsAddress, "Address to bind the prometheus metrics endpoint to, 0.0.0.0 to expose it on all the interfaces")
 		cmd.Flags().StringP("command", "c", c.cfg.Command, "Command to start the user application")
 		cmd.Flags().String("cmd-type", c.cfg.CommandType, "Type of command to start the user application (native/docker/docker-compose)")
 		cmd.Flags().Uint64P("build-delay", "b", c.cfg.BuildDelay, "User provided time to wait docker container build")
@@ -271,20 +288,29 @@ func (c *CmdConfigurator) AddUncommonFlags(cmd *cobra.Command) {
 	switch cmd.Name() {
 	case "record":
 		cmd.Flags().Uint64("record-timer", 0, "User provided time to record its application")
-	case "test", "rerecord":
+		cmd.Flags().Bool("denoise", c.cfg.Record.Denoise, "Replay the recorded testcases after recording and add the fields which change between runs to their noise")
+		cmd.Flags().Float64("sample-percentage", c.cfg.Record.SamplePercentage, "Percentage of the incoming traffic to record as testcases")
+		cmd.Flags().Uint("rate-limit", c.cfg.Record.RateLimit, "Maximum number of testcases recorded per endpoint per minute, 0 for no limit")
+		cmd.Flags().Bool("dedup", c.cfg.Record.Dedup, "Drop the testcases with the same method, path template, status code and JSON schemas as an already recorded one")
+	case "test", "rerecord", "denoise":
 		cmd.Flags().StringSliceP("test-sets", "t", utils.Keys(c.cfg.Test.SelectedTests), "Testsets to run e.g. --testsets \"test-set-1, test-set-2\"")
 		cmd.Flags().String("host", c.cfg.Test.Host, "Custom host to replace the actual host in the testcases")
 		cmd.Flags().Uint32("port", c.cfg.Test.Port, "Custom port to replace the actual port in the testcases")
-		if cmd.Name() == "test" {
+		if cmd.Name() == "test" || cmd.Name() == "denoise" {
 			cmd.Flags().Uint64P("delay", "d", 5, "User provided time to run its application")
 			cmd.Flags().Uint64("api-timeout", c.cfg.Test.APITimeout, "User provided timeout for calling its application")
 			cmd.Flags().String("mongo-password", c.cfg.Test.MongoPassword, "Authentication password for mocking MongoDB conn")
+			cmd.Flags().String("postgres-password", c.cfg.Test.PostgresPassword, "Authentication password for simulating the SCRAM-SHA-256 auth of Postgres conn")
 			cmd.Flags().String("coverage-report-path", c.cfg.Test.CoverageReportPath, "Write a go coverage profile to the file in the given directory.")
 			cmd.Flags().VarP(&c.cfg.Test.Language, "language", "l", "Application programming language")
 			cmd.Flags().Bool("ignore-ordering", c.cfg.Test.IgnoreOrdering, "Ignore ordering of array in response")
 			cmd.Flags().Bool("skip-coverage", c.cfg.Test.SkipCoverage, "skip code coverage computation while running the test cases")
 			cmd.Flags().Bool("remove-unused-mocks", c.cfg.Test.RemoveUnusedMocks, "Clear the unused mocks for the passed test-sets")
 			cmd.Flags().Bool("fallBack-on-miss", c.cfg.Test.FallBackOnMiss, "Enable connecting to actual service if mock not found during test mode")
+			cmd.Flags().Bool("assert-dependencies", c.cfg.Test.AssertDependencies, "Fail the testcases whose outgoing calls to the dependencies differ from the recorded ones")
+			cmd.Flags().Int("parallel", c.cfg.Test.Parallel, "Number of application instances to run the testsets on at once (docker run commands only)")
+			cmd.Flags().StringSlice("report-formats", c.cfg.Test.ReportFormats, "Formats to export the test run to along with the yaml reports (junit/json/sarif)")
+			cmd.Flags().Int("rerun-failures", c.cfg.Test.RerunFailures, "Number of times to rerun a failed testcase, the testcases passing on rerun are marked as flaky")
 			cmd.Flags().String("jacoco-agent-path", c.cfg.Test.JacocoAgentPath, "Only applicable for test coverage for Java projects. You can override the jacoco agent jar by proving its path")
 			cmd.Flags().String("base-path", c.cfg.Test.BasePath, "Custom api basePath/origin to replace the actual basePath/origin in the testcases; App flag is ignored and app will not be started & instrumented when this is set since the application running on a different machine")
 			cmd.Flags().Bool("update-temp", c.cfg.Test.UpdateTemplate, "Update the template with the result of the testcases.")
@@ -302,6 +328,7 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"delay":                 "delay",
 		"apiTimeout":            "api-timeout",
 		"mongoPassword":         "mongo-password",
+		"postgresPassword":      "postgres-password",
 		"coverageReportPath":    "coverage-report-path",
 		"language":              "language",
 		"ignoreOrdering":        "ignore-ordering",
@@ -309,6 +336,8 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"removeUnusedMocks":     "remove-unused-mocks",
 		"goCoverage":            "go-coverage",
 		"fallBackOnMiss":        "fallBack-on-miss",
+		"assertDependencies":    "assert-dependencies",
+		"rerunFailures":         "rerun-failures",
 		"basePath":              "base-path",
 		"updateTemplate":        "update-template",
 		"mocking":               "mocking",
@@ -327,6 +356,8 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"port":                  "port",
 		"proxyPort":             "proxy-port",
 		"dnsPort":               "dns-port",
+		"metricsPort":           "metrics-port",
+		"metricsAddress":        "metrics-address",
 		"command":               "command",
 		"cmdType":               "cmd-type",
 		"buildDelay":            "build-delay",
@@ -345,6 +376,8 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"keployContainer":       "keploy-container",
 		"keployNetwork":         "keploy-network",
 		"recordTimer":           "record-timer",
+		"samplePercentage":      "sample-percentage",
+		"rateLimit":             "rate-limit",
 		"urlMethods":            "url-methods",
 		"inCi":                  "in-ci",
 	}
@@ -402,7 +435,15 @@ func (c *CmdConfigurator) PreProcessFlags(cmd *cobra.Command) error {
 	viper.SetEnvPrefix("KEPLOY")
 
 	//used to bind flags specific to the command for eg: testsets, delay, recordTimer etc. (nested flags)
-	err = utils.BindFlagsToViper(c.logger, cmd, "")
+	viperKeyPrefix := ""
+	if cmd.Name() == "denoise" {
+		// the denoise command takes the flags of the test command
+		viperKeyPrefix = "test"
+	}
+	if cmd.Name() == "quarantine" || cmd.Parent() != nil && cmd.Parent().Name() == "report" {
+		viperKeyPrefix = "report"
+	}
+	err = utils.BindFlagsToViper(c.logger, cmd, viperKeyPrefix)
 	if err != nil {
 		errMsg := "failed to bind cmd specific flags to viper"
 		utils.LogError(c.logger, err, errMsg)
@@ -527,7 +568,7 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 			utils.LogError(c.logger, err, errMsg)
 			return errors.New(errMsg)
 		}
-	case "record", "test", "rerecord":
+	case "record", "test", "rerecord", "denoise":
 
 		if cmd.Parent() != nil && cmd.Parent().Name() == "contract" {
 			path, err := cmd.Flags().GetString("path")
@@ -649,7 +690,13 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 		}
 		config.SetByPassPorts(c.cfg, bypassPorts)
 
-		if cmd.Name() == "test" || cmd.Name() == "rerecord" {
+		if cmd.Name() == "record" && (c.cfg.Record.SamplePercentage <= 0 || c.cfg.Record.SamplePercentage > 100) {
+			errMsg := "the sample percentage should be greater than 0 and at most 100"
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+
+		if cmd.Name() == "test" || cmd.Name() == "rerecord" || cmd.Name() == "denoise" {
 			//check if the keploy folder exists
 			if _, err := os.Stat(c.cfg.Path); os.IsNotExist(err) {
 				recordCmd := models.HighlightGrayString("keploy record")
@@ -684,6 +731,33 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 				return nil
 			}
 
+			if cmd.Name() == "denoise" {
+				c.cfg.Test.SkipCoverage = true
+			}
+
+			for _, format := range c.cfg.Test.ReportFormats {
+				if !slices.Contains(reportSvc.Formats, format) {
+					errMsg := fmt.Sprintf("unknown report format %q, must be one of %s", format, strings.Join(reportSvc.Formats, ", "))
+					utils.LogError(c.logger, nil, errMsg)
+					return errors.New(errMsg)
+				}
+			}
+
+			if c.cfg.Test.Parallel < 0 {
+				errMsg := "the number of parallel instances can't be negative"
+				utils.LogError(c.logger, nil, errMsg)
+				return errors.New(errMsg)
+			}
+			if c.cfg.Test.RerunFailures < 0 {
+				errMsg := "the number of reruns of the failed testcases can't be negative"
+				utils.LogError(c.logger, nil, errMsg)
+				return errors.New(errMsg)
+			}
+			// the coverage of the instances can't be merged, so it isn't computed in parallel runs
+			if c.cfg.Test.Parallel > 1 {
+				c.cfg.Test.SkipCoverage = true
+			}
+
 			// skip coverage by default if command is of type docker
 			if utils.CmdType(c.cfg.CommandType) != "native" && !cmd.Flags().Changed("skip-coverage") {
 				c.cfg.Test.SkipCoverage = true
@@ -716,6 +790,18 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 
 	case "templatize":
 		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+	case "list", "show", "diff", "compare", "quarantine":
+		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+	case "export":
+		if cmd.Parent() == nil || cmd.Parent().Name() != "report" {
+			return nil
+		}
+		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+		if !slices.Contains(reportSvc.Formats, c.cfg.Report.Format) {
+			errMsg := fmt.Sprintf("unknown report format %q, must be one of %s", c.cfg.Report.Format, strings.Join(reportSvc.Formats, ", "))
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
 	case "gen":
 		if os.Getenv("API_KEY") == "" {
 			utils.LogError(c.logger, nil, "API_KEY is not set")
@@ -51,7 +51,7 @@ func Get(ctx context.Context, cmd string, cfg *config.Config, logger *zap.Logger
 		return orchestrator.New(logger, recordSvc, replaySvc, cfg), nil
 	case "record":
 		return recordSvc, nil
-	case "test", "normalize", "templatize":
+	case "test", "normalize", "templatize", "denoise":
 		return replaySvc, nil
 	case "contract":
 		return contractSvc, nil
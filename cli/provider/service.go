@@ -6,9 +6,11 @@ import (
 
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg/platform/telemetry"
+	"go.keploy.io/server/v2/pkg/platform/yaml/reportdb"
 	"go.keploy.io/server/v2/pkg/service"
 	"go.keploy.io/server/v2/utils"
 
+	"go.keploy.io/server/v2/pkg/service/report"
 	"go.keploy.io/server/v2/pkg/service/tools"
 	"go.keploy.io/server/v2/pkg/service/utgen"
 	"go.uber.org/zap"
@@ -43,9 +45,11 @@ func (n *ServiceProvider) GetService(ctx context.Context, cmd string) (interface
 	switch cmd {
 	case "config", "update", "login", "export":
 		return tools.NewTools(n.logger, tel, n.auth), nil
+	case "report", "quarantine":
+		return report.New(n.logger, reportdb.New(n.logger, n.cfg.Path+"/reports"), n.cfg), nil
 	case "gen":
 		return utgen.NewUnitTestGenerator(n.cfg, tel, n.auth, n.logger)
-	case "record", "test", "mock", "normalize", "templatize", "rerecord", "contract":
+	case "record", "test", "mock", "normalize", "templatize", "rerecord", "contract", "denoise":
 		return Get(ctx, cmd, n.cfg, n.logger, tel, n.auth)
 	default:
 		return nil, errors.New("invalid command")
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("quarantine", Quarantine)
+}
+
+func Quarantine(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "quarantine",
+		Short:   "Add the flaky testcases of the latest test runs to the ignored tests of the config file",
+		Example: `keploy quarantine --runs 10`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			report, ok := getReportService(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			err := report.Quarantine(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to quarantine the flaky testcases")
+			}
+			return nil
+		},
+	}
+	if err := cmdConfigurator.AddFlags(cmd); err != nil {
+		utils.LogError(logger, err, "failed to add quarantine flags")
+		return nil
+	}
+	return cmd
+}
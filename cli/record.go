@@ -2,10 +2,12 @@ package cli
 
 import (
 	"context"
+	"slices"
 
 	"github.com/spf13/cobra"
 	"go.keploy.io/server/v2/config"
 	recordSvc "go.keploy.io/server/v2/pkg/service/record"
+	replaySvc "go.keploy.io/server/v2/pkg/service/replay"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 )
@@ -14,7 +16,7 @@ func init() {
 	Register("record", Record)
 }
 
-func Record(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+func Record(ctx context.Context, logger *zap.Logger, cfg *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:     "record",
 		Short:   "record the keploy testcases from the API calls",
@@ -35,12 +37,35 @@ func Record(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFa
 				return nil
 			}
 
+			var replay replaySvc.Service
+			var testSetIDs []string
+			if cfg.Record.Denoise {
+				svc, err := serviceFactory.GetService(ctx, "denoise")
+				if err != nil {
+					utils.LogError(logger, err, "failed to get the service to denoise the testcases")
+					return nil
+				}
+				if replay, ok = svc.(replaySvc.Service); !ok {
+					utils.LogError(logger, nil, "service doesn't satisfy replay service interface")
+					return nil
+				}
+				testSetIDs, err = replay.GetAllTestSetIDs(ctx)
+				if err != nil {
+					utils.LogError(logger, err, "failed to get the existing test sets")
+					return nil
+				}
+			}
+
+			serveMetrics(ctx, logger, cfg)
 			err = record.Start(ctx, false)
 			if err != nil {
 				utils.LogError(logger, err, "failed to record")
 				return nil
 			}
 
+			if cfg.Record.Denoise {
+				denoiseRecorded(logger, cfg, replay, testSetIDs)
+			}
 			return nil
 		},
 	}
@@ -53,3 +78,31 @@ func Record(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFa
 
 	return cmd
 }
+
+// denoiseRecorded denoises the test sets recorded since the given ones were listed. The recording stops
+// once keploy is asked to stop, so the test cases are replayed with a new context.
+func denoiseRecorded(logger *zap.Logger, cfg *config.Config, replay replaySvc.Service, existing []string) {
+	ctx := utils.NewCtx()
+	testSetIDs, err := replay.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to get the recorded test sets")
+		return
+	}
+	cfg.Test.SelectedTests = map[string][]string{}
+	for _, testSetID := range testSetIDs {
+		if !slices.Contains(existing, testSetID) {
+			cfg.Test.SelectedTests[testSetID] = []string{}
+		}
+	}
+	if len(cfg.Test.SelectedTests) == 0 {
+		logger.Info("no test set recorded, skipping the denoising")
+		return
+	}
+
+	logger.Info("denoising the recorded testcases", zap.Any("test-sets", utils.Keys(cfg.Test.SelectedTests)))
+	defer utils.ExecCancel()
+	err = replay.Denoise(ctx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to denoise the testcases")
+	}
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	reportSvc "go.keploy.io/server/v2/pkg/service/report"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("report", Report)
+}
+
+func Report(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "report",
+		Short: "Browse and export the reports of the test runs",
+	}
+
+	cmd.AddCommand(ListReports(ctx, logger, serviceFactory, cmdConfigurator))
+	cmd.AddCommand(ShowReport(ctx, logger, serviceFactory, cmdConfigurator))
+	cmd.AddCommand(DiffReport(ctx, logger, serviceFactory, cmdConfigurator))
+	cmd.AddCommand(CompareReports(ctx, logger, serviceFactory, cmdConfigurator))
+	cmd.AddCommand(ExportReport(ctx, logger, serviceFactory, cmdConfigurator))
+	for _, subCmd := range cmd.Commands() {
+		err := cmdConfigurator.AddFlags(subCmd)
+		if err != nil {
+			utils.LogError(logger, err, "failed to add flags to command", zap.String("command", subCmd.Name()))
+		}
+	}
+	return cmd
+}
+
+// getReportService returns the report service.
+func getReportService(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory) (reportSvc.Service, bool) {
+	svc, err := serviceFactory.GetService(ctx, "report")
+	if err != nil {
+		utils.LogError(logger, err, "failed to get service")
+		return nil, false
+	}
+	report, ok := svc.(reportSvc.Service)
+	if !ok {
+		utils.LogError(logger, nil, "service doesn't satisfy report service interface")
+		return nil, false
+	}
+	return report, true
+}
+
+func ListReports(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "list",
+		Short:   "List the test runs along with their results",
+		Example: `keploy report list`,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			report, ok := getReportService(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			err := report.List(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to list the test runs")
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func ShowReport(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "show [test-run]",
+		Short:   "Show the results of each test set of a test run, the latest one by default",
+		Example: `keploy report show test-run-3`,
+		Args:    cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			report, ok := getReportService(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			testRunID := ""
+			if len(args) == 1 {
+				testRunID = args[0]
+			}
+			err := report.Show(ctx, testRunID)
+			if err != nil {
+				utils.LogError(logger, err, "failed to show the test run")
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func DiffReport(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "diff <test-set> [testcase...]",
+		Short:   "Print the diffs of the failed testcases of a test set",
+		Example: `keploy report diff test-set-0 test-3 --test-run test-run-3`,
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			report, ok := getReportService(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			err := report.Diff(ctx, args[0], args[1:])
+			if err != nil {
+				utils.LogError(logger, err, "failed to print the diffs")
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func CompareReports(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "compare <base-test-run> <head-test-run>",
+		Short:   "List the newly failing, newly passing and flaky testcases between two test runs",
+		Example: `keploy report compare test-run-2 test-run-5`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			report, ok := getReportService(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			err := report.Compare(ctx, args[0], args[1])
+			if err != nil {
+				utils.LogError(logger, err, "failed to compare the test runs")
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func ExportReport(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "export",
+		Short:   "Export a test run as JUnit XML, a JSON summary or SARIF",
+		Example: `keploy report export --format junit --test-run test-run-3 -o junit.xml`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			report, ok := getReportService(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			err := report.Export(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to export the test run")
+			}
+			return nil
+		},
+	}
+	return cmd
+}
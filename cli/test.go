@@ -15,7 +15,7 @@ func init() {
 	Register("test", Test)
 }
 
-func Test(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+func Test(ctx context.Context, logger *zap.Logger, cfg *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
 	var testCmd = &cobra.Command{
 		Use:     "test",
 		Short:   "run the recorded testcases and execute assertions",
@@ -44,6 +44,7 @@ func Test(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFact
 					utils.ExecCancel()
 				}
 			}()
+			serveMetrics(ctx, logger, cfg)
 			err = replay.Start(ctx)
 			if err != nil {
 				utils.LogError(logger, err, "failed to replay")
@@ -9,34 +9,40 @@ import (
 )
 
 type Config struct {
-	Path                  string       `json:"path" yaml:"path" mapstructure:"path"`
-	AppID                 uint64       `json:"appId" yaml:"appId" mapstructure:"appId"`
-	AppName               string       `json:"appName" yaml:"appName" mapstructure:"appName"`
-	Command               string       `json:"command" yaml:"command" mapstructure:"command"`
-	Templatize            Templatize   `json:"templatize" yaml:"templatize" mapstructure:"templatize"`
-	Port                  uint32       `json:"port" yaml:"port" mapstructure:"port"`
-	DNSPort               uint32       `json:"dnsPort" yaml:"dnsPort" mapstructure:"dnsPort"`
-	ProxyPort             uint32       `json:"proxyPort" yaml:"proxyPort" mapstructure:"proxyPort"`
-	Debug                 bool         `json:"debug" yaml:"debug" mapstructure:"debug"`
-	DisableTele           bool         `json:"disableTele" yaml:"disableTele" mapstructure:"disableTele"`
-	DisableANSI           bool         `json:"disableANSI" yaml:"disableANSI" mapstructure:"disableANSI"`
-	InDocker              bool         `json:"inDocker" yaml:"-" mapstructure:"inDocker"`
-	ContainerName         string       `json:"containerName" yaml:"containerName" mapstructure:"containerName"`
-	NetworkName           string       `json:"networkName" yaml:"networkName" mapstructure:"networkName"`
-	BuildDelay            uint64       `json:"buildDelay" yaml:"buildDelay" mapstructure:"buildDelay"`
-	Test                  Test         `json:"test" yaml:"test" mapstructure:"test"`
-	Record                Record       `json:"record" yaml:"record" mapstructure:"record"`
-	Gen                   UtGen        `json:"gen" yaml:"-" mapstructure:"gen"`
-	Normalize             Normalize    `json:"normalize" yaml:"-" mapstructure:"normalize"`
-	ReRecord              ReRecord     `json:"rerecord" yaml:"-" mapstructure:"rerecord"`
-	ConfigPath            string       `json:"configPath" yaml:"configPath" mapstructure:"configPath"`
-	BypassRules           []BypassRule `json:"bypassRules" yaml:"bypassRules" mapstructure:"bypassRules"`
-	EnableTesting         bool         `json:"enableTesting" yaml:"-" mapstructure:"enableTesting"`
-	GenerateGithubActions bool         `json:"generateGithubActions" yaml:"generateGithubActions" mapstructure:"generateGithubActions"`
-	KeployContainer       string       `json:"keployContainer" yaml:"keployContainer" mapstructure:"keployContainer"`
-	KeployNetwork         string       `json:"keployNetwork" yaml:"keployNetwork" mapstructure:"keployNetwork"`
-	CommandType           string       `json:"cmdType" yaml:"cmdType" mapstructure:"cmdType"`
-	Contract              Contract     `json:"contract" yaml:"contract" mapstructure:"contract"`
+	Path                  string         `json:"path" yaml:"path" mapstructure:"path"`
+	AppID                 uint64         `json:"appId" yaml:"appId" mapstructure:"appId"`
+	AppName               string         `json:"appName" yaml:"appName" mapstructure:"appName"`
+	Command               string         `json:"command" yaml:"command" mapstructure:"command"`
+	Templatize            Templatize     `json:"templatize" yaml:"templatize" mapstructure:"templatize"`
+	Port                  uint32         `json:"port" yaml:"port" mapstructure:"port"`
+	DNSPort               uint32         `json:"dnsPort" yaml:"dnsPort" mapstructure:"dnsPort"`
+	ProxyPort             uint32         `json:"proxyPort" yaml:"proxyPort" mapstructure:"proxyPort"`
+	MetricsPort           uint32         `json:"metricsPort" yaml:"metricsPort" mapstructure:"metricsPort"`          // port of the prometheus metrics endpoint, disabled if 0
+	MetricsAddress        string         `json:"metricsAddress" yaml:"metricsAddress" mapstructure:"metricsAddress"` // address the prometheus metrics endpoint is bound to
+	Debug                 bool           `json:"debug" yaml:"debug" mapstructure:"debug"`
+	DisableTele           bool           `json:"disableTele" yaml:"disableTele" mapstructure:"disableTele"`
+	DisableANSI           bool           `json:"disableANSI" yaml:"disableANSI" mapstructure:"disableANSI"`
+	InDocker              bool           `json:"inDocker" yaml:"-" mapstructure:"inDocker"`
+	ContainerName         string         `json:"containerName" yaml:"containerName" mapstructure:"containerName"`
+	NetworkName           string         `json:"networkName" yaml:"networkName" mapstructure:"networkName"`
+	BuildDelay            uint64         `json:"buildDelay" yaml:"buildDelay" mapstructure:"buildDelay"`
+	Test                  Test           `json:"test" yaml:"test" mapstructure:"test"`
+	Record                Record         `json:"record" yaml:"record" mapstructure:"record"`
+	Gen                   UtGen          `json:"gen" yaml:"-" mapstructure:"gen"`
+	Normalize             Normalize      `json:"normalize" yaml:"-" mapstructure:"normalize"`
+	ReRecord              ReRecord       `json:"rerecord" yaml:"-" mapstructure:"rerecord"`
+	Report                Report         `json:"report" yaml:"-" mapstructure:"report"`
+	ConfigPath            string         `json:"configPath" yaml:"configPath" mapstructure:"configPath"`
+	BypassRules           []BypassRule   `json:"bypassRules" yaml:"bypassRules" mapstructure:"bypassRules"`
+	ProtocolMap           []ProtocolRule `json:"protocolMap" yaml:"protocolMap" mapstructure:"protocolMap"`
+	EnableTesting         bool           `json:"enableTesting" yaml:"-" mapstructure:"enableTesting"`
+	GenerateGithubActions bool           `json:"generateGithubActions" yaml:"generateGithubActions" mapstructure:"generateGithubActions"`
+	KeployContainer       string         `json:"keployContainer" yaml:"keployContainer" mapstructure:"keployContainer"`
+	KeployNetwork         string         `json:"keployNetwork" yaml:"keployNetwork" mapstructure:"keployNetwork"`
+	CommandType           string         `json:"cmdType" yaml:"cmdType" mapstructure:"cmdType"`
+	Contract              Contract       `json:"contract" yaml:"contract" mapstructure:"contract"`
+	Proto                 Proto          `json:"proto" yaml:"proto" mapstructure:"proto"`
+	Redis                 Redis          `json:"redis" yaml:"redis" mapstructure:"redis"`
 
 	InCi           bool   `json:"inCi" yaml:"inCi" mapstructure:"inCi"`
 	InstallationID string `json:"-" yaml:"-" mapstructure:"-"`
@@ -66,6 +72,23 @@ type Templatize struct {
 type Record struct {
 	Filters     []Filter      `json:"filters" yaml:"filters" mapstructure:"filters"`
 	RecordTimer time.Duration `json:"recordTimer" yaml:"recordTimer" mapstructure:"recordTimer"`
+	Denoise     bool          `json:"denoise" yaml:"denoise" mapstructure:"denoise"`
+	Redact      Redact        `json:"redact" yaml:"redact" mapstructure:"redact"`
+	// SamplePercentage is the share of the incoming traffic to record as testcases, in percent.
+	SamplePercentage float64 `json:"samplePercentage" yaml:"samplePercentage" mapstructure:"samplePercentage"`
+	// RateLimit is the maximum number of testcases recorded per endpoint per minute, 0 for no limit.
+	RateLimit uint `json:"rateLimit" yaml:"rateLimit" mapstructure:"rateLimit"`
+	// Dedup drops the testcases with the same shape as an already recorded one.
+	Dedup bool `json:"dedup" yaml:"dedup" mapstructure:"dedup"`
+}
+
+// Redact lists the secrets and the personal data to replace by placeholders in the recorded test cases and mocks.
+type Redact struct {
+	Headers   []string `json:"headers" yaml:"headers" mapstructure:"headers"`       // names of the headers, case insensitive
+	JSONPaths []string `json:"jsonPaths" yaml:"jsonPaths" mapstructure:"jsonPaths"` // dotted paths in the bodies of the responses, "*" matches any key or index
+	Regexes   []string `json:"regexes" yaml:"regexes" mapstructure:"regexes"`       // only the capture groups are replaced, if any
+	Detectors []string `json:"detectors" yaml:"detectors" mapstructure:"detectors"` // built-in detectors: email, pan, jwt
+	Key       string   `json:"key" yaml:"key" mapstructure:"key"`                   // key of the placeholders, generated in the keploy directory if empty
 }
 
 type ReRecord struct {
@@ -88,17 +111,48 @@ type Mappings struct {
 	Self            string              `json:"self" yaml:"self" mapstructure:"self"`
 }
 
+// Proto points keploy to the protobuf definitions of the gRPC services called by the application,
+// so that gRPC messages are stored as JSON and matched field by field.
+type Proto struct {
+	DescriptorSet string   `json:"descriptorSet" yaml:"descriptorSet" mapstructure:"descriptorSet"` // path to a FileDescriptorSet generated by protoc --descriptor_set_out
+	Dir           string   `json:"dir" yaml:"dir" mapstructure:"dir"`                               // directory containing the .proto files
+	ImportPaths   []string `json:"importPaths" yaml:"importPaths" mapstructure:"importPaths"`       // additional import paths used to compile the .proto files
+}
+
+// Redis configures the matching of the redis commands with the recorded ones.
+type Redis struct {
+	// Noise lists the arguments ignored while matching, by the name of the command ("*" for every command).
+	// An entry is either the position of an argument, starting from 1, or an option (e.g. EX) whose value is ignored.
+	Noise map[string][]string `json:"noise" yaml:"noise" mapstructure:"noise"`
+}
+
 type Normalize struct {
 	SelectedTests []SelectedTests `json:"selectedTests" yaml:"selectedTests" mapstructure:"selectedTests"`
 	TestRun       string          `json:"testReport" yaml:"testReport" mapstructure:"testReport"`
 }
 
+type Report struct {
+	TestRun string `json:"testRun" yaml:"testRun" mapstructure:"testRun"`
+	Format  string `json:"format" yaml:"format" mapstructure:"format"`
+	Output  string `json:"output" yaml:"output" mapstructure:"output"`
+	Runs    int    `json:"runs" yaml:"runs" mapstructure:"runs"`
+}
+
 type BypassRule struct {
 	Path string `json:"path" yaml:"path" mapstructure:"path"`
 	Host string `json:"host" yaml:"host" mapstructure:"host"`
 	Port uint   `json:"port" yaml:"port" mapstructure:"port"`
 }
 
+// ProtocolRule sets the protocol of the outgoing connections to a host and/or port, so that they
+// are handled by that integration instead of being detected from their traffic. The "detect" protocol
+// has the protocol detected from the greeting of the server, in case the client waits for it.
+type ProtocolRule struct {
+	Host     string `json:"host" yaml:"host" mapstructure:"host"`
+	Port     uint   `json:"port" yaml:"port" mapstructure:"port"`
+	Protocol string `json:"protocol" yaml:"protocol" mapstructure:"protocol"` // name of the integration, e.g. mysql, or detect
+}
+
 type Filter struct {
 	BypassRule `mapstructure:",squash"`
 	URLMethods []string          `json:"urlMethods" yaml:"urlMethods" mapstructure:"urlMethods"`
@@ -116,6 +170,7 @@ type Test struct {
 	CoverageReportPath  string              `json:"coverageReportPath" yaml:"coverageReportPath" mapstructure:"coverageReportPath"` // directory path to store the coverage files
 	IgnoreOrdering      bool                `json:"ignoreOrdering" yaml:"ignoreOrdering" mapstructure:"ignoreOrdering"`
 	MongoPassword       string              `json:"mongoPassword" yaml:"mongoPassword" mapstructure:"mongoPassword"`
+	PostgresPassword    string              `json:"postgresPassword" yaml:"postgresPassword" mapstructure:"postgresPassword"`
 	Language            Language            `json:"language" yaml:"language" mapstructure:"language"`
 	RemoveUnusedMocks   bool                `json:"removeUnusedMocks" yaml:"removeUnusedMocks" mapstructure:"removeUnusedMocks"`
 	FallBackOnMiss      bool                `json:"fallBackOnMiss" yaml:"fallBackOnMiss" mapstructure:"fallBackOnMiss"`
@@ -127,6 +182,10 @@ type Test struct {
 	DisableMockUpload   bool                `json:"disableMockUpload" yaml:"disableMockUpload" mapstructure:"disableMockUpload"`
 	UseLocalMock        bool                `json:"useLocalMock" yaml:"useLocalMock" mapstructure:"useLocalMock"`
 	UpdateTemplate      bool                `json:"updateTemplate" yaml:"updateTemplate" mapstructure:"updateTemplate"`
+	AssertDependencies  bool                `json:"assertDependencies" yaml:"assertDependencies" mapstructure:"assertDependencies"` // fail the test cases whose dependency calls differ from the recorded ones
+	Parallel            int                 `json:"parallel" yaml:"parallel" mapstructure:"parallel"`                               // number of application instances running the test sets at once
+	ReportFormats       []string            `json:"reportFormats" yaml:"reportFormats" mapstructure:"reportFormats"`                // formats the test run is exported to along with the yaml reports, e.g. junit
+	RerunFailures       int                 `json:"rerunFailures" yaml:"rerunFailures" mapstructure:"rerunFailures"`                // number of times a failed test case is rerun, the ones passing on rerun are marked flaky
 }
 
 type Language string
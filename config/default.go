@@ -16,6 +16,8 @@ command: ""
 port: 0
 proxyPort: 16789
 dnsPort: 26789
+metricsPort: 0
+metricsAddress: "127.0.0.1"
 debug: false
 disableANSI: false
 disableTele: false
@@ -36,22 +38,45 @@ test:
   coverageReportPath: ""
   ignoreOrdering: true
   mongoPassword: "default@123"
+  postgresPassword: ""
   language: ""
   removeUnusedMocks: false
   basePath: ""
   mocking: true
   disableLineCoverage: false
   fallbackOnMiss: false
+  assertDependencies: false
+  parallel: 1
+  reportFormats: []
+  rerunFailures: 0
   disableMockUpload: true
 record:
   recordTimer: 0s
+  denoise: false
   filters: []
+  samplePercentage: 100
+  rateLimit: 0
+  dedup: false
+  redact:
+    headers: []
+    jsonPaths: []
+    regexes: []
+    detectors: []
+    key: ""
 contract:
   driven: "consumer"
   servicesMapping: {}
   self: "s1"
+proto:
+  descriptorSet: ""
+  dir: ""
+  importPaths: []
+  noise: {}
+redis:
+  noise: {}
 configPath: ""
 bypassRules: []
+protocolMap: []
 `
 
 func GetDefaultConfig() string {
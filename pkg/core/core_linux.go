@@ -27,7 +27,13 @@ type Core struct {
 	logger       *zap.Logger
 	id           utils.AutoInc
 	apps         sync.Map
+
+	mu sync.Mutex
+	// hooked is the number of apps hooked at the moment
+	hooked       int
 	proxyStarted bool
+	proxyErrGrp  *errgroup.Group
+	proxyCancel  context.CancelFunc
 }
 
 func New(logger *zap.Logger, hook Hooks, proxy Proxy, tester Tester, client docker.Client) *Core {
@@ -106,20 +112,26 @@ func (c *Core) Hook(ctx context.Context, id uint64, opts models.HookOptions) err
 	hookCtx, hookCtxCancel := context.WithCancel(hookCtx)
 	hookCtx = context.WithValue(hookCtx, models.ErrGroupKey, hookErrGrp)
 
-	// create a new error group for the proxy
-	proxyErrGrp, _ := errgroup.WithContext(ctx)
-	proxyCtx := context.WithoutCancel(ctx) //so that main context doesn't cancel the proxyCtx to control the lifecycle of the proxy
-	proxyCtx, proxyCtxCancel := context.WithCancel(proxyCtx)
-	proxyCtx = context.WithValue(proxyCtx, models.ErrGroupKey, proxyErrGrp)
+	c.mu.Lock()
+	c.hooked++
+	c.mu.Unlock()
 
 	g.Go(func() error {
 		<-ctx.Done()
 
-		proxyCtxCancel()
-		err = proxyErrGrp.Wait()
-		if err != nil {
-			utils.LogError(c.logger, err, "failed to stop the proxy")
+		// the proxy is shared by the hooked apps, so it is stopped along with the last one
+		c.mu.Lock()
+		c.hooked--
+		last := c.hooked == 0
+		if last && c.proxyStarted {
+			c.proxyCancel()
+			err := c.proxyErrGrp.Wait()
+			if err != nil {
+				utils.LogError(c.logger, err, "failed to stop the proxy")
+			}
+			c.proxyStarted = false
 		}
+		c.mu.Unlock()
 
 		hookCtxCancel()
 		err := hookErrGrp.Wait()
@@ -129,7 +141,9 @@ func (c *Core) Hook(ctx context.Context, id uint64, opts models.HookOptions) err
 
 		//deleting in order to free the memory in case of rerecord. otherwise different app id will be created for the same app.
 		c.apps.Delete(id)
-		c.id = utils.AutoInc{}
+		if last {
+			c.id = utils.AutoInc{}
+		}
 
 		return nil
 	})
@@ -148,31 +162,39 @@ func (c *Core) Hook(ctx context.Context, id uint64, opts models.HookOptions) err
 		return hookErr
 	}
 
-	if c.proxyStarted {
-		c.logger.Debug("Proxy already started")
-		// return nil
-	}
-
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	// TODO: Hooks can be loaded multiple times but proxy should be started only once
-	// if there is another containerized app, then we need to pass new (ip:port) of proxy to the eBPF
-	// as the network namespace is different for each container and so is the keploy/proxy IP to communicate with the app.
-	// start proxy
-	err = c.Proxy.StartProxy(proxyCtx, ProxyOptions{
-		DNSIPv4Addr: a.KeployIPv4Addr(),
-		//DnsIPv6Addr: ""
-	})
-	if err != nil {
-		utils.LogError(c.logger, err, "failed to start proxy")
-		return hookErr
+	// the proxy is started once and shared by all the apps hooked at the same time, as their
+	// connections are told apart by the app id of the destination info.
+	c.mu.Lock()
+	if c.proxyStarted {
+		c.logger.Debug("Proxy already started")
+	} else {
+		// create a new error group for the proxy
+		proxyErrGrp, _ := errgroup.WithContext(ctx)
+		proxyCtx := context.WithoutCancel(ctx) //so that main context doesn't cancel the proxyCtx to control the lifecycle of the proxy
+		proxyCtx, proxyCtxCancel := context.WithCancel(proxyCtx)
+		proxyCtx = context.WithValue(proxyCtx, models.ErrGroupKey, proxyErrGrp)
+
+		err = c.Proxy.StartProxy(proxyCtx, ProxyOptions{
+			DNSIPv4Addr: a.KeployIPv4Addr(),
+			//DnsIPv6Addr: ""
+		})
+		if err != nil {
+			proxyCtxCancel()
+			c.mu.Unlock()
+			utils.LogError(c.logger, err, "failed to start proxy")
+			return hookErr
+		}
+		c.proxyErrGrp = proxyErrGrp
+		c.proxyCancel = proxyCtxCancel
+		c.proxyStarted = true
 	}
-
-	c.proxyStarted = true
+	c.mu.Unlock()
 
 	// For keploy test bench
 	if opts.EnableTesting {
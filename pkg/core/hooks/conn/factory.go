@@ -5,18 +5,22 @@ package conn
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
 
 	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/grpc"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 )
@@ -29,12 +33,17 @@ type Factory struct {
 	inactivityThreshold time.Duration
 	mutex               *sync.RWMutex
 	logger              *zap.Logger
+	// grpcParsers holds the state of the HTTP/2 connections, whose gRPC calls span several turns.
+	// The parser of a connection whose frames couldn't be parsed is nil, its data being dropped.
+	grpcParsers map[ID]*grpc.StreamParser
+	protos      pkg.ProtoRegistryCache
 }
 
 // NewFactory creates a new instance of the factory.
 func NewFactory(inactivityThreshold time.Duration, logger *zap.Logger) *Factory {
 	return &Factory{
 		connections:         make(map[ID]*Tracker),
+		grpcParsers:         make(map[ID]*grpc.StreamParser),
 		mutex:               &sync.RWMutex{},
 		inactivityThreshold: inactivityThreshold,
 		logger:              logger,
@@ -52,7 +61,12 @@ func (factory *Factory) ProcessActiveTrackers(ctx context.Context, t chan *model
 		case <-ctx.Done():
 			return
 		default:
-			ok, requestBuf, responseBuf, reqTimestampTest, resTimestampTest := tracker.IsComplete()
+			// the websocket sessions are captured once they are closed, or before their tracker is deleted
+			if session, ok := tracker.CompleteWebSocket(tracker.IsInactive(factory.inactivityThreshold)); ok {
+				captureWebSocket(ctx, factory.logger, t, session, opts)
+				continue
+			}
+			ok, requestBuf, responseBuf, reqTimestampTest, resTimestampTest, respChunks := tracker.IsComplete()
 			if ok {
 
 				if len(requestBuf) == 0 || len(responseBuf) == 0 {
@@ -60,6 +74,29 @@ func (factory *Factory) ProcessActiveTrackers(ctx context.Context, t chan *model
 					continue
 				}
 
+				parser, ok := factory.grpcParsers[connID]
+				if ok || bytes.HasPrefix(requestBuf, []byte(http2.ClientPreface)) {
+					if !ok {
+						parser = grpc.NewStreamParser(factory.protos.Get(ctx, factory.logger, opts.Proto))
+						factory.grpcParsers[connID] = parser
+					}
+					if parser == nil {
+						factory.logger.Debug("dropping the data of a grpc connection whose frames couldn't be parsed", zap.Any("Request Size", len(requestBuf)), zap.Any("Response Size", len(responseBuf)))
+						continue
+					}
+					streams, err := parser.Feed(requestBuf, responseBuf, reqTimestampTest, resTimestampTest)
+					if err != nil {
+						utils.LogError(factory.logger, err, "failed to parse the http2 frames of the grpc connection")
+						// The frames which follow can't be parsed reliably anymore, nor can they be parsed as HTTP/1.
+						factory.grpcParsers[connID] = nil
+						continue
+					}
+					for _, stream := range streams {
+						captureGrpc(ctx, factory.logger, t, stream, opts)
+					}
+					continue
+				}
+
 				parsedHTTPReq, err := pkg.ParseHTTPRequest(requestBuf)
 				if err != nil {
 					utils.LogError(factory.logger, err, "failed to parse the http request from byte array", zap.Any("requestBuf", requestBuf))
@@ -70,7 +107,7 @@ func (factory *Factory) ProcessActiveTrackers(ctx context.Context, t chan *model
 					utils.LogError(factory.logger, err, "failed to parse the http response from byte array", zap.Any("responseBuf", responseBuf))
 					continue
 				}
-				capture(ctx, factory.logger, t, parsedHTTPReq, parsedHTTPRes, reqTimestampTest, resTimestampTest, opts)
+				capture(ctx, factory.logger, t, parsedHTTPReq, parsedHTTPRes, responseBuf, respChunks, reqTimestampTest, resTimestampTest, opts)
 
 			} else if tracker.IsInactive(factory.inactivityThreshold) {
 				trackersToDelete = append(trackersToDelete, connID)
@@ -81,6 +118,7 @@ func (factory *Factory) ProcessActiveTrackers(ctx context.Context, t chan *model
 	// Delete all the processed trackers.
 	for _, key := range trackersToDelete {
 		delete(factory.connections, key)
+		delete(factory.grpcParsers, key)
 	}
 }
 
@@ -97,7 +135,7 @@ func (factory *Factory) GetOrCreate(connectionID ID) *Tracker {
 	return tracker
 }
 
-func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req *http.Request, resp *http.Response, reqTimeTest time.Time, resTimeTest time.Time, opts models.IncomingOptions) {
+func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req *http.Request, resp *http.Response, responseBuf []byte, respChunks []RespChunk, reqTimeTest time.Time, resTimeTest time.Time, opts models.IncomingOptions) {
 	reqBody, err := io.ReadAll(req.Body)
 	if err != nil {
 		utils.LogError(logger, err, "failed to read the http request body")
@@ -112,7 +150,9 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 	}()
 
 	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
+	// a stream may be cut off by the client, in which case the events sent so far are recorded
+	cutOff := errors.Is(err, io.ErrUnexpectedEOF) && pkg.IsHTTPStream(resp.Header)
+	if err != nil && !cutOff {
 		utils.LogError(logger, err, "failed to read the http response body")
 		return
 	}
@@ -121,6 +161,19 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 		logger.Debug("The request is a filtered request")
 		return
 	}
+
+	// the streamed responses are recorded as their events rather than as a body
+	var stream []models.HTTPStreamEvent
+	if pkg.IsHTTPStream(resp.Header) {
+		stream, err = streamEvents(resp.Header, slices.Contains(resp.TransferEncoding, "chunked"), responseBuf, respChunks, reqTimeTest)
+		if err != nil {
+			// the writes of the response can't be told apart, so all the events are timed with the end of the response
+			logger.Debug("failed to time the events of the streamed response", zap.Error(err))
+			parser := pkg.NewHTTPStreamParser(resp.Header)
+			stream = append(parser.Feed(respBody, resTimeTest.Sub(reqTimeTest)), parser.Close(resTimeTest.Sub(reqTimeTest))...)
+		}
+		respBody = []byte{}
+	}
 	var formData []models.FormData
 	if contentType := req.Header.Get("Content-Type"); strings.HasPrefix(contentType, "multipart/form-data") {
 		parts := strings.Split(contentType, ";")
@@ -140,7 +193,7 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 
 	t <- &models.TestCase{
 		Version: models.GetVersion(),
-		Name:    pkg.ToYamlHTTPHeader(req.Header)["Keploy-Test-Name"],
+		Name:    req.Header.Get("Keploy-Test-Name"),
 		Kind:    models.HTTP,
 		Created: time.Now().Unix(),
 		HTTPReq: models.HTTPReq{
@@ -151,16 +204,20 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 			// URL: fmt.Sprintf("%s://%s%s?%s", req.URL.Scheme, req.Host, req.URL.Path, req.URL.RawQuery),
 			URL: fmt.Sprintf("http://%s%s", req.Host, req.URL.RequestURI()),
 			//  URL: string(b),
-			Form:      formData,
-			Header:    pkg.ToYamlHTTPHeader(req.Header),
-			Body:      string(reqBody),
-			URLParams: pkg.URLParams(req),
-			Timestamp: reqTimeTest,
+			Form:         formData,
+			Header:       pkg.ToYamlHTTPHeader(req.Header),
+			Body:         string(reqBody),
+			BodyEncoding: models.EncodingOf(string(reqBody)),
+			URLParams:    pkg.URLParams(req),
+			Timestamp:    reqTimeTest,
 		},
 		HTTPResp: models.HTTPResp{
 			StatusCode:    resp.StatusCode,
 			Header:        pkg.ToYamlHTTPHeader(resp.Header),
 			Body:          string(respBody),
+			BodyEncoding:  models.EncodingOf(string(respBody)),
+			Stream:        stream,
+			StreamCutOff:  cutOff,
 			Timestamp:     resTimeTest,
 			StatusMessage: http.StatusText(resp.StatusCode),
 		},
@@ -169,6 +226,43 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 	}
 }
 
+func captureGrpc(_ context.Context, logger *zap.Logger, t chan *models.TestCase, stream models.GrpcStream, opts models.IncomingOptions) {
+	pseudoHeaders := stream.GrpcReq.Headers.PseudoHeaders
+	scheme := pseudoHeaders[":scheme"]
+	if scheme == "" {
+		scheme = "http"
+	}
+	// The filters are defined for http requests, so they are applied on the request carrying the call.
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL: &url.URL{
+			Scheme: scheme,
+			Host:   pseudoHeaders[":authority"],
+			Path:   pseudoHeaders[":path"],
+		},
+		Host:   pseudoHeaders[":authority"],
+		Header: http.Header{},
+	}
+	for key, value := range stream.GrpcReq.Headers.OrdinaryHeaders {
+		req.Header.Set(key, value)
+	}
+
+	if isFiltered(logger, req, opts) {
+		logger.Debug("The grpc request is a filtered request")
+		return
+	}
+
+	t <- &models.TestCase{
+		Version:  models.GetVersion(),
+		Name:     stream.GrpcReq.Headers.OrdinaryHeaders["keploy-test-name"],
+		Kind:     models.GRPC_EXPORT,
+		Created:  time.Now().Unix(),
+		GrpcReq:  stream.GrpcReq,
+		GrpcResp: stream.GrpcResp,
+		Noise:    map[string][]string{},
+	}
+}
+
 func extractFormData(logger *zap.Logger, body []byte, contentType string) []models.FormData {
 	boundary := ""
 	if strings.HasPrefix(contentType, "multipart/form-data") {
@@ -0,0 +1,124 @@
+//go:build linux
+
+package conn
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+var (
+	headerEnd = []byte("\r\n\r\n")
+	lastChunk = []byte("\r\n0\r\n\r\n")
+)
+
+// isStreamEnd tells whether a response buffer holds a whole chunked stream, i.e. whether its last chunk was written.
+func isStreamEnd(resp []byte) bool {
+	if !bytes.HasSuffix(resp, lastChunk) {
+		return false
+	}
+	i := bytes.Index(resp, headerEnd)
+	if i < 0 {
+		return false
+	}
+	parsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(resp[:i+len(headerEnd)])), nil)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(parsed.TransferEncoding, "chunked") && pkg.IsHTTPStream(parsed.Header)
+}
+
+// streamEvents splits a streamed response into its events, each of them timed with the write of the response
+// which completed it.
+func streamEvents(header http.Header, chunked bool, resp []byte, chunks []RespChunk, reqTime time.Time) ([]models.HTTPStreamEvent, error) {
+	i := bytes.Index(resp, headerEnd)
+	if i < 0 {
+		return nil, fmt.Errorf("the end of the headers of the response is missing")
+	}
+	parser := pkg.NewHTTPStreamParser(header)
+	body := &dechunker{chunked: chunked}
+
+	var (
+		events []models.HTTPStreamEvent
+		offset time.Duration
+		start  = i + len(headerEnd)
+	)
+	for _, chunk := range chunks {
+		offset = chunk.Timestamp.Sub(reqTime)
+		if chunk.End <= start {
+			continue
+		}
+		data, err := body.feed(resp[start:chunk.End])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, parser.Feed(data, offset)...)
+		start = chunk.End
+	}
+	if start < len(resp) {
+		data, err := body.feed(resp[start:])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, parser.Feed(data, offset)...)
+	}
+	return append(events, parser.Close(offset)...), nil
+}
+
+// dechunker decodes a chunked body as it is received.
+type dechunker struct {
+	chunked   bool
+	sizeLine  []byte
+	remaining int64
+	crlf      int
+	done      bool
+}
+
+// feed decodes the next piece of the body and returns its data.
+func (d *dechunker) feed(p []byte) ([]byte, error) {
+	if !d.chunked {
+		return p, nil
+	}
+	var data []byte
+	for len(p) > 0 && !d.done {
+		switch {
+		case d.remaining > 0:
+			n := min(d.remaining, int64(len(p)))
+			data = append(data, p[:n]...)
+			p = p[n:]
+			d.remaining -= n
+			if d.remaining == 0 {
+				d.crlf = 2
+			}
+		case d.crlf > 0:
+			p = p[1:]
+			d.crlf--
+		default:
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				d.sizeLine = append(d.sizeLine, p...)
+				return data, nil
+			}
+			d.sizeLine = append(d.sizeLine, p[:i]...)
+			p = p[i+1:]
+			size, _, _ := strings.Cut(strings.TrimSpace(string(d.sizeLine)), ";")
+			d.sizeLine = nil
+			n, err := strconv.ParseInt(size, 16, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid chunk size %q", size)
+			}
+			d.remaining = n
+			d.done = n == 0
+		}
+	}
+	return data, nil
+}
@@ -3,6 +3,7 @@
 package conn
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"sync"
@@ -15,6 +16,12 @@ import (
 	// "log"
 )
 
+// RespChunk is a write of the response: the end of its data in the response buffer and the time it was written.
+type RespChunk struct {
+	End       int
+	Timestamp time.Time
+}
+
 // Tracker is a routine-safe container that holds a conn with unique ID, and able to create new conn.
 type Tracker struct {
 	connID         ID
@@ -42,6 +49,8 @@ type Tracker struct {
 	userResps [][]byte
 	// userReqBufs is a slice of the Request data received in the user side on this conn
 	userReqs [][]byte
+	// userRespChunks is a slice of the writes of the Response data on this conn
+	userRespChunks [][]RespChunk
 
 	// req and resp are the buffers to store the request and response data for the current request
 	// reset after 2 seconds of inactivity
@@ -49,6 +58,13 @@ type Tracker struct {
 	reqSize  uint64
 	resp     []byte
 	req      []byte
+	// respChunks are the writes of the current response, which the events of the streamed responses are timed with
+	respChunks []RespChunk
+	// respEnded indicates that the current response is a stream whose last chunk was written
+	respEnded bool
+	// webSocket is the session of the conn once its request was upgraded to websocket, the data of the conn
+	// is then added to the session instead of the requests and the responses
+	webSocket *webSocketSession
 
 	// Additional fields to know when to capture request or response info
 	// reset after 2 seconds of inactivity
@@ -78,6 +94,7 @@ func NewTracker(connID ID, logger *zap.Logger) *Tracker {
 		userRespSizes:   []uint64{},
 		userReqSizes:    []uint64{},
 		userResps:       [][]byte{},
+		userRespChunks:  [][]RespChunk{},
 		userReqs:        [][]byte{},
 		mutex:           sync.RWMutex{},
 		logger:          logger,
@@ -106,8 +123,9 @@ func (conn *Tracker) decRecordTestCount() {
 	atomic.AddInt32(&conn.recTestCounter, -1)
 }
 
-// IsComplete checks if the current conn has valid request & response info to capture and also returns the request and response data buffer.
-func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time) {
+// IsComplete checks if the current conn has valid request & response info to capture and also returns the request and response data buffer,
+// along with the writes of the response.
+func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time, []RespChunk) {
 	conn.mutex.Lock()
 	defer conn.mutex.Unlock()
 
@@ -123,6 +141,7 @@ func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time) {
 	recordTraffic := false
 
 	requestBuf, responseBuf := []byte{}, []byte{}
+	var respChunks []RespChunk
 
 	var reqTimestamps, respTimestamp time.Time
 
@@ -170,6 +189,10 @@ func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time) {
 				//popping out the current request & response data
 				conn.userReqs = conn.userReqs[1:]
 				conn.userResps = conn.userResps[1:]
+				if len(conn.userRespChunks) > 0 {
+					respChunks = conn.userRespChunks[0]
+					conn.userRespChunks = conn.userRespChunks[1:]
+				}
 			} else {
 				conn.logger.Debug("no data buffer for request or response", zap.Any("Length of RecvBufQueue", len(conn.userReqs)), zap.Any("Length of SentBufQueue", len(conn.userResps)))
 			}
@@ -185,7 +208,7 @@ func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time) {
 		// // decrease the recTestCounter
 		conn.decRecordTestCount()
 		conn.logger.Debug("verified recording", zap.Any("recordTraffic", recordTraffic))
-	} else if conn.lastChunkWasResp && elapsedTime >= uint64(time.Second*2) { // Check if 2 seconds has passed since the last activity.
+	} else if conn.lastChunkWasResp && (elapsedTime >= uint64(time.Second*2) || conn.respEnded) { // Check if 2 seconds has passed since the last activity, or if the stream ended.
 		conn.logger.Debug("might be last request on the conn")
 
 		if len(conn.userReqSizes) > 0 && len(conn.kernelReqSizes) > 0 {
@@ -214,6 +237,7 @@ func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time) {
 				conn.userReqs = conn.userReqs[1:]
 
 				responseBuf = conn.resp
+				respChunks = conn.respChunks
 				respTimestamp = time.Now()
 			} else {
 				conn.logger.Debug("no data buffer for request", zap.Any("Length of RecvBufQueue", len(conn.userReqs)))
@@ -254,7 +278,7 @@ func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time) {
 		conn.logger.Debug(fmt.Sprintf("TestRequestTimestamp:%v || TestResponseTimestamp:%v", reqTimestamps, respTimestamp))
 	}
 
-	return recordTraffic, requestBuf, responseBuf, reqTimestamps, respTimestamp
+	return recordTraffic, requestBuf, responseBuf, reqTimestamps, respTimestamp, respChunks
 }
 
 // reset resets the conn's request and response data buffers.
@@ -266,6 +290,8 @@ func (conn *Tracker) reset() {
 	conn.respSize = 0
 	conn.resp = []byte{}
 	conn.req = []byte{}
+	conn.respChunks = nil
+	conn.respEnded = false
 }
 
 func (conn *Tracker) verifyRequestData(expectedRecvBytes, actualRecvBytes uint64) bool {
@@ -295,6 +321,11 @@ func (conn *Tracker) AddDataEvent(event SocketDataEvent) {
 
 	conn.logger.Debug(fmt.Sprintf("Got a data event from eBPF, Direction:%v || current Event Size:%v || ConnectionID:%v\n", event.Direction, event.MsgSize, event.ConnID))
 
+	if conn.webSocket != nil {
+		conn.addWebSocketData(event)
+		return
+	}
+
 	switch event.Direction {
 	case EgressTraffic:
 		// Capturing the timestamp of response as the response just started to come.
@@ -313,6 +344,9 @@ func (conn *Tracker) AddDataEvent(event SocketDataEvent) {
 		// Append the message (up to msgLength) to the conn's sent buffer
 		conn.resp = append(conn.resp, event.Msg[:msgLength]...)
 		conn.respSize += uint64(event.MsgSize)
+		conn.respChunks = append(conn.respChunks, RespChunk{End: len(conn.resp), Timestamp: ConvertUnixNanoToTime(event.TimestampNano)})
+		// the streams are captured as soon as they end, the inactivity of the conn is only waited for otherwise
+		conn.respEnded = isStreamEnd(conn.resp)
 
 		//Handling multiple request on same conn to support conn:keep-alive
 		if conn.firstRequest || conn.lastChunkWasReq {
@@ -329,6 +363,10 @@ func (conn *Tracker) AddDataEvent(event SocketDataEvent) {
 			conn.firstRequest = false
 		}
 
+		if bytes.HasPrefix(conn.resp, switchingProtocols) {
+			conn.startWebSocket(ConvertUnixNanoToTime(event.TimestampNano))
+		}
+
 	case IngressTraffic:
 		// Capturing the timestamp of request as the request just started to come.
 		if conn.isNewRequest {
@@ -357,6 +395,10 @@ func (conn *Tracker) AddDataEvent(event SocketDataEvent) {
 			conn.userResps = append(conn.userResps, conn.resp)
 			conn.resp = []byte{}
 
+			conn.userRespChunks = append(conn.userRespChunks, conn.respChunks)
+			conn.respChunks = nil
+			conn.respEnded = false
+
 			conn.lastChunkWasReq = true
 			conn.lastChunkWasResp = false
 
@@ -390,6 +432,9 @@ func (conn *Tracker) AddCloseEvent(event SocketCloseEvent) {
 		conn.logger.Debug("Changed close info timestamp due to new request", zap.Any("from", conn.closeTimestamp), zap.Any("to", event.TimestampNano))
 	}
 	conn.closeTimestamp = event.TimestampNano
+	if conn.webSocket != nil {
+		conn.webSocket.closed = true
+	}
 	conn.logger.Debug(fmt.Sprintf("Got a close event from eBPF on connectionId:%v\n", event.ConnID))
 }
 
@@ -0,0 +1,186 @@
+//go:build linux
+
+package conn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+var switchingProtocols = []byte("HTTP/1.1 101")
+
+// webSocketSession holds the traffic of a conn whose request was upgraded to websocket. The frames which follow
+// the handshake are kept as they were read and written, and are only parsed once the session is over.
+type webSocketSession struct {
+	req          []byte
+	resp         []byte
+	reqTimestamp time.Time
+	// start is the time the handshake response was written, the offsets of the messages are counted from it
+	start  time.Time
+	chunks []webSocketChunk
+	closed bool
+}
+
+// webSocketChunk is a read or a write of the frames of a session.
+type webSocketChunk struct {
+	origin    models.OriginType
+	data      []byte
+	timestamp time.Time
+}
+
+// upgradedHandshake returns the headers of a response which accepts a websocket handshake, along with the frames
+// written after them, ok being false if the response isn't one of them or its headers aren't whole yet.
+func upgradedHandshake(resp []byte) (headers []byte, frames []byte, ok bool) {
+	if !bytes.HasPrefix(resp, switchingProtocols) {
+		return nil, nil, false
+	}
+	i := bytes.Index(resp, headerEnd)
+	if i < 0 {
+		return nil, nil, false
+	}
+	parsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(resp[:i+len(headerEnd)])), nil)
+	if err != nil || !pkg.IsWebSocketUpgrade(parsed.Header) {
+		return nil, nil, false
+	}
+	return resp[:i+len(headerEnd)], resp[i+len(headerEnd):], true
+}
+
+// startWebSocket switches the conn to a websocket session once the handshake response is written. The handshake
+// request is the last one queued on the conn, so it is taken out of the queues of the http requests.
+func (conn *Tracker) startWebSocket(timestamp time.Time) {
+	headers, frames, ok := upgradedHandshake(conn.resp)
+	if !ok || len(conn.userReqs) == 0 {
+		return
+	}
+	session := &webSocketSession{
+		req:          conn.userReqs[len(conn.userReqs)-1],
+		resp:         append([]byte(nil), headers...),
+		reqTimestamp: timestamp,
+		start:        timestamp,
+	}
+	conn.userReqs = conn.userReqs[:len(conn.userReqs)-1]
+	if n := len(conn.userReqSizes); n > 0 {
+		conn.userReqSizes = conn.userReqSizes[:n-1]
+	}
+	if n := len(conn.kernelReqSizes); n > 0 {
+		conn.kernelReqSizes = conn.kernelReqSizes[:n-1]
+	}
+	if n := len(conn.reqTimestamps); n > 0 {
+		session.reqTimestamp = conn.reqTimestamps[n-1]
+		conn.reqTimestamps = conn.reqTimestamps[:n-1]
+	}
+	if len(frames) > 0 {
+		session.chunks = append(session.chunks, webSocketChunk{origin: models.FromServer, data: append([]byte(nil), frames...), timestamp: timestamp})
+	}
+	conn.reset()
+	conn.webSocket = session
+	conn.logger.Debug("the conn was upgraded to a websocket session")
+}
+
+// addWebSocketData adds the data of an event to the websocket session of the conn.
+func (conn *Tracker) addWebSocketData(event SocketDataEvent) {
+	msgLength := event.MsgSize
+	if event.MsgSize > EventBodyMaxSize {
+		msgLength = EventBodyMaxSize
+	}
+	origin := models.FromServer
+	if event.Direction == IngressTraffic {
+		origin = models.FromClient
+	}
+	conn.webSocket.chunks = append(conn.webSocket.chunks, webSocketChunk{
+		origin:    origin,
+		data:      append([]byte(nil), event.Msg[:msgLength]...),
+		timestamp: ConvertUnixNanoToTime(event.TimestampNano),
+	})
+}
+
+// CompleteWebSocket returns the websocket session of the conn once it is closed, or once the conn is inactive
+// since the tracker is about to be deleted.
+func (conn *Tracker) CompleteWebSocket(inactive bool) (*webSocketSession, bool) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.webSocket == nil || !(conn.webSocket.closed || inactive) {
+		return nil, false
+	}
+	session := conn.webSocket
+	conn.webSocket = nil
+	return session, true
+}
+
+// captureWebSocket turns a websocket session into a testcase: the handshake is its request and response, the
+// messages exchanged after it are replayed and compared in their order.
+func captureWebSocket(_ context.Context, logger *zap.Logger, t chan *models.TestCase, session *webSocketSession, opts models.IncomingOptions) {
+	req, err := pkg.ParseHTTPRequest(session.req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake request")
+		return
+	}
+	resp, err := pkg.ParseHTTPResponse(session.resp, req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake response")
+		return
+	}
+	if isFiltered(logger, req, opts) {
+		logger.Debug("The websocket handshake is a filtered request")
+		return
+	}
+
+	deflate := pkg.IsWebSocketDeflate(resp.Header.Get("Sec-WebSocket-Extensions"))
+	parsers := map[models.OriginType]*pkg.WebSocketParser{
+		models.FromClient: pkg.NewWebSocketParser(models.FromClient, deflate),
+		models.FromServer: pkg.NewWebSocketParser(models.FromServer, deflate),
+	}
+	var (
+		messages []models.WebSocketMessage
+		end      = session.start
+	)
+	for _, chunk := range session.chunks {
+		msgs, err := parsers[chunk.origin].Feed(chunk.data, chunk.timestamp.Sub(session.start))
+		if err != nil {
+			// the frames which follow can't be parsed reliably anymore, the messages so far are recorded
+			logger.Debug("failed to parse the websocket frames of the session", zap.Error(err))
+			break
+		}
+		for _, msg := range msgs {
+			if msg.Type == models.WebSocketPing || msg.Type == models.WebSocketPong {
+				continue
+			}
+			messages = append(messages, msg)
+		}
+		end = chunk.timestamp
+	}
+
+	t <- &models.TestCase{
+		Version: models.GetVersion(),
+		Name:    req.Header.Get("Keploy-Test-Name"),
+		Kind:    models.WebSocket,
+		Created: time.Now().Unix(),
+		HTTPReq: models.HTTPReq{
+			Method:     models.Method(req.Method),
+			ProtoMajor: req.ProtoMajor,
+			ProtoMinor: req.ProtoMinor,
+			URL:        fmt.Sprintf("http://%s%s", req.Host, req.URL.RequestURI()),
+			Header:     pkg.ToYamlHTTPHeader(req.Header),
+			URLParams:  pkg.URLParams(req),
+			Timestamp:  session.reqTimestamp,
+		},
+		HTTPResp: models.HTTPResp{
+			StatusCode:    resp.StatusCode,
+			Header:        pkg.ToYamlHTTPHeader(resp.Header),
+			Timestamp:     end,
+			StatusMessage: http.StatusText(resp.StatusCode),
+		},
+		WebSocket: messages,
+		Noise:     map[string][]string{},
+	}
+}
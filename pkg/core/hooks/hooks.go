@@ -36,6 +36,8 @@ func NewHooks(logger *zap.Logger, cfg *config.Config) *Hooks {
 		proxyIP6:  [4]uint32{0000, 0000, 0000, 0001},
 		proxyPort: cfg.ProxyPort,
 		dnsPort:   cfg.DNSPort,
+
+		dockerAppKeys: map[uint64]uint64{},
 	}
 }
 
@@ -87,7 +89,12 @@ type Hooks struct {
 	objects     bpfObjects
 	writev      link.Link
 	writevRet   link.Link
-	appID       uint64
+
+	// dockerAppKeys are the keys of the docker apps in the dockerAppRegistrationMap, by app id
+	dockerAppKeys map[uint64]uint64
+	loadMu        sync.Mutex
+	// loaded is the number of apps using the eBPF programs
+	loaded int
 }
 
 func (h *Hooks) Load(ctx context.Context, id uint64, opts core.HookCfg) error {
@@ -96,7 +103,19 @@ func (h *Hooks) Load(ctx context.Context, id uint64, opts core.HookCfg) error {
 		ID: id,
 	})
 
-	err := h.load(ctx, opts)
+	// the eBPF programs are loaded for the first app only, the next apps (e.g. the instances of
+	// a parallel test run) are just registered to them.
+	h.loadMu.Lock()
+	var err error
+	if h.loaded == 0 {
+		err = h.load(ctx, opts)
+	} else {
+		err = h.register(ctx, opts)
+	}
+	if err == nil {
+		h.loaded++
+	}
+	h.loadMu.Unlock()
 	if err != nil {
 		return err
 	}
@@ -109,7 +128,14 @@ func (h *Hooks) Load(ctx context.Context, id uint64, opts core.HookCfg) error {
 	g.Go(func() error {
 		defer utils.Recover(h.logger)
 		<-ctx.Done()
-		h.unLoad(ctx)
+		h.loadMu.Lock()
+		h.loaded--
+		if h.loaded == 0 {
+			h.unLoad(ctx)
+		} else {
+			h.unregister(id)
+		}
+		h.loadMu.Unlock()
 
 		//deleting in order to free the memory in case of rerecord.
 		h.sess.Delete(id)
@@ -408,6 +434,11 @@ func (h *Hooks) load(ctx context.Context, opts core.HookCfg) error {
 
 	h.logger.Info("keploy initialized and probes added to the kernel.")
 
+	return h.register(ctx, opts)
+}
+
+// register sends the info of the app, and of the proxy it is redirected to, to the eBPF programs.
+func (h *Hooks) register(ctx context.Context, opts core.HookCfg) error {
 	var clientInfo structs.ClientInfo = structs.ClientInfo{}
 
 	switch opts.Mode {
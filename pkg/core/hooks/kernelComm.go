@@ -23,8 +23,12 @@ func (h *Hooks) Get(_ context.Context, srcPort uint16) (*core.NetworkAddress, er
 	if err != nil {
 		return nil, err
 	}
-	// TODO : need to implement eBPF code to differentiate between different apps
-	s, ok := h.sess.Get(0)
+	// the docker apps are told apart by the client id set by the eBPF programs, the native apps
+	// all share the network namespace of keploy, so their connections belong to the first app.
+	s, ok := h.sess.Get(d.ClientID)
+	if !ok {
+		s, ok = h.sess.Get(0)
+	}
 	if !ok {
 		return nil, fmt.Errorf("session not found")
 	}
@@ -84,21 +88,39 @@ func (h *Hooks) SendAgentInfo(agentInfo structs.AgentInfo) error {
 	return nil
 }
 
-func (h *Hooks) SendDockerAppInfo(_ uint64, dockerAppInfo structs.DockerAppInfo) error {
-	if h.appID != 0 {
-		err := h.dockerAppRegistrationMap.Delete(h.appID)
+func (h *Hooks) SendDockerAppInfo(id uint64, dockerAppInfo structs.DockerAppInfo) error {
+	h.m.Lock()
+	defer h.m.Unlock()
+	if key, ok := h.dockerAppKeys[id]; ok {
+		err := h.dockerAppRegistrationMap.Delete(key)
 		if err != nil {
 			utils.LogError(h.logger, err, "failed to remove entry from dockerAppRegistrationMap")
 			return err
 		}
+		delete(h.dockerAppKeys, id)
 	}
 	r := rand.New(rand.NewSource(rand.Int63()))
-	randomNum := r.Uint64()
-	h.appID = randomNum
-	err := h.dockerAppRegistrationMap.Update(h.appID, dockerAppInfo, ebpf.UpdateAny)
+	key := r.Uint64()
+	err := h.dockerAppRegistrationMap.Update(key, dockerAppInfo, ebpf.UpdateAny)
 	if err != nil {
 		utils.LogError(h.logger, err, "failed to send the dockerAppInfo info to the ebpf program")
 		return err
 	}
+	h.dockerAppKeys[id] = key
 	return nil
 }
+
+// unregister removes the app from the eBPF programs, when the other apps still use them.
+func (h *Hooks) unregister(id uint64) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	if key, ok := h.dockerAppKeys[id]; ok {
+		if err := h.dockerAppRegistrationMap.Delete(key); err != nil {
+			utils.LogError(h.logger, err, "failed to remove entry from dockerAppRegistrationMap")
+		}
+		delete(h.dockerAppKeys, id)
+	}
+	if err := h.clientRegistrationMap.Delete(id); err != nil {
+		utils.LogError(h.logger, err, "failed to remove the app info from the ebpf program")
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"net"
 	"os"
 	"strings"
-	"sync"
 
 	"github.com/miekg/dns"
 	"go.keploy.io/server/v2/pkg/models"
@@ -62,11 +61,11 @@ func (p *Proxy) startUDPDNSServer(_ context.Context) error {
 	return nil
 }
 
-// For DNS caching
-var cache = struct {
-	sync.RWMutex
-	m map[string][]dns.RR
-}{m: make(map[string][]dns.RR)}
+// dnsAnswer is the answer of a question along with the response code sent for it.
+type dnsAnswer struct {
+	rrs   []dns.RR
+	rcode int
+}
 
 func generateCacheKey(name string, qtype uint16) string {
 	return fmt.Sprintf("%s-%s", name, dns.TypeToString[qtype])
@@ -82,49 +81,19 @@ func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	for _, question := range r.Question {
 		p.logger.Debug("", zap.Any("Record Type", question.Qtype), zap.Any("Received Query", question.Name))
 
-		key := generateCacheKey(question.Name, question.Qtype)
-
-		// Check if the answer is cached
-		cache.RLock()
-		answers, found := cache.m[key]
-		cache.RUnlock()
-
-		if !found {
-			// If not found in cache, resolve the DNS query only in case of record mode
-			//TODO: Add support for passThrough here using the src<->dst mapping
-			if models.GetMode() == models.MODE_RECORD {
-				answers = resolveDNSQuery(p.logger, question.Name)
-			}
-
-			if len(answers) == 0 {
-				// If the resolution failed, return a default A record with Proxy IP
-				if question.Qtype == dns.TypeA {
-					answers = []dns.RR{&dns.A{
-						Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 3600},
-						A:   net.ParseIP(p.IP4),
-					}}
-					p.logger.Debug("failed to resolve dns query hence sending proxy ip4", zap.Any("proxy Ip", p.IP4))
-				} else if question.Qtype == dns.TypeAAAA {
-					answers = []dns.RR{&dns.AAAA{
-						Hdr:  dns.RR_Header{Name: question.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 3600},
-						AAAA: net.ParseIP(p.IP6),
-					}}
-					p.logger.Debug("failed to resolve dns query hence sending proxy ip6", zap.Any("proxy Ip", p.IP6))
-
-				}
-
-				p.logger.Debug(fmt.Sprintf("Answers[when resolution failed for query:%v]:\n%v\n", question.Qtype, answers))
-			}
-
-			// Cache the answer
-			cache.Lock()
-			cache.m[key] = answers
-			cache.Unlock()
-			p.logger.Debug(fmt.Sprintf("Answers[after caching it]:\n%v\n", answers))
+		var answer dnsAnswer
+		if models.GetMode() == models.MODE_TEST {
+			// The answers are served from the mocks of the running test set, hence they are not cached.
+			answer = p.mockedDNSAnswer(question)
+		} else {
+			answer = p.cachedDNSAnswer(question)
+		}
+		if answer.rcode != dns.RcodeSuccess {
+			msg.Rcode = answer.rcode
 		}
 
-		p.logger.Debug(fmt.Sprintf("Answers[before appending to msg]:\n%v\n", answers))
-		msg.Answer = append(msg.Answer, answers...)
+		p.logger.Debug(fmt.Sprintf("Answers[before appending to msg]:\n%v\n", answer.rrs))
+		msg.Answer = append(msg.Answer, answer.rrs...)
 		p.logger.Debug(fmt.Sprintf("Answers[After appending to msg]:\n%v\n", msg.Answer))
 	}
 
@@ -137,6 +106,73 @@ func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	}
 }
 
+// cachedDNSAnswer returns the answer of the question from the cache of the recording session. The
+// question is resolved (and recorded as a mock) in record mode when it is asked for the first time.
+// The answers which couldn't be resolved aren't cached, so that they are resolved again.
+func (p *Proxy) cachedDNSAnswer(question dns.Question) dnsAnswer {
+	key := generateCacheKey(question.Name, question.Qtype)
+
+	// Check if the answer is cached
+	p.dnsMutex.RLock()
+	answer, found := p.dnsCache[key]
+	p.dnsMutex.RUnlock()
+	if found {
+		return answer
+	}
+
+	// If not found in cache, resolve the DNS query only in case of record mode
+	//TODO: Add support for passThrough here using the src<->dst mapping
+	resolved := false
+	if models.GetMode() == models.MODE_RECORD {
+		answer, resolved = p.recordDNSQuery(question)
+	}
+
+	if !hasAddress(answer.rrs) && isAddressQuestion(question) {
+		// If the resolution failed, return a default A/AAAA record with Proxy IP
+		answer = dnsAnswer{rrs: p.proxyIPAnswers(question), rcode: dns.RcodeSuccess}
+		resolved = false
+		p.logger.Debug(fmt.Sprintf("Answers[when resolution failed for query:%v]:\n%v\n", question.Qtype, answer.rrs))
+	}
+	if !resolved || answer.rcode == dns.RcodeServerFailure {
+		return answer
+	}
+
+	// Cache the answer
+	p.dnsMutex.Lock()
+	p.dnsCache[key] = answer
+	p.dnsMutex.Unlock()
+	p.logger.Debug(fmt.Sprintf("Answers[after caching it]:\n%v\n", answer.rrs))
+	return answer
+}
+
+// resetDNSCache clears the cached answers, so that the questions are resolved and recorded again
+// by a new recording session.
+func (p *Proxy) resetDNSCache() {
+	p.dnsMutex.Lock()
+	defer p.dnsMutex.Unlock()
+	p.dnsCache = make(map[string]dnsAnswer)
+}
+
+// proxyIPAnswers returns the proxy IP as the answer of an A or AAAA question, so that the application
+// connects to the proxy. There is no answer for the other types of questions.
+func (p *Proxy) proxyIPAnswers(question dns.Question) []dns.RR {
+	switch question.Qtype {
+	case dns.TypeA:
+		p.logger.Debug("sending proxy ip4 as the answer", zap.Any("proxy Ip", p.IP4))
+		return []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 3600},
+			A:   net.ParseIP(p.IP4),
+		}}
+	case dns.TypeAAAA:
+		p.logger.Debug("sending proxy ip6 as the answer", zap.Any("proxy Ip", p.IP6))
+		return []dns.RR{&dns.AAAA{
+			Hdr:  dns.RR_Header{Name: question.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 3600},
+			AAAA: net.ParseIP(p.IP6),
+		}}
+	}
+	return nil
+}
+
 // TODO: passThrough the dns queries rather than resolving them.
 func resolveDNSQuery(logger *zap.Logger, domain string) []dns.RR {
 	// Remove the last dot from the domain name if it exists
@@ -0,0 +1,206 @@
+//go:build linux
+
+package proxy
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+const resolvConfPath = "/etc/resolv.conf"
+
+// isAddressQuestion checks whether the question asks for an IP address, whose answer is rewritten to the proxy IP.
+func isAddressQuestion(question dns.Question) bool {
+	return question.Qtype == dns.TypeA || question.Qtype == dns.TypeAAAA
+}
+
+// hasAddress checks whether the answer contains an A or AAAA record.
+func hasAddress(rrs []dns.RR) bool {
+	for _, rr := range rrs {
+		switch rr.(type) {
+		case *dns.A, *dns.AAAA:
+			return true
+		}
+	}
+	return false
+}
+
+// recordDNSQuery resolves the question using the nameservers of the system and records the answer as
+// a DNS mock. Unlike the system resolver, the nameservers answer every type of question (SRV, TXT, MX,
+// CNAME, ...). If they can't be reached, A/AAAA questions are resolved by the system resolver, and the
+// answer is not recorded. It returns whether the answer was recorded.
+func (p *Proxy) recordDNSQuery(question dns.Question) (dnsAnswer, bool) {
+	reqTimestamp := time.Now()
+	resp, err := exchangeDNSQuery(question)
+	if err != nil {
+		p.logger.Debug("failed to resolve the dns query using the nameservers", zap.Any("query", question.Name), zap.Error(err))
+		if !isAddressQuestion(question) {
+			return dnsAnswer{rcode: dns.RcodeServerFailure}, false
+		}
+		var rrs []dns.RR
+		for _, rr := range resolveDNSQuery(p.logger, question.Name) {
+			if rr.Header().Rrtype == question.Qtype {
+				rrs = append(rrs, rr)
+			}
+		}
+		return dnsAnswer{rrs: rrs, rcode: dns.RcodeSuccess}, false
+	}
+
+	answer := dnsAnswer{rrs: resp.Answer, rcode: resp.Rcode}
+	p.recordDNSMock(question, answer, reqTimestamp, time.Now())
+	return answer, true
+}
+
+// exchangeDNSQuery sends the question to the nameservers of the system until one of them responds.
+func exchangeDNSQuery(question dns.Question) (*dns.Msg, error) {
+	conf, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.Servers) == 0 {
+		return nil, errors.New("no nameserver found in " + resolvConfPath)
+	}
+
+	query := new(dns.Msg)
+	query.SetQuestion(question.Name, question.Qtype)
+	query.RecursionDesired = true
+
+	client := &dns.Client{Timeout: time.Duration(conf.Timeout) * time.Second}
+	for _, server := range conf.Servers {
+		addr := net.JoinHostPort(server, conf.Port)
+		resp, _, err := client.Exchange(query, addr)
+		if err == nil && resp.Truncated {
+			// the answer doesn't fit in a udp message, so it is asked for again over tcp
+			tcpClient := &dns.Client{Net: "tcp", Timeout: client.Timeout}
+			resp, _, err = tcpClient.Exchange(query, addr)
+		}
+		if err != nil {
+			continue
+		}
+		return resp, nil
+	}
+	return nil, errors.New("none of the nameservers responded")
+}
+
+// recordDNSMock sends the answer of the question as a DNS mock to every recording session.
+func (p *Proxy) recordDNSMock(question dns.Question, answer dnsAnswer, reqTimestamp, resTimestamp time.Time) {
+	rrs := make([]string, 0, len(answer.rrs))
+	for _, rr := range answer.rrs {
+		rrs = append(rrs, rr.String())
+	}
+
+	for _, session := range p.sessions.GetAll() {
+		if session.Mode != models.MODE_RECORD || session.MC == nil {
+			continue
+		}
+		session.MC <- &models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.DNS,
+			Spec: models.MockSpec{
+				// DNS answers are not bound to a test case, so they are served during the whole test set.
+				Metadata: map[string]string{"type": "config"},
+				DNSReq: &models.DNSReq{
+					Name:  question.Name,
+					Qtype: dns.TypeToString[question.Qtype],
+				},
+				DNSResp: &models.DNSResp{
+					Rcode:   dns.RcodeToString[answer.rcode],
+					Answers: rrs,
+				},
+				ReqTimestampMock: reqTimestamp,
+				ResTimestampMock: resTimestamp,
+			},
+		}
+	}
+}
+
+// mockedDNSAnswer returns the recorded answer of the question. The addresses in the answer of an A/AAAA
+// question are rewritten to the proxy IP, so that the application connects to the proxy. The proxy IP is
+// also the answer of an A/AAAA question which wasn't recorded (or had no address).
+func (p *Proxy) mockedDNSAnswer(question dns.Question) dnsAnswer {
+	answer, found := p.findDNSMock(question)
+	if !found {
+		p.logger.Debug("no dns mock found for the query", zap.Any("query", question.Name), zap.Any("type", dns.TypeToString[question.Qtype]))
+	}
+	if !isAddressQuestion(question) {
+		return answer
+	}
+
+	if !hasAddress(answer.rrs) {
+		return dnsAnswer{rrs: p.proxyIPAnswers(question), rcode: dns.RcodeSuccess}
+	}
+	proxyIP4, proxyIP6 := net.ParseIP(p.IP4), net.ParseIP(p.IP6)
+	for i, rr := range answer.rrs {
+		switch rr := rr.(type) {
+		case *dns.A:
+			answer.rrs[i] = &dns.A{Hdr: rr.Hdr, A: proxyIP4}
+		case *dns.AAAA:
+			answer.rrs[i] = &dns.AAAA{Hdr: rr.Hdr, AAAA: proxyIP6}
+		}
+	}
+	return answer
+}
+
+// findDNSMock looks up the recorded answer of the question in the mocks of the running test sets.
+// The mock is flagged as used but not consumed, since the same question is asked again whenever
+// the application's cache expires.
+func (p *Proxy) findDNSMock(question dns.Question) (dnsAnswer, bool) {
+	var (
+		answer dnsAnswer
+		found  bool
+	)
+	p.MockManagers.Range(func(_, v interface{}) bool {
+		mockManager := v.(*MockManager)
+		mocks, err := mockManager.GetUnFilteredMocksByKind(models.DNS)
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to get the unfiltered mocks")
+			return true
+		}
+		for _, mock := range mocks {
+			if mock.Spec.DNSReq == nil || mock.Spec.DNSResp == nil {
+				continue
+			}
+			if !strings.EqualFold(mock.Spec.DNSReq.Name, question.Name) || mock.Spec.DNSReq.Qtype != dns.TypeToString[question.Qtype] {
+				continue
+			}
+			answer, err = decodeDNSResp(*mock.Spec.DNSResp)
+			if err != nil {
+				utils.LogError(p.logger, err, "failed to decode the dns mock", zap.Any("mock name", mock.Name))
+				continue
+			}
+			err = mockManager.FlagMockAsUsed(*mock)
+			if err != nil {
+				utils.LogError(p.logger, err, "failed to flag mock as used")
+			}
+			found = true
+			return false
+		}
+		return true
+	})
+	return answer, found
+}
+
+// decodeDNSResp parses the resource records of a DNS mock, which are stored in the zone file format.
+func decodeDNSResp(resp models.DNSResp) (dnsAnswer, error) {
+	rcode, ok := dns.StringToRcode[resp.Rcode]
+	if !ok {
+		return dnsAnswer{}, errors.New("unknown dns response code " + resp.Rcode)
+	}
+	answer := dnsAnswer{rcode: rcode}
+	for _, s := range resp.Answers {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			return dnsAnswer{}, err
+		}
+		answer.rrs = append(answer.rrs, rr)
+	}
+	return answer, nil
+}
@@ -0,0 +1,133 @@
+//go:build linux
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestDecodeDNSResp(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    models.DNSResp
+		rcode   int
+		rrs     []uint16
+		wantErr bool
+	}{
+		{name: "no answer", resp: models.DNSResp{Rcode: "NXDOMAIN"}, rcode: dns.RcodeNameError},
+		{
+			name: "srv",
+			resp: models.DNSResp{Rcode: "NOERROR", Answers: []string{
+				"_grpc._tcp.users.svc.\t30\tIN\tSRV\t0 50 8080 users-0.users.svc.",
+				"_grpc._tcp.users.svc.\t30\tIN\tSRV\t0 50 8080 users-1.users.svc.",
+			}},
+			rcode: dns.RcodeSuccess,
+			rrs:   []uint16{dns.TypeSRV, dns.TypeSRV},
+		},
+		{
+			name: "cname and address",
+			resp: models.DNSResp{Rcode: "NOERROR", Answers: []string{
+				"www.example.com.\t300\tIN\tCNAME\texample.com.",
+				"example.com.\t300\tIN\tA\t93.184.216.34",
+			}},
+			rcode: dns.RcodeSuccess,
+			rrs:   []uint16{dns.TypeCNAME, dns.TypeA},
+		},
+		{name: "unknown response code", resp: models.DNSResp{Rcode: "WHATEVER"}, wantErr: true},
+		{name: "invalid record", resp: models.DNSResp{Rcode: "NOERROR", Answers: []string{"not a record"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, err := decodeDNSResp(tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeDNSResp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if answer.rcode != tt.rcode {
+				t.Errorf("decodeDNSResp() rcode = %d, want %d", answer.rcode, tt.rcode)
+			}
+			if len(answer.rrs) != len(tt.rrs) {
+				t.Fatalf("decodeDNSResp() = %v, want %d records", answer.rrs, len(tt.rrs))
+			}
+			for i, rr := range answer.rrs {
+				if rr.Header().Rrtype != tt.rrs[i] {
+					t.Errorf("record %d = %v, want a record of type %s", i, rr, dns.TypeToString[tt.rrs[i]])
+				}
+			}
+		})
+	}
+}
+
+func dnsMock(name, qname, qtype, rcode string, answers ...string) *models.Mock {
+	return &models.Mock{
+		Name: name,
+		Kind: models.DNS,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{"type": "config"},
+			DNSReq:   &models.DNSReq{Name: qname, Qtype: qtype},
+			DNSResp:  &models.DNSResp{Rcode: rcode, Answers: answers},
+		},
+	}
+}
+
+func TestMockedDNSAnswer(t *testing.T) {
+	p := &Proxy{logger: zap.NewNop(), IP4: "127.0.0.1", IP6: "::1"}
+	m := NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), zap.NewNop())
+	m.SetUnFilteredMocks([]*models.Mock{
+		dnsMock("mock-0", "_grpc._tcp.users.svc.", "SRV", "NOERROR", "_grpc._tcp.users.svc.\t30\tIN\tSRV\t0 50 8080 users-0.users.svc."),
+		dnsMock("mock-1", "Example.com.", "A", "NOERROR", "example.com.\t300\tIN\tA\t93.184.216.34"),
+		dnsMock("mock-2", "missing.example.com.", "TXT", "NXDOMAIN"),
+		dnsMock("mock-3", "example.com.", "AAAA", "NOERROR", "example.com.\t300\tIN\tAAAA\t2606:2800:220:1:248:1893:25c8:1946"),
+	})
+	p.MockManagers.Store(uint64(1), m)
+
+	tests := []struct {
+		name     string
+		question dns.Question
+		rcode    int
+		want     []string // the data of the records
+	}{
+		{name: "srv", question: dns.Question{Name: "_grpc._tcp.users.svc.", Qtype: dns.TypeSRV}, want: []string{"0 50 8080 users-0.users.svc."}},
+		{name: "address rewritten to the proxy", question: dns.Question{Name: "example.com.", Qtype: dns.TypeA}, want: []string{"127.0.0.1"}},
+		{name: "ipv6 address rewritten to the proxy", question: dns.Question{Name: "example.com.", Qtype: dns.TypeAAAA}, want: []string{"::1"}},
+		{name: "recorded error", question: dns.Question{Name: "missing.example.com.", Qtype: dns.TypeTXT}, rcode: dns.RcodeNameError},
+		{name: "address not recorded", question: dns.Question{Name: "other.example.com.", Qtype: dns.TypeA}, want: []string{"127.0.0.1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer := p.mockedDNSAnswer(tt.question)
+			if answer.rcode != tt.rcode {
+				t.Errorf("mockedDNSAnswer() rcode = %d, want %d", answer.rcode, tt.rcode)
+			}
+			if len(answer.rrs) != len(tt.want) {
+				t.Fatalf("mockedDNSAnswer() = %v, want %v", answer.rrs, tt.want)
+			}
+			for i, rr := range answer.rrs {
+				data := rr.String()[len(rr.Header().String()):]
+				if data != tt.want[i] {
+					t.Errorf("record %d = %q, want %q", i, data, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// the mocks are flagged as used but kept, since the question is asked again once the cache expires
+	p.mockedDNSAnswer(dns.Question{Name: "example.com.", Qtype: dns.TypeA})
+	mocks, err := m.GetUnFilteredMocksByKind(models.DNS)
+	if err != nil || len(mocks) != 4 {
+		t.Errorf("GetUnFilteredMocksByKind() = %d mocks, %v, want the 4 mocks kept", len(mocks), err)
+	}
+	consumed := map[string]int{}
+	for _, mock := range m.GetConsumedMocks() {
+		consumed[mock.Name] = mock.Usage
+	}
+	if consumed["mock-1"] != 2 || consumed["mock-0"] != 1 {
+		t.Errorf("GetConsumedMocks() = %v, want mock-1 used twice and mock-0 once", consumed)
+	}
+}
@@ -54,7 +54,9 @@ func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 
 			// bestMatchedIndx := 0
 			// fuzzy match gives the index for the best matched generic mock
+			matchStart := time.Now()
 			matched, genericResponses, err := fuzzyMatch(ctx, genericRequests, mockDb)
+			integrations.MockMatch(mockDb, models.GENERIC, "", matched, err, matchStart)
 			if err != nil {
 				utils.LogError(logger, err, "error while matching generic mocks")
 			}
@@ -26,7 +26,7 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetUnFilteredMocks()
+			mocks, err := mockDb.GetUnFilteredMocksByKind(models.GENERIC)
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
 			}
@@ -35,9 +35,6 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 			var unfilteredMocks []*models.Mock
 
 			for _, mock := range mocks {
-				if mock.Kind != "Generic" {
-					continue
-				}
 				if mock.TestModeInfo.IsFiltered {
 					filteredMocks = append(filteredMocks, mock)
 				} else {
@@ -54,10 +51,10 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 			if index != -1 {
 				responseMock := make([]models.Payload, len(filteredMocks[index].Spec.GenericResponses))
 				copy(responseMock, filteredMocks[index].Spec.GenericResponses)
-				originalFilteredMock := *filteredMocks[index]
-				filteredMocks[index].TestModeInfo.IsFiltered = false
-				filteredMocks[index].TestModeInfo.SortOrder = math.MaxInt64
-				isUpdated := mockDb.UpdateUnFilteredMock(&originalFilteredMock, filteredMocks[index])
+				updatedMock := *filteredMocks[index]
+				updatedMock.TestModeInfo.IsFiltered = false
+				updatedMock.TestModeInfo.SortOrder = math.MaxInt64
+				isUpdated := mockDb.UpdateUnFilteredMock(filteredMocks[index], &updatedMock)
 				if isUpdated {
 					continue
 				}
@@ -78,11 +75,11 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 			if index != -1 {
 				responseMock := make([]models.Payload, len(totalMocks[index].Spec.GenericResponses))
 				copy(responseMock, totalMocks[index].Spec.GenericResponses)
-				originalFilteredMock := *totalMocks[index]
 				if totalMocks[index].TestModeInfo.IsFiltered {
-					totalMocks[index].TestModeInfo.IsFiltered = false
-					totalMocks[index].TestModeInfo.SortOrder = math.MaxInt64
-					isUpdated := mockDb.UpdateUnFilteredMock(&originalFilteredMock, totalMocks[index])
+					updatedMock := *totalMocks[index]
+					updatedMock.TestModeInfo.IsFiltered = false
+					updatedMock.TestModeInfo.SortOrder = math.MaxInt64
+					isUpdated := mockDb.UpdateUnFilteredMock(totalMocks[index], &updatedMock)
 					if isUpdated {
 						continue
 					}
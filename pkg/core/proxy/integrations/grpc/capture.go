@@ -0,0 +1,261 @@
+//go:build linux
+
+package grpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// constants for parsing the raw HTTP/2 traffic
+const (
+	// frameHeaderLen is the length of the header preceding the payload of every HTTP/2 frame.
+	frameHeaderLen = 9
+	// maxFrameSize is the largest frame size a peer can negotiate.
+	maxFrameSize = 1<<24 - 1
+	// initialHeaderTableSize is the size of the hpack dynamic table until a peer changes it.
+	initialHeaderTableSize = 4096
+)
+
+// StreamParser reassembles the gRPC calls served by the application from the HTTP/2 traffic of an
+// ingress connection. The traffic is captured in chunks which do not respect the frame boundaries,
+// hence the trailing partial frame of each direction is buffered until the rest of it arrives.
+type StreamParser struct {
+	sic *StreamInfoCollection
+
+	prefaceRead bool
+	reqBuf      []byte
+	respBuf     []byte
+
+	// The hpack decoders keep the dynamic table of each direction for the whole connection, so
+	// every header block has to go through them in order.
+	reqDecoder  *hpack.Decoder
+	respDecoder *hpack.Decoder
+
+	// headerBlocks holds the header block fragments of the streams waiting for a CONTINUATION frame.
+	reqHeaderBlocks  map[uint32]*headerBlock
+	respHeaderBlocks map[uint32]*headerBlock
+
+	reqTimestamps map[uint32]time.Time
+}
+
+type headerBlock struct {
+	fragment  []byte
+	endStream bool
+}
+
+func NewStreamParser(protos *pkg.ProtoRegistry) *StreamParser {
+	return &StreamParser{
+		sic:              NewStreamInfoCollection(protos),
+		reqDecoder:       hpack.NewDecoder(initialHeaderTableSize, nil),
+		respDecoder:      hpack.NewDecoder(initialHeaderTableSize, nil),
+		reqHeaderBlocks:  make(map[uint32]*headerBlock),
+		respHeaderBlocks: make(map[uint32]*headerBlock),
+		reqTimestamps:    make(map[uint32]time.Time),
+	}
+}
+
+// Feed parses the request and the response chunks of a turn on the connection and returns the
+// gRPC calls which were completed by the server in this turn. The request timestamp of a call is
+// the one of the turn in which its headers were received.
+func (p *StreamParser) Feed(req, resp []byte, reqTimestamp, respTimestamp time.Time) ([]models.GrpcStream, error) {
+	p.reqBuf = append(p.reqBuf, req...)
+	p.respBuf = append(p.respBuf, resp...)
+
+	if !p.prefaceRead {
+		if len(p.reqBuf) < len(http2.ClientPreface) {
+			return nil, nil
+		}
+		if !bytes.HasPrefix(p.reqBuf, []byte(http2.ClientPreface)) {
+			return nil, fmt.Errorf("connection does not start with the http2 client preface")
+		}
+		p.reqBuf = p.reqBuf[len(http2.ClientPreface):]
+		p.prefaceRead = true
+	}
+
+	var err error
+	p.reqBuf, err = p.parseFrames(p.reqBuf, func(frame http2.Frame) error {
+		return p.processRequestFrame(frame, reqTimestamp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var completed []models.GrpcStream
+	p.respBuf, err = p.parseFrames(p.respBuf, func(frame http2.Frame) error {
+		stream, done, err := p.processResponseFrame(frame, respTimestamp)
+		if done {
+			completed = append(completed, stream)
+		}
+		return err
+	})
+	return completed, err
+}
+
+// parseFrames hands over every complete frame of the buffer and returns the remaining bytes.
+func (p *StreamParser) parseFrames(buf []byte, process func(http2.Frame) error) ([]byte, error) {
+	for len(buf) >= frameHeaderLen {
+		length := int(buf[0])<<16 | int(buf[1])<<8 | int(buf[2])
+		if len(buf) < frameHeaderLen+length {
+			break
+		}
+		framer := http2.NewFramer(io.Discard, bytes.NewReader(buf[:frameHeaderLen+length]))
+		framer.SetMaxReadFrameSize(maxFrameSize)
+		// Every frame is read by a new framer, which can't validate the order of the frames.
+		framer.AllowIllegalReads = true
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return nil, fmt.Errorf("error reading frame %v", err)
+		}
+		if err := process(frame); err != nil {
+			return nil, err
+		}
+		buf = buf[frameHeaderLen+length:]
+	}
+	// Keep the remaining bytes in a fresh slice, so that the buffer of the caller is not retained.
+	return append([]byte(nil), buf...), nil
+}
+
+func (p *StreamParser) processRequestFrame(frame http2.Frame, timestamp time.Time) error {
+	switch frame := frame.(type) {
+	case *http2.SettingsFrame:
+		// The header table size announced by the client limits the dynamic table of the server's encoder.
+		if size, ok := frame.Value(http2.SettingHeaderTableSize); ok && !frame.IsAck() {
+			p.respDecoder.SetAllowedMaxDynamicTableSize(size)
+		}
+	case *http2.HeadersFrame:
+		if _, ok := p.reqTimestamps[frame.StreamID]; !ok {
+			p.reqTimestamps[frame.StreamID] = timestamp
+		}
+		p.reqHeaderBlocks[frame.StreamID] = &headerBlock{fragment: append([]byte(nil), frame.HeaderBlockFragment()...), endStream: frame.StreamEnded()}
+		if frame.HeadersEnded() {
+			return p.addRequestHeaders(frame.StreamID)
+		}
+	case *http2.ContinuationFrame:
+		block, ok := p.reqHeaderBlocks[frame.StreamID]
+		if !ok {
+			return fmt.Errorf("unexpected CONTINUATION for stream %d", frame.StreamID)
+		}
+		block.fragment = append(block.fragment, frame.HeaderBlockFragment()...)
+		if frame.HeadersEnded() {
+			return p.addRequestHeaders(frame.StreamID)
+		}
+	case *http2.DataFrame:
+		p.sic.AddPayloadForRequest(frame.StreamID, frame.Data())
+	case *http2.RSTStreamFrame:
+		p.resetStream(frame.StreamID)
+	}
+	return nil
+}
+
+func (p *StreamParser) processResponseFrame(frame http2.Frame, timestamp time.Time) (models.GrpcStream, bool, error) {
+	switch frame := frame.(type) {
+	case *http2.SettingsFrame:
+		// The header table size announced by the server limits the dynamic table of the client's encoder.
+		if size, ok := frame.Value(http2.SettingHeaderTableSize); ok && !frame.IsAck() {
+			p.reqDecoder.SetAllowedMaxDynamicTableSize(size)
+		}
+	case *http2.HeadersFrame:
+		p.respHeaderBlocks[frame.StreamID] = &headerBlock{fragment: append([]byte(nil), frame.HeaderBlockFragment()...), endStream: frame.StreamEnded()}
+		if frame.HeadersEnded() {
+			return p.addResponseHeaders(frame.StreamID, timestamp)
+		}
+	case *http2.ContinuationFrame:
+		block, ok := p.respHeaderBlocks[frame.StreamID]
+		if !ok {
+			return models.GrpcStream{}, false, fmt.Errorf("unexpected CONTINUATION for stream %d", frame.StreamID)
+		}
+		block.fragment = append(block.fragment, frame.HeaderBlockFragment()...)
+		if frame.HeadersEnded() {
+			return p.addResponseHeaders(frame.StreamID, timestamp)
+		}
+	case *http2.DataFrame:
+		p.sic.AddPayloadForResponse(frame.StreamID, frame.Data())
+		if frame.StreamEnded() {
+			stream, ok := p.completeStream(frame.StreamID, timestamp)
+			return stream, ok, nil
+		}
+	case *http2.RSTStreamFrame:
+		p.resetStream(frame.StreamID)
+	}
+	return models.GrpcStream{}, false, nil
+}
+
+func (p *StreamParser) addRequestHeaders(streamID uint32) error {
+	block := p.reqHeaderBlocks[streamID]
+	delete(p.reqHeaderBlocks, streamID)
+	pseudoHeaders, ordinaryHeaders, err := decodeHeaderBlock(p.reqDecoder, block.fragment)
+	if err != nil {
+		return err
+	}
+	p.sic.AddHeadersForRequest(streamID, pseudoHeaders, true)
+	p.sic.AddHeadersForRequest(streamID, ordinaryHeaders, false)
+	return nil
+}
+
+func (p *StreamParser) addResponseHeaders(streamID uint32, timestamp time.Time) (models.GrpcStream, bool, error) {
+	block := p.respHeaderBlocks[streamID]
+	delete(p.respHeaderBlocks, streamID)
+	pseudoHeaders, ordinaryHeaders, err := decodeHeaderBlock(p.respDecoder, block.fragment)
+	if err != nil {
+		return models.GrpcStream{}, false, err
+	}
+	// If this is the last fragment of a stream from the server, it has to be a trailer.
+	p.sic.AddHeadersForResponse(streamID, pseudoHeaders, true, block.endStream)
+	p.sic.AddHeadersForResponse(streamID, ordinaryHeaders, false, block.endStream)
+	if !block.endStream {
+		return models.GrpcStream{}, false, nil
+	}
+	stream, ok := p.completeStream(streamID, timestamp)
+	return stream, ok, nil
+}
+
+// completeStream returns the call of a stream closed by the server and clears it, as the stream ID
+// can be reused by the client. Plain HTTP/2 requests served on the same connection are dropped.
+func (p *StreamParser) completeStream(streamID uint32, timestamp time.Time) (models.GrpcStream, bool) {
+	grpcReq, grpcResp := p.sic.FetchCompletedStream(streamID)
+	grpcReq.Timestamp = p.reqTimestamps[streamID]
+	grpcResp.Timestamp = timestamp
+	p.resetStream(streamID)
+
+	if !strings.HasPrefix(grpcReq.Headers.OrdinaryHeaders["content-type"], "application/grpc") {
+		return models.GrpcStream{}, false
+	}
+	return models.GrpcStream{StreamID: streamID, GrpcReq: grpcReq, GrpcResp: grpcResp}, true
+}
+
+func (p *StreamParser) resetStream(streamID uint32) {
+	p.sic.ResetStream(streamID)
+	delete(p.reqTimestamps, streamID)
+	delete(p.reqHeaderBlocks, streamID)
+	delete(p.respHeaderBlocks, streamID)
+}
+
+// decodeHeaderBlock decodes a complete header block and splits the pseudo headers from the ordinary ones.
+func decodeHeaderBlock(decoder *hpack.Decoder, block []byte) (pseudoHeaders, ordinaryHeaders map[string]string, err error) {
+	hf, err := decoder.DecodeFull(block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode headers: %v", err)
+	}
+
+	pseudoHeaders = make(map[string]string)
+	ordinaryHeaders = make(map[string]string)
+
+	for _, header := range hf {
+		if header.IsPseudo() {
+			pseudoHeaders[header.Name] = header.Value
+		} else {
+			ordinaryHeaders[header.Name] = header.Value
+		}
+	}
+
+	return pseudoHeaders, ordinaryHeaders, nil
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"net"
 
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
@@ -14,9 +15,9 @@ import (
 	"golang.org/x/net/http2"
 )
 
-func decodeGrpc(ctx context.Context, logger *zap.Logger, _ []byte, clientConn net.Conn, _ *models.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+func decodeGrpc(ctx context.Context, logger *zap.Logger, _ []byte, clientConn net.Conn, _ *models.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions, protos *pkg.ProtoRegistry) error {
 	framer := http2.NewFramer(clientConn, clientConn)
-	srv := NewTranscoder(logger, framer, mockDb)
+	srv := NewTranscoder(logger, framer, mockDb, protos, opts.Noise)
 	// fake server in the test mode
 	err := srv.ListenAndServe(ctx)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net"
 
+	"go.keploy.io/server/v2/pkg"
 	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
@@ -14,7 +15,7 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-func encodeGrpc(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+func encodeGrpc(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions, protos *pkg.ProtoRegistry) error {
 
 	// Send the client preface to the server. This should be the first thing sent from the client.
 	_, err := destConn.Write(reqBuf)
@@ -27,7 +28,7 @@ func encodeGrpc(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 		return ctx.Err()
 	}
 
-	streamInfoCollection := NewStreamInfoCollection()
+	streamInfoCollection := NewStreamInfoCollection(protos)
 	reqFromClient := true
 
 	serverSideDecoder := NewDecoder()
@@ -7,6 +7,7 @@ import (
 	"context"
 	"net"
 
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
@@ -21,6 +22,8 @@ func init() {
 
 type Grpc struct {
 	logger *zap.Logger
+
+	protos pkg.ProtoRegistryCache
 }
 
 func NewGrpc(logger *zap.Logger) integrations.Integrations {
@@ -44,7 +47,7 @@ func (g *Grpc) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, m
 		return err
 	}
 
-	err = encodeGrpc(ctx, logger, reqBuf, src, dst, mocks, opts)
+	err = encodeGrpc(ctx, logger, reqBuf, src, dst, mocks, opts, g.protos.Get(ctx, g.logger, opts.Proto))
 	if err != nil {
 		utils.LogError(logger, err, "failed to encode the grpc message into the yaml")
 		return err
@@ -60,7 +63,7 @@ func (g *Grpc) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *models.Co
 		return err
 	}
 
-	err = decodeGrpc(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	err = decodeGrpc(ctx, logger, reqBuf, src, dstCfg, mockDb, opts, g.protos.Get(ctx, g.logger, opts.Proto))
 	if err != nil {
 		utils.LogError(logger, err, "failed to decode the grpc message from the yaml")
 		return err
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
 	"go.uber.org/zap"
 
 	"go.keploy.io/server/v2/pkg/models"
@@ -32,13 +33,18 @@ func FilterMocksRelatedToGrpc(mocks []*models.Mock) []*models.Mock {
 	return res
 }
 
-func FilterMocksBasedOnGrpcRequest(ctx context.Context, _ *zap.Logger, grpcReq models.GrpcReq, mockDb integrations.MockMemDb) (*models.Mock, error) {
+// FilterMocksBasedOnGrpcRequest finds the mock recorded for the given request and removes it from the
+// filtered mocks. Until the client half-closes the stream (streamEnded is false), only the mocks of
+// streams in which the server answered before the end of the request stream are considered, and the
+// messages received so far only need to be a prefix of the recorded ones. The messages stored as JSON
+// are compared field by field, ignoring the fields present in the noise.
+func FilterMocksBasedOnGrpcRequest(ctx context.Context, logger *zap.Logger, grpcReq models.GrpcReq, mockDb integrations.MockMemDb, streamEnded bool, noise map[string][]string) (*models.Mock, error) {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetFilteredMocks()
+			mocks, err := mockDb.GetFilteredMocksByKind(models.GRPC_EXPORT)
 			if err != nil {
 				return nil, fmt.Errorf("error while getting tsc mocks %v", err)
 			}
@@ -71,13 +77,12 @@ func FilterMocksBasedOnGrpcRequest(ctx context.Context, _ *zap.Logger, grpcReq m
 					continue
 				}
 
-				// Investigate the compression flag.
-				if have.Body.CompressionFlag != grpcReq.Body.CompressionFlag {
+				// Investigate the messages sent on the stream.
+				received := grpcReq.Messages()
+				if !matchStreamMessages(logger, have.Messages(), received, streamEnded, noise) {
 					continue
 				}
-
-				// Investigate the body.
-				if have.Body.DecodedData != grpcReq.Body.DecodedData {
+				if !streamEnded && !respondsBeforeHalfClose(*mock.Spec.GRPCResp, len(received)) {
 					continue
 				}
 
@@ -97,3 +102,51 @@ func FilterMocksBasedOnGrpcRequest(ctx context.Context, _ *zap.Logger, grpcReq m
 		}
 	}
 }
+
+// matchStreamMessages compares the recorded messages of a stream with the received ones. If the
+// stream is still open, the received messages only need to be a prefix of the recorded messages.
+func matchStreamMessages(logger *zap.Logger, have, received []models.GrpcStreamMessage, streamEnded bool, noise map[string][]string) bool {
+	if len(received) > len(have) || (streamEnded && len(received) != len(have)) {
+		return false
+	}
+	for i := range received {
+		if !matchMessage(logger, have[i].GrpcLengthPrefixedMessage, received[i].GrpcLengthPrefixedMessage, noise) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchMessage(logger *zap.Logger, have, received models.GrpcLengthPrefixedMessage, noise map[string][]string) bool {
+	// Investigate the compression flag.
+	if have.CompressionFlag != received.CompressionFlag {
+		return false
+	}
+
+	// Messages decoded using their descriptor are compared field by field, so that the order
+	// of the fields on the wire does not matter.
+	if have.DecodedJSON != "" && received.DecodedJSON != "" {
+		exp, act := have.DecodedJSON, received.DecodedJSON
+		validatedJSON, err := matcherUtils.ValidateAndMarshalJSON(logger, &exp, &act)
+		if err != nil || !validatedJSON.IsIdentical() {
+			return false
+		}
+		result, err := matcherUtils.JSONDiffWithNoiseControl(validatedJSON, noise, false)
+		return err == nil && result.IsExact()
+	}
+
+	// Investigate the body. A message stored as JSON can't be compared with protoscope text, which
+	// happens when the protobuf definitions were only configured while recording or while replaying.
+	return have.DecodedJSON == received.DecodedJSON && have.DecodedData == received.DecodedData
+}
+
+// respondsBeforeHalfClose reports whether the recorded server sent a message after receiving at most
+// the given number of request messages, i.e. without waiting for the client to close its side.
+func respondsBeforeHalfClose(resp models.GrpcResp, received int) bool {
+	for _, msg := range resp.Stream {
+		if msg.AfterRequests > 0 && msg.AfterRequests <= received {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+//go:build linux
+
+package grpc
+
+import (
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func scopeMsg(data string) models.GrpcStreamMessage {
+	return models.GrpcStreamMessage{GrpcLengthPrefixedMessage: models.GrpcLengthPrefixedMessage{DecodedData: data}}
+}
+
+func jsonMsg(data string) models.GrpcStreamMessage {
+	return models.GrpcStreamMessage{GrpcLengthPrefixedMessage: models.GrpcLengthPrefixedMessage{DecodedJSON: data}}
+}
+
+func TestMatchStreamMessages(t *testing.T) {
+	recorded := []models.GrpcStreamMessage{scopeMsg("1: 1"), scopeMsg("1: 2"), scopeMsg("1: 3")}
+	tests := []struct {
+		name        string
+		have        []models.GrpcStreamMessage
+		received    []models.GrpcStreamMessage
+		streamEnded bool
+		noise       map[string][]string
+		want        bool
+	}{
+		{name: "prefix of an open stream", have: recorded, received: recorded[:2], want: true},
+		{name: "prefix of an ended stream", have: recorded, received: recorded[:2], streamEnded: true},
+		{name: "whole ended stream", have: recorded, received: recorded, streamEnded: true, want: true},
+		{name: "more messages than recorded", have: recorded[:1], received: recorded},
+		{name: "other message", have: recorded, received: []models.GrpcStreamMessage{scopeMsg("1: 1"), scopeMsg("1: 5")}},
+		{name: "no message yet", have: recorded, want: true},
+		{name: "json fields in another order", have: []models.GrpcStreamMessage{jsonMsg(`{"a":1,"b":"x"}`)}, received: []models.GrpcStreamMessage{jsonMsg(`{"b":"x","a":1}`)}, streamEnded: true, want: true},
+		{name: "json field in the noise", have: []models.GrpcStreamMessage{jsonMsg(`{"a":1,"ts":"1"}`)}, received: []models.GrpcStreamMessage{jsonMsg(`{"a":1,"ts":"2"}`)}, streamEnded: true, noise: map[string][]string{"ts": {}}, want: true},
+		{name: "json field out of the noise", have: []models.GrpcStreamMessage{jsonMsg(`{"a":1,"ts":"1"}`)}, received: []models.GrpcStreamMessage{jsonMsg(`{"a":1,"ts":"2"}`)}, streamEnded: true},
+		{name: "json against protoscope", have: []models.GrpcStreamMessage{jsonMsg(`{"a":1}`)}, received: []models.GrpcStreamMessage{scopeMsg("1: 1")}, streamEnded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchStreamMessages(zap.NewNop(), tt.have, tt.received, tt.streamEnded, tt.noise); got != tt.want {
+				t.Errorf("matchStreamMessages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchMessageCompression(t *testing.T) {
+	have := models.GrpcLengthPrefixedMessage{DecodedData: "1: 1"}
+	received := models.GrpcLengthPrefixedMessage{CompressionFlag: 1, DecodedData: "1: 1"}
+	if matchMessage(zap.NewNop(), have, received, nil) {
+		t.Error("matchMessage() = true, want the compressed message told apart")
+	}
+}
+
+func TestRespondsBeforeHalfClose(t *testing.T) {
+	resp := func(after ...int) models.GrpcResp {
+		var r models.GrpcResp
+		for _, n := range after {
+			r.Stream = append(r.Stream, models.GrpcStreamMessage{AfterRequests: n})
+		}
+		return r
+	}
+	tests := []struct {
+		name     string
+		resp     models.GrpcResp
+		received int
+		want     bool
+	}{
+		{name: "unary", resp: resp(), received: 1},
+		{name: "answered after the half close", resp: resp(0, 0), received: 3},
+		{name: "answered after the first message", resp: resp(1, 2), received: 1, want: true},
+		{name: "answered after more messages", resp: resp(2), received: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := respondsBeforeHalfClose(tt.resp, tt.received); got != tt.want {
+				t.Errorf("respondsBeforeHalfClose() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
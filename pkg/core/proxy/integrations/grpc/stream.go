@@ -4,13 +4,10 @@ package grpc
 
 import (
 	"context"
-	"encoding/binary"
-	"fmt"
 	"sync"
 	"time"
 
-	"github.com/protocolbuffers/protoscope"
-
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/models"
 )
 
@@ -22,11 +19,26 @@ type StreamInfoCollection struct {
 	StreamInfo       map[uint32]models.GrpcStream
 	ReqTimestampMock time.Time
 	ResTimestampMock time.Time
+	// states holds the per stream bookkeeping which is not a part of the mock itself.
+	states map[uint32]*streamState
+	// protos is used to decode the messages as JSON. It is nil if no protobuf definitions are configured.
+	protos *pkg.ProtoRegistry
+}
+
+// streamState keeps the partially received length-prefixed messages of a stream,
+// since a single gRPC message can be split across several DATA frames and a single
+// DATA frame can carry several messages.
+type streamState struct {
+	openedAt time.Time
+	reqBuf   []byte
+	respBuf  []byte
 }
 
-func NewStreamInfoCollection() *StreamInfoCollection {
+func NewStreamInfoCollection(protos *pkg.ProtoRegistry) *StreamInfoCollection {
 	return &StreamInfoCollection{
 		StreamInfo: make(map[uint32]models.GrpcStream),
+		states:     make(map[uint32]*streamState),
+		protos:     protos,
 	}
 }
 
@@ -37,9 +49,20 @@ func (sic *StreamInfoCollection) InitialiseStream(streamID uint32) {
 	_, ok := sic.StreamInfo[streamID]
 	if !ok {
 		sic.StreamInfo[streamID] = models.NewGrpcStream(streamID)
+		sic.states[streamID] = &streamState{openedAt: time.Now()}
 	}
 }
 
+// state returns the bookkeeping of the stream. The caller must hold the mutex.
+func (sic *StreamInfoCollection) state(streamID uint32) *streamState {
+	st, ok := sic.states[streamID]
+	if !ok {
+		st = &streamState{openedAt: time.Now()}
+		sic.states[streamID] = st
+	}
+	return st
+}
+
 func (sic *StreamInfoCollection) AddHeadersForRequest(streamID uint32, headers map[string]string, isPseudo bool) {
 	// Initialise the stream before acquiring the lock for yourself.
 	sic.InitialiseStream(streamID)
@@ -81,36 +104,66 @@ func (sic *StreamInfoCollection) AddHeadersForResponse(streamID uint32, headers
 // AddPayloadForRequest adds the DATA frame to the stream.
 // A data frame always appears after at least one header frame. Hence, we implicitly
 // assume that the stream has been initialised.
+// Every complete length-prefixed message found in the frame is appended to the request stream,
+// while a trailing partial message is buffered until the next DATA frame arrives.
 func (sic *StreamInfoCollection) AddPayloadForRequest(streamID uint32, payload []byte) {
 	sic.mutex.Lock()
 	defer sic.mutex.Unlock()
 
+	st := sic.state(streamID)
+	var msgs [][]byte
+	msgs, st.reqBuf = pkg.SplitLengthPrefixedMessages(append(st.reqBuf, payload...))
+
 	// We cannot modify non pointer values in nested entries in map.
 	// Create a copy and overwrite it.
 	info := sic.StreamInfo[streamID]
-	info.GrpcReq.Body = createLengthPrefixedMessageFromPayload(payload)
+	md := sic.protos.InputType(info.GrpcReq.Headers.PseudoHeaders[KLabelForPath])
+	for _, msg := range msgs {
+		streamMsg := models.GrpcStreamMessage{
+			GrpcLengthPrefixedMessage: pkg.DecodeLengthPrefixedMessage(msg, md),
+			Offset:                    time.Since(st.openedAt),
+		}
+		if len(info.GrpcReq.Stream) == 0 {
+			info.GrpcReq.Body = streamMsg.GrpcLengthPrefixedMessage
+		}
+		info.GrpcReq.Stream = append(info.GrpcReq.Stream, streamMsg)
+	}
 	sic.StreamInfo[streamID] = info
 }
 
 // AddPayloadForResponse adds the DATA frame to the stream.
 // A data frame always appears after at least one header frame. Hence, we implicitly
 // assume that the stream has been initialised.
+// Each response message remembers how many request messages preceded it, so that the
+// replay of a bidi stream can interleave the responses with the requests of the client.
 func (sic *StreamInfoCollection) AddPayloadForResponse(streamID uint32, payload []byte) {
 	sic.mutex.Lock()
 	defer sic.mutex.Unlock()
 
+	st := sic.state(streamID)
+	var msgs [][]byte
+	msgs, st.respBuf = pkg.SplitLengthPrefixedMessages(append(st.respBuf, payload...))
+
 	// We cannot modify non pointer values in nested entries in map.
 	// Create a copy and overwrite it.
 	info := sic.StreamInfo[streamID]
-	info.GrpcResp.Body = createLengthPrefixedMessageFromPayload(payload)
+	md := sic.protos.OutputType(info.GrpcReq.Headers.PseudoHeaders[KLabelForPath])
+	for _, msg := range msgs {
+		streamMsg := models.GrpcStreamMessage{
+			GrpcLengthPrefixedMessage: pkg.DecodeLengthPrefixedMessage(msg, md),
+			Offset:                    time.Since(st.openedAt),
+			AfterRequests:             len(info.GrpcReq.Stream),
+		}
+		if len(info.GrpcResp.Stream) == 0 {
+			info.GrpcResp.Body = streamMsg.GrpcLengthPrefixedMessage
+		}
+		info.GrpcResp.Stream = append(info.GrpcResp.Stream, streamMsg)
+	}
 	sic.StreamInfo[streamID] = info
 }
 
 func (sic *StreamInfoCollection) PersistMockForStream(_ context.Context, streamID uint32, mocks chan<- *models.Mock) {
-	sic.mutex.Lock()
-	defer sic.mutex.Unlock()
-	grpcReq := sic.StreamInfo[streamID].GrpcReq
-	grpcResp := sic.StreamInfo[streamID].GrpcResp
+	grpcReq, grpcResp := sic.FetchCompletedStream(streamID)
 	// save the mock
 	mocks <- &models.Mock{
 		Version: models.GetVersion(),
@@ -125,6 +178,21 @@ func (sic *StreamInfoCollection) PersistMockForStream(_ context.Context, streamI
 	}
 }
 
+// FetchCompletedStream returns the request and the response of a stream closed by the server,
+// in the form they are persisted in.
+func (sic *StreamInfoCollection) FetchCompletedStream(streamID uint32) (models.GrpcReq, models.GrpcResp) {
+	sic.mutex.Lock()
+	defer sic.mutex.Unlock()
+	grpcReq := sic.StreamInfo[streamID].GrpcReq
+	grpcResp := sic.StreamInfo[streamID].GrpcResp
+	// Unary calls are stored in the Body alone to keep the mocks readable and backward compatible.
+	if len(grpcReq.Stream) <= 1 && len(grpcResp.Stream) <= 1 {
+		grpcReq.Stream = nil
+		grpcResp.Stream = nil
+	}
+	return grpcReq, grpcResp
+}
+
 func (sic *StreamInfoCollection) FetchRequestForStream(streamID uint32) models.GrpcReq {
 	sic.mutex.Lock()
 	defer sic.mutex.Unlock()
@@ -137,46 +205,5 @@ func (sic *StreamInfoCollection) ResetStream(streamID uint32) {
 	defer sic.mutex.Unlock()
 
 	delete(sic.StreamInfo, streamID)
-}
-
-func createLengthPrefixedMessageFromPayload(data []byte) models.GrpcLengthPrefixedMessage {
-	msg := models.GrpcLengthPrefixedMessage{}
-
-	// If the body is not length prefixed, we return the default value.
-	if len(data) < 5 {
-		return msg
-	}
-
-	// The first byte is the compression flag.
-	msg.CompressionFlag = uint(data[0])
-
-	// The next 4 bytes are message length.
-	msg.MessageLength = binary.BigEndian.Uint32(data[1:5])
-
-	// The payload could be empty. We only parse it if it is present.
-	if len(data) >= 5 {
-		// Use protoscope to decode the message.
-		msg.DecodedData = protoscope.Write(data[5:], protoscope.WriterOptions{})
-	}
-
-	return msg
-}
-
-func createPayloadFromLengthPrefixedMessage(msg models.GrpcLengthPrefixedMessage) ([]byte, error) {
-	scanner := protoscope.NewScanner(msg.DecodedData)
-	encodedData, err := scanner.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("could not encode grpc msg using protoscope: %v", err)
-	}
-
-	// Note that the encoded length is present in the msg, but it is also equal to the len of encodedData.
-	// We should give the preference to the length of encodedData, since the mocks might have been altered.
-
-	// Reserve 1 byte for compression flag, 4 bytes for length capture.
-	payload := make([]byte, 1+4)
-	payload[0] = uint8(msg.CompressionFlag)
-	binary.BigEndian.PutUint32(payload[1:5], uint32(len(encodedData)))
-	payload = append(payload, encodedData...)
-
-	return payload, nil
+	delete(sic.states, streamID)
 }
@@ -0,0 +1,101 @@
+//go:build linux
+
+package grpc
+
+import (
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// lengthPrefixed returns the wire format of a message holding the varint 1: value.
+func lengthPrefixed(value byte) []byte {
+	return []byte{0, 0, 0, 0, 2, 0x08, value}
+}
+
+func TestStreamInfoCollectionMessages(t *testing.T) {
+	type frame struct {
+		request bool
+		payload []byte
+	}
+	two := append(lengthPrefixed(1), lengthPrefixed(2)...)
+	tests := []struct {
+		name         string
+		frames       []frame
+		wantReq      []string
+		wantResp     []string
+		wantAfter    []int
+		wantStreamed bool
+		wantReqBody  string
+		wantRespBody string
+	}{
+		{
+			name:         "unary",
+			frames:       []frame{{true, lengthPrefixed(1)}, {false, lengthPrefixed(2)}},
+			wantReq:      []string{"1: 1\n"},
+			wantResp:     []string{"1: 2\n"},
+			wantAfter:    []int{1},
+			wantReqBody:  "1: 1\n",
+			wantRespBody: "1: 2\n",
+		},
+		{
+			name:         "message split across frames",
+			frames:       []frame{{true, lengthPrefixed(1)[:3]}, {true, lengthPrefixed(1)[3:]}, {false, lengthPrefixed(3)}},
+			wantReq:      []string{"1: 1\n"},
+			wantResp:     []string{"1: 3\n"},
+			wantAfter:    []int{1},
+			wantReqBody:  "1: 1\n",
+			wantRespBody: "1: 3\n",
+		},
+		{
+			name:         "bidi with several messages per frame",
+			frames:       []frame{{true, two}, {false, lengthPrefixed(5)}, {true, lengthPrefixed(3)}, {false, append(lengthPrefixed(6), lengthPrefixed(7)[:4]...)}, {false, lengthPrefixed(7)[4:]}},
+			wantReq:      []string{"1: 1\n", "1: 2\n", "1: 3\n"},
+			wantResp:     []string{"1: 5\n", "1: 6\n", "1: 7\n"},
+			wantAfter:    []int{2, 3, 3},
+			wantStreamed: true,
+			wantReqBody:  "1: 1\n",
+			wantRespBody: "1: 5\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sic := NewStreamInfoCollection(nil)
+			sic.AddHeadersForRequest(1, map[string]string{KLabelForPath: "/pkg.Service/Method"}, true)
+			sic.AddHeadersForResponse(1, map[string]string{":status": "200"}, true, false)
+			for _, f := range tt.frames {
+				if f.request {
+					sic.AddPayloadForRequest(1, f.payload)
+				} else {
+					sic.AddPayloadForResponse(1, f.payload)
+				}
+			}
+
+			live := sic.StreamInfo[1]
+			var gotReq, gotResp []string
+			var gotAfter []int
+			for _, msg := range live.GrpcReq.Stream {
+				gotReq = append(gotReq, msg.DecodedData)
+			}
+			for _, msg := range live.GrpcResp.Stream {
+				gotResp = append(gotResp, msg.DecodedData)
+				gotAfter = append(gotAfter, msg.AfterRequests)
+			}
+			if !reflect.DeepEqual(gotReq, tt.wantReq) || !reflect.DeepEqual(gotResp, tt.wantResp) || !reflect.DeepEqual(gotAfter, tt.wantAfter) {
+				t.Errorf("messages = %q, %q after %v requests, want %q, %q after %v", gotReq, gotResp, gotAfter, tt.wantReq, tt.wantResp, tt.wantAfter)
+			}
+
+			req, resp := sic.FetchCompletedStream(1)
+			if streamed := req.Stream != nil || resp.Stream != nil; streamed != tt.wantStreamed {
+				t.Errorf("persisted as a stream = %v, want %v", streamed, tt.wantStreamed)
+			}
+			if req.Body.DecodedData != tt.wantReqBody || resp.Body.DecodedData != tt.wantRespBody {
+				t.Errorf("bodies = %q, %q, want %q, %q", req.Body.DecodedData, resp.Body.DecodedData, tt.wantReqBody, tt.wantRespBody)
+			}
+			if got := (models.GrpcReq{Body: req.Body, Stream: req.Stream}).Messages(); len(got) != len(tt.wantReq) {
+				t.Errorf("persisted request messages = %d, want %d", len(got), len(tt.wantReq))
+			}
+		})
+	}
+}
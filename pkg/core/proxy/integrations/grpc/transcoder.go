@@ -6,8 +6,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 
 	"go.uber.org/zap"
@@ -21,18 +25,59 @@ type Transcoder struct {
 	logger  *zap.Logger
 	framer  *http2.Framer
 	decoder *hpack.Decoder
+	// streams holds the client streams which have already been matched with a mock.
+	streams map[uint32]*replayStream
+	protos  *pkg.ProtoRegistry
+	// noise holds the fields of the messages ignored while matching them with the mocks.
+	noise map[string][]string
+	// writeMu serializes the writes on the framer, since the responses of the streams are written by
+	// their own goroutines while the frames of the client are read.
+	writeMu sync.Mutex
+	writers sync.WaitGroup
 }
 
-func NewTranscoder(logger *zap.Logger, framer *http2.Framer, mockDb integrations.MockMemDb) *Transcoder {
+// replayStream is a client stream matched with a mock whose response is being written back.
+type replayStream struct {
+	mock *models.Mock
+	// released is the number of response messages handed over to the writer of the stream.
+	released int
+	// checked is the number of request messages compared with the mock.
+	checked int
+	cancel  context.CancelFunc
+
+	mu sync.Mutex
+	// bursts holds the parts of the response waiting to be written, in order.
+	bursts []responseBurst
+	notify chan struct{}
+}
+
+// responseBurst is a part of the mocked response that the recorded server had sent at once, i.e.
+// without receiving any request message in between.
+type responseBurst struct {
+	msgs     []models.GrpcStreamMessage
+	trailers bool
+}
+
+func NewTranscoder(logger *zap.Logger, framer *http2.Framer, mockDb integrations.MockMemDb, protos *pkg.ProtoRegistry, noise map[string][]string) *Transcoder {
 	return &Transcoder{
 		logger:  logger,
 		framer:  framer,
 		mockDb:  mockDb,
-		sic:     NewStreamInfoCollection(),
+		sic:     NewStreamInfoCollection(protos),
 		decoder: NewDecoder(),
+		streams: make(map[uint32]*replayStream),
+		protos:  protos,
+		noise:   noise,
 	}
 }
 
+// write runs a write on the framer, serialized with the writes of the other streams.
+func (srv *Transcoder) write(fn func() error) error {
+	srv.writeMu.Lock()
+	defer srv.writeMu.Unlock()
+	return fn()
+}
+
 func (srv *Transcoder) WriteInitialSettingsFrame() error {
 	var settings []http2.Setting
 	// TODO : Get Settings from config file.
@@ -40,7 +85,7 @@ func (srv *Transcoder) WriteInitialSettingsFrame() error {
 		ID:  http2.SettingMaxFrameSize,
 		Val: 16384,
 	})
-	return srv.framer.WriteSettings(settings...)
+	return srv.write(func() error { return srv.framer.WriteSettings(settings...) })
 }
 
 func (srv *Transcoder) ProcessPingFrame(pingFrame *http2.PingFrame) error {
@@ -60,7 +105,7 @@ func (srv *Transcoder) ProcessPingFrame(pingFrame *http2.PingFrame) error {
 	}
 
 	// Write the ACK for the PING request.
-	return srv.framer.WritePing(true, pingFrame.Data)
+	return srv.write(func() error { return srv.framer.WritePing(true, pingFrame.Data) })
 
 }
 
@@ -73,82 +118,188 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 	}
 	srv.sic.AddPayloadForRequest(id, dataFrame.Data())
 
-	if dataFrame.StreamEnded() {
-		defer srv.sic.ResetStream(dataFrame.StreamID)
+	streamEnded := dataFrame.StreamEnded()
+	if streamEnded {
+		defer srv.sic.ResetStream(id)
+		defer delete(srv.streams, id)
 	}
 
 	grpcReq := srv.sic.FetchRequestForStream(id)
 
-	// Fetch all the mocks. We can't assume that the grpc calls are made in a certain order.
-	mock, err := FilterMocksBasedOnGrpcRequest(ctx, srv.logger, grpcReq, srv.mockDb)
-	if err != nil {
-		return fmt.Errorf("failed match mocks: %v", err)
-	}
-	if mock == nil {
-		return fmt.Errorf("failed to mock the output for unrecorded outgoing grpc call")
+	stream, ok := srv.streams[id]
+	if !ok {
+		// Fetch all the mocks. We can't assume that the grpc calls are made in a certain order.
+		matchStart := time.Now()
+		mock, err := FilterMocksBasedOnGrpcRequest(ctx, srv.logger, grpcReq, srv.mockDb, streamEnded, srv.noise)
+		// a request whose stream isn't over yet may still match once the client sends more messages
+		if mock != nil || streamEnded || err != nil {
+			integrations.MockMatch(srv.mockDb, models.GRPC_EXPORT, grpcReq.Headers.PseudoHeaders[KLabelForPath], mock != nil, err, matchStart)
+		}
+		if err != nil {
+			return fmt.Errorf("failed match mocks: %v", err)
+		}
+		if mock == nil {
+			if !streamEnded {
+				// The client may still send more messages on this stream, wait for them.
+				return nil
+			}
+			return fmt.Errorf("failed to mock the output for unrecorded outgoing grpc call")
+		}
+		stream = srv.startStream(ctx, id, mock, len(grpcReq.Messages()))
+	} else if !srv.checkRequest(stream, grpcReq, streamEnded) {
+		// The mock was picked before the client sent these messages, and the recorded client sent others.
+		utils.LogError(srv.logger, nil, "the grpc request messages do not match the mock picked for the stream", zap.Any("stream_id", id), zap.Any("mock", stream.mock.Name))
+		integrations.MockMatch(srv.mockDb, models.GRPC_EXPORT, grpcReq.Headers.PseudoHeaders[KLabelForPath], false, nil, time.Now())
+		stream.cancel()
+		delete(srv.streams, id)
+		return srv.write(func() error { return srv.framer.WriteRSTStream(id, http2.ErrCodeInternal) })
 	}
 
-	grpcMockResp := mock.Spec.GRPCResp
-
-	// First, send the headers frame.
-	buf := new(bytes.Buffer)
-	encoder := hpack.NewEncoder(buf)
+	srv.releaseResponse(stream, len(grpcReq.Stream), streamEnded)
+	return nil
+}
 
-	// The pseudo headers should be written before ordinary ones.
-	for key, value := range grpcMockResp.Headers.PseudoHeaders {
-		err := encoder.WriteField(hpack.HeaderField{
-			Name:  key,
-			Value: value,
-		})
-		if err != nil {
-			utils.LogError(srv.logger, err, "could not encode pseudo header", zap.Any("key", key), zap.Any("value", value))
-			return err
-		}
+// checkRequest compares the request messages received since the mock of the stream was picked with the
+// recorded ones. A mock is picked as soon as the recorded server had answered the messages received so
+// far, so the rest of the request stream has to be checked as it arrives.
+func (srv *Transcoder) checkRequest(stream *replayStream, grpcReq models.GrpcReq, streamEnded bool) bool {
+	have, received := stream.mock.Spec.GRPCReq.Messages(), grpcReq.Messages()
+	if len(received) > len(have) || (streamEnded && len(received) != len(have)) {
+		return false
 	}
-	for key, value := range grpcMockResp.Headers.OrdinaryHeaders {
-		err := encoder.WriteField(hpack.HeaderField{
-			Name:  key,
-			Value: value,
-		})
-		if err != nil {
-			utils.LogError(srv.logger, err, "could not encode ordinary header", zap.Any("key", key), zap.Any("value", value))
-			return err
+	for ; stream.checked < len(received); stream.checked++ {
+		if !matchMessage(srv.logger, have[stream.checked].GrpcLengthPrefixedMessage, received[stream.checked].GrpcLengthPrefixedMessage, srv.noise) {
+			return false
 		}
 	}
+	return true
+}
 
-	// The headers are prepared. Write the frame.
-	srv.logger.Info("Writing the first set of headers in a new HEADER frame.")
-	err = srv.framer.WriteHeaders(http2.HeadersFrameParam{
-		StreamID:      id,
-		BlockFragment: buf.Bytes(),
-		EndStream:     false,
-		EndHeaders:    true,
-	})
-	if err != nil {
-		utils.LogError(srv.logger, err, "could not write the first set of headers onto client")
-		return err
+// startStream starts the writer of the response of a stream matched with the given mock.
+func (srv *Transcoder) startStream(ctx context.Context, id uint32, mock *models.Mock, checked int) *replayStream {
+	ctx, cancel := context.WithCancel(ctx)
+	stream := &replayStream{
+		mock:    mock,
+		checked: checked,
+		cancel:  cancel,
+		notify:  make(chan struct{}, 1),
 	}
+	srv.streams[id] = stream
+	srv.writers.Add(1)
+	go func() {
+		defer srv.writers.Done()
+		defer cancel()
+		srv.writeResponse(ctx, id, stream)
+	}()
+	return stream
+}
 
-	payload, err := createPayloadFromLengthPrefixedMessage(grpcMockResp.Body)
-	if err != nil {
-		utils.LogError(srv.logger, err, "could not create grpc payload from mocks")
-		return err
+// releaseResponse hands over to the writer of the stream the part of the mocked response that the
+// recorded server had sent after receiving the given number of request messages. Once the client has
+// closed its side of the stream, the remaining messages are released followed by the trailers.
+func (srv *Transcoder) releaseResponse(stream *replayStream, received int, streamEnded bool) {
+	msgs := stream.mock.Spec.GRPCResp.Messages()
+	pending := stream.released
+	for pending < len(msgs) && (streamEnded || msgs[pending].AfterRequests <= received) {
+		pending++
+	}
+	if pending == stream.released && !streamEnded {
+		return
 	}
 
-	// Write the DATA frame with the payload.
-	err = srv.framer.WriteData(id, false, payload)
-	if err != nil {
-		utils.LogError(srv.logger, err, "could not write the data frame onto the client")
-		return err
+	stream.mu.Lock()
+	stream.bursts = append(stream.bursts, responseBurst{msgs: msgs[stream.released:pending], trailers: streamEnded})
+	stream.mu.Unlock()
+	stream.released = pending
+	select {
+	case stream.notify <- struct{}{}:
+	default:
+	}
+}
+
+// writeResponse writes the bursts of the response as they are released, until the trailers. The
+// recorded gap between the messages of a burst is kept, without holding up the other streams of the
+// connection.
+func (srv *Transcoder) writeResponse(ctx context.Context, id uint32, stream *replayStream) {
+	grpcMockResp := stream.mock.Spec.GRPCResp
+	md := srv.protos.OutputType(stream.mock.Spec.GRPCReq.Headers.PseudoHeaders[KLabelForPath])
+	headersSent := false
+
+	for {
+		stream.mu.Lock()
+		bursts := stream.bursts
+		stream.bursts = nil
+		stream.mu.Unlock()
+
+		for _, burst := range bursts {
+			if len(burst.msgs) > 0 && !headersSent {
+				// First, send the headers frame.
+				srv.logger.Info("Writing the first set of headers in a new HEADER frame.")
+				err := srv.writeHeaders(id, grpcMockResp.Headers, false)
+				if err != nil {
+					utils.LogError(srv.logger, err, "could not write the first set of headers onto client")
+					return
+				}
+				headersSent = true
+			}
+
+			for i, msg := range burst.msgs {
+				// Keep the recorded gap between the messages of the same burst.
+				if i > 0 {
+					if gap := msg.Offset - burst.msgs[i-1].Offset; gap > 0 {
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(gap):
+						}
+					}
+				}
+
+				payload, err := pkg.EncodeLengthPrefixedMessage(msg.GrpcLengthPrefixedMessage, md)
+				if err != nil {
+					utils.LogError(srv.logger, err, "could not create grpc payload from mocks")
+					// Fail the call instead of the whole connection, which carries the other streams.
+					err = srv.write(func() error { return srv.framer.WriteRSTStream(id, http2.ErrCodeInternal) })
+					if err != nil {
+						utils.LogError(srv.logger, err, "could not reset the stream")
+					}
+					return
+				}
+
+				// Write the DATA frame with the payload.
+				err = srv.write(func() error { return srv.framer.WriteData(id, false, payload) })
+				if err != nil {
+					utils.LogError(srv.logger, err, "could not write the data frame onto the client")
+					return
+				}
+			}
+
+			if burst.trailers {
+				// The trailer is prepared. Write the frame.
+				srv.logger.Info("Writing the trailers in a different HEADER frame")
+				err := srv.writeHeaders(id, grpcMockResp.Trailers, true)
+				if err != nil {
+					utils.LogError(srv.logger, err, "could not write the trailers onto client")
+				}
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-stream.notify:
+		}
 	}
+}
 
-	// Reset the buffer and start with a new encoding.
-	buf = new(bytes.Buffer)
-	encoder = hpack.NewEncoder(buf)
+// writeHeaders encodes the headers and writes them in a single HEADERS frame.
+func (srv *Transcoder) writeHeaders(id uint32, headers models.GrpcHeaders, endStream bool) error {
+	buf := new(bytes.Buffer)
+	encoder := hpack.NewEncoder(buf)
 
-	//Prepare the trailers.
-	//The pseudo headers should be written before ordinary ones.
-	for key, value := range grpcMockResp.Trailers.PseudoHeaders {
+	// The pseudo headers should be written before ordinary ones.
+	for key, value := range headers.PseudoHeaders {
 		err := encoder.WriteField(hpack.HeaderField{
 			Name:  key,
 			Value: value,
@@ -158,7 +309,7 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 			return err
 		}
 	}
-	for key, value := range grpcMockResp.Trailers.OrdinaryHeaders {
+	for key, value := range headers.OrdinaryHeaders {
 		err := encoder.WriteField(hpack.HeaderField{
 			Name:  key,
 			Value: value,
@@ -169,20 +320,14 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 		}
 	}
 
-	// The trailer is prepared. Write the frame.
-	srv.logger.Info("Writing the trailers in a different HEADER frame")
-	err = srv.framer.WriteHeaders(http2.HeadersFrameParam{
-		StreamID:      id,
-		BlockFragment: buf.Bytes(),
-		EndStream:     true,
-		EndHeaders:    true,
+	return srv.write(func() error {
+		return srv.framer.WriteHeaders(http2.HeadersFrameParam{
+			StreamID:      id,
+			BlockFragment: buf.Bytes(),
+			EndStream:     endStream,
+			EndHeaders:    true,
+		})
 	})
-	if err != nil {
-		utils.LogError(srv.logger, err, "could not write the trailers onto client")
-		return err
-	}
-
-	return nil
 }
 
 func (srv *Transcoder) ProcessWindowUpdateFrame(_ *http2.WindowUpdateFrame) error {
@@ -193,6 +338,10 @@ func (srv *Transcoder) ProcessWindowUpdateFrame(_ *http2.WindowUpdateFrame) erro
 
 func (srv *Transcoder) ProcessResetStreamFrame(resetStreamFrame *http2.RSTStreamFrame) error {
 	srv.sic.ResetStream(resetStreamFrame.StreamID)
+	if stream, ok := srv.streams[resetStreamFrame.StreamID]; ok {
+		stream.cancel()
+		delete(srv.streams, resetStreamFrame.StreamID)
+	}
 	return nil
 }
 
@@ -201,7 +350,7 @@ func (srv *Transcoder) ProcessSettingsFrame(settingsFrame *http2.SettingsFrame)
 	// There is no actual server to tune the settings on. We already know the default settings from record mode.
 	// TODO : Add support for dynamically updating the settings.
 	if !settingsFrame.IsAck() {
-		return srv.framer.WriteSettingsAck()
+		return srv.write(srv.framer.WriteSettingsAck)
 	}
 	return nil
 }
@@ -284,7 +433,12 @@ func (srv *Transcoder) ProcessGenericFrame(ctx context.Context, frame http2.Fram
 }
 
 // ListenAndServe is a forever blocking call that reads one frame at a time, and responds to them.
+// The responses of the streams are written by their own goroutines, which are stopped on return.
 func (srv *Transcoder) ListenAndServe(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer srv.writers.Wait()
+	defer cancel()
+
 	err := srv.WriteInitialSettingsFrame()
 	if err != nil {
 		utils.LogError(srv.logger, err, "could not write initial settings frame")
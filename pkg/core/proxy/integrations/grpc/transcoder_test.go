@@ -0,0 +1,154 @@
+//go:build linux
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+)
+
+// bidiMock returns the mock of a stream of the given number of request messages, with a response message sent
+// after receiving each of the given numbers of request messages.
+func bidiMock(requests int, after ...int) *models.Mock {
+	mock := &models.Mock{Kind: models.GRPC_EXPORT, Spec: models.MockSpec{GRPCReq: &models.GrpcReq{}, GRPCResp: &models.GrpcResp{
+		Headers:  models.GrpcHeaders{PseudoHeaders: map[string]string{":status": "200"}},
+		Trailers: models.GrpcHeaders{OrdinaryHeaders: map[string]string{"grpc-status": "0"}},
+	}}}
+	for i := 0; i < requests; i++ {
+		mock.Spec.GRPCReq.Stream = append(mock.Spec.GRPCReq.Stream, scopeMsg(fmt.Sprintf("1: %d", i+1)))
+	}
+	for i, n := range after {
+		msg := scopeMsg(fmt.Sprintf("1: %d", 10+i))
+		msg.AfterRequests = n
+		mock.Spec.GRPCResp.Stream = append(mock.Spec.GRPCResp.Stream, msg)
+	}
+	return mock
+}
+
+func trailersOnly(mock *models.Mock) *models.Mock {
+	mock.Spec.GRPCResp.Headers = models.GrpcHeaders{}
+	return mock
+}
+
+func TestTranscoderReleaseResponse(t *testing.T) {
+	type step struct {
+		received    int
+		streamEnded bool
+	}
+	tests := []struct {
+		name  string
+		mock  *models.Mock
+		steps []step
+		want  []string // the released bursts, as the number of their messages and whether the trailers follow
+	}{
+		{name: "server streaming", mock: bidiMock(1, 1, 1, 1), steps: []step{{1, false}, {1, true}}, want: []string{"3", "0+trailers"}},
+		{name: "answered before any request", mock: bidiMock(2, 0, 0), steps: []step{{1, false}, {2, false}, {2, true}}, want: []string{"2", "0+trailers"}},
+		{name: "answered on the half close", mock: bidiMock(2, 2, 2), steps: []step{{1, false}, {2, true}}, want: []string{"2+trailers"}},
+		{name: "bidi", mock: bidiMock(3, 1, 2, 2, 3), steps: []step{{1, false}, {2, false}, {3, false}, {3, true}}, want: []string{"1", "2", "1", "0+trailers"}},
+		{name: "unary", mock: bidiMock(1), steps: []step{{1, true}}, want: []string{"1+trailers"}},
+		{name: "trailers only", mock: trailersOnly(bidiMock(1)), steps: []step{{1, true}}, want: []string{"0+trailers"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &Transcoder{logger: zap.NewNop()}
+			stream := &replayStream{mock: tt.mock, notify: make(chan struct{}, 1)}
+			for _, s := range tt.steps {
+				srv.releaseResponse(stream, s.received, s.streamEnded)
+			}
+			var got []string
+			for _, burst := range stream.bursts {
+				b := fmt.Sprint(len(burst.msgs))
+				if burst.trailers {
+					b += "+trailers"
+				}
+				got = append(got, b)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bursts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscoderCheckRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		received    []models.GrpcStreamMessage
+		streamEnded bool
+		want        bool
+	}{
+		{name: "messages arriving after the match", received: []models.GrpcStreamMessage{scopeMsg("1: 1"), scopeMsg("1: 2")}, want: true},
+		{name: "stream ended early", received: []models.GrpcStreamMessage{scopeMsg("1: 1"), scopeMsg("1: 2")}, streamEnded: true},
+		{name: "other late message", received: []models.GrpcStreamMessage{scopeMsg("1: 1"), scopeMsg("1: 9")}},
+		{name: "more messages than recorded", received: []models.GrpcStreamMessage{scopeMsg("1: 1"), scopeMsg("1: 2"), scopeMsg("1: 3"), scopeMsg("1: 4")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &Transcoder{logger: zap.NewNop()}
+			stream := &replayStream{mock: bidiMock(3, 1), checked: 1}
+			if got := srv.checkRequest(stream, models.GrpcReq{Stream: tt.received}, tt.streamEnded); got != tt.want {
+				t.Errorf("checkRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTranscoderWriteResponse checks that the response of a stream is written as its bursts are released,
+// the headers before the first message and the trailers at the end.
+func TestTranscoderWriteResponse(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	srv := NewTranscoder(zap.NewNop(), http2.NewFramer(server, server), nil, nil, nil)
+	frames := make(chan string, 16)
+	go func() {
+		framer := http2.NewFramer(client, client)
+		for {
+			frame, err := framer.ReadFrame()
+			if err != nil {
+				close(frames)
+				return
+			}
+			switch f := frame.(type) {
+			case *http2.HeadersFrame:
+				frames <- fmt.Sprintf("headers %d end=%v", f.StreamID, f.StreamEnded())
+			case *http2.DataFrame:
+				frames <- fmt.Sprintf("data %d %x", f.StreamID, f.Data())
+			}
+		}
+	}()
+	next := func() string {
+		select {
+		case frame := <-frames:
+			return frame
+		case <-time.After(5 * time.Second):
+			return "timeout"
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := srv.startStream(ctx, 1, bidiMock(2, 1, 2), 1)
+	srv.releaseResponse(stream, 1, false)
+	for _, want := range []string{"headers 1 end=false", "data 1 0000000002080a"} {
+		if got := next(); got != want {
+			t.Fatalf("frame = %q, want %q", got, want)
+		}
+	}
+	srv.releaseResponse(stream, 2, true)
+	for _, want := range []string{"data 1 0000000002080b", "headers 1 end=true"} {
+		if got := next(); got != want {
+			t.Fatalf("frame = %q, want %q", got, want)
+		}
+	}
+	srv.writers.Wait()
+}
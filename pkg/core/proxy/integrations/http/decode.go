@@ -13,6 +13,7 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"time"
 
 	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
@@ -87,7 +88,9 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				body:   reqBody,
 				raw:    reqBuf,
 			}
+			matchStart := time.Now()
 			ok, stub, err := match(ctx, logger, input, mockDb)
+			integrations.MockMatch(mockDb, models.HTTP, request.Method+" "+request.Host+request.URL.Path, ok, err, matchStart)
 			if err != nil {
 				utils.LogError(logger, err, "error while matching http mocks", zap.Any("metadata", getReqMeta(request)))
 				errCh <- err
@@ -46,8 +46,12 @@ type finalHTTP struct {
 }
 
 // MatchType function determines if the outgoing network call is HTTP by comparing the
-// message format with that of an HTTP text message.
+// message format with that of an HTTP text message. The websocket handshakes are left to the
+// websocket integration.
 func (h *HTTP) MatchType(_ context.Context, buf []byte) bool {
+	if pkg.IsWebSocketHandshake(buf) {
+		return false
+	}
 	isHTTP := bytes.HasPrefix(buf[:], []byte("HTTP/")) ||
 		bytes.HasPrefix(buf[:], []byte("GET ")) ||
 		bytes.HasPrefix(buf[:], []byte("POST ")) ||
@@ -172,18 +176,20 @@ func ParseFinalHTTP(_ context.Context, logger *zap.Logger, mock *finalHTTP, dest
 		Spec: models.MockSpec{
 			Metadata: meta,
 			HTTPReq: &models.HTTPReq{
-				Method:     models.Method(req.Method),
-				ProtoMajor: req.ProtoMajor,
-				ProtoMinor: req.ProtoMinor,
-				URL:        req.URL.String(),
-				Header:     pkg.ToYamlHTTPHeader(req.Header),
-				Body:       string(reqBody),
-				URLParams:  pkg.URLParams(req),
+				Method:       models.Method(req.Method),
+				ProtoMajor:   req.ProtoMajor,
+				ProtoMinor:   req.ProtoMinor,
+				URL:          req.URL.String(),
+				Header:       pkg.ToYamlHTTPHeader(req.Header),
+				Body:         string(reqBody),
+				BodyEncoding: models.EncodingOf(string(reqBody)),
+				URLParams:    pkg.URLParams(req),
 			},
 			HTTPResp: &models.HTTPResp{
-				StatusCode: respParsed.StatusCode,
-				Header:     pkg.ToYamlHTTPHeader(respParsed.Header),
-				Body:       string(respBody),
+				StatusCode:   respParsed.StatusCode,
+				Header:       pkg.ToYamlHTTPHeader(respParsed.Header),
+				Body:         string(respBody),
+				BodyEncoding: models.EncodingOf(string(respBody)),
 			},
 			Created:          time.Now().Unix(),
 			ReqTimestampMock: mock.resTimestampMock,
@@ -35,14 +35,8 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 			return false, nil, ctx.Err()
 		}
 
-		mocks, err := mockDb.GetUnFilteredMocks()
-		var unfilteredMocks []*models.Mock
-		for _, mock := range mocks {
-			if mock.Kind != "Http" {
-				continue
-			}
-			unfilteredMocks = append(unfilteredMocks, mock)
-		}
+		// only the mocks of the same method and path are candidates
+		unfilteredMocks, err := mockDb.GetUnFilteredMocksByKey(integrations.HTTPKey(input.method, input.url.Path))
 		if err != nil {
 			utils.LogError(logger, err, "failed to get unfilteredMocks mocks")
 			return false, nil, errors.New("error while matching the request with the mocks")
@@ -62,7 +56,7 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 
 			//if the content type is present in http request then we need to check for the same type in the mock
 			if input.header.Get("Content-Type") != "" {
-				if input.header.Get("Content-Type") != mock.Spec.HTTPReq.Header["Content-Type"] {
+				if input.header.Get("Content-Type") != mock.Spec.HTTPReq.Header.Get("Content-Type") {
 					logger.Debug("The content type of mock and request aren't the same")
 					continue
 				}
@@ -212,7 +206,7 @@ func bodyMatch(logger *zap.Logger, mockBody, reqBody []byte) (bool, error) {
 	return true, nil
 }
 
-func mapsHaveSameKeys(map1 map[string]string, map2 map[string][]string) bool {
+func mapsHaveSameKeys[V any](map1 map[string]V, map2 map[string][]string) bool {
 	if len(map1) != len(map2) {
 		return false
 	}
@@ -379,11 +373,12 @@ func isCSV(data []byte) bool {
 // updateMock processes the matched mock based on its filtered status.
 func updateMock(_ context.Context, logger *zap.Logger, matchedMock *models.Mock, mockDb integrations.MockMemDb) bool {
 	if matchedMock.TestModeInfo.IsFiltered {
-		originalMatchedMock := *matchedMock
-		matchedMock.TestModeInfo.IsFiltered = false
-		matchedMock.TestModeInfo.SortOrder = math.MaxInt
+		// the matched mock is shared with the mock store, so a copy is updated
+		updatedMock := *matchedMock
+		updatedMock.TestModeInfo.IsFiltered = false
+		updatedMock.TestModeInfo.SortOrder = math.MaxInt
 		//UpdateUnFilteredMock also marks the mock as used
-		updated := mockDb.UpdateUnFilteredMock(&originalMatchedMock, matchedMock)
+		updated := mockDb.UpdateUnFilteredMock(matchedMock, &updatedMock)
 		return updated
 	}
 
@@ -0,0 +1,133 @@
+//go:build linux
+
+package integrations
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// The mocks are indexed by the keys below in the mock store, so that the integrations only go through
+// the candidate mocks of a request instead of all the mocks of the test set.
+
+// KindKey is the index key of the mocks of a kind.
+func KindKey(kind models.Kind) string {
+	return "kind|" + string(kind)
+}
+
+// HTTPKey is the index key of the http mocks of a method and a path.
+func HTTPKey(method, path string) string {
+	return "http|" + strings.ToUpper(method) + " " + path
+}
+
+// WebSocketKey is the index key of the websocket mocks of a path.
+func WebSocketKey(path string) string {
+	return "websocket|" + path
+}
+
+// MongoKey is the index key of the mongo mocks which run a command on a collection.
+func MongoKey(collection string) string {
+	return "mongo|" + collection
+}
+
+// SQLKey is the index key of the postgres and mysql mocks which run a query with the same fingerprint.
+func SQLKey(kind models.Kind, query string) string {
+	return "sql|" + string(kind) + "|" + SQLFingerprint(query)
+}
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumber        = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlPlaceholder   = regexp.MustCompile(`\$\d+|\?`)
+	sqlValueList     = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	sqlSpaces        = regexp.MustCompile(`\s+`)
+	sqlOperators     = regexp.MustCompile(`\s*([=<>!,()])\s*`)
+
+	// mongoCommand matches the command of an OP_MSG body and its collection, e.g. {"find":"users",...}
+	mongoCommand = regexp.MustCompile(`^\{\s*"[A-Za-z]+"\s*:\s*"([^"]+)"`)
+)
+
+// SQLFingerprint normalizes a query, so that the queries which only differ by their literals, their
+// placeholders or their formatting have the same fingerprint.
+func SQLFingerprint(query string) string {
+	fp := sqlStringLiteral.ReplaceAllString(query, "?")
+	fp = sqlPlaceholder.ReplaceAllString(fp, "?")
+	fp = sqlNumber.ReplaceAllString(fp, "?")
+	fp = sqlValueList.ReplaceAllString(fp, "(?)")
+	fp = sqlSpaces.ReplaceAllString(fp, " ")
+	fp = sqlOperators.ReplaceAllString(fp, "$1")
+	return strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(fp), ";")))
+}
+
+// MongoCollection returns the collection of the command in the body of an OP_MSG section.
+func MongoCollection(section string) (string, bool) {
+	section = strings.TrimPrefix(section, "{ SectionSingle msg: ")
+	m := mongoCommand.FindStringSubmatch(section)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// MockKeys returns the index keys of a mock.
+func MockKeys(mock *models.Mock) []string {
+	keys := []string{KindKey(mock.Kind)}
+	add := func(key string) {
+		for _, k := range keys {
+			if k == key {
+				return
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	switch mock.Kind {
+	case models.HTTP:
+		if mock.Spec.HTTPReq == nil {
+			break
+		}
+		if u, err := url.Parse(mock.Spec.HTTPReq.URL); err == nil {
+			add(HTTPKey(string(mock.Spec.HTTPReq.Method), u.Path))
+		}
+	case models.WebSocket:
+		if mock.Spec.HTTPReq == nil {
+			break
+		}
+		if u, err := url.Parse(mock.Spec.HTTPReq.URL); err == nil {
+			add(WebSocketKey(u.Path))
+		}
+	case models.Mongo:
+		for _, req := range mock.Spec.MongoRequests {
+			msg, ok := req.Message.(*models.MongoOpMessage)
+			if !ok || len(msg.Sections) == 0 {
+				continue
+			}
+			if collection, ok := MongoCollection(msg.Sections[0]); ok {
+				add(MongoKey(collection))
+			}
+		}
+	case models.Postgres:
+		for _, req := range mock.Spec.PostgresRequests {
+			if req.Query.String != "" {
+				add(SQLKey(mock.Kind, req.Query.String))
+			}
+			for _, parse := range req.Parses {
+				add(SQLKey(mock.Kind, parse.Query))
+			}
+		}
+	case models.MySQL:
+		for _, req := range mock.Spec.MySQLRequests {
+			switch msg := req.Message.(type) {
+			case *mysql.QueryPacket:
+				add(SQLKey(mock.Kind, msg.Query))
+			case *mysql.StmtPreparePacket:
+				add(SQLKey(mock.Kind, msg.Query))
+			}
+		}
+	}
+	return keys
+}
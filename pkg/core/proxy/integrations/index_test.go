@@ -0,0 +1,113 @@
+//go:build linux
+
+package integrations
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+func TestSQLFingerprint(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "literals", query: "SELECT * FROM users WHERE id = 42 AND name = 'O''Brien'", want: "select * from users where id=? and name=?"},
+		{name: "postgres placeholders", query: "SELECT * FROM users WHERE id = $1 AND age > $2", want: "select * from users where id=? and age>?"},
+		{name: "mysql placeholders", query: "select * from users where id = ?", want: "select * from users where id=?"},
+		{name: "value lists", query: "INSERT INTO users (id, name) VALUES (1, 'a'), (2, 'b')", want: "insert into users(id,name)values(?),(?)"},
+		{name: "in list", query: "SELECT * FROM users WHERE id IN ($1, $2, $3)", want: "select * from users where id in(?)"},
+		{name: "formatting", query: "  SELECT *\n\tFROM users\n WHERE id = 1 ;", want: "select * from users where id=?"},
+		{name: "numbers in names", query: "SELECT * FROM table_42 WHERE col1 = 1.5", want: "select * from table_42 where col1=?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SQLFingerprint(tt.query); got != tt.want {
+				t.Errorf("SQLFingerprint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMongoCollection(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		want    string
+		wantOK  bool
+	}{
+		{name: "find", section: `{ SectionSingle msg: {"find":"users","filter":{},"$db":"app"} }`, want: "users", wantOK: true},
+		{name: "plain body", section: `{"insert": "orders", "documents": []}`, want: "orders", wantOK: true},
+		{name: "command without collection", section: `{ SectionSingle msg: {"ping":1,"$db":"admin"} }`},
+		{name: "sequence", section: `{ SectionSingle identifier: documents , msgs: [ {"_id":1} ] }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := MongoCollection(tt.section)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("MongoCollection() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMockKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		mock *models.Mock
+		want []string
+	}{
+		{
+			name: "http",
+			mock: &models.Mock{Kind: models.HTTP, Spec: models.MockSpec{HTTPReq: &models.HTTPReq{Method: "get", URL: "http://api.example.com/users/1?full=true"}}},
+			want: []string{"kind|Http", "http|GET /users/1"},
+		},
+		{
+			name: "http without request",
+			mock: &models.Mock{Kind: models.HTTP},
+			want: []string{"kind|Http"},
+		},
+		{
+			name: "websocket",
+			mock: &models.Mock{Kind: models.WebSocket, Spec: models.MockSpec{HTTPReq: &models.HTTPReq{Method: "GET", URL: "ws://api.example.com/feed"}}},
+			want: []string{KindKey(models.WebSocket), "websocket|/feed"},
+		},
+		{
+			name: "mongo",
+			mock: &models.Mock{Kind: models.Mongo, Spec: models.MockSpec{MongoRequests: []models.MongoRequest{
+				{Message: &models.MongoOpMessage{Sections: []string{`{ SectionSingle msg: {"find":"users"} }`}}},
+				{Message: &models.MongoOpMessage{Sections: []string{`{ SectionSingle msg: {"find":"users"} }`}}},
+				{Message: &models.MongoOpMessage{Sections: []string{`{ SectionSingle msg: {"hello":1} }`}}},
+			}}},
+			want: []string{KindKey(models.Mongo), "mongo|users"},
+		},
+		{
+			name: "postgres",
+			mock: &models.Mock{Kind: models.Postgres, Spec: models.MockSpec{PostgresRequests: []models.Backend{
+				{Query: pgproto3.Query{String: "SELECT 1"}},
+				{Parses: []pgproto3.Parse{{Query: "SELECT * FROM users WHERE id = $1"}}},
+			}}},
+			want: []string{KindKey(models.Postgres), "sql|Postgres|select ?", "sql|Postgres|select * from users where id=?"},
+		},
+		{
+			name: "mysql",
+			mock: &models.Mock{Kind: models.MySQL, Spec: models.MockSpec{MySQLRequests: []mysql.Request{
+				{PacketBundle: mysql.PacketBundle{Message: &mysql.QueryPacket{Query: "SELECT * FROM users WHERE id = 1"}}},
+				{PacketBundle: mysql.PacketBundle{Message: &mysql.StmtPreparePacket{Query: "SELECT * FROM users WHERE id = ?"}}},
+			}}},
+			want: []string{KindKey(models.MySQL), "sql|MySQL|select * from users where id=?"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MockKeys(tt.mock); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MockKeys() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
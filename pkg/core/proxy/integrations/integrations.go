@@ -6,8 +6,10 @@ package integrations
 import (
 	"context"
 	"net"
+	"time"
 
 	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/metrics"
 	"go.uber.org/zap"
 )
 
@@ -25,6 +27,8 @@ const (
 	POSTGRES_V2 integrationType = "postgres_v2"
 	MONGO       integrationType = "mongo"
 	REDIS       integrationType = "redis"
+	KAFKA       integrationType = "kafka"
+	WEBSOCKET   integrationType = "websocket"
 )
 
 var Registered = make(map[string]Initializer)
@@ -35,6 +39,14 @@ type Integrations interface {
 	MockOutgoing(ctx context.Context, src net.Conn, dstCfg *models.ConditionalDstCfg, mockDb MockMemDb, opts models.OutgoingOptions) error
 }
 
+// ServerFirst is implemented by the integrations of the protocols in which the server speaks first.
+// As there is no request of the client to match, these protocols are detected from the greeting of
+// the server while recording and from the kind of the recorded mocks while replaying.
+type ServerFirst interface {
+	MatchGreeting(ctx context.Context, greeting []byte) bool
+	MockKind() models.Kind
+}
+
 func Register(name string, i Initializer) {
 	Registered[name] = i
 }
@@ -42,9 +54,27 @@ func Register(name string, i Initializer) {
 type MockMemDb interface {
 	GetFilteredMocks() ([]*models.Mock, error)
 	GetUnFilteredMocks() ([]*models.Mock, error)
+	// The lookups below return the indexed mocks without copying them, so they must not be modified.
+	GetFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error)
+	GetUnFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error)
+	GetFilteredMocksByKey(key string) ([]*models.Mock, error)
+	GetUnFilteredMocksByKey(key string) ([]*models.Mock, error)
 	UpdateUnFilteredMock(old *models.Mock, new *models.Mock) bool
 	DeleteFilteredMock(mock models.Mock) bool
 	DeleteUnFilteredMock(mock models.Mock) bool
 	// Flag the mock as used which matches the external request from application in test mode
 	FlagMockAsUsed(mock models.Mock) error
+	// FlagCallAsUnmatched records a dependency call which matched no mock, described by its kind and,
+	// when it is known, by its request (see MockMatch).
+	FlagCallAsUnmatched(kind models.Kind, call string)
+}
+
+// MockMatch records the result of matching a dependency call with the mocks. The calls which matched no
+// mock are reported along with the consumed mocks, so that the test case making them fails. The failures
+// to match, which end the connection, are only counted in the metrics.
+func MockMatch(mockDb MockMemDb, kind models.Kind, call string, matched bool, err error, start time.Time) {
+	metrics.MockMatch(kind, matched && err == nil, start)
+	if !matched && err == nil {
+		mockDb.FlagCallAsUnmatched(kind, call)
+	}
 }
@@ -0,0 +1,111 @@
+//go:build linux
+
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeKafka serves the requests of the client from the mocks. The recorded response is replayed
+// with the correlation id of the request, as the ids are assigned by the client per connection.
+// A request which matches no mock is only passed through to the broker if FallBackOnMiss is set.
+func decodeKafka(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *models.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+
+		frames := newFrameReader(clientConn, reqBuf)
+		for {
+			frame, err := frames.next(ctx)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the request message from the client")
+				}
+				errCh <- err
+				return
+			}
+
+			req, err := decodeRequest(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to decode the kafka request")
+				errCh <- err
+				return
+			}
+			logger.Debug("received a kafka request", zap.String("api", req.Header.APIName), zap.Int16("api key", req.Header.APIKey), zap.Int16("version", req.Header.APIVersion))
+
+			matchStart := time.Now()
+			mock, err := match(ctx, logger, req, mockDb)
+			integrations.MockMatch(mockDb, models.KAFKA, "", mock != nil, err, matchStart)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching kafka mocks")
+				errCh <- err
+				return
+			}
+			if mock == nil {
+				if !opts.FallBackOnMiss {
+					errCh <- fmt.Errorf("no kafka mock matched the %s request", req.Header.APIName)
+					return
+				}
+				logger.Debug("no kafka mock found for the request, passing it through", zap.String("api", req.Header.APIName), zap.Any("request", req))
+				_, err = pUtil.PassThrough(ctx, logger, clientConn, dstCfg, [][]byte{frame})
+				if err != nil {
+					utils.LogError(logger, err, "failed to pass through the kafka request")
+					errCh <- err
+					return
+				}
+				continue
+			}
+
+			resp := mock.Spec.KafkaResponse
+			if resp == nil {
+				// the broker doesn't respond to the produce requests with acks=0
+				continue
+			}
+			payload, err := util.DecodeBase64(resp.Payload)
+			if err == nil && len(payload) < 8 {
+				err = errors.New("incomplete kafka response frame")
+			}
+			if err != nil {
+				utils.LogError(logger, err, "failed to decode the recorded kafka response", zap.String("mock", mock.Name))
+				errCh <- err
+				return
+			}
+			binary.BigEndian.PutUint32(payload[4:8], uint32(req.Header.CorrelationID))
+
+			_, err = clientConn.Write(payload)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				utils.LogError(logger, err, "failed to write the response message to the client application")
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
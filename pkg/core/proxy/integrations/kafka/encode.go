@@ -0,0 +1,151 @@
+//go:build linux
+
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingRequest is a request which is waiting for the response of the broker.
+type pendingRequest struct {
+	req       *models.KafkaRequest
+	timestamp time.Time
+}
+
+// encodeKafka forwards the traffic between the client and the broker, and records every request along
+// with its response as a mock. The clients pipeline the requests on a connection, so the responses
+// are paired with the requests by the correlation id.
+func encodeKafka(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	var (
+		mu      sync.Mutex
+		pending = make(map[int32]pendingRequest)
+	)
+	errCh := make(chan error, 2)
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	// Read the requests of the client and forward them to the broker
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		frames := newFrameReader(clientConn, reqBuf)
+		for {
+			frame, err := frames.next(ctx)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the request message from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			reqTimestamp := time.Now()
+			_, err = destConn.Write(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write request message to the destination server")
+				errCh <- err
+				return nil
+			}
+
+			req, err := decodeRequest(frame)
+			if err != nil {
+				logger.Debug("failed to decode the kafka request, it won't be recorded", zap.Error(err))
+				continue
+			}
+			if isFireAndForget(req) {
+				saveMock(req, nil, reqTimestamp, reqTimestamp, mocks)
+				continue
+			}
+			mu.Lock()
+			pending[req.Header.CorrelationID] = pendingRequest{req: req, timestamp: reqTimestamp}
+			mu.Unlock()
+		}
+	})
+
+	// Read the responses of the broker and forward them to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		frames := newFrameReader(destConn, nil)
+		for {
+			frame, err := frames.next(ctx)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the response message from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+			resTimestamp := time.Now()
+			_, err = clientConn.Write(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write response message to the client")
+				errCh <- err
+				return nil
+			}
+
+			if len(frame) < 8 {
+				logger.Debug("ignoring the incomplete kafka response frame")
+				continue
+			}
+			correlationID := int32(binary.BigEndian.Uint32(frame[4:8]))
+			mu.Lock()
+			p, ok := pending[correlationID]
+			delete(pending, correlationID)
+			mu.Unlock()
+			if !ok {
+				logger.Debug("no kafka request found for the response", zap.Int32("correlation id", correlationID))
+				continue
+			}
+
+			resp, err := decodeResponse(frame, p.req.Header)
+			if err != nil {
+				// the response is still recorded, as the payload is replayed as it is
+				logger.Debug("failed to decode the kafka response", zap.Error(err))
+			}
+			saveMock(p.req, resp, p.timestamp, resTimestamp, mocks)
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func saveMock(req *models.KafkaRequest, resp *models.KafkaResponse, reqTimestamp, resTimestamp time.Time, mocks chan<- *models.Mock) {
+	metadata := map[string]string{}
+	if schema, ok := apis[req.Header.APIKey]; !ok || !schema.perTest {
+		metadata["type"] = "config"
+	}
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.KAFKA,
+		Spec: models.MockSpec{
+			Metadata:         metadata,
+			KafkaRequest:     req,
+			KafkaResponse:    resp,
+			ReqTimestampMock: reqTimestamp,
+			ResTimestampMock: resTimestamp,
+		},
+	}
+}
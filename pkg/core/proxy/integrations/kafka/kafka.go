@@ -0,0 +1,79 @@
+//go:build linux
+
+// Package kafka provides the integration for recording and replaying the kafka wire protocol.
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("kafka", NewKafka)
+}
+
+type Kafka struct {
+	logger *zap.Logger
+}
+
+func NewKafka(logger *zap.Logger) integrations.Integrations {
+	return &Kafka{
+		logger: logger,
+	}
+}
+
+// MatchType determines if the outgoing network call is kafka by decoding the first request. Only the
+// decoded APIs are accepted, and the request has to fill the frame exactly, as a bare header check
+// would also accept other binary protocols (e.g. the postgres startup message looks like Metadata v0).
+func (k *Kafka) MatchType(_ context.Context, buf []byte) bool {
+	if len(buf) < 4 {
+		return false
+	}
+	size := int(int32(binary.BigEndian.Uint32(buf)))
+	if size < 8 || len(buf) < 4+size {
+		return false
+	}
+	req, err := decodeRequest(buf[:4+size])
+	return err == nil && req.Header.APIName != ""
+}
+
+func (k *Kafka) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := k.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial kafka message")
+		return err
+	}
+
+	err = encodeKafka(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the kafka message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (k *Kafka) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *models.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := k.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial kafka message")
+		return err
+	}
+
+	err = decodeKafka(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the kafka message")
+		return err
+	}
+	return nil
+}
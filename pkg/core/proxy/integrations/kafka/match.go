@@ -0,0 +1,162 @@
+//go:build linux
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ignoredFields differ between the runs of a producer, so they are left out while matching the requests.
+var ignoredFields = map[string]bool{
+	"base_timestamp":  true,
+	"max_timestamp":   true,
+	"timestamp_delta": true,
+}
+
+// minFuzzyScore is the share of the fields of a request which have to match a mock of the same API,
+// when none of the mocks matches exactly.
+const minFuzzyScore = 0.8
+
+// match returns the mock recorded for the request. The requests are matched by the API, its version
+// and the decoded body, or by the raw body for the APIs which are not decoded. The mocks recorded
+// during the test cases are consumed, the others are shared by the test set.
+func match(ctx context.Context, logger *zap.Logger, req *models.KafkaRequest, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		filtered, err := mockDb.GetFilteredMocksByKind(models.KAFKA)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+		unfiltered, err := mockDb.GetUnFilteredMocksByKind(models.KAFKA)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting config mocks %v", err)
+		}
+
+		mock, isFiltered := bestMatch(logger, req, kafkaMocks(filtered), kafkaMocks(unfiltered))
+		if mock == nil {
+			return nil, nil
+		}
+
+		switch {
+		case isFiltered:
+			if !mockDb.DeleteFilteredMock(*mock) {
+				// the mock has been consumed by another connection in the meantime
+				continue
+			}
+		case mock.Spec.Metadata["type"] != "config":
+			if !mockDb.DeleteUnFilteredMock(*mock) {
+				continue
+			}
+		default:
+			if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+				logger.Debug("failed to flag the kafka mock as used", zap.Error(err))
+			}
+		}
+		return mock, nil
+	}
+}
+
+func kafkaMocks(mocks []*models.Mock) []*models.Mock {
+	var out []*models.Mock
+	for _, mock := range mocks {
+		if mock.Spec.KafkaRequest == nil {
+			continue
+		}
+		out = append(out, mock)
+	}
+	return out
+}
+
+// bestMatch looks for an exact match in the test case mocks first, then in the rest of the mocks.
+// Otherwise the mock sharing the most fields with the request is chosen.
+func bestMatch(logger *zap.Logger, req *models.KafkaRequest, filtered, unfiltered []*models.Mock) (*models.Mock, bool) {
+	reqFields := flatten(req.Body)
+	var (
+		best         *models.Mock
+		bestFiltered bool
+		bestScore    float64
+	)
+	for i, mocks := range [][]*models.Mock{filtered, unfiltered} {
+		for _, mock := range mocks {
+			expected := mock.Spec.KafkaRequest
+			if expected.Header.APIKey != req.Header.APIKey || expected.Header.APIVersion != req.Header.APIVersion {
+				continue
+			}
+			if req.Body == nil || expected.Body == nil {
+				if req.RawBody == expected.RawBody {
+					return mock, i == 0
+				}
+				continue
+			}
+			score := similarity(reqFields, flatten(expected.Body))
+			if score == 1 {
+				return mock, i == 0
+			}
+			if score > bestScore {
+				best, bestFiltered, bestScore = mock, i == 0, score
+			}
+		}
+	}
+	if bestScore < minFuzzyScore {
+		return nil, false
+	}
+	logger.Debug("no exact match found for the kafka request, using the closest mock", zap.String("api", req.Header.APIName), zap.String("mock", best.Name), zap.Float64("score", bestScore))
+	return best, bestFiltered
+}
+
+// flatten maps the path of every leaf of the body to its JSON value. The values are compared as JSON,
+// since the bodies loaded from the yaml don't have the integer types of the decoded ones.
+func flatten(body map[string]interface{}) map[string]string {
+	out := map[string]string{}
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				if ignoredFields[key] {
+					continue
+				}
+				walk(path+"."+key, child)
+			}
+		case []interface{}:
+			out[path+".length"] = fmt.Sprint(len(v))
+			for i, child := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), child)
+			}
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				b = []byte(fmt.Sprint(v))
+			}
+			out[path] = string(b)
+		}
+	}
+	walk("", body)
+	return out
+}
+
+// similarity is the share of the leaves having the same value in both the bodies.
+func similarity(a, b map[string]string) float64 {
+	total := len(a)
+	if len(b) > total {
+		total = len(b)
+	}
+	if total == 0 {
+		return 1
+	}
+	same := 0
+	for path, v := range a {
+		if bv, ok := b[path]; ok && bv == v {
+			same++
+		}
+	}
+	return float64(same) / float64(total)
+}
@@ -0,0 +1,153 @@
+//go:build linux
+
+package kafka
+
+import (
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func kafkaMock(name string, apiKey, apiVersion int16, body map[string]interface{}, rawBody string) *models.Mock {
+	return &models.Mock{
+		Name: name,
+		Kind: models.KAFKA,
+		Spec: models.MockSpec{KafkaRequest: &models.KafkaRequest{
+			Header:  models.KafkaRequestHeader{APIKey: apiKey, APIVersion: apiVersion},
+			Body:    body,
+			RawBody: rawBody,
+		}},
+	}
+}
+
+// produceBody is the body of a produce request of a record to the orders topic.
+func produceBody(value string, timestamp interface{}, partitions ...interface{}) map[string]interface{} {
+	if len(partitions) == 0 {
+		partitions = []interface{}{map[string]interface{}{
+			"index": int32(0),
+			"records": []interface{}{map[string]interface{}{
+				"base_timestamp": timestamp,
+				"records":        []interface{}{map[string]interface{}{"value": value, "timestamp_delta": timestamp}},
+			}},
+		}}
+	}
+	return map[string]interface{}{
+		"acks":       int16(-1),
+		"timeout_ms": int32(30000),
+		"topic_data": []interface{}{map[string]interface{}{"name": "orders", "partition_data": partitions}},
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	order1 := kafkaMock("mock-0", apiProduce, 9, produceBody("order-1", 1), "")
+	order2 := kafkaMock("mock-1", apiProduce, 9, produceBody("order-2", 1), "")
+	loaded := kafkaMock("mock-2", apiProduce, 9, produceBody("order-3", 1), "")
+	// the integers loaded from the yaml files aren't of the decoded types
+	loaded.Spec.KafkaRequest.Body["acks"] = -1
+	otherVersion := kafkaMock("mock-3", apiProduce, 8, produceBody("order-4", 1), "")
+	raw := kafkaMock("mock-4", 99, 0, nil, "AQID")
+
+	tests := []struct {
+		name       string
+		req        *models.KafkaRequest
+		filtered   []*models.Mock
+		unfiltered []*models.Mock
+		want       *models.Mock
+		isFiltered bool
+	}{
+		{
+			name:       "exact match",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce, APIVersion: 9}, Body: produceBody("order-2", 1)},
+			unfiltered: []*models.Mock{order1, order2},
+			want:       order2,
+		},
+		{
+			name:       "timestamps ignored",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce, APIVersion: 9}, Body: produceBody("order-1", int64(1700000000000))},
+			unfiltered: []*models.Mock{order2, order1},
+			want:       order1,
+		},
+		{
+			name:       "mocks of the test case first",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce, APIVersion: 9}, Body: produceBody("order-1", 1)},
+			filtered:   []*models.Mock{order1},
+			unfiltered: []*models.Mock{order1},
+			want:       order1,
+			isFiltered: true,
+		},
+		{
+			name:       "integers loaded from the yaml",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce, APIVersion: 9}, Body: produceBody("order-3", 1)},
+			unfiltered: []*models.Mock{loaded},
+			want:       loaded,
+		},
+		{
+			name:       "closest mock",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce, APIVersion: 9}, Body: produceBody("order-9", 1)},
+			unfiltered: []*models.Mock{order1},
+			want:       order1,
+		},
+		{
+			name: "no mock close enough",
+			req: &models.KafkaRequest{
+				Header: models.KafkaRequestHeader{APIKey: apiProduce, APIVersion: 9},
+				Body:   produceBody("", nil, map[string]interface{}{"index": int32(3)}),
+			},
+			unfiltered: []*models.Mock{order1},
+		},
+		{
+			name:       "other version",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce, APIVersion: 9}, Body: produceBody("order-4", 1)},
+			unfiltered: []*models.Mock{otherVersion},
+		},
+		{
+			name:       "raw body",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: 99}, RawBody: "AQID"},
+			unfiltered: []*models.Mock{raw},
+			want:       raw,
+		},
+		{
+			name:       "other raw body",
+			req:        &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: 99}, RawBody: "BAUG"},
+			unfiltered: []*models.Mock{raw},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isFiltered := bestMatch(zap.NewNop(), tt.req, tt.filtered, tt.unfiltered)
+			if got != tt.want || isFiltered != tt.isFiltered {
+				t.Errorf("bestMatch() = %v, %v, want %v, %v", mockName(got), isFiltered, mockName(tt.want), tt.isFiltered)
+			}
+		})
+	}
+}
+
+func mockName(mock *models.Mock) string {
+	if mock == nil {
+		return "nil"
+	}
+	return mock.Name
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]interface{}
+		want float64
+	}{
+		{name: "empty", a: map[string]interface{}{}, b: map[string]interface{}{}, want: 1},
+		{name: "same", a: map[string]interface{}{"a": 1, "b": "x"}, b: map[string]interface{}{"a": int32(1), "b": "x"}, want: 1},
+		{name: "half", a: map[string]interface{}{"a": 1, "b": "x"}, b: map[string]interface{}{"a": 1, "b": "y"}, want: 0.5},
+		{name: "missing field", a: map[string]interface{}{"a": 1}, b: map[string]interface{}{"a": 1, "b": "y"}, want: 0.5},
+		{name: "array lengths", a: map[string]interface{}{"a": []interface{}{1}}, b: map[string]interface{}{"a": []interface{}{1, 2}}, want: 1.0 / 3},
+		{name: "ignored fields", a: map[string]interface{}{"a": 1, "max_timestamp": 1}, b: map[string]interface{}{"a": 1, "max_timestamp": 2}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := similarity(flatten(tt.a), flatten(tt.b)); got != tt.want {
+				t.Errorf("similarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+//go:build linux
+
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// maxFrameSize bounds the size of a frame, so that a corrupt length doesn't exhaust the memory.
+// It is well above the default limit of the brokers (socket.request.max.bytes).
+const maxFrameSize = 1 << 30
+
+// frameReader reads the size delimited frames of a kafka connection.
+type frameReader struct {
+	conn net.Conn
+	buf  []byte
+}
+
+func newFrameReader(conn net.Conn, initial []byte) *frameReader {
+	return &frameReader{conn: conn, buf: initial}
+}
+
+// next returns the next complete frame, including its size prefix.
+func (fr *frameReader) next(ctx context.Context) ([]byte, error) {
+	chunk := make([]byte, 32*1024)
+	for {
+		if len(fr.buf) >= 4 {
+			size := int(int32(binary.BigEndian.Uint32(fr.buf)))
+			if size < 0 || size > maxFrameSize {
+				return nil, fmt.Errorf("invalid kafka frame size %d", size)
+			}
+			if len(fr.buf) >= 4+size {
+				frame := fr.buf[: 4+size : 4+size]
+				fr.buf = fr.buf[4+size:]
+				return frame, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		n, err := fr.conn.Read(chunk)
+		fr.buf = append(fr.buf, chunk[:n]...)
+		if err != nil {
+			if err == io.EOF && n > 0 {
+				continue
+			}
+			return nil, err
+		}
+	}
+}
+
+// decodeRequest decodes a request frame. The body of the APIs which are not decoded (or of the
+// versions newer than the known ones) is kept as base64.
+func decodeRequest(frame []byte) (*models.KafkaRequest, error) {
+	if len(frame) < 4 || int(binary.BigEndian.Uint32(frame)) != len(frame)-4 {
+		return nil, errors.New("incomplete kafka request frame")
+	}
+	r := &reader{buf: frame[4:]}
+	apiKey, err := r.int16()
+	if err != nil {
+		return nil, err
+	}
+	apiVersion, err := r.int16()
+	if err != nil {
+		return nil, err
+	}
+	correlationID, err := r.int32()
+	if err != nil {
+		return nil, err
+	}
+	// the client id is never a compact string, even in the flexible versions
+	clientID, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.KafkaRequest{
+		Header: models.KafkaRequestHeader{
+			APIKey:        apiKey,
+			APIVersion:    apiVersion,
+			CorrelationID: correlationID,
+		},
+	}
+	if clientID != nil {
+		req.Header.ClientID = clientID.(string)
+	}
+
+	schema, ok := apis[apiKey]
+	if !ok || apiVersion < 0 || apiVersion > schema.maxVersion {
+		req.RawBody = base64.StdEncoding.EncodeToString(r.buf[r.off:])
+		return req, nil
+	}
+	req.Header.APIName = schema.name
+
+	r.flexible = schema.flexible(apiVersion)
+	// request header v2 carries tagged fields
+	if err := r.skipTaggedFields(); err != nil {
+		return nil, err
+	}
+	req.Body, err = decodeFields(r, schema.request, apiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the %s v%d request: %w", schema.name, apiVersion, err)
+	}
+	if r.remaining() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes in the %s v%d request", r.remaining(), schema.name, apiVersion)
+	}
+	return req, nil
+}
+
+// decodeResponse decodes a response frame of the given request. The body is left out if it can't be
+// decoded, since the payload is replayed as it is.
+func decodeResponse(frame []byte, req models.KafkaRequestHeader) (*models.KafkaResponse, error) {
+	if len(frame) < 8 {
+		return nil, errors.New("incomplete kafka response frame")
+	}
+	resp := &models.KafkaResponse{
+		CorrelationID: int32(binary.BigEndian.Uint32(frame[4:8])),
+		Payload:       base64.StdEncoding.EncodeToString(frame),
+	}
+	schema, ok := apis[req.APIKey]
+	if !ok || req.APIVersion > schema.maxVersion {
+		return resp, nil
+	}
+
+	r := &reader{buf: frame[8:], flexible: schema.flexible(req.APIVersion)}
+	// response header v1 carries tagged fields, except for ApiVersions whose response header is
+	// always v0, so that the clients can parse it without knowing the version of the broker.
+	if req.APIKey != apiAPIVersions {
+		if err := r.skipTaggedFields(); err != nil {
+			return resp, err
+		}
+	}
+	body, err := decodeFields(r, schema.response, req.APIVersion)
+	if err != nil {
+		return resp, fmt.Errorf("failed to decode the %s v%d response: %w", schema.name, req.APIVersion, err)
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// isFireAndForget tells whether the broker doesn't respond to the request, i.e. a produce request with acks=0.
+func isFireAndForget(req *models.KafkaRequest) bool {
+	if req.Header.APIKey != apiProduce || req.Body == nil {
+		return false
+	}
+	acks, ok := req.Body["acks"].(int16)
+	return ok && acks == 0
+}
@@ -0,0 +1,334 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// builder encodes the primitive types of the kafka protocol.
+type builder struct {
+	b []byte
+}
+
+func (w *builder) int8(v int8) *builder {
+	w.b = append(w.b, byte(v))
+	return w
+}
+
+func (w *builder) int16(v int16) *builder {
+	w.b = binary.BigEndian.AppendUint16(w.b, uint16(v))
+	return w
+}
+
+func (w *builder) int32(v int32) *builder {
+	w.b = binary.BigEndian.AppendUint32(w.b, uint32(v))
+	return w
+}
+
+func (w *builder) int64(v int64) *builder {
+	w.b = binary.BigEndian.AppendUint64(w.b, uint64(v))
+	return w
+}
+
+func (w *builder) uvarint(v uint64) *builder {
+	w.b = binary.AppendUvarint(w.b, v)
+	return w
+}
+
+func (w *builder) varint(v int64) *builder {
+	w.b = binary.AppendVarint(w.b, v)
+	return w
+}
+
+func (w *builder) raw(b []byte) *builder {
+	w.b = append(w.b, b...)
+	return w
+}
+
+// str encodes a classic string, int16 length prefixed.
+func (w *builder) str(s string) *builder {
+	return w.int16(int16(len(s))).raw([]byte(s))
+}
+
+// cstr encodes a compact string, unsigned varint length+1 prefixed.
+func (w *builder) cstr(s string) *builder {
+	return w.uvarint(uint64(len(s) + 1)).raw([]byte(s))
+}
+
+// frame prefixes the bytes with their size.
+func (w *builder) frame() []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(w.b))), w.b...)
+}
+
+func requestHeader(apiKey, apiVersion int16, correlationID int32) *builder {
+	return (&builder{}).int16(apiKey).int16(apiVersion).int32(correlationID).str("app")
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		buf      []byte
+		flexible bool
+		read     func(r *reader) (interface{}, error)
+		want     interface{}
+		wantErr  bool
+	}{
+		{name: "string", buf: (&builder{}).str("abc").b, read: func(r *reader) (interface{}, error) { return r.string() }, want: "abc"},
+		{name: "null string", buf: (&builder{}).int16(-1).b, read: func(r *reader) (interface{}, error) { return r.string() }, want: nil},
+		{name: "compact string", buf: (&builder{}).cstr("abc").b, flexible: true, read: func(r *reader) (interface{}, error) { return r.string() }, want: "abc"},
+		{name: "null compact string", buf: (&builder{}).uvarint(0).b, flexible: true, read: func(r *reader) (interface{}, error) { return r.string() }, want: nil},
+		{name: "short string", buf: (&builder{}).int16(5).raw([]byte("ab")).b, read: func(r *reader) (interface{}, error) { return r.string() }, wantErr: true},
+		{name: "bytes", buf: (&builder{}).int32(2).raw([]byte{1, 2}).b, read: func(r *reader) (interface{}, error) { return r.bytes() }, want: []byte{1, 2}},
+		{name: "uuid", buf: make([]byte, 16), read: func(r *reader) (interface{}, error) { return r.uuid() }, want: "AAAAAAAAAAAAAAAAAAAAAA"},
+		{
+			name:     "tagged fields",
+			buf:      (&builder{}).uvarint(2).uvarint(0).uvarint(1).int8(9).uvarint(1).uvarint(2).int16(7).int8(42).b,
+			flexible: true,
+			read: func(r *reader) (interface{}, error) {
+				if err := r.skipTaggedFields(); err != nil {
+					return nil, err
+				}
+				return r.int8()
+			},
+			want: int8(42),
+		},
+		{name: "empty varint", buf: nil, read: func(r *reader) (interface{}, error) { return r.uvarint() }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.read(&reader{buf: tt.buf, flexible: tt.flexible})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("read error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("read = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderBytes(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want interface{}
+	}{
+		{b: nil, want: nil},
+		{b: []byte(`{"id":1}`), want: `{"id":1}`},
+		{b: []byte("line\n"), want: "line\n"},
+		{b: []byte{0, 1, 2}, want: base64.StdEncoding.EncodeToString([]byte{0, 1, 2})},
+		{b: []byte{0xff}, want: base64.StdEncoding.EncodeToString([]byte{0xff})},
+	}
+	for _, tt := range tests {
+		if got := renderBytes(tt.b); got != tt.want {
+			t.Errorf("renderBytes(%q) = %#v, want %#v", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   []byte
+		apiName string
+		body    map[string]interface{}
+		rawBody string
+		wantErr bool
+	}{
+		{
+			name:    "classic version",
+			frame:   requestHeader(apiMetadata, 1, 7).int32(1).str("orders").frame(),
+			apiName: "Metadata",
+			body:    map[string]interface{}{"topics": []interface{}{map[string]interface{}{"name": "orders"}}},
+		},
+		{
+			// the header and every structure end with their tagged fields
+			name:    "flexible version",
+			frame:   requestHeader(apiMetadata, 9, 7).uvarint(0).uvarint(2).cstr("orders").uvarint(0).int8(1).int8(0).int8(1).uvarint(0).frame(),
+			apiName: "Metadata",
+			body: map[string]interface{}{
+				"topics":                                []interface{}{map[string]interface{}{"name": "orders"}},
+				"allow_auto_topic_creation":             true,
+				"include_cluster_authorized_operations": false,
+				"include_topic_authorized_operations":   true,
+			},
+		},
+		{
+			name:    "api versions",
+			frame:   requestHeader(apiAPIVersions, 3, 1).uvarint(0).cstr("librdkafka").cstr("2.3.0").uvarint(0).frame(),
+			apiName: "ApiVersions",
+			body:    map[string]interface{}{"client_software_name": "librdkafka", "client_software_version": "2.3.0"},
+		},
+		{
+			name:    "api which isn't decoded",
+			frame:   requestHeader(99, 0, 1).raw([]byte{1, 2, 3}).frame(),
+			rawBody: base64.StdEncoding.EncodeToString([]byte{1, 2, 3}),
+		},
+		{
+			name:    "version newer than the known ones",
+			frame:   requestHeader(apiMetadata, 42, 1).raw([]byte{4, 5}).frame(),
+			rawBody: base64.StdEncoding.EncodeToString([]byte{4, 5}),
+		},
+		{name: "trailing bytes", frame: requestHeader(apiMetadata, 1, 7).int32(0).int8(1).frame(), wantErr: true},
+		{name: "truncated body", frame: requestHeader(apiMetadata, 1, 7).int32(1).int16(6).raw([]byte("ord")).frame(), wantErr: true},
+		{name: "corrupt array length", frame: requestHeader(apiMetadata, 1, 7).int32(1 << 30).frame(), wantErr: true},
+		{name: "size mismatch", frame: append(requestHeader(apiMetadata, 1, 7).int32(0).frame(), 0), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := decodeRequest(tt.frame)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if req.Header.ClientID != "app" || req.Header.APIName != tt.apiName {
+				t.Errorf("decodeRequest() header = %+v, want the client app and the api %q", req.Header, tt.apiName)
+			}
+			if !reflect.DeepEqual(req.Body, tt.body) {
+				t.Errorf("decodeRequest() body = %#v, want %#v", req.Body, tt.body)
+			}
+			if req.RawBody != tt.rawBody {
+				t.Errorf("decodeRequest() raw body = %q, want %q", req.RawBody, tt.rawBody)
+			}
+		})
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	// the response header of ApiVersions has no tagged fields, even in the flexible versions
+	apiVersions := (&builder{}).int32(5).int16(0).
+		uvarint(2).int16(apiAPIVersions).int16(0).int16(3).uvarint(0).
+		int32(0).uvarint(0).frame()
+	resp, err := decodeResponse(apiVersions, models.KafkaRequestHeader{APIKey: apiAPIVersions, APIVersion: 3})
+	if err != nil {
+		t.Fatalf("decodeResponse() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"error_code": int16(0),
+		"api_keys": []interface{}{map[string]interface{}{
+			"api_key": apiAPIVersions, "min_version": int16(0), "max_version": int16(3),
+		}},
+		"throttle_time_ms": int32(0),
+	}
+	if resp.CorrelationID != 5 || !reflect.DeepEqual(resp.Body, want) {
+		t.Errorf("decodeResponse() = %d, %#v, want 5, %#v", resp.CorrelationID, resp.Body, want)
+	}
+	if resp.Payload != base64.StdEncoding.EncodeToString(apiVersions) {
+		t.Error("decodeResponse() payload isn't the raw frame")
+	}
+
+	// the payload is kept for the responses which can't be decoded
+	resp, err = decodeResponse(apiVersions, models.KafkaRequestHeader{APIKey: apiMetadata, APIVersion: 9})
+	if err == nil || resp == nil || resp.Body != nil || resp.Payload == "" {
+		t.Errorf("decodeResponse() of a corrupt response = %+v, %v, want the payload alone and an error", resp, err)
+	}
+	if _, err := decodeResponse([]byte{0, 0, 0, 1}, models.KafkaRequestHeader{}); err == nil {
+		t.Error("decodeResponse() of an incomplete frame didn't fail")
+	}
+}
+
+// recordBatch encodes a record batch of the records, compressing them with the codec.
+func recordBatch(t *testing.T, codec int16, values ...string) []byte {
+	t.Helper()
+	records := &builder{}
+	for i, value := range values {
+		rec := (&builder{}).int8(0).varint(int64(i)).varint(int64(i)).
+			varint(-1). // null key
+			varint(int64(len(value))).raw([]byte(value)).
+			varint(1).varint(2).raw([]byte("id")).varint(1).raw([]byte("x"))
+		records.varint(int64(len(rec.b))).raw(rec.b)
+	}
+	payload := records.b
+	if codec == 1 {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		payload = buf.Bytes()
+	}
+	// the fields after the batch length
+	rest := (&builder{}).int32(0).int8(2).int32(0).int16(codec).int32(int32(len(values) - 1)).
+		int64(1700000000000).int64(1700000000001).int64(-1).int16(-1).int32(-1).int32(int32(len(values))).
+		raw(payload)
+	return (&builder{}).int64(0).int32(int32(len(rest.b))).raw(rest.b).b
+}
+
+func TestDecodeRecordBatches(t *testing.T) {
+	record := func(value string, i int64) interface{} {
+		return map[string]interface{}{
+			"attributes":      int8(0),
+			"timestamp_delta": i,
+			"offset_delta":    i,
+			"key":             nil,
+			"value":           value,
+			"headers":         []interface{}{map[string]interface{}{"key": "id", "value": "x"}},
+		}
+	}
+	legacy := (&builder{}).int64(0).int32(6).int32(0).int8(1).int8(0).b
+
+	tests := []struct {
+		name    string
+		b       []byte
+		records [][]interface{} // the records of each batch, nil for a raw batch
+	}{
+		{name: "uncompressed", b: recordBatch(t, 0, "a", "b"), records: [][]interface{}{{record("a", 0), record("b", 1)}}},
+		{name: "gzip", b: recordBatch(t, 1, `{"id":1}`), records: [][]interface{}{{record(`{"id":1}`, 0)}}},
+		{
+			name:    "partial batch at the end",
+			b:       append(recordBatch(t, 0, "a"), recordBatch(t, 0, "b")[:30]...),
+			records: [][]interface{}{{record("a", 0)}},
+		},
+		{name: "legacy message set", b: legacy, records: [][]interface{}{nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := decodeRecordBatches(tt.b)
+			if len(batches) != len(tt.records) {
+				t.Fatalf("decodeRecordBatches() = %d batches, want %d", len(batches), len(tt.records))
+			}
+			for i, batch := range batches {
+				b := batch.(map[string]interface{})
+				if tt.records[i] == nil {
+					if b["raw"] == nil {
+						t.Errorf("batch %d = %v, want it kept raw", i, b)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(b["records"], tt.records[i]) {
+					t.Errorf("batch %d records = %#v, want %#v", i, b["records"], tt.records[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsFireAndForget(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *models.KafkaRequest
+		want bool
+	}{
+		{name: "acks=0", req: &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce}, Body: map[string]interface{}{"acks": int16(0)}}, want: true},
+		{name: "acks=all", req: &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce}, Body: map[string]interface{}{"acks": int16(-1)}}},
+		{name: "raw produce", req: &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiProduce}}},
+		{name: "fetch", req: &models.KafkaRequest{Header: models.KafkaRequestHeader{APIKey: apiFetch}, Body: map[string]interface{}{"acks": int16(0)}}},
+	}
+	for _, tt := range tests {
+		if got := isFireAndForget(tt.req); got != tt.want {
+			t.Errorf("isFireAndForget() of %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+//go:build linux
+
+package kafka
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+var errShortBuffer = errors.New("kafka message is shorter than expected")
+
+// reader decodes the primitive types of the kafka protocol. The flexible versions of a message use
+// the compact (unsigned varint length prefixed) encoding for the strings, bytes and arrays, and carry
+// tagged fields at the end of every structure.
+//
+// see https://kafka.apache.org/protocol#protocol_types
+type reader struct {
+	buf      []byte
+	off      int
+	flexible bool
+}
+
+func (r *reader) remaining() int {
+	return len(r.buf) - r.off
+}
+
+func (r *reader) next(n int) ([]byte, error) {
+	if n < 0 || r.remaining() < n {
+		return nil, errShortBuffer
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *reader) int8() (int8, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return int8(b[0]), nil
+}
+
+func (r *reader) int16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *reader) int32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *reader) int64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *reader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.off:])
+	if n <= 0 {
+		return 0, errShortBuffer
+	}
+	r.off += n
+	return v, nil
+}
+
+func (r *reader) varint() (int64, error) {
+	v, n := binary.Varint(r.buf[r.off:])
+	if n <= 0 {
+		return 0, errShortBuffer
+	}
+	r.off += n
+	return v, nil
+}
+
+func (r *reader) bool() (bool, error) {
+	v, err := r.int8()
+	return v != 0, err
+}
+
+// uuid returns the uuid in the base64 url encoding, which is how kafka displays the topic ids.
+func (r *reader) uuid() (string, error) {
+	b, err := r.next(16)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// length reads the length prefix of a nullable string, bytes or array. A negative length means null.
+func (r *reader) length(classic func() (int, error)) (int, error) {
+	if !r.flexible {
+		return classic()
+	}
+	v, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	return int(v) - 1, nil
+}
+
+// string reads a (nullable) string, which is returned as nil if it is null.
+func (r *reader) string() (interface{}, error) {
+	n, err := r.length(func() (int, error) {
+		v, err := r.int16()
+		return int(v), err
+	})
+	if err != nil || n < 0 {
+		return nil, err
+	}
+	b, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// bytes reads (nullable) bytes, which are returned as nil if they are null.
+func (r *reader) bytes() ([]byte, error) {
+	n, err := r.length(func() (int, error) {
+		v, err := r.int32()
+		return int(v), err
+	})
+	if err != nil || n < 0 {
+		return nil, err
+	}
+	return r.next(n)
+}
+
+func (r *reader) arrayLen() (int, error) {
+	return r.length(func() (int, error) {
+		v, err := r.int32()
+		return int(v), err
+	})
+}
+
+// skipTaggedFields skips the tagged fields of a structure in a flexible version, none of them is decoded.
+func (r *reader) skipTaggedFields() error {
+	if !r.flexible {
+		return nil
+	}
+	count, err := r.uvarint()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		if _, err := r.uvarint(); err != nil {
+			return err
+		}
+		size, err := r.uvarint()
+		if err != nil {
+			return err
+		}
+		if _, err := r.next(int(size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderBytes renders the bytes as text if they are printable, and as base64 otherwise.
+func renderBytes(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	if !utf8.Valid(b) {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	for _, c := range string(b) {
+		if !unicode.IsPrint(c) && !unicode.IsSpace(c) {
+			return base64.StdEncoding.EncodeToString(b)
+		}
+	}
+	return string(b)
+}
+
+// fieldType is the type of a field in the schema of a kafka message.
+type fieldType int
+
+const (
+	typeInt8 fieldType = iota
+	typeInt16
+	typeInt32
+	typeInt64
+	typeBool
+	typeUUID
+	typeString
+	typeBytes
+	typeRecords
+	typeArray  // array of the primitive type in elem
+	typeStruct // array of structures made of fields
+)
+
+// field is a field of a kafka message, which is present in the versions [minVersion, maxVersion].
+type field struct {
+	name       string
+	typ        fieldType
+	elem       fieldType
+	fields     []field
+	minVersion int16
+	maxVersion int16 // -1 means the field is present in all the versions since minVersion
+}
+
+func newField(name string, typ fieldType) field {
+	return field{name: name, typ: typ, maxVersion: -1}
+}
+
+// array declares an array of primitives.
+func array(name string, elem fieldType) field {
+	f := newField(name, typeArray)
+	f.elem = elem
+	return f
+}
+
+// structs declares an array of structures.
+func structs(name string, fields ...field) field {
+	f := newField(name, typeStruct)
+	f.fields = fields
+	return f
+}
+
+func (f field) from(version int16) field {
+	f.minVersion = version
+	return f
+}
+
+func (f field) until(version int16) field {
+	f.maxVersion = version
+	return f
+}
+
+func (f field) in(version int16) bool {
+	return version >= f.minVersion && (f.maxVersion < 0 || version <= f.maxVersion)
+}
+
+// decodeFields decodes a structure made of the fields present in the version, and its tagged fields.
+func decodeFields(r *reader, fields []field, version int16) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if !f.in(version) {
+			continue
+		}
+		var (
+			v   interface{}
+			err error
+		)
+		switch f.typ {
+		case typeArray, typeStruct:
+			v, err = decodeArray(r, f, version)
+		default:
+			v, err = decodePrimitive(r, f.typ)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", f.name, err)
+		}
+		out[f.name] = v
+	}
+	if err := r.skipTaggedFields(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeArray(r *reader, f field, version int16) (interface{}, error) {
+	n, err := r.arrayLen()
+	if err != nil || n < 0 {
+		return nil, err
+	}
+	if n > r.remaining() {
+		// every element takes at least a byte, so the length is corrupt
+		return nil, errShortBuffer
+	}
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		var item interface{}
+		if f.typ == typeStruct {
+			item, err = decodeFields(r, f.fields, version)
+		} else {
+			item, err = decodePrimitive(r, f.elem)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func decodePrimitive(r *reader, typ fieldType) (interface{}, error) {
+	switch typ {
+	case typeInt8:
+		return r.int8()
+	case typeInt16:
+		return r.int16()
+	case typeInt32:
+		return r.int32()
+	case typeInt64:
+		return r.int64()
+	case typeBool:
+		return r.bool()
+	case typeUUID:
+		return r.uuid()
+	case typeString:
+		return r.string()
+	case typeBytes:
+		b, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return renderBytes(b), nil
+	case typeRecords:
+		b, err := r.bytes()
+		if err != nil || b == nil {
+			return nil, err
+		}
+		return decodeRecordBatches(b), nil
+	}
+	return nil, fmt.Errorf("unknown field type %d", typ)
+}
@@ -0,0 +1,239 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// constants for the record batches
+//
+// see https://kafka.apache.org/documentation/#recordbatch
+const (
+	// batchHeaderLen is the length of the batch header up to (and including) the record count.
+	batchHeaderLen = 61
+	// magicOffset is the offset of the magic byte, which is the version of the batch format.
+	magicOffset = 16
+	// compressionMask is the mask of the compression codec in the batch attributes.
+	compressionMask = 0x07
+)
+
+var compressionCodecs = map[int16]string{
+	0: "none",
+	1: "gzip",
+	2: "snappy",
+	3: "lz4",
+	4: "zstd",
+}
+
+// xerialHeader starts the snappy payloads framed by the java clients.
+var xerialHeader = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+// decodeRecordBatches decodes the record batches of a produce request or a fetch response. A fetch
+// response may end with a partial batch, which is left out. The legacy message sets (magic 0 and 1)
+// and the batches which can't be decompressed are kept as base64.
+func decodeRecordBatches(b []byte) []interface{} {
+	var batches []interface{}
+	for len(b) > magicOffset {
+		batchLen := int(int32(binary.BigEndian.Uint32(b[8:12])))
+		if batchLen < 0 || len(b) < 12+batchLen {
+			// partial batch at the end of a fetch response
+			break
+		}
+		batch := b[:12+batchLen]
+		b = b[12+batchLen:]
+
+		if batch[magicOffset] != 2 || len(batch) < batchHeaderLen {
+			batches = append(batches, map[string]interface{}{
+				"magic": int8(batch[magicOffset]),
+				"raw":   base64.StdEncoding.EncodeToString(batch),
+			})
+			continue
+		}
+		batches = append(batches, decodeRecordBatch(batch))
+	}
+	return batches
+}
+
+func decodeRecordBatch(batch []byte) map[string]interface{} {
+	r := &reader{buf: batch}
+	// the errors are not checked as the length of the header has been checked by the caller
+	baseOffset, _ := r.int64()
+	_, _ = r.int32() // batch length
+	leaderEpoch, _ := r.int32()
+	magic, _ := r.int8()
+	_, _ = r.int32() // crc
+	attributes, _ := r.int16()
+	lastOffsetDelta, _ := r.int32()
+	baseTimestamp, _ := r.int64()
+	maxTimestamp, _ := r.int64()
+	producerID, _ := r.int64()
+	producerEpoch, _ := r.int16()
+	baseSequence, _ := r.int32()
+	count, _ := r.int32()
+
+	codec := attributes & compressionMask
+	out := map[string]interface{}{
+		"base_offset":            baseOffset,
+		"partition_leader_epoch": leaderEpoch,
+		"magic":                  magic,
+		"attributes":             attributes,
+		"compression":            compressionCodecs[codec],
+		"last_offset_delta":      lastOffsetDelta,
+		"base_timestamp":         baseTimestamp,
+		"max_timestamp":          maxTimestamp,
+		"producer_id":            producerID,
+		"producer_epoch":         producerEpoch,
+		"base_sequence":          baseSequence,
+	}
+
+	payload, err := decompress(codec, batch[batchHeaderLen:])
+	if err != nil {
+		out["records_raw"] = base64.StdEncoding.EncodeToString(batch[batchHeaderLen:])
+		return out
+	}
+	records, err := decodeRecords(payload, int(count))
+	if err != nil {
+		out["records_raw"] = base64.StdEncoding.EncodeToString(batch[batchHeaderLen:])
+		return out
+	}
+	out["records"] = records
+	return out
+}
+
+func decompress(codec int16, b []byte) ([]byte, error) {
+	switch codec {
+	case 0:
+		return b, nil
+	case 1:
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case 2:
+		if !bytes.HasPrefix(b, xerialHeader) {
+			return snappy.Decode(nil, b)
+		}
+		// xerial framing: header, version and compatible version, followed by length prefixed blocks
+		var out []byte
+		for b = b[len(xerialHeader)+8:]; len(b) > 0; {
+			if len(b) < 4 {
+				return nil, errShortBuffer
+			}
+			n := int(binary.BigEndian.Uint32(b))
+			if len(b) < 4+n {
+				return nil, errShortBuffer
+			}
+			block, err := snappy.Decode(nil, b[4:4+n])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, block...)
+			b = b[4+n:]
+		}
+		return out, nil
+	case 4:
+		zr, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return zr.DecodeAll(b, nil)
+	}
+	return nil, fmt.Errorf("unsupported compression codec %d", codec)
+}
+
+// decodeRecords decodes the records of a batch, whose fields are varint encoded.
+func decodeRecords(b []byte, count int) ([]interface{}, error) {
+	r := &reader{buf: b}
+	records := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		length, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		rec, err := r.next(int(length))
+		if err != nil {
+			return nil, err
+		}
+		record, err := decodeRecord(&reader{buf: rec})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func decodeRecord(r *reader) (map[string]interface{}, error) {
+	attributes, err := r.int8()
+	if err != nil {
+		return nil, err
+	}
+	timestampDelta, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	offsetDelta, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	key, err := varBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	value, err := varBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	headerCount, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if headerCount < 0 || int(headerCount) > r.remaining() {
+		return nil, errors.New("invalid record header count")
+	}
+	headers := make([]interface{}, 0, headerCount)
+	for i := int64(0); i < headerCount; i++ {
+		hKey, err := varBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		hValue, err := varBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, map[string]interface{}{
+			"key":   renderBytes(hKey),
+			"value": renderBytes(hValue),
+		})
+	}
+	return map[string]interface{}{
+		"attributes":      attributes,
+		"timestamp_delta": timestampDelta,
+		"offset_delta":    offsetDelta,
+		"key":             renderBytes(key),
+		"value":           renderBytes(value),
+		"headers":         headers,
+	}, nil
+}
+
+// varBytes reads the varint length prefixed bytes of a record, a negative length means null.
+func varBytes(r *reader) ([]byte, error) {
+	n, err := r.varint()
+	if err != nil || n < 0 {
+		return nil, err
+	}
+	return r.next(int(n))
+}
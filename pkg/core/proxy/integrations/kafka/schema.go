@@ -0,0 +1,321 @@
+//go:build linux
+
+package kafka
+
+// API keys of the decoded kafka APIs.
+//
+// see https://kafka.apache.org/protocol#protocol_api_keys
+const (
+	apiProduce         int16 = 0
+	apiFetch           int16 = 1
+	apiMetadata        int16 = 3
+	apiOffsetCommit    int16 = 8
+	apiFindCoordinator int16 = 10
+	apiJoinGroup       int16 = 11
+	apiHeartbeat       int16 = 12
+	apiSyncGroup       int16 = 14
+	apiAPIVersions     int16 = 18
+)
+
+// apiSchema is the schema of the request and the response of a kafka API, following the message
+// definitions of kafka (clients/src/main/resources/common/message). The versions newer than
+// maxVersion are not decoded, as their layout is unknown.
+type apiSchema struct {
+	name         string
+	maxVersion   int16
+	flexibleFrom int16
+	// perTest tells whether the mocks of the API belong to the test case during which they are
+	// recorded. The mocks of the other APIs are shared by the whole test set, as the requests are
+	// repeated by the clients regardless of the test case (e.g. heartbeats and fetches).
+	perTest  bool
+	request  []field
+	response []field
+}
+
+func (s apiSchema) flexible(version int16) bool {
+	return version >= s.flexibleFrom
+}
+
+var apis = map[int16]apiSchema{
+	apiProduce: {
+		name:         "Produce",
+		maxVersion:   11,
+		flexibleFrom: 9,
+		perTest:      true,
+		request: []field{
+			newField("transactional_id", typeString).from(3),
+			newField("acks", typeInt16),
+			newField("timeout_ms", typeInt32),
+			structs("topic_data",
+				newField("name", typeString),
+				structs("partition_data",
+					newField("index", typeInt32),
+					newField("records", typeRecords),
+				),
+			),
+		},
+		response: []field{
+			structs("responses",
+				newField("name", typeString),
+				structs("partition_responses",
+					newField("index", typeInt32),
+					newField("error_code", typeInt16),
+					newField("base_offset", typeInt64),
+					newField("log_append_time_ms", typeInt64).from(2),
+					newField("log_start_offset", typeInt64).from(5),
+					structs("record_errors",
+						newField("batch_index", typeInt32),
+						newField("batch_index_error_message", typeString),
+					).from(8),
+					newField("error_message", typeString).from(8),
+				),
+			),
+			newField("throttle_time_ms", typeInt32).from(1),
+		},
+	},
+	apiFetch: {
+		name:         "Fetch",
+		maxVersion:   17,
+		flexibleFrom: 12,
+		request: []field{
+			newField("replica_id", typeInt32).until(14),
+			newField("max_wait_ms", typeInt32),
+			newField("min_bytes", typeInt32),
+			newField("max_bytes", typeInt32).from(3),
+			newField("isolation_level", typeInt8).from(4),
+			newField("session_id", typeInt32).from(7),
+			newField("session_epoch", typeInt32).from(7),
+			structs("topics",
+				newField("topic", typeString).until(12),
+				newField("topic_id", typeUUID).from(13),
+				structs("partitions",
+					newField("partition", typeInt32),
+					newField("current_leader_epoch", typeInt32).from(9),
+					newField("fetch_offset", typeInt64),
+					newField("last_fetched_epoch", typeInt32).from(12),
+					newField("log_start_offset", typeInt64).from(5),
+					newField("partition_max_bytes", typeInt32),
+				),
+			),
+			structs("forgotten_topics_data",
+				newField("topic", typeString).until(12),
+				newField("topic_id", typeUUID).from(13),
+				array("partitions", typeInt32),
+			).from(7),
+			newField("rack_id", typeString).from(11),
+		},
+		response: []field{
+			newField("throttle_time_ms", typeInt32).from(1),
+			newField("error_code", typeInt16).from(7),
+			newField("session_id", typeInt32).from(7),
+			structs("responses",
+				newField("topic", typeString).until(12),
+				newField("topic_id", typeUUID).from(13),
+				structs("partitions",
+					newField("partition_index", typeInt32),
+					newField("error_code", typeInt16),
+					newField("high_watermark", typeInt64),
+					newField("last_stable_offset", typeInt64).from(4),
+					newField("log_start_offset", typeInt64).from(5),
+					structs("aborted_transactions",
+						newField("producer_id", typeInt64),
+						newField("first_offset", typeInt64),
+					).from(4),
+					newField("preferred_read_replica", typeInt32).from(11),
+					newField("records", typeRecords),
+				),
+			),
+		},
+	},
+	apiMetadata: {
+		name:         "Metadata",
+		maxVersion:   12,
+		flexibleFrom: 9,
+		request: []field{
+			structs("topics",
+				newField("topic_id", typeUUID).from(10),
+				newField("name", typeString),
+			),
+			newField("allow_auto_topic_creation", typeBool).from(4),
+			newField("include_cluster_authorized_operations", typeBool).from(8).until(10),
+			newField("include_topic_authorized_operations", typeBool).from(8),
+		},
+		response: []field{
+			newField("throttle_time_ms", typeInt32).from(3),
+			structs("brokers",
+				newField("node_id", typeInt32),
+				newField("host", typeString),
+				newField("port", typeInt32),
+				newField("rack", typeString).from(1),
+			),
+			newField("cluster_id", typeString).from(2),
+			newField("controller_id", typeInt32).from(1),
+			structs("topics",
+				newField("error_code", typeInt16),
+				newField("name", typeString),
+				newField("topic_id", typeUUID).from(10),
+				newField("is_internal", typeBool).from(1),
+				structs("partitions",
+					newField("error_code", typeInt16),
+					newField("partition_index", typeInt32),
+					newField("leader_id", typeInt32),
+					newField("leader_epoch", typeInt32).from(7),
+					array("replica_nodes", typeInt32),
+					array("isr_nodes", typeInt32),
+					array("offline_replicas", typeInt32).from(5),
+				),
+				newField("topic_authorized_operations", typeInt32).from(8),
+			),
+			newField("cluster_authorized_operations", typeInt32).from(8).until(10),
+		},
+	},
+	apiOffsetCommit: {
+		name:         "OffsetCommit",
+		maxVersion:   9,
+		flexibleFrom: 8,
+		perTest:      true,
+		request: []field{
+			newField("group_id", typeString),
+			newField("generation_id_or_member_epoch", typeInt32).from(1),
+			newField("member_id", typeString).from(1),
+			newField("group_instance_id", typeString).from(7),
+			newField("retention_time_ms", typeInt64).from(2).until(4),
+			structs("topics",
+				newField("name", typeString),
+				structs("partitions",
+					newField("partition_index", typeInt32),
+					newField("committed_offset", typeInt64),
+					newField("committed_leader_epoch", typeInt32).from(6),
+					newField("commit_timestamp", typeInt64).from(1).until(1),
+					newField("committed_metadata", typeString),
+				),
+			),
+		},
+		response: []field{
+			newField("throttle_time_ms", typeInt32).from(3),
+			structs("topics",
+				newField("name", typeString),
+				structs("partitions",
+					newField("partition_index", typeInt32),
+					newField("error_code", typeInt16),
+				),
+			),
+		},
+	},
+	apiFindCoordinator: {
+		name:         "FindCoordinator",
+		maxVersion:   6,
+		flexibleFrom: 3,
+		request: []field{
+			newField("key", typeString).until(3),
+			newField("key_type", typeInt8).from(1),
+			array("coordinator_keys", typeString).from(4),
+		},
+		response: []field{
+			newField("throttle_time_ms", typeInt32).from(1),
+			newField("error_code", typeInt16).until(3),
+			newField("error_message", typeString).from(1).until(3),
+			newField("node_id", typeInt32).until(3),
+			newField("host", typeString).until(3),
+			newField("port", typeInt32).until(3),
+			structs("coordinators",
+				newField("key", typeString),
+				newField("node_id", typeInt32),
+				newField("host", typeString),
+				newField("port", typeInt32),
+				newField("error_code", typeInt16),
+				newField("error_message", typeString),
+			).from(4),
+		},
+	},
+	apiJoinGroup: {
+		name:         "JoinGroup",
+		maxVersion:   9,
+		flexibleFrom: 6,
+		request: []field{
+			newField("group_id", typeString),
+			newField("session_timeout_ms", typeInt32),
+			newField("rebalance_timeout_ms", typeInt32).from(1),
+			newField("member_id", typeString),
+			newField("group_instance_id", typeString).from(5),
+			newField("protocol_type", typeString),
+			structs("protocols",
+				newField("name", typeString),
+				newField("metadata", typeBytes),
+			),
+			newField("reason", typeString).from(8),
+		},
+		response: []field{
+			newField("throttle_time_ms", typeInt32).from(2),
+			newField("error_code", typeInt16),
+			newField("generation_id", typeInt32),
+			newField("protocol_type", typeString).from(7),
+			newField("protocol_name", typeString),
+			newField("leader", typeString),
+			newField("skip_assignment", typeBool).from(9),
+			newField("member_id", typeString),
+			structs("members",
+				newField("member_id", typeString),
+				newField("group_instance_id", typeString).from(5),
+				newField("metadata", typeBytes),
+			),
+		},
+	},
+	apiHeartbeat: {
+		name:         "Heartbeat",
+		maxVersion:   4,
+		flexibleFrom: 4,
+		request: []field{
+			newField("group_id", typeString),
+			newField("generation_id", typeInt32),
+			newField("member_id", typeString),
+			newField("group_instance_id", typeString).from(3),
+		},
+		response: []field{
+			newField("throttle_time_ms", typeInt32).from(1),
+			newField("error_code", typeInt16),
+		},
+	},
+	apiSyncGroup: {
+		name:         "SyncGroup",
+		maxVersion:   5,
+		flexibleFrom: 4,
+		request: []field{
+			newField("group_id", typeString),
+			newField("generation_id", typeInt32),
+			newField("member_id", typeString),
+			newField("group_instance_id", typeString).from(3),
+			newField("protocol_type", typeString).from(5),
+			newField("protocol_name", typeString).from(5),
+			structs("assignments",
+				newField("member_id", typeString),
+				newField("assignment", typeBytes),
+			),
+		},
+		response: []field{
+			newField("throttle_time_ms", typeInt32).from(1),
+			newField("error_code", typeInt16),
+			newField("protocol_type", typeString).from(5),
+			newField("protocol_name", typeString).from(5),
+			newField("assignment", typeBytes),
+		},
+	},
+	apiAPIVersions: {
+		name:         "ApiVersions",
+		maxVersion:   4,
+		flexibleFrom: 3,
+		request: []field{
+			newField("client_software_name", typeString).from(3),
+			newField("client_software_version", typeString).from(3),
+		},
+		response: []field{
+			newField("error_code", typeInt16),
+			structs("api_keys",
+				newField("api_key", typeInt16),
+				newField("min_version", typeInt16),
+				newField("max_version", typeInt16),
+			),
+			newField("throttle_time_ms", typeInt32).from(1),
+		},
+	},
+}
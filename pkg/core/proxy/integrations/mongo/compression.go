@@ -0,0 +1,76 @@
+//go:build linux
+
+package mongo
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+)
+
+// decompress unwraps the body of an OP_COMPRESSED message into the original wire message, so that
+// it can be decoded like any other message. It also returns the compressor used by the peer.
+//
+// see https://github.com/mongodb/specifications/blob/master/source/compression/OP_COMPRESSED.md
+func decompress(reqID, responseTo int32, wmBody []byte) ([]byte, wiremessage.CompressorID, error) {
+	originalOpCode, rem, ok := wiremessage.ReadCompressedOriginalOpCode(wmBody)
+	if !ok {
+		return nil, 0, errors.New("malformed OP_COMPRESSED: missing original opcode")
+	}
+	uncompressedSize, rem, ok := wiremessage.ReadCompressedUncompressedSize(rem)
+	if !ok {
+		return nil, 0, errors.New("malformed OP_COMPRESSED: missing uncompressed size")
+	}
+	compressorID, rem, ok := wiremessage.ReadCompressedCompressorID(rem)
+	if !ok {
+		return nil, 0, errors.New("malformed OP_COMPRESSED: missing compressor id")
+	}
+	compressedMsg, _, ok := wiremessage.ReadCompressedCompressedMessage(rem, int32(len(rem)))
+	if !ok {
+		return nil, 0, errors.New("malformed OP_COMPRESSED: missing compressed message")
+	}
+
+	payload, err := driver.DecompressPayload(compressedMsg, driver.CompressionOpts{
+		Compressor:       compressorID,
+		UncompressedSize: uncompressedSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decompress the %v message: %v", compressorID, err)
+	}
+	if len(payload) != int(uncompressedSize) {
+		return nil, 0, fmt.Errorf("malformed OP_COMPRESSED: expected %d uncompressed bytes, got %d", uncompressedSize, len(payload))
+	}
+
+	wm := wiremessage.AppendHeader(nil, 16+uncompressedSize, reqID, responseTo, originalOpCode)
+	return append(wm, payload...), compressorID, nil
+}
+
+// compress wraps an encoded wire message into an OP_COMPRESSED message using the given compressor.
+// The message is returned as it is for the noop compressor, which means the peer did not compress.
+func compress(wm []byte, compressorID wiremessage.CompressorID) ([]byte, error) {
+	if compressorID == wiremessage.CompressorNoOp {
+		return wm, nil
+	}
+	length, reqID, responseTo, opCode, wmBody, ok := wiremessage.ReadHeader(wm)
+	if !ok || int(length) > len(wm) {
+		return nil, errors.New("malformed wire message: insufficient bytes")
+	}
+	compressed, err := driver.CompressPayload(wmBody, driver.CompressionOpts{
+		Compressor: compressorID,
+		ZlibLevel:  wiremessage.DefaultZlibLevel,
+		ZstdLevel:  wiremessage.DefaultZstdLevel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress the message with %v: %v", compressorID, err)
+	}
+
+	idx, dst := wiremessage.AppendHeaderStart(nil, reqID, responseTo, wiremessage.OpCompressed)
+	dst = wiremessage.AppendCompressedOriginalOpCode(dst, opCode)
+	dst = wiremessage.AppendCompressedUncompressedSize(dst, int32(len(wmBody)))
+	dst = wiremessage.AppendCompressedCompressorID(dst, compressorID)
+	dst = wiremessage.AppendCompressedCompressedMessage(dst, compressed)
+	return bsoncore.UpdateLength(dst, idx, int32(len(dst))), nil
+}
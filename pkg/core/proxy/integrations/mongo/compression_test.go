@@ -0,0 +1,104 @@
+//go:build linux
+
+package mongo
+
+import (
+	"bytes"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+)
+
+func pingMsg(t *testing.T) []byte {
+	t.Helper()
+	doc, err := bson.Marshal(bson.D{{Key: "ping", Value: 1}, {Key: "$db", Value: "admin"}})
+	if err != nil {
+		t.Fatalf("failed to marshal the command: %v", err)
+	}
+	idx, wm := wiremessage.AppendHeaderStart(nil, 7, 3, wiremessage.OpMsg)
+	wm = wiremessage.AppendMsgFlags(wm, 0)
+	wm = wiremessage.AppendMsgSectionType(wm, wiremessage.SingleDocument)
+	wm = append(wm, doc...)
+	return bsoncore.UpdateLength(wm, idx, int32(len(wm)))
+}
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		name       string
+		compressor wiremessage.CompressorID
+	}{
+		{name: "snappy", compressor: wiremessage.CompressorSnappy},
+		{name: "zlib", compressor: wiremessage.CompressorZLib},
+		{name: "zstd", compressor: wiremessage.CompressorZstd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := pingMsg(t)
+			compressed, err := compress(wm, tt.compressor)
+			if err != nil {
+				t.Fatalf("compress() error = %v", err)
+			}
+			length, reqID, responseTo, opCode, body, ok := wiremessage.ReadHeader(compressed)
+			if !ok || int(length) != len(compressed) || opCode != wiremessage.OpCompressed {
+				t.Fatalf("compress() returned a malformed OP_COMPRESSED message: length %d of %d bytes, opcode %v", length, len(compressed), opCode)
+			}
+			if reqID != 7 || responseTo != 3 {
+				t.Errorf("compress() header ids = %d, %d, want 7, 3", reqID, responseTo)
+			}
+
+			got, compressor, err := decompress(reqID, responseTo, body)
+			if err != nil {
+				t.Fatalf("decompress() error = %v", err)
+			}
+			if compressor != tt.compressor {
+				t.Errorf("decompress() compressor = %v, want %v", compressor, tt.compressor)
+			}
+			if !bytes.Equal(got, wm) {
+				t.Errorf("decompress() = %x, want the original message %x", got, wm)
+			}
+		})
+	}
+}
+
+func TestCompressNoop(t *testing.T) {
+	wm := pingMsg(t)
+	got, err := compress(wm, wiremessage.CompressorNoOp)
+	if err != nil {
+		t.Fatalf("compress() error = %v", err)
+	}
+	if !bytes.Equal(got, wm) {
+		t.Errorf("compress() = %x, want the message unchanged", got)
+	}
+}
+
+func TestCompressionMalformed(t *testing.T) {
+	wm := pingMsg(t)
+	compressed, err := compress(wm, wiremessage.CompressorSnappy)
+	if err != nil {
+		t.Fatalf("compress() error = %v", err)
+	}
+	_, _, _, _, body, _ := wiremessage.ReadHeader(compressed)
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "empty", body: nil},
+		{name: "missing uncompressed size", body: body[:6]},
+		{name: "missing compressor id", body: body[:8]},
+		{name: "truncated message", body: body[:len(body)-4]},
+		{name: "wrong uncompressed size", body: append([]byte{body[0], body[1], body[2], body[3], 1, 0, 0, 0}, body[8:]...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decompress(7, 3, tt.body); err == nil {
+				t.Error("decompress() error = nil, want an error")
+			}
+		})
+	}
+	if _, err := compress(wm[:10], wiremessage.CompressorSnappy); err == nil {
+		t.Error("compress() of a truncated message error = nil, want an error")
+	}
+}
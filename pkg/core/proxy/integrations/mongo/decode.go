@@ -124,7 +124,7 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 				var maxMatchScore = 0.0
 				var configMocks []*models.Mock
 				for {
-					configMocks, err = mockDb.GetUnFilteredMocks()
+					configMocks, err = mockDb.GetUnFilteredMocksByKind(models.Mongo)
 					if err != nil {
 						utils.LogError(logger, err, "error while getting config mock")
 					}
@@ -238,7 +238,12 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 							return
 						}
 						requestID := wiremessage.NextRequestID()
-						heathCheckReplyBuffer := replyMessage.Encode(responseTo, requestID)
+						heathCheckReplyBuffer, err := compress(replyMessage.Encode(responseTo, requestID), mongoRequests[0].Header.CompressorID)
+						if err != nil {
+							utils.LogError(logger, err, "failed to compress the health check reply", zap.Any("for request with id", responseTo))
+							errCh <- err
+							return
+						}
 						responseTo = requestID
 						logger.Debug(fmt.Sprintf("the bufffer response is: %v", string(heathCheckReplyBuffer)))
 						_, err = clientConn.Write(heathCheckReplyBuffer)
@@ -263,7 +268,13 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 							errCh <- err
 							return
 						}
-						_, err = clientConn.Write(message.Encode(responseTo, wiremessage.NextRequestID()))
+						respBuffer, err := compress(message.Encode(responseTo, wiremessage.NextRequestID()), mongoRequests[0].Header.CompressorID)
+						if err != nil {
+							utils.LogError(logger, err, "failed to compress the health check opmsg", zap.Any("for request with id", responseTo))
+							errCh <- err
+							return
+						}
+						_, err = clientConn.Write(respBuffer)
 						if err != nil {
 							if ctx.Err() != nil {
 								return
@@ -278,7 +289,9 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 				// handle for the non-heartbeat request from the client
 
 				// match the incoming request with the recorded tcsMocks and return a mocked response which matches most with incoming request
+				matchStart := time.Now()
 				matched, matchedMock, err := match(ctx, logger, mongoRequests, mockDb)
+				integrations.MockMatch(mockDb, models.Mongo, "", matched, err, matchStart)
 				if err != nil {
 					errCh <- err
 					utils.LogError(logger, err, "error while matching mongo mocks")
@@ -312,7 +325,14 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 						return
 					}
 					requestID := wiremessage.NextRequestID()
-					_, err = clientConn.Write(message.Encode(responseTo, requestID))
+					// the server replies with the compressor of the request, if it was compressed
+					respBuffer, err := compress(message.Encode(responseTo, requestID), mongoRequests[0].Header.CompressorID)
+					if err != nil {
+						utils.LogError(logger, err, "failed to compress the mongo response", zap.Any("for request with id", responseTo))
+						errCh <- err
+						return
+					}
+					_, err = clientConn.Write(respBuffer)
 					if err != nil {
 						if ctx.Err() != nil {
 							return
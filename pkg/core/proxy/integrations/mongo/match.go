@@ -19,22 +19,17 @@ import (
 
 // match mathces and returns the best matching mock for the incoming mongo requests.
 func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.MongoRequest, mockDb integrations.MockMemDb) (bool, *models.Mock, error) {
+	// the mocks of the collection of the requests are matched first, then all the mongo mocks
+	indexed := true
 	for {
 		select {
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetFilteredMocks()
+			tcsMocks, keyed, err := filteredMongoMocks(mongoRequests, mockDb, indexed)
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting tcs mock: %v", err)
 			}
-			var tcsMocks []*models.Mock
-			for _, mock := range mocks {
-				if mock.Kind != "Mongo" {
-					continue
-				}
-				tcsMocks = append(tcsMocks, mock)
-			}
 			maxMatchScore := 0.0
 			bestMatchIndex := -1
 			// iterate over the tcsMocks and compare the incoming mongo requests with the recorded mongo requests.
@@ -77,6 +72,10 @@ func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.Mongo
 					}
 				}
 			}
+			if bestMatchIndex == -1 && keyed {
+				indexed = false
+				continue
+			}
 			if bestMatchIndex == -1 {
 				return false, nil, nil
 			}
@@ -90,6 +89,24 @@ func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.Mongo
 	}
 }
 
+// filteredMongoMocks returns the filtered mongo mocks which may match the requests. If indexed is set and
+// the requests run a command on a collection, only the mocks of the collection are returned, and keyed
+// reports it. The mocks aren't copied, so they must not be modified.
+func filteredMongoMocks(mongoRequests []models.MongoRequest, mockDb integrations.MockMemDb, indexed bool) (mocks []*models.Mock, keyed bool, err error) {
+	if indexed && len(mongoRequests) > 0 {
+		if msg, ok := mongoRequests[0].Message.(*models.MongoOpMessage); ok && len(msg.Sections) > 0 {
+			if collection, ok := integrations.MongoCollection(msg.Sections[0]); ok {
+				mocks, err = mockDb.GetFilteredMocksByKey(integrations.MongoKey(collection))
+				if err != nil || len(mocks) > 0 {
+					return mocks, err == nil, err
+				}
+			}
+		}
+	}
+	mocks, err = mockDb.GetFilteredMocksByKind(models.Mongo)
+	return mocks, false, err
+}
+
 func compareOpMsgSection(logger *zap.Logger, expectedSection, actualSection string) float64 {
 	// check that the sections are of same type. SectionSingle (section[16] is "m") or SectionSequence (section[16] is "i").
 	if (len(expectedSection) < 16 || len(actualSection) < 16) && expectedSection[16] != actualSection[16] {
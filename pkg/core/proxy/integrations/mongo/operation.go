@@ -73,6 +73,16 @@ func Decode(wm []byte, logger *zap.Logger) (Operation, models.MongoHeader, inter
 	)
 
 	switch opCode {
+	case wiremessage.OpCompressed:
+		// the compressed message is decoded as the original one, so that the mocks don't depend on
+		// the compression negotiated by the client. The compressor is kept to reply in kind.
+		uncompressedWm, compressorID, err := decompress(reqID, responseTo, wmBody)
+		if err != nil {
+			return nil, messageHeader, &models.MongoOpMessage{}, err
+		}
+		op, messageHeader, mongoMsg, err = Decode(uncompressedWm, logger)
+		messageHeader.CompressorID = compressorID
+		return op, messageHeader, mongoMsg, err
 	case wiremessage.OpQuery:
 		// decodeQuery is a helper function to decode the OpQuery operation
 		op, err = decodeQuery(reqID, wmBody)
@@ -4,6 +4,7 @@
 package mysql
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"net"
@@ -15,6 +16,7 @@ import (
 	"go.keploy.io/server/v2/utils"
 
 	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
 	"go.uber.org/zap"
 )
 
@@ -33,10 +35,39 @@ func New(logger *zap.Logger) integrations.Integrations {
 }
 
 func (m *MySQL) MatchType(_ context.Context, _ []byte) bool {
-	//Returning false here because sql parser is using the ports to check if the packet is mysql or not.
+	// Returning false here because the server speaks first in mysql, hence it is detected by MatchGreeting.
 	return false
 }
 
+// MatchGreeting checks whether the greeting of the server is a MySQL initial handshake packet
+// (protocol version 10) or an ERR packet, sent when the server refuses the connection.
+//
+// ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_handshake_v10.html
+func (m *MySQL) MatchGreeting(_ context.Context, greeting []byte) bool {
+	if len(greeting) < 5 {
+		return false
+	}
+	length := int(uint32(greeting[0]) | uint32(greeting[1])<<8 | uint32(greeting[2])<<16)
+	// the greeting is the first packet, so its sequence id is 0
+	if length == 0 || len(greeting) < 4+length || greeting[3] != 0 {
+		return false
+	}
+	payload := greeting[4 : 4+length]
+	switch payload[0] {
+	case mysql.HandshakeV10:
+		// the protocol version is followed by the null terminated server version
+		return bytes.IndexByte(payload[1:], 0x00) > 0
+	case mysql.ERR:
+		// ERR packet: header, 2 bytes of error code and the message
+		return length > 3
+	}
+	return false
+}
+
+func (m *MySQL) MockKind() models.Kind {
+	return models.MySQL
+}
+
 func (m *MySQL) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
 	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
 
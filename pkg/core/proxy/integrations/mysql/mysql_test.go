@@ -0,0 +1,32 @@
+//go:build linux
+
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMatchGreeting(t *testing.T) {
+	tests := []struct {
+		name     string
+		greeting []byte
+		want     bool
+	}{
+		{name: "handshake v10", greeting: append([]byte{12, 0, 0, 0, 10}, "8.0.36\x00\x01\x00\x00\x00"...), want: true},
+		{name: "handshake split over reads", greeting: append([]byte{40, 0, 0, 0, 10}, "8.0.36\x00"...)},
+		{name: "server version not terminated", greeting: append([]byte{7, 0, 0, 0, 10}, "8.0.36"...)},
+		{name: "err packet", greeting: append([]byte{18, 0, 0, 0, 0xff, 0x69, 0x04}, "Host is blocked"...), want: true},
+		{name: "not the first packet", greeting: append([]byte{12, 0, 0, 1, 10}, "8.0.36\x00\x01\x00\x00\x00"...)},
+		{name: "smtp greeting", greeting: []byte("220 mail.example.com ESMTP\r\n")},
+		{name: "too short", greeting: []byte{1, 0, 0}},
+	}
+	m := &MySQL{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.MatchGreeting(context.Background(), tt.greeting); got != tt.want {
+				t.Errorf("MatchGreeting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
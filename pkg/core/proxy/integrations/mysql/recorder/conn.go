@@ -434,7 +434,12 @@ func handleCachingSha2Password(ctx context.Context, logger *zap.Logger, authPkt
 	var result handshakeRes
 	switch auth {
 	case mysql.PerformFullAuthentication:
-		result, err = handleFullAuth(ctx, logger, clientConn, destConn, decodeCtx)
+		// Over TLS the client sends the password in clear text instead of requesting the public key
+		if decodeCtx.UseSSL {
+			result, err = handleFullAuthOverTLS(ctx, logger, clientConn, destConn, decodeCtx)
+		} else {
+			result, err = handleFullAuth(ctx, logger, clientConn, destConn, decodeCtx)
+		}
 		if err != nil {
 			return res, fmt.Errorf("failed to handle caching sha2 password full auth: %w", err)
 		}
@@ -623,3 +628,73 @@ func handleFullAuth(ctx context.Context, logger *zap.Logger, clientConn, destCon
 	logger.Debug("full auth is handled successfully")
 	return res, nil
 }
+
+func handleFullAuthOverTLS(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, decodeCtx *wire.DecodeContext) (handshakeRes, error) {
+	res := handshakeRes{
+		req:  make([]mysql.Request, 0),
+		resp: make([]mysql.Response, 0),
+	}
+
+	// read the plain password from the client
+	plainPass, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read plain password from client")
+		return res, err
+	}
+	_, err = destConn.Write(plainPass)
+	if err != nil {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		utils.LogError(logger, err, "failed to write plain password to server")
+		return res, err
+	}
+
+	plainPassPkt, err := mysqlUtils.BytesToMySQLPacket(plainPass)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse MySQL packet")
+		return res, err
+	}
+
+	// The password itself is not stored in the mock, only the header is needed to simulate the auth.
+	res.req = append(res.req, mysql.Request{
+		PacketBundle: mysql.PacketBundle{
+			Header: &mysql.PacketInfo{
+				Header: &plainPassPkt.Header,
+				Type:   mysql.PlainPassword,
+			},
+			Message: "",
+		},
+	})
+
+	// read the final response from the server (ok or error)
+	finalServerResponse, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read final response from server")
+		return res, err
+	}
+	_, err = clientConn.Write(finalServerResponse)
+	if err != nil {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		utils.LogError(logger, err, "failed to write final response to client")
+		return res, err
+	}
+
+	finalResPkt, err := wire.DecodePayload(ctx, logger, finalServerResponse, clientConn, decodeCtx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode final response packet during caching sha2 password full auth over TLS")
+		return res, err
+	}
+
+	res.resp = append(res.resp, mysql.Response{
+		PacketBundle: *finalResPkt,
+	})
+
+	// Set the final response operation of the handshake
+	res.responseOperation = finalResPkt.Header.Type
+
+	logger.Debug("full auth over TLS is handled successfully")
+	return res, nil
+}
@@ -0,0 +1,100 @@
+//go:build linux
+
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func packet(seq byte, payload ...byte) []byte {
+	return append([]byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}, payload...)
+}
+
+func TestHandleFullAuthOverTLS(t *testing.T) {
+	plainPassword := packet(5, []byte("secret\x00")...)
+	tests := []struct {
+		name     string
+		response []byte
+		wantType string
+	}{
+		{name: "ok", response: packet(6, mysql.OK, 0, 0, 2, 0, 0, 0), wantType: mysql.StatusToString(mysql.OK)},
+		{name: "access denied", response: packet(6, append([]byte{mysql.ERR, 0x15, 0x04, '#', '2', '8', '0', '0', '0'}, "Access denied"...)...), wantType: mysql.StatusToString(mysql.ERR)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, client := net.Pipe()
+			destConn, server := net.Pipe()
+			defer clientConn.Close()
+			defer destConn.Close()
+
+			decodeCtx := &wire.DecodeContext{
+				Mode:            models.MODE_RECORD,
+				LastOp:          wire.NewLastOpMap(),
+				ServerGreetings: wire.NewGreetings(),
+				UseSSL:          true,
+			}
+			decodeCtx.ServerGreetings.Store(clientConn, &mysql.HandshakeV10Packet{CapabilityFlags: uint32(mysql.CLIENT_PROTOCOL_41)})
+
+			errc := make(chan error, 1)
+			go func() {
+				defer close(errc)
+				if _, err := client.Write(plainPassword); err != nil {
+					errc <- err
+					return
+				}
+				got := make([]byte, len(plainPassword))
+				if _, err := server.Read(got); err != nil {
+					errc <- err
+					return
+				}
+				if !bytes.Equal(got, plainPassword) {
+					t.Errorf("server received %q, want the password packet %q", got, plainPassword)
+				}
+				if _, err := server.Write(tt.response); err != nil {
+					errc <- err
+					return
+				}
+				got = make([]byte, len(tt.response))
+				if _, err := client.Read(got); err != nil {
+					errc <- err
+					return
+				}
+				if !bytes.Equal(got, tt.response) {
+					t.Errorf("client received %q, want the server response %q", got, tt.response)
+				}
+			}()
+
+			res, err := handleFullAuthOverTLS(context.Background(), zap.NewNop(), clientConn, destConn, decodeCtx)
+			if err != nil {
+				t.Fatalf("handleFullAuthOverTLS() error = %v", err)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("failed to exchange the packets: %v", err)
+			}
+
+			if len(res.req) != 1 || res.req[0].Header.Type != mysql.PlainPassword {
+				t.Fatalf("handleFullAuthOverTLS() recorded the requests %+v, want a single plain password", res.req)
+			}
+			if res.req[0].Message != "" {
+				t.Errorf("handleFullAuthOverTLS() recorded the password %q", res.req[0].Message)
+			}
+			if seq := res.req[0].Header.Header.SequenceID; seq != 5 {
+				t.Errorf("plain password sequence id = %d, want 5", seq)
+			}
+			if len(res.resp) != 1 || res.resp[0].Header.Type != tt.wantType {
+				t.Fatalf("handleFullAuthOverTLS() recorded the responses %+v, want a single %s", res.resp, tt.wantType)
+			}
+			if res.responseOperation != tt.wantType {
+				t.Errorf("responseOperation = %q, want %q", res.responseOperation, tt.wantType)
+			}
+		})
+	}
+}
@@ -101,20 +101,18 @@ func simulateInitialHandshake(ctx context.Context, logger *zap.Logger, clientCon
 			return res, nil
 		}
 
-		// Get the SSL request from the mock
-		_, ok = req[reqIdx].Message.(*mysql.SSLRequestPacket)
-		if !ok {
-			utils.LogError(logger, nil, "failed to assert mock SSL request packet", zap.Any("expected", req[reqIdx].PacketBundle.Header.Type))
-			return res, nil
-		}
-
-		// Match the SSL request from the client with the mock
-		err = matchSSLRequest(ctx, logger, req[reqIdx].PacketBundle, *pkt)
-		if err != nil {
-			utils.LogError(logger, err, "error while matching SSL request")
-			return res, err
+		// Get the SSL request from the mock, if the mock was recorded without SSL the TLS upgrade is completed locally anyway
+		if _, ok = req[reqIdx].Message.(*mysql.SSLRequestPacket); ok {
+			// Match the SSL request from the client with the mock
+			err = matchSSLRequest(ctx, logger, req[reqIdx].PacketBundle, *pkt)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching SSL request")
+				return res, err
+			}
+			reqIdx++ // matched with the mock so increment the index
+		} else {
+			logger.Debug("mock for the initial handshake was recorded without SSL, upgrading the client connection to TLS")
 		}
-		reqIdx++ // matched with the mock so increment the index
 
 		// Upgrade the client connection to TLS
 		reader := bufio.NewReader(clientConn)
@@ -169,6 +167,15 @@ func simulateInitialHandshake(ctx context.Context, logger *zap.Logger, clientCon
 			utils.LogError(logger, err, "failed to decode handshake response from client")
 			return res, err
 		}
+	} else if _, ok := req[reqIdx].Message.(*mysql.SSLRequestPacket); ok {
+		// The mock was recorded with SSL but the client doesn't request it, so skip the SSL request
+		logger.Debug("mock for the initial handshake was recorded with SSL, but the client did not request SSL")
+		reqIdx++
+	}
+
+	if len(req) < reqIdx+1 {
+		utils.LogError(logger, nil, "no mysql mocks found for handshake response")
+		return res, fmt.Errorf("no mysql mocks found for handshake response")
 	}
 
 	_, ok = pkt.Message.(*mysql.HandshakeResponse41Packet)
@@ -395,7 +402,13 @@ func simulateCacheSha2Password(ctx context.Context, logger *zap.Logger, clientCo
 	//simulate the caching_sha2_password auth mechanism
 	switch CachingSha2PasswordMechanism {
 	case mysql.CachingSha2PasswordToString(mysql.PerformFullAuthentication):
-		err := simulateFullAuth(ctx, logger, clientConn, cacheSha2PassMock, initialHandshakeMock, mockDb, decodeCtx)
+		var err error
+		// Over TLS the client sends the password in clear text instead of requesting the public key
+		if decodeCtx.UseSSL {
+			err = simulateFullAuthOverTLS(ctx, logger, clientConn, cacheSha2PassMock, initialHandshakeMock, mockDb, decodeCtx)
+		} else {
+			err = simulateFullAuth(ctx, logger, clientConn, cacheSha2PassMock, initialHandshakeMock, mockDb, decodeCtx)
+		}
 		if err != nil {
 			utils.LogError(logger, err, "failed to simulate full auth")
 			return err
@@ -599,3 +612,75 @@ func simulateFullAuth(ctx context.Context, logger *zap.Logger, clientConn net.Co
 
 	return nil
 }
+
+func simulateFullAuthOverTLS(ctx context.Context, logger *zap.Logger, clientConn net.Conn, fullAuthMocks reqResp, initialHandshakeMock *models.Mock, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext) error {
+
+	resp := fullAuthMocks.resp
+	req := fullAuthMocks.req
+
+	// Read the plain password from the client
+	plainPasswordBuf, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read plain password from client")
+		return err
+	}
+
+	// Get the packet from the buffer
+	plainPassPkt, err := mysqlUtils.BytesToMySQLPacket(plainPasswordBuf)
+	if err != nil {
+		utils.LogError(logger, err, "failed to convert plain password to packet")
+		return err
+	}
+
+	if len(req) < 1 {
+		utils.LogError(logger, nil, "no mysql mocks found for plain password during full auth")
+		return fmt.Errorf("no mysql mocks found for plain password during full auth")
+	}
+
+	// Get the plain password from the mock
+	plainPassMock := req[0].PacketBundle
+
+	if plainPassMock.Header.Type != mysql.PlainPassword {
+		utils.LogError(logger, nil, "expected plain password mock not found", zap.Any("found", plainPassMock.Header.Type))
+		return fmt.Errorf("expected %s but found %s", mysql.PlainPassword, plainPassMock.Header.Type)
+	}
+
+	// The password is not recorded, so we should just check the sequence number
+	if plainPassMock.Header.Header.SequenceID != plainPassPkt.Header.SequenceID {
+		utils.LogError(logger, nil, "sequence number mismatch for plain password", zap.Any("expected", plainPassMock.Header.Header.SequenceID), zap.Any("actual", plainPassPkt.Header.SequenceID))
+		return fmt.Errorf("sequence number mismatch for plain password")
+	}
+
+	//Now send the final response (OK/Err) to the client
+	if len(resp) < 1 {
+		utils.LogError(logger, nil, "final response mock not found for full auth over TLS")
+		return fmt.Errorf("final response mock not found for full auth over TLS")
+	}
+
+	logger.Debug("final response for full auth over TLS", zap.Any("response", resp[0].PacketBundle.Header.Type))
+
+	buf, err := wire.EncodeToBinary(ctx, logger, &resp[0].PacketBundle, clientConn, decodeCtx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode final response packet for full auth over TLS")
+		return err
+	}
+
+	_, err = clientConn.Write(buf)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		utils.LogError(logger, err, "failed to write final response for full auth over TLS to the client")
+		return err
+	}
+
+	// Same as the full auth without TLS, afterwards only fast auth success is expected.
+	ok := mockDb.DeleteUnFilteredMock(*initialHandshakeMock)
+	if !ok {
+		utils.LogError(logger, nil, "failed to delete unfiltered mock during full auth over TLS")
+	}
+
+	logger.Debug("full auth over TLS completed successfully")
+
+	return nil
+}
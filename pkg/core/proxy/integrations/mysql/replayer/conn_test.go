@@ -0,0 +1,100 @@
+//go:build linux
+
+package replayer
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// mockDb records the mocks deleted during the handshake, the other lookups are left unimplemented.
+type mockDb struct {
+	integrations.MockMemDb
+	deleted []string
+}
+
+func (db *mockDb) DeleteUnFilteredMock(mock models.Mock) bool {
+	db.deleted = append(db.deleted, mock.Name)
+	return true
+}
+
+func TestSimulateFullAuthOverTLS(t *testing.T) {
+	plainPassword := func(seq uint8) mysql.Request {
+		return mysql.Request{PacketBundle: mysql.PacketBundle{
+			Header: &mysql.PacketInfo{Header: &mysql.Header{PayloadLength: 7, SequenceID: seq}, Type: mysql.PlainPassword},
+		}}
+	}
+	ok := mysql.Response{PacketBundle: mysql.PacketBundle{
+		Header:  &mysql.PacketInfo{Header: &mysql.Header{PayloadLength: 7, SequenceID: 6}, Type: mysql.StatusToString(mysql.OK)},
+		Message: &mysql.OKPacket{Header: mysql.OK, StatusFlags: 2},
+	}}
+
+	tests := []struct {
+		name    string
+		mocks   reqResp
+		wantErr bool
+	}{
+		{name: "recorded password", mocks: reqResp{req: []mysql.Request{plainPassword(5)}, resp: []mysql.Response{ok}}},
+		{name: "other sequence id", mocks: reqResp{req: []mysql.Request{plainPassword(3)}, resp: []mysql.Response{ok}}, wantErr: true},
+		{name: "public key request recorded", mocks: reqResp{req: []mysql.Request{{PacketBundle: mysql.PacketBundle{
+			Header: &mysql.PacketInfo{Header: &mysql.Header{PayloadLength: 1, SequenceID: 5}, Type: mysql.CachingSha2PasswordToString(mysql.RequestPublicKey)},
+		}}}, resp: []mysql.Response{ok}}, wantErr: true},
+		{name: "no password recorded", mocks: reqResp{resp: []mysql.Response{ok}}, wantErr: true},
+		{name: "no response recorded", mocks: reqResp{req: []mysql.Request{plainPassword(5)}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, client := net.Pipe()
+			defer clientConn.Close()
+			defer client.Close()
+
+			decodeCtx := &wire.DecodeContext{
+				Mode:            models.MODE_TEST,
+				LastOp:          wire.NewLastOpMap(),
+				ServerGreetings: wire.NewGreetings(),
+				UseSSL:          true,
+			}
+			decodeCtx.ServerGreetings.Store(clientConn, &mysql.HandshakeV10Packet{CapabilityFlags: uint32(mysql.CLIENT_PROTOCOL_41)})
+
+			received := make(chan []byte, 1)
+			go func() {
+				defer close(received)
+				if _, err := client.Write([]byte("\x07\x00\x00\x05secret\x00")); err != nil {
+					return
+				}
+				buf := make([]byte, 64)
+				n, err := client.Read(buf)
+				if err != nil {
+					return
+				}
+				received <- buf[:n]
+			}()
+
+			db := &mockDb{}
+			handshake := &models.Mock{Name: "mock-0"}
+			err := simulateFullAuthOverTLS(context.Background(), zap.NewNop(), clientConn, tt.mocks, handshake, db, decodeCtx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("simulateFullAuthOverTLS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			want := []byte{7, 0, 0, 6, mysql.OK, 0, 0, 2, 0, 0, 0}
+			if got := <-received; !bytes.Equal(got, want) {
+				t.Errorf("client received %v, want the recorded OK packet %v", got, want)
+			}
+			if len(db.deleted) != 1 || db.deleted[0] != handshake.Name {
+				t.Errorf("deleted the mocks %v, want the handshake mock %q", db.deleted, handshake.Name)
+			}
+		})
+	}
+}
@@ -11,7 +11,6 @@ import (
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
-	intgUtil "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
 	"go.keploy.io/server/v2/utils"
@@ -138,16 +137,38 @@ func matchHanshakeResponse41(_ context.Context, _ *zap.Logger, expected, actual
 	return nil
 }
 
-func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext) (*mysql.Response, bool, error) {
+// commandMocks returns the mysql mocks which may match the command. If indexed is set and the command
+// runs a query, only the mocks of the fingerprint of the query are returned, and keyed reports it.
+// The mocks aren't copied, so they must not be modified.
+func commandMocks(req mysql.Request, mockDb integrations.MockMemDb, indexed bool) (mocks []*models.Mock, keyed bool, err error) {
+	var query string
+	switch msg := req.Message.(type) {
+	case *mysql.QueryPacket:
+		query = msg.Query
+	case *mysql.StmtPreparePacket:
+		query = msg.Query
+	}
+	if indexed && query != "" {
+		mocks, err = mockDb.GetUnFilteredMocksByKey(integrations.SQLKey(models.MySQL, query))
+		if err != nil || len(mocks) > 0 {
+			return mocks, err == nil, err
+		}
+	}
+	mocks, err = mockDb.GetUnFilteredMocksByKind(models.MySQL)
+	return mocks, false, err
+}
 
+func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext) (*mysql.Response, bool, error) {
+	// the mocks of the query of the command are matched first, then all the mysql mocks
+	indexed := true
 	for {
 
 		if ctx.Err() != nil {
 			return nil, false, ctx.Err()
 		}
 
-		// Get the tcs mocks from the mockDb
-		unfiltered, err := mockDb.GetUnFilteredMocks()
+		// Get the mysql mocks from the mockDb
+		mocks, keyed, err := commandMocks(req, mockDb, indexed)
 		if err != nil {
 			if ctx.Err() != nil {
 				return nil, false, ctx.Err()
@@ -156,9 +177,6 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 			return nil, false, err
 		}
 
-		// Get the mysql mocks
-		mocks := intgUtil.GetMockByKind(unfiltered, "MySQL")
-
 		if len(mocks) == 0 {
 			if ctx.Err() != nil {
 				return nil, false, ctx.Err()
@@ -293,6 +311,10 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 				}
 			}
 		}
+		if matchedResp == nil && keyed {
+			indexed = false
+			continue
+		}
 		if matchedResp == nil {
 			logger.Debug("No matching mock found for the command", zap.Any("command", req))
 
@@ -319,7 +341,8 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 
 		// Delete the matched mock from the mockDb
 
-		ok := updateMock(ctx, logger, matchedMock, mockDb)
+		copyMock := *matchedMock
+		ok := updateMock(ctx, logger, &copyMock, mockDb)
 		if !ok {
 			//TODO: see what to do in case of failed deletion
 			logger.Debug("failed to update the matched mock")
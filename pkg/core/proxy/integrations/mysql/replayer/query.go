@@ -66,7 +66,9 @@ func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn ne
 			}
 
 			// Match the request with the mock
+			matchStart := time.Now()
 			resp, ok, err := matchCommand(ctx, logger, req, mockDb, decodeCtx)
+			integrations.MockMatch(mockDb, models.MySQL, commandQuery(commandPkt), ok, err, matchStart)
 			if err != nil {
 				if err == io.EOF {
 					return io.EOF
@@ -110,3 +112,17 @@ func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn ne
 		}
 	}
 }
+
+// commandQuery returns the query of a command, to describe it when it matches no mock.
+func commandQuery(pkt *mysql.PacketBundle) string {
+	if pkt == nil {
+		return ""
+	}
+	switch msg := pkt.Message.(type) {
+	case *mysql.QueryPacket:
+		return msg.Query
+	case *mysql.StmtPreparePacket:
+		return msg.Query
+	}
+	return ""
+}
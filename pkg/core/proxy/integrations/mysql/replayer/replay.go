@@ -10,7 +10,6 @@ import (
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
-	intgUtil "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
@@ -23,15 +22,13 @@ import (
 func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, _ *models.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
 	errCh := make(chan error, 1)
 
-	unfiltered, err := mockDb.GetUnFilteredMocks()
+	// Get the mysql mocks
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.MySQL)
 	if err != nil {
 		utils.LogError(logger, err, "failed to get unfiltered mocks")
 		return err
 	}
 
-	// Get the mysql mocks
-	mocks := intgUtil.GetMockByKind(unfiltered, "MySQL")
-
 	if len(mocks) == 0 {
 		utils.LogError(logger, nil, "no mysql mocks found")
 		return nil
@@ -41,7 +38,9 @@ func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, _ *mod
 	// Get the mocks having "config" metadata
 	for _, mock := range mocks {
 		if mock.Spec.Metadata["type"] == "config" {
-			configMocks = append(configMocks, mock)
+			// the handshake updates the mocks, hence they are copied
+			copyMock := *mock
+			configMocks = append(configMocks, &copyMock)
 		}
 	}
 
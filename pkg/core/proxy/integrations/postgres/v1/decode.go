@@ -20,14 +20,18 @@ import (
 	"go.uber.org/zap"
 )
 
-func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *models.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *models.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
 	pgRequests := [][]byte{reqBuf}
 	errCh := make(chan error, 1)
+	// SCRAM-SHA-256 auth is simulated only if the password is configured, otherwise the client is asked for MD5.
+	simulateScram := opts.PostgresPassword != ""
 
 	go func(errCh chan error, pgRequests [][]byte) {
 		defer pUtil.Recover(logger, clientConn, nil)
 		// close should be called from the producer of the channel
 		defer close(errCh)
+		// sc is the ongoing SCRAM auth of the connection, if any.
+		var sc *scramConversation
 		for {
 			// Since protocol packets have to be parsed for checking stream end,
 			// clientConnection have deadline for read to determine the end of stream.
@@ -59,8 +63,22 @@ func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 			if len(pgRequests) == 0 {
 				continue
 			}
+			if sc != nil {
+				done, err := sc.handle(ctx, logger, clientConn, pgRequests[0], mockDb)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				if done {
+					sc = nil
+				}
+				pgRequests = [][]byte{}
+				continue
+			}
 			var mutex sync.Mutex
-			matched, pgResponses, err := matchingReadablePG(ctx, logger, &mutex, pgRequests, mockDb)
+			matchStart := time.Now()
+			matched, pgResponses, err := matchingReadablePG(ctx, logger, &mutex, pgRequests, mockDb, simulateScram)
+			integrations.MockMatch(mockDb, models.Postgres, "", matched, err, matchStart)
 			if err != nil {
 				errCh <- fmt.Errorf("error while matching tcs mocks %v", err)
 				return
@@ -90,6 +108,9 @@ func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 					errCh <- err
 				}
 			}
+			if simulateScram && startsSASL(pgResponses) {
+				sc = &scramConversation{password: opts.PostgresPassword}
+			}
 			// Clear the buffer for the next dependency call
 			pgRequests = [][]byte{}
 		}
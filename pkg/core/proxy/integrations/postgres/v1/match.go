@@ -67,22 +67,62 @@ func IsValuePresent(connectionid string, value string) bool {
 	return false
 }
 
-func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mutex, requestBuffers [][]byte, mockDb integrations.MockMemDb) (bool, []models.Frontend, error) {
+// postgresMocks returns copies of the postgres mocks to match the requests against, since the matching
+// merges the requests of the mocks. If indexed is set and the requests run a query, only the mocks of
+// the fingerprint of the query are returned, and keyed reports it.
+func postgresMocks(logger *zap.Logger, requestBuffers [][]byte, mockDb integrations.MockMemDb, indexed bool) (mocks []*models.Mock, keyed bool, err error) {
+	var indexedMocks []*models.Mock
+	if query := pgQuery(logger, requestBuffers); indexed && query != "" {
+		indexedMocks, err = mockDb.GetUnFilteredMocksByKey(integrations.SQLKey(models.Postgres, query))
+		keyed = true
+	}
+	if err == nil && len(indexedMocks) == 0 {
+		indexedMocks, err = mockDb.GetUnFilteredMocksByKind(models.Postgres)
+		keyed = false
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	mocks = make([]*models.Mock, 0, len(indexedMocks))
+	for _, mock := range indexedMocks {
+		copyMock := *mock
+		mocks = append(mocks, &copyMock)
+	}
+	return mocks, keyed, nil
+}
+
+// pgQuery returns the first query run by the requests, either as a simple query or as a parse.
+func pgQuery(logger *zap.Logger, requestBuffers [][]byte) string {
+	for _, reqBuff := range requestBuffers {
+		if len(reqBuff) < 8 {
+			continue
+		}
+		pgReq := decodePgRequest(reqBuff, logger)
+		if pgReq == nil {
+			continue
+		}
+		if pgReq.Query.String != "" {
+			return pgReq.Query.String
+		}
+		for _, parse := range pgReq.Parses {
+			if parse.Query != "" {
+				return parse.Query
+			}
+		}
+	}
+	return ""
+}
+
+func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mutex, requestBuffers [][]byte, mockDb integrations.MockMemDb, simulateScram bool) (bool, []models.Frontend, error) {
+	// the mocks of the query of the requests are matched first, then all the postgres mocks
+	indexed := true
 	for {
 		select {
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
 
-			mocks, err := mockDb.GetUnFilteredMocks()
-			var tcsMocks []*models.Mock
-
-			for _, mock := range mocks {
-				if mock.Kind != "Postgres" {
-					continue
-				}
-				tcsMocks = append(tcsMocks, mock)
-			}
+			tcsMocks, keyed, err := postgresMocks(logger, requestBuffers, mockDb, indexed)
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting tcs mocks %v", err)
 			}
@@ -136,16 +176,22 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 						}
 
 						switch {
-						case bufStr == "AAAACATSFi8=":
+						case bufStr == sslRequestPayload:
 							ssl := models.Frontend{
 								Payload: "Tg==",
 							}
 							return true, []models.Frontend{ssl}, nil
-						case initMock.Spec.PostgresRequests[requestIndex].Identfier == "StartupRequest" && isStartupPacket(reqBuff) && initMock.Spec.PostgresRequests[requestIndex].Payload != "AAAACATSFi8=" && initMock.Spec.PostgresResponses[requestIndex].AuthType == 10:
-							logger.Debug("CHANGING TO MD5 for Response", zap.String("mock", initMock.Name), zap.String("Req", bufStr))
+						case initMock.Spec.PostgresRequests[requestIndex].Identfier == "StartupRequest" && isStartupPacket(reqBuff) && initMock.Spec.PostgresRequests[requestIndex].Payload != sslRequestPayload && initMock.Spec.PostgresResponses[requestIndex].AuthType == 10:
 							res := make([]models.Frontend, len(initMock.Spec.PostgresResponses))
 							copy(res, initMock.Spec.PostgresResponses)
-							res[requestIndex].AuthType = 5
+							if simulateScram {
+								// the auth is simulated with the configured password, hence the channel binding (SCRAM-SHA-256-PLUS) is not offered.
+								logger.Debug("Simulating SCRAM-SHA-256 for Response", zap.String("mock", initMock.Name), zap.String("Req", bufStr))
+								res[requestIndex].AuthenticationSASL = pgproto3.AuthenticationSASL{AuthMechanisms: []string{util.SCRAM_SHA_256}}
+							} else {
+								logger.Debug("CHANGING TO MD5 for Response", zap.String("mock", initMock.Name), zap.String("Req", bufStr))
+								res[requestIndex].AuthType = 5
+							}
 							err := mockDb.FlagMockAsUsed(initMock)
 							if err != nil {
 								logger.Error("failed to flag mock as used", zap.Error(err))
@@ -299,6 +345,10 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 				}
 				return true, matchedMock.Spec.PostgresResponses, nil
 			}
+			if keyed {
+				indexed = false
+				continue
+			}
 			return false, nil, nil
 		}
 	}
@@ -57,6 +57,12 @@ func (p *PostgresV1) RecordOutgoing(ctx context.Context, src net.Conn, dst net.C
 		utils.LogError(logger, err, "failed to read the initial postgres message")
 		return err
 	}
+	if isSSLRequest(reqBuf) {
+		src, dst, reqBuf, err = recordSSLNegotiation(ctx, logger, reqBuf, src, dst, mocks)
+		if err != nil {
+			return err
+		}
+	}
 	err = encodePostgres(ctx, logger, reqBuf, src, dst, mocks, opts)
 	if err != nil {
 		// TODO: why debug log?
@@ -74,6 +80,12 @@ func (p *PostgresV1) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *mod
 		utils.LogError(logger, err, "failed to read the initial postgres message")
 		return err
 	}
+	if isSSLRequest(reqBuf) {
+		src, reqBuf, err = simulateSSLNegotiation(ctx, logger, src, mockDb)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = decodePostgres(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
 	if err != nil {
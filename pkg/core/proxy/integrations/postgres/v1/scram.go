@@ -0,0 +1,154 @@
+//go:build linux
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/scram"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// scramIterations is the iteration count sent to the client in the simulated server-first-message.
+const scramIterations = 4096
+
+// scramConversation simulates the SCRAM-SHA-256 auth of the postgres server for a client connection.
+// The recorded conversation can't be replayed, as the nonce of the client is random, so the server
+// messages are generated from the configured password instead.
+type scramConversation struct {
+	password    string
+	clientFirst string
+	serverFirst string
+	salt        string // raw salt sent in the server-first-message
+}
+
+// startsSASL checks whether the responses ask the client for the SASL auth.
+func startsSASL(responses []models.Frontend) bool {
+	for _, resp := range responses {
+		if len(resp.PacketTypes) > 0 && resp.PacketTypes[0] == "R" && resp.AuthType == AuthTypeSASL {
+			return true
+		}
+	}
+	return false
+}
+
+// handle responds to a SASL message of the client. It returns true once the auth is completed.
+func (s *scramConversation) handle(ctx context.Context, logger *zap.Logger, clientConn net.Conn, reqBuf []byte, mockDb integrations.MockMemDb) (bool, error) {
+	if len(reqBuf) < 5 || reqBuf[0] != 'p' {
+		return false, errors.New("expected a SASL message from the postgres client")
+	}
+	body := reqBuf[5:]
+
+	if s.serverFirst == "" {
+		initial := pgproto3.SASLInitialResponse{}
+		err := initial.Decode(body)
+		if err != nil {
+			utils.LogError(logger, err, "failed to decode the SASL initial response")
+			return false, err
+		}
+		if initial.AuthMechanism != util.SCRAM_SHA_256 {
+			return false, fmt.Errorf("unsupported SASL mechanism %q", initial.AuthMechanism)
+		}
+		s.clientFirst = string(initial.Data)
+		s.serverFirst, s.salt, err = scram.NewServerFirstMessage(s.clientFirst, scramIterations)
+		if err != nil {
+			utils.LogError(logger, err, "failed to generate the SCRAM server first message")
+			return false, err
+		}
+		logger.Debug("simulating the SCRAM server first message", zap.String("client first", s.clientFirst), zap.String("server first", s.serverFirst))
+
+		// the recorded continue message can't be used, but it shouldn't be left unused either.
+		flagAuthMock(ctx, logger, mockDb, func(resp models.Frontend) bool {
+			return resp.AuthType == AuthTypeSASLContinue
+		})
+
+		_, err = clientConn.Write((&pgproto3.AuthenticationSASLContinue{Data: []byte(s.serverFirst)}).Encode(nil))
+		if err != nil {
+			utils.LogError(logger, err, "failed to write the SASL continue message to the client")
+			return false, err
+		}
+		return false, nil
+	}
+
+	final := pgproto3.SASLResponse{}
+	err := final.Decode(body)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the SASL response")
+		return false, err
+	}
+	authMessage, err := scram.BuildAuthMessage(s.clientFirst, s.serverFirst, string(final.Data))
+	if err != nil {
+		utils.LogError(logger, err, "failed to build the SCRAM auth message")
+		return false, err
+	}
+	signature, err := scram.GenerateServerFinalMessage(authMessage, util.SCRAM_SHA_256, s.password, s.salt, scramIterations, logger)
+	if err != nil {
+		utils.LogError(logger, err, "failed to generate the SCRAM server signature")
+		return false, err
+	}
+	resp := (&pgproto3.AuthenticationSASLFinal{Data: []byte("v=" + signature)}).Encode(nil)
+
+	// The recorded final message is followed by AuthenticationOk and the parameters of the session,
+	// which are replayed as they are.
+	mock := flagAuthMock(ctx, logger, mockDb, func(resp models.Frontend) bool {
+		return len(resp.PacketTypes) > 1 && resp.PacketTypes[0] == "R" && resp.PacketTypes[1] == "R"
+	})
+	if mock != nil {
+		rest := mock.Spec.PostgresResponses[0]
+		rest.PacketTypes = rest.PacketTypes[1:]
+		rest.AuthType = AuthTypeOk
+		encoded, err := postgresDecoderFrontend(rest)
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode the recorded messages following the SASL final message")
+			return false, err
+		}
+		resp = append(resp, encoded...)
+	} else {
+		logger.Debug("no recorded SASL final message found, completing the auth without the session parameters")
+		resp = append(resp, (&pgproto3.AuthenticationOk{}).Encode(nil)...)
+		resp = append(resp, (&pgproto3.ReadyForQuery{TxStatus: 'I'}).Encode(nil)...)
+	}
+
+	_, err = clientConn.Write(resp)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the SASL final message to the client")
+		return false, err
+	}
+	return true, nil
+}
+
+// flagAuthMock flags the first unused mock of a SASL response message, whose response satisfies the
+// given condition, as used. It returns the flagged mock, or nil if there is none.
+func flagAuthMock(ctx context.Context, logger *zap.Logger, mockDb integrations.MockMemDb, match func(models.Frontend) bool) *models.Mock {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.Postgres)
+	if err != nil {
+		utils.LogError(logger, err, "failed to get the unfiltered mocks")
+		return nil
+	}
+	for _, mock := range mocks {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if len(mock.Spec.PostgresRequests) == 0 || len(mock.Spec.PostgresResponses) == 0 {
+			continue
+		}
+		req := mock.Spec.PostgresRequests[0]
+		if len(req.PacketTypes) == 0 || req.PacketTypes[0] != "p" || !match(mock.Spec.PostgresResponses[0]) {
+			continue
+		}
+		err := mockDb.FlagMockAsUsed(*mock)
+		if err != nil {
+			utils.LogError(logger, err, "failed to flag mock as used")
+		}
+		return mock
+	}
+	return nil
+}
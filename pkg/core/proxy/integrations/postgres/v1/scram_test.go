@@ -0,0 +1,250 @@
+//go:build linux
+
+package v1
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/xdg-go/scram"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockDb serves the postgres mocks of a connection, the lookups the auth doesn't make are left unimplemented.
+type mockDb struct {
+	integrations.MockMemDb
+	mocks []*models.Mock
+	used  []string
+}
+
+func (db *mockDb) GetUnFilteredMocksByKind(models.Kind) ([]*models.Mock, error) {
+	return db.mocks, nil
+}
+
+func (db *mockDb) GetFilteredMocksByKind(models.Kind) ([]*models.Mock, error) {
+	return nil, nil
+}
+
+func (db *mockDb) FlagMockAsUsed(mock models.Mock) error {
+	db.used = append(db.used, mock.Name)
+	return nil
+}
+
+func saslMock(name string, resp models.Frontend) *models.Mock {
+	return &models.Mock{Name: name, Kind: models.Postgres, Spec: models.MockSpec{
+		PostgresRequests:  []models.Backend{{PacketTypes: []string{"p"}}},
+		PostgresResponses: []models.Frontend{resp},
+	}}
+}
+
+// receive reads a message sent by the server to the client during the auth.
+func receive(conn net.Conn) (pgproto3.BackendMessage, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return nil, err
+	}
+
+	var msg pgproto3.BackendMessage
+	switch header[0] {
+	case 'R':
+		if len(body) < 4 {
+			return nil, fmt.Errorf("invalid authentication message %q", body)
+		}
+		switch binary.BigEndian.Uint32(body) {
+		case AuthTypeOk:
+			msg = &pgproto3.AuthenticationOk{}
+		case AuthTypeSASLContinue:
+			msg = &pgproto3.AuthenticationSASLContinue{}
+		case AuthTypeSASLFinal:
+			msg = &pgproto3.AuthenticationSASLFinal{}
+		default:
+			return nil, fmt.Errorf("unexpected authentication type %d", binary.BigEndian.Uint32(body))
+		}
+	case 'S':
+		msg = &pgproto3.ParameterStatus{}
+	case 'Z':
+		msg = &pgproto3.ReadyForQuery{}
+	default:
+		return nil, fmt.Errorf("unexpected message type %q", header[0])
+	}
+	return msg, msg.Decode(body)
+}
+
+func TestScramConversation(t *testing.T) {
+	recorded := []*models.Mock{
+		saslMock("mock-1", models.Frontend{PacketTypes: []string{"R"}, AuthType: AuthTypeSASLContinue}),
+		saslMock("mock-2", models.Frontend{
+			PacketTypes:             []string{"R", "R", "S", "Z"},
+			AuthType:                AuthTypeSASLFinal,
+			ParameterStatusCombined: []pgproto3.ParameterStatus{{Name: "server_version", Value: "16.2"}},
+			ReadyForQuery:           pgproto3.ReadyForQuery{TxStatus: 'I'},
+		}),
+	}
+
+	tests := []struct {
+		name           string
+		clientPassword string
+		mocks          []*models.Mock
+		wantMsgs       []pgproto3.BackendMessage
+		wantUsed       []string
+		wantErr        bool
+	}{
+		{
+			name:           "recorded session parameters",
+			clientPassword: "postgres",
+			mocks:          recorded,
+			wantMsgs:       []pgproto3.BackendMessage{&pgproto3.AuthenticationOk{}, &pgproto3.ParameterStatus{Name: "server_version", Value: "16.2"}, &pgproto3.ReadyForQuery{TxStatus: 'I'}},
+			wantUsed:       []string{"mock-1", "mock-2"},
+		},
+		{
+			name:           "nothing recorded",
+			clientPassword: "postgres",
+			wantMsgs:       []pgproto3.BackendMessage{&pgproto3.AuthenticationOk{}, &pgproto3.ReadyForQuery{TxStatus: 'I'}},
+		},
+		{
+			name:           "other password",
+			clientPassword: "secret",
+			wantMsgs:       []pgproto3.BackendMessage{&pgproto3.AuthenticationOk{}, &pgproto3.ReadyForQuery{TxStatus: 'I'}},
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, client := net.Pipe()
+			defer clientConn.Close()
+			defer client.Close()
+
+			scramClient, err := scram.SHA256.NewClient("", tt.clientPassword, "")
+			if err != nil {
+				t.Fatalf("failed to create the scram client: %v", err)
+			}
+			conv := scramClient.NewConversation()
+			clientFirst, err := conv.Step("")
+			if err != nil {
+				t.Fatalf("failed to start the scram conversation: %v", err)
+			}
+
+			db := &mockDb{mocks: tt.mocks}
+			sc := &scramConversation{password: "postgres"}
+			type result struct {
+				done bool
+				err  error
+			}
+			handle := func(msg []byte) chan result {
+				res := make(chan result, 1)
+				go func() {
+					done, err := sc.handle(context.Background(), zap.NewNop(), clientConn, msg, db)
+					res <- result{done, err}
+				}()
+				return res
+			}
+
+			res := handle((&pgproto3.SASLInitialResponse{AuthMechanism: "SCRAM-SHA-256", Data: []byte(clientFirst)}).Encode(nil))
+			msg, err := receive(client)
+			if err != nil {
+				t.Fatalf("failed to receive the server first message: %v", err)
+			}
+			serverFirst, ok := msg.(*pgproto3.AuthenticationSASLContinue)
+			if !ok {
+				t.Fatalf("received %T, want the SASL continue message", msg)
+			}
+			if r := <-res; r.done || r.err != nil {
+				t.Fatalf("handle() of the initial response = %v, %v, want the auth to go on", r.done, r.err)
+			}
+
+			clientFinal, err := conv.Step(string(serverFirst.Data))
+			if err != nil {
+				t.Fatalf("failed to process the server first message: %v", err)
+			}
+			res = handle((&pgproto3.SASLResponse{Data: []byte(clientFinal)}).Encode(nil))
+			msg, err = receive(client)
+			if err != nil {
+				t.Fatalf("failed to receive the server final message: %v", err)
+			}
+			serverFinal, ok := msg.(*pgproto3.AuthenticationSASLFinal)
+			if !ok {
+				t.Fatalf("received %T, want the SASL final message", msg)
+			}
+			serverFinalData := string(serverFinal.Data)
+
+			var got []pgproto3.BackendMessage
+			for range tt.wantMsgs {
+				msg, err := receive(client)
+				if err != nil {
+					t.Fatalf("failed to receive the messages following the auth: %v", err)
+				}
+				got = append(got, msg)
+			}
+			if r := <-res; !r.done || r.err != nil {
+				t.Fatalf("handle() of the final response = %v, %v, want the auth to be completed", r.done, r.err)
+			}
+
+			_, err = conv.Step(serverFinalData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("client verification of the server signature error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.wantMsgs) {
+				t.Errorf("received %+v after the auth, want %+v", got, tt.wantMsgs)
+			}
+			if !reflect.DeepEqual(db.used, tt.wantUsed) {
+				t.Errorf("flagged the mocks %v as used, want %v", db.used, tt.wantUsed)
+			}
+		})
+	}
+}
+
+func TestScramConversationInvalidMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{name: "not a SASL message", msg: (&pgproto3.Query{String: "SELECT 1"}).Encode(nil)},
+		{name: "too short", msg: []byte{'p', 0}},
+		{name: "unsupported mechanism", msg: (&pgproto3.SASLInitialResponse{AuthMechanism: "SCRAM-SHA-256-PLUS", Data: []byte("p=tls-server-end-point,,n=,r=abc")}).Encode(nil)},
+		{name: "no client nonce", msg: (&pgproto3.SASLInitialResponse{AuthMechanism: "SCRAM-SHA-256", Data: []byte("n,,n=")}).Encode(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &scramConversation{password: "postgres"}
+			done, err := sc.handle(context.Background(), zap.NewNop(), nil, tt.msg, &mockDb{})
+			if done || err == nil {
+				t.Errorf("handle() = %v, %v, want an error", done, err)
+			}
+		})
+	}
+}
+
+func TestStartsSASL(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []models.Frontend
+		want      bool
+	}{
+		{name: "sasl", responses: []models.Frontend{{PacketTypes: []string{"R"}, AuthType: AuthTypeSASL}}, want: true},
+		{name: "md5", responses: []models.Frontend{{PacketTypes: []string{"R"}, AuthType: AuthTypeMD5Password}}},
+		{name: "query response", responses: []models.Frontend{{PacketTypes: []string{"T", "D", "C", "Z"}}}},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startsSASL(tt.responses); got != tt.want {
+				t.Errorf("startsSASL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
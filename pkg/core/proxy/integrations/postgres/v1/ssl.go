@@ -0,0 +1,165 @@
+//go:build linux
+
+package v1
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pTls "go.keploy.io/server/v2/pkg/core/proxy/tls"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// sslRequestPayload is the base64 encoded SSLRequest, as it is stored in the mocks.
+const sslRequestPayload = "AAAACATSFi8="
+
+// isSSLRequest checks whether the message is the SSLRequest, which is sent by the client before the
+// startup message to ask the server for upgrading the connection to TLS.
+func isSSLRequest(buf []byte) bool {
+	return len(buf) == 8 && binary.BigEndian.Uint32(buf[4:8]) == sslRequestNumber
+}
+
+// recordSSLNegotiation forwards the SSLRequest of the client to the server. If the server accepts it,
+// the TLS of the client is terminated using the keploy CA and the connection to the server is upgraded
+// as well, so that the rest of the conversation can be recorded in plaintext. The response of the
+// server is recorded as a config mock, for the negotiation to be simulated the same way while replaying.
+//
+// It returns the connections to be used from now on along with the startup message of the client.
+func recordSSLNegotiation(ctx context.Context, logger *zap.Logger, sslRequest []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock) (net.Conn, net.Conn, []byte, error) {
+	reqTimestampMock := time.Now()
+	_, err := destConn.Write(sslRequest)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the ssl request to the postgres server")
+		return nil, nil, nil, err
+	}
+
+	// The server responds with a single byte, 'S' for accepting and 'N' for declining the request.
+	sslResponse := make([]byte, 1)
+	_, err = io.ReadFull(destConn, sslResponse)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the ssl response from the postgres server")
+		return nil, nil, nil, err
+	}
+	_, err = clientConn.Write(sslResponse)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the ssl response to the client")
+		return nil, nil, nil, err
+	}
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.Postgres,
+		Spec: models.MockSpec{
+			PostgresRequests: []models.Backend{{
+				Identfier: "StartupRequest",
+				Length:    uint32(len(sslRequest)),
+				Payload:   base64.StdEncoding.EncodeToString(sslRequest),
+			}},
+			PostgresResponses: []models.Frontend{{
+				Payload: base64.StdEncoding.EncodeToString(sslResponse),
+			}},
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: time.Now(),
+			Metadata:         map[string]string{"type": "config"},
+		},
+		ConnectionID: ctx.Value(models.ClientConnectionIDKey).(string),
+	}
+
+	switch sslResponse[0] {
+	case 'S':
+		logger.Debug("upgrading the postgres connection to TLS")
+		clientConn, err = pTls.HandleTLSConnection(ctx, logger, clientConn)
+		if err != nil {
+			utils.LogError(logger, err, "failed to handle the TLS connection of the postgres client")
+			return nil, nil, nil, err
+		}
+		tlsDestConn := tls.Client(destConn, &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         pTls.DstURL,
+		})
+		err = tlsDestConn.Handshake()
+		if err != nil {
+			utils.LogError(logger, err, "failed to complete the TLS handshake with the postgres server")
+			return nil, nil, nil, err
+		}
+		destConn = tlsDestConn
+	case 'N':
+		logger.Debug("the postgres server declined the ssl request")
+	default:
+		return nil, nil, nil, fmt.Errorf("unexpected response to the ssl request: %q", sslResponse[0])
+	}
+
+	startup, err := pUtil.ReadInitialBuf(ctx, logger, clientConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the startup message after the ssl negotiation")
+		return nil, nil, nil, err
+	}
+	return clientConn, destConn, startup, nil
+}
+
+// simulateSSLNegotiation answers the SSLRequest of the client the way the server did while recording.
+// If the server accepted it, the TLS of the client is terminated using the keploy CA, since the mocks
+// are recorded in plaintext. The request is declined if its response wasn't recorded.
+//
+// It returns the connection to be used from now on along with the startup message of the client.
+func simulateSSLNegotiation(ctx context.Context, logger *zap.Logger, clientConn net.Conn, mockDb integrations.MockMemDb) (net.Conn, []byte, error) {
+	sslResponse := recordedSSLResponse(logger, mockDb)
+	_, err := clientConn.Write([]byte{sslResponse})
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the ssl response to the client")
+		return nil, nil, err
+	}
+	if sslResponse == 'S' {
+		clientConn, err = pTls.HandleTLSConnection(ctx, logger, clientConn)
+		if err != nil {
+			utils.LogError(logger, err, "failed to handle the TLS connection of the postgres client")
+			return nil, nil, err
+		}
+	}
+	startup, err := pUtil.ReadInitialBuf(ctx, logger, clientConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the startup message after the ssl negotiation")
+		return nil, nil, err
+	}
+	return clientConn, startup, nil
+}
+
+// recordedSSLResponse returns the response of the server to the SSLRequest found in the mocks, or 'N'
+// if there is none. The config mocks are shared by the connections, so the mock isn't consumed.
+func recordedSSLResponse(logger *zap.Logger, mockDb integrations.MockMemDb) byte {
+	filtered, err := mockDb.GetFilteredMocksByKind(models.Postgres)
+	if err != nil {
+		logger.Debug("failed to get the filtered postgres mocks", zap.Error(err))
+	}
+	unfiltered, err := mockDb.GetUnFilteredMocksByKind(models.Postgres)
+	if err != nil {
+		logger.Debug("failed to get the unfiltered postgres mocks", zap.Error(err))
+	}
+	for _, mock := range append(filtered, unfiltered...) {
+		for i, req := range mock.Spec.PostgresRequests {
+			if req.Payload != sslRequestPayload || i >= len(mock.Spec.PostgresResponses) {
+				continue
+			}
+			resp, err := base64.StdEncoding.DecodeString(mock.Spec.PostgresResponses[i].Payload)
+			if err != nil || len(resp) != 1 {
+				continue
+			}
+			if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+				logger.Debug("failed to flag the ssl negotiation mock as used", zap.Error(err))
+			}
+			return resp[0]
+		}
+	}
+	logger.Debug("the response to the ssl request wasn't recorded, declining it")
+	return 'N'
+}
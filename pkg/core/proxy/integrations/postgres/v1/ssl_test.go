@@ -0,0 +1,70 @@
+//go:build linux
+
+package v1
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestIsSSLRequest(t *testing.T) {
+	sslRequest, _ := base64.StdEncoding.DecodeString(sslRequestPayload)
+	tests := []struct {
+		name string
+		buf  []byte
+		want bool
+	}{
+		{name: "ssl request", buf: sslRequest, want: true},
+		{name: "gssenc request", buf: []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x30}},
+		{name: "startup message", buf: []byte{0, 0, 0, 9, 0, 3, 0, 0, 0}},
+		{name: "too short", buf: sslRequest[:4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSSLRequest(tt.buf); got != tt.want {
+				t.Errorf("isSSLRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordedSSLResponse(t *testing.T) {
+	sslMock := func(name, resp string) *models.Mock {
+		return &models.Mock{Name: name, Kind: models.Postgres, Spec: models.MockSpec{
+			PostgresRequests:  []models.Backend{{Identfier: "StartupRequest", Payload: sslRequestPayload}},
+			PostgresResponses: []models.Frontend{{Payload: base64.StdEncoding.EncodeToString([]byte(resp))}},
+			Metadata:          map[string]string{"type": "config"},
+		}}
+	}
+	startup := &models.Mock{Name: "mock-0", Kind: models.Postgres, Spec: models.MockSpec{
+		PostgresRequests:  []models.Backend{{Identfier: "StartupRequest", Payload: "AAAACQADAAAA"}},
+		PostgresResponses: []models.Frontend{{PacketTypes: []string{"R"}, AuthType: AuthTypeSASL}},
+	}}
+
+	tests := []struct {
+		name     string
+		mocks    []*models.Mock
+		want     byte
+		wantUsed []string
+	}{
+		{name: "accepted", mocks: []*models.Mock{startup, sslMock("mock-1", "S")}, want: 'S', wantUsed: []string{"mock-1"}},
+		{name: "declined", mocks: []*models.Mock{sslMock("mock-1", "N")}, want: 'N', wantUsed: []string{"mock-1"}},
+		{name: "not recorded", mocks: []*models.Mock{startup}, want: 'N'},
+		{name: "invalid response", mocks: []*models.Mock{sslMock("mock-1", "SN")}, want: 'N'},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &mockDb{mocks: tt.mocks}
+			if got := recordedSSLResponse(zap.NewNop(), db); got != tt.want {
+				t.Errorf("recordedSSLResponse() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(db.used, tt.wantUsed) {
+				t.Errorf("flagged the mocks %v as used, want %v", db.used, tt.wantUsed)
+			}
+		})
+	}
+}
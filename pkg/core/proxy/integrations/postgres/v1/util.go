@@ -135,11 +135,17 @@ func postgresDecoderFrontend(response models.Frontend) ([]byte, error) {
 			case AuthTypeSSPI:
 				return nil, errors.New("AuthTypeSSPI is unimplemented")
 			case AuthTypeSASL:
-				msg = &pgproto3.AuthenticationSASL{}
+				msg = &pgproto3.AuthenticationSASL{
+					AuthMechanisms: response.AuthenticationSASL.AuthMechanisms,
+				}
 			case AuthTypeSASLContinue:
-				msg = &pgproto3.AuthenticationSASLContinue{}
+				msg = &pgproto3.AuthenticationSASLContinue{
+					Data: response.AuthenticationSASLContinue.Data,
+				}
 			case AuthTypeSASLFinal:
-				msg = &pgproto3.AuthenticationSASLFinal{}
+				msg = &pgproto3.AuthenticationSASLFinal{
+					Data: response.AuthenticationSASLFinal.Data,
+				}
 			default:
 				return nil, fmt.Errorf("unknown authentication type: %d", response.AuthType)
 			}
@@ -7,132 +7,85 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 )
 
+// encodeRedis forwards the traffic between the client and the server, and records the commands along
+// with their replies. The commands sent before the replies of the previous ones (pipelines), and the
+// commands of a transaction (MULTI ... EXEC) are recorded in a single mock.
 func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
-
-	var redisRequests []models.Payload
-	var redisResponses []models.Payload
-
-	bufStr := string(reqBuf)
-	dataType := models.String
-
-	if bufStr != "" {
-		redisRequests = append(redisRequests, models.Payload{
-			Origin: models.FromClient,
-			Message: []models.OutputBinary{
-				{
-					Type: dataType,
-					Data: bufStr,
-				},
-			},
-		})
-	}
-	_, err := destConn.Write(reqBuf)
-	if err != nil {
-		utils.LogError(logger, err, "failed to write request message to the destination server")
-		return err
-	}
-	errCh := make(chan error, 1)
+	rec := &recorder{logger: logger, mocks: mocks}
+	errCh := make(chan error, 2)
 
 	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
 	if !ok {
 		return errors.New("failed to get the error group from the context")
 	}
 
-	reqTimestampMock := time.Now()
-
-	// Read and process responses from the destination server
+	// Read the commands of the client and forward them to the server
 	g.Go(func() error {
 		defer pUtil.Recover(logger, clientConn, destConn)
-		defer close(errCh)
-
+		var unparsed []byte
+		data := reqBuf
+		chunk := make([]byte, 32*1024)
 		for {
-			// Read the response from the destination server
-			resp, err := pUtil.ReadBytes(ctx, logger, destConn)
-			if err != nil {
-				if err == io.EOF {
-					logger.Debug("Response complete, exiting the loop.")
-					// if there is any buffer left before EOF, we must send it to the client and save this as mock
-					if len(resp) != 0 {
-						resTimestampMock := time.Now()
-						_, err = clientConn.Write(resp)
-						if err != nil {
-							utils.LogError(logger, err, "failed to write response message to the client")
-							errCh <- err
-							return nil
-						}
-						processBuffer(resp, models.FromServer, &redisResponses)
-						saveMock(redisRequests, redisResponses, reqTimestampMock, resTimestampMock, mocks)
-					}
-					break
+			if len(data) > 0 {
+				// the commands are queued before being forwarded, so that they precede their replies
+				unparsed = rec.addCommands(append(unparsed, data...))
+				_, err := destConn.Write(data)
+				if err != nil {
+					utils.LogError(logger, err, "failed to write request message to the destination server")
+					errCh <- err
+					return nil
 				}
-				utils.LogError(logger, err, "failed to read the response message from the destination server")
-				errCh <- err
-				return nil
 			}
-
-			// Write the response message to the client
-			_, err = clientConn.Write(resp)
-			if err != nil {
-				utils.LogError(logger, err, "failed to write response message to the client")
+			n, err := clientConn.Read(chunk)
+			if err != nil && n == 0 {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the request message from the client")
+				}
 				errCh <- err
 				return nil
 			}
+			data = chunk[:n]
+		}
+	})
 
-			resTimestampMock := time.Now()
-			processBuffer(resp, models.FromServer, &redisResponses)
-
-			// Save the mock with both request and response
-			if len(redisRequests) > 0 && len(redisResponses) > 0 {
-				saveMock(redisRequests, redisResponses, reqTimestampMock, resTimestampMock, mocks)
-				redisRequests = []models.Payload{}
-				redisResponses = []models.Payload{}
-			}
-
-			// Read the next request from the client
-			reqBuf, err = pUtil.ReadBytes(ctx, logger, clientConn)
-			if err != nil {
+	// Read the replies of the server and forward them to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		defer rec.flush()
+		var unparsed []byte
+		chunk := make([]byte, 32*1024)
+		for {
+			n, err := destConn.Read(chunk)
+			if err != nil && n == 0 {
 				if err != io.EOF {
-					utils.LogError(logger, err, "failed to read the request message from the client")
-					errCh <- err
-					return nil
+					utils.LogError(logger, err, "failed to read the response message from the destination server")
 				}
 				errCh <- err
 				return nil
 			}
-
-			bufStr := string(reqBuf)
-			dataType := models.String
-
-			if bufStr != "" {
-				redisRequests = append(redisRequests, models.Payload{
-					Origin: models.FromClient,
-					Message: []models.OutputBinary{
-						{
-							Type: dataType,
-							Data: bufStr,
-						},
-					},
-				})
-			}
-			_, err = destConn.Write(reqBuf)
+			data := chunk[:n]
+			// the replies are recorded before being forwarded, so that the next commands of the client
+			// aren't mixed up with the current ones
+			unparsed = rec.addReplies(append(unparsed, data...))
+			_, err = clientConn.Write(data)
 			if err != nil {
-				utils.LogError(logger, err, "failed to write request message to the destination server")
+				utils.LogError(logger, err, "failed to write response message to the client")
 				errCh <- err
 				return nil
 			}
-			reqTimestampMock = time.Now()
 		}
-		return nil
 	})
 
 	select {
@@ -146,28 +99,134 @@ func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 	}
 }
 
-func processBuffer(buffer []byte, origin models.OriginType, payloads *[]models.Payload) {
-	bufStr := string(buffer)
-	buffDataType := models.String
-
-	if bufStr != "" {
-		*payloads = append(*payloads, models.Payload{
-			Origin: origin,
-			Message: []models.OutputBinary{
-				{
-					Type: buffDataType,
-					Data: bufStr,
-				},
-			},
-		})
+// recorder pairs the commands of a connection with their replies.
+type recorder struct {
+	logger *zap.Logger
+	mocks  chan<- *models.Mock
+
+	mu           sync.Mutex
+	commands     []models.RedisCommand
+	replies      [][]byte
+	expected     []int // number of replies still expected for each command
+	next         int   // index of the first command waiting for its replies
+	inTx         bool
+	disabled     bool // set when the traffic isn't RESP, so nothing is recorded
+	reqTimestamp time.Time
+}
+
+// addCommands queues the complete commands of the buffer and returns the rest of it.
+func (r *recorder) addCommands(buf []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.disabled {
+		return nil
+	}
+	commands, _, rest, err := parseCommands(buf)
+	if err != nil {
+		r.disable(err)
+		return nil
+	}
+	for _, cmd := range commands {
+		if len(r.commands) == 0 {
+			r.reqTimestamp = time.Now()
+		}
+		r.commands = append(r.commands, cmd)
+		r.replies = append(r.replies, nil)
+		r.expected = append(r.expected, expectedReplies(cmd))
+		switch cmd.Name {
+		case "MULTI":
+			r.inTx = true
+		case "EXEC", "DISCARD":
+			r.inTx = false
+		}
+	}
+	return rest
+}
+
+// addReplies pairs the complete replies of the buffer with the queued commands and returns the rest of
+// it. The mock is saved once every command has been replied, unless a transaction is in progress.
+func (r *recorder) addReplies(buf []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.disabled {
+		return nil
+	}
+	for len(buf) > 0 {
+		v, n, err := parseValue(buf)
+		if err == errIncomplete {
+			break
+		}
+		if err != nil {
+			r.disable(err)
+			return nil
+		}
+		reply := buf[:n]
+		buf = buf[n:]
+
+		p, isPush := v.(push)
+		switch {
+		case r.next < len(r.commands) && (!isPush || isSubscription(r.commands[r.next].Name) && isSubscriptionPush(p)):
+			r.replies[r.next] = append(r.replies[r.next], reply...)
+			r.expected[r.next]--
+			if r.expected[r.next] <= 0 {
+				r.next++
+			}
+		case len(r.commands) > 0:
+			// out of band messages are replayed after the reply of the last command
+			last := len(r.commands) - 1
+			r.replies[last] = append(r.replies[last], reply...)
+		default:
+			r.logger.Debug("ignoring the redis message which doesn't reply to any command", zap.String("message", string(reply)))
+		}
+
+		if r.next == len(r.commands) && !r.inTx {
+			r.save(len(r.commands))
+		}
+	}
+	return append([]byte(nil), buf...)
+}
+
+// flush saves the commands which have been replied, when the connection is closed.
+func (r *recorder) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.disabled {
+		r.save(r.next)
+	}
+}
+
+func (r *recorder) disable(err error) {
+	r.logger.Debug("failed to parse the redis traffic, the connection won't be recorded", zap.Error(err))
+	r.disabled = true
+	r.commands, r.replies, r.expected, r.next = nil, nil, nil, 0
+}
+
+// save sends the mock of the first count commands and resets the batch.
+func (r *recorder) save(count int) {
+	if count > 0 {
+		responses := make([]models.Payload, count)
+		for i, reply := range r.replies[:count] {
+			responses[i] = replyPayload(reply)
+		}
+		saveMock(r.commands[:count], responses, r.reqTimestamp, time.Now(), r.mocks)
+	}
+	r.commands, r.replies, r.expected, r.next = nil, nil, nil, 0
+}
+
+func replyPayload(reply []byte) models.Payload {
+	data, dataType := string(reply), models.String
+	if !util.IsASCII(data) {
+		data, dataType = util.EncodeBase64(reply), "binary"
+	}
+	return models.Payload{
+		Origin:  models.FromServer,
+		Message: []models.OutputBinary{{Type: dataType, Data: data}},
 	}
 }
 
-func saveMock(requests, responses []models.Payload, reqTimestampMock, resTimestampMock time.Time, mocks chan<- *models.Mock) {
-	redisRequestsCopy := make([]models.Payload, len(requests))
-	redisResponsesCopy := make([]models.Payload, len(responses))
-	copy(redisResponsesCopy, responses)
-	copy(redisRequestsCopy, requests)
+func saveMock(commands []models.RedisCommand, responses []models.Payload, reqTimestampMock, resTimestampMock time.Time, mocks chan<- *models.Mock) {
+	redisCommandsCopy := make([]models.RedisCommand, len(commands))
+	copy(redisCommandsCopy, commands)
 
 	metadata := make(map[string]string)
 	metadata["type"] = "config"
@@ -177,8 +236,8 @@ func saveMock(requests, responses []models.Payload, reqTimestampMock, resTimesta
 		Name:    "mocks",
 		Kind:    models.REDIS,
 		Spec: models.MockSpec{
-			RedisRequests:    redisRequestsCopy,
-			RedisResponses:   redisResponsesCopy,
+			RedisCommands:    redisCommandsCopy,
+			RedisResponses:   responses,
 			ReqTimestampMock: reqTimestampMock,
 			ResTimestampMock: resTimestampMock,
 			Metadata:         metadata,
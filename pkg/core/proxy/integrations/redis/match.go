@@ -4,148 +4,249 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
-
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
 )
 
-// fuzzyMatch performs a fuzzy matching algorithm to find the best matching mock for the given request.
-// It takes a context, a request buffer, and a mock database as input parameters.
-// The function iterates over the mocks in the database and applies the fuzzy matching algorithm to find the best match.
-// If a match is found, it returns the corresponding response mock and a boolean value indicating success.
-// If no match is found, it returns false and a nil response.
-// If an error occurs during the matching process, it returns an error.
-func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb) (bool, []models.Payload, error) {
-	for {
-		select {
-		case <-ctx.Done():
-			return false, nil, ctx.Err()
-		default:
-			mocks, err := mockDb.GetUnFilteredMocks()
-			if err != nil {
-				return false, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
-			}
-
-			var filteredMocks []*models.Mock
-			var unfilteredMocks []*models.Mock
-
-			for _, mock := range mocks {
-				if mock.Kind != "Redis" {
-					continue
-				}
-				if mock.TestModeInfo.IsFiltered {
-					filteredMocks = append(filteredMocks, mock)
-				} else {
-					unfilteredMocks = append(unfilteredMocks, mock)
-				}
-			}
-
-			index := findExactMatch(filteredMocks, reqBuff)
-
-			if index == -1 {
-				index = findBinaryMatch(filteredMocks, reqBuff, 0.9)
-			}
+// redisMock is a mock along with the replies of each of its commands.
+type redisMock struct {
+	mock     *models.Mock
+	commands []models.RedisCommand
+	replies  [][]byte
+}
 
-			if index != -1 {
-				responseMock := make([]models.Payload, len(filteredMocks[index].Spec.RedisResponses))
-				copy(responseMock, filteredMocks[index].Spec.RedisResponses)
-				originalFilteredMock := *filteredMocks[index]
-				filteredMocks[index].TestModeInfo.IsFiltered = false
-				filteredMocks[index].TestModeInfo.SortOrder = math.MaxInt64
-				isUpdated := mockDb.UpdateUnFilteredMock(&originalFilteredMock, filteredMocks[index])
-				if !isUpdated {
-					continue
-				}
-				return true, responseMock, nil
-			}
+// noise holds the arguments ignored while matching, by the name of the command ("*" for every command).
+// An entry is either the position of an argument, starting from 1, or an option whose value is ignored.
+type noise map[string][]string
 
-			index = findExactMatch(unfilteredMocks, reqBuff)
+func newNoise(cfg map[string][]string) noise {
+	n := noise{}
+	for name, entries := range cfg {
+		name = strings.ToUpper(name)
+		for _, entry := range entries {
+			n[name] = append(n[name], strings.ToUpper(entry))
+		}
+	}
+	return n
+}
 
-			if index != -1 {
-				responseMock := make([]models.Payload, len(unfilteredMocks[index].Spec.RedisResponses))
-				copy(responseMock, unfilteredMocks[index].Spec.RedisResponses)
-				return true, responseMock, nil
+// ignored returns the positions of the arguments of the command which are ignored.
+func (n noise) ignored(cmd models.RedisCommand) map[int]bool {
+	ignored := map[int]bool{}
+	for _, entries := range [][]string{n["*"], n[cmd.Name]} {
+		for _, entry := range entries {
+			if pos, err := strconv.Atoi(entry); err == nil {
+				ignored[pos-1] = true
+				continue
 			}
-
-			totalMocks := append(filteredMocks, unfilteredMocks...)
-			index = findBinaryMatch(totalMocks, reqBuff, 0.4)
-
-			if index != -1 {
-				responseMock := make([]models.Payload, len(totalMocks[index].Spec.RedisResponses))
-				copy(responseMock, totalMocks[index].Spec.RedisResponses)
-				originalFilteredMock := *totalMocks[index]
-				if totalMocks[index].TestModeInfo.IsFiltered {
-					totalMocks[index].TestModeInfo.IsFiltered = false
-					totalMocks[index].TestModeInfo.SortOrder = math.MaxInt64
-					isUpdated := mockDb.UpdateUnFilteredMock(&originalFilteredMock, totalMocks[index])
-					if !isUpdated {
-						continue
-					}
+			for i, arg := range cmd.Args {
+				if strings.EqualFold(arg, entry) {
+					ignored[i+1] = true
 				}
-				return true, responseMock, nil
 			}
+		}
+	}
+	return ignored
+}
 
-			return false, nil, nil
+// match tells whether the command matches the recorded one. The names and the arguments, except for the
+// noisy ones, have to be the same.
+func (n noise) match(expected, actual models.RedisCommand) bool {
+	if expected.Name != actual.Name || len(expected.Args) != len(actual.Args) {
+		return false
+	}
+	ignored := n.ignored(expected)
+	for i := range expected.Args {
+		if expected.Args[i] != actual.Args[i] && !ignored[i] {
+			return false
 		}
 	}
+	return true
 }
 
-// TODO: need to generalize this function for different types of integrations.
-func findBinaryMatch(tcsMocks []*models.Mock, reqBuffs [][]byte, mxSim float64) int {
-	// TODO: need find a proper similarity index to set a benchmark for matching or need to find another way to do approximate matching
-	mxIdx := -1
-	for idx, mock := range tcsMocks {
-		if len(mock.Spec.RedisRequests) == len(reqBuffs) {
-			for requestIndex, reqBuff := range reqBuffs {
-				mockReq, err := util.DecodeBase64(mock.Spec.RedisRequests[requestIndex].Message[0].Data)
-				if err != nil {
-					mockReq = []byte(mock.Spec.RedisRequests[requestIndex].Message[0].Data)
-				}
+// session replays the mocks on a connection. The commands of a pipeline or of a transaction are recorded
+// in a single mock, so the mock stays active until all its commands have been replayed.
+type session struct {
+	logger *zap.Logger
+	mockDb integrations.MockMemDb
+	noise  noise
+	active *redisMock
+	pos    int
+}
 
-				similarity := fuzzyCheck(mockReq, reqBuff)
-				if mxSim < similarity {
-					mxSim = similarity
-					mxIdx = idx
-				}
-			}
+// reply returns the recorded reply of the first command. The following commands, which have already
+// been received, are used to choose between the mocks which start with the same command.
+func (s *session) reply(ctx context.Context, commands []models.RedisCommand) ([]byte, bool, error) {
+	cmd := commands[0]
+	if s.active != nil && s.noise.match(s.active.commands[s.pos], cmd) {
+		return s.advance(), true, nil
+	}
 
-		}
+	s.active = nil
+	matchStart := time.Now()
+	rm, err := s.match(ctx, commands)
+	integrations.MockMatch(s.mockDb, models.REDIS, cmd.Name, rm != nil, err, matchStart)
+	if err != nil || rm == nil {
+		return nil, false, err
 	}
-	return mxIdx
+	s.active, s.pos = rm, 0
+	return s.advance(), true, nil
 }
 
-func fuzzyCheck(encoded, reqBuf []byte) float64 {
-	k := util.AdaptiveK(len(reqBuf), 3, 8, 5)
-	shingles1 := util.CreateShingles(encoded, k)
-	shingles2 := util.CreateShingles(reqBuf, k)
-	similarity := util.JaccardSimilarity(shingles1, shingles2)
-	return similarity
+func (s *session) advance() []byte {
+	reply := s.active.replies[s.pos]
+	s.pos++
+	if s.pos == len(s.active.commands) {
+		s.active = nil
+	}
+	return reply
 }
 
-func findExactMatch(tcsMocks []*models.Mock, reqBuffs [][]byte) int {
-	for idx, mock := range tcsMocks {
-		if len(mock.Spec.RedisRequests) == len(reqBuffs) {
-			matched := true // Flag to track if all requests match
+// match finds the mock whose commands match the most of the received ones, looking in the mocks of the
+// current test case first. The mocks of the test case are moved to the end of the config mocks once used.
+func (s *session) match(ctx context.Context, commands []models.RedisCommand) (*redisMock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := s.mockDb.GetUnFilteredMocksByKind(models.REDIS)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
 
-			for requestIndex, reqBuff := range reqBuffs {
+		var filteredMocks, unfilteredMocks []*redisMock
+		for _, mock := range mocks {
+			rm, err := newRedisMock(mock)
+			if err != nil {
+				s.logger.Debug("failed to parse the redis mock", zap.String("mock", mock.Name), zap.Error(err))
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, rm)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, rm)
+			}
+		}
 
-				bufStr := string(reqBuff)
+		if rm := s.bestMatch(filteredMocks, commands); rm != nil {
+			updatedMock := *rm.mock
+			updatedMock.TestModeInfo.IsFiltered = false
+			updatedMock.TestModeInfo.SortOrder = math.MaxInt64
+			if !s.mockDb.UpdateUnFilteredMock(rm.mock, &updatedMock) {
+				continue
+			}
+			return rm, nil
+		}
 
-				// Compare the encoded data
-				if mock.Spec.RedisRequests[requestIndex].Message[0].Data != bufStr {
-					matched = false
-					break // Exit the loop if any request doesn't match
-				}
+		if rm := s.bestMatch(unfilteredMocks, commands); rm != nil {
+			if err := s.mockDb.FlagMockAsUsed(*rm.mock); err != nil {
+				s.logger.Debug("failed to flag the redis mock as used", zap.Error(err))
 			}
+			return rm, nil
+		}
+		return nil, nil
+	}
+}
+
+// bestMatch returns the mock sharing the longest prefix of commands with the received ones. The mocks are
+// in their sort order, so the earliest recorded mock wins a tie.
+func (s *session) bestMatch(mocks []*redisMock, commands []models.RedisCommand) *redisMock {
+	var (
+		best      *redisMock
+		bestScore int
+	)
+	for _, rm := range mocks {
+		score := 0
+		for score < len(rm.commands) && score < len(commands) && s.noise.match(rm.commands[score], commands[score]) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rm, score
+		}
+	}
+	return best
+}
 
-			if matched {
-				return idx
+// newRedisMock pairs the commands of the mock with their replies. The mocks recorded before the commands
+// were decoded hold the raw traffic instead, which is parsed here.
+func newRedisMock(mock *models.Mock) (*redisMock, error) {
+	rm := &redisMock{mock: mock, commands: mock.Spec.RedisCommands}
+	if len(rm.commands) > 0 {
+		rm.replies = make([][]byte, len(rm.commands))
+		for i := 0; i < len(rm.replies) && i < len(mock.Spec.RedisResponses); i++ {
+			reply, err := payloadBytes(mock.Spec.RedisResponses[i])
+			if err != nil {
+				return nil, err
 			}
+			rm.replies[i] = reply
+		}
+		return rm, nil
+	}
+
+	var requests, responses []byte
+	for _, payload := range mock.Spec.RedisRequests {
+		b, err := payloadBytes(payload)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, b...)
+	}
+	for _, payload := range mock.Spec.RedisResponses {
+		b, err := payloadBytes(payload)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, b...)
+	}
+	commands, _, rest, err := parseCommands(requests)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 || len(rest) > 0 {
+		return nil, errors.New("the recorded requests aren't complete redis commands")
+	}
+	rm.commands = commands
+	rm.replies = make([][]byte, len(commands))
+
+	var replies [][]byte
+	for buf := responses; len(buf) > 0; {
+		_, n, err := parseValue(buf)
+		if err != nil {
+			break
+		}
+		replies = append(replies, buf[:n])
+		buf = buf[n:]
+	}
+	if len(replies) == len(commands) {
+		rm.replies = replies
+	} else {
+		// the replies can't be told apart, so all of them are sent after the last command
+		rm.replies[len(commands)-1] = responses
+	}
+	return rm, nil
+}
+
+func payloadBytes(payload models.Payload) ([]byte, error) {
+	var out []byte
+	for _, msg := range payload.Message {
+		if msg.Type == models.String {
+			out = append(out, msg.Data...)
+			continue
+		}
+		b, err := util.DecodeBase64(msg.Data)
+		if err != nil {
+			return nil, err
 		}
+		out = append(out, b...)
 	}
-	return -1
+	return out, nil
 }
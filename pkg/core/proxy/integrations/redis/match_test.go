@@ -0,0 +1,260 @@
+//go:build linux
+
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func cmd(name string, args ...string) models.RedisCommand {
+	return models.RedisCommand{Name: name, Args: args}
+}
+
+// sameCommands compares the commands, the commands without arguments being the same whether they were parsed
+// from an array or from an inline command.
+func sameCommands(got, want []models.RedisCommand) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || len(got[i].Args) != len(want[i].Args) {
+			return false
+		}
+		for j := range want[i].Args {
+			if got[i].Args[j] != want[i].Args[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestNoiseIgnored(t *testing.T) {
+	n := newNoise(map[string][]string{
+		"*":     {"px"},
+		"set":   {"ex"},
+		"zadd":  {"2"},
+		"hmset": {"3", "5"},
+	})
+	tests := []struct {
+		name string
+		cmd  models.RedisCommand
+		want map[int]bool
+	}{
+		{name: "no noise", cmd: cmd("GET", "user:1"), want: map[int]bool{}},
+		{name: "option of the command", cmd: cmd("SET", "k", "v", "EX", "60"), want: map[int]bool{3: true}},
+		{name: "option in any case", cmd: cmd("SET", "k", "v", "ex", "60"), want: map[int]bool{3: true}},
+		{name: "option of every command", cmd: cmd("SET", "k", "v", "PX", "100"), want: map[int]bool{3: true}},
+		{name: "positions", cmd: cmd("HMSET", "h", "f1", "v1", "f2", "v2"), want: map[int]bool{2: true, 4: true}},
+		{name: "position", cmd: cmd("ZADD", "z", "1700000000", "m"), want: map[int]bool{1: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.ignored(tt.cmd); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ignored() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoiseMatch(t *testing.T) {
+	n := newNoise(map[string][]string{"set": {"ex"}, "zadd": {"2"}})
+	tests := []struct {
+		name     string
+		expected models.RedisCommand
+		actual   models.RedisCommand
+		want     bool
+	}{
+		{name: "same command", expected: cmd("GET", "user:1"), actual: cmd("GET", "user:1"), want: true},
+		{name: "other key", expected: cmd("GET", "user:1"), actual: cmd("GET", "user:2")},
+		{name: "other name", expected: cmd("GET", "user:1"), actual: cmd("DEL", "user:1")},
+		{name: "other arguments count", expected: cmd("DEL", "a"), actual: cmd("DEL", "a", "b")},
+		{name: "noisy option", expected: cmd("SET", "k", "v", "EX", "60"), actual: cmd("SET", "k", "v", "EX", "61"), want: true},
+		{name: "value besides the noisy option", expected: cmd("SET", "k", "v", "EX", "60"), actual: cmd("SET", "k", "w", "EX", "61")},
+		{name: "noisy position", expected: cmd("ZADD", "z", "1700000000", "m"), actual: cmd("ZADD", "z", "1700000042", "m"), want: true},
+		{name: "noise of another command", expected: cmd("GET", "k", "EX"), actual: cmd("GET", "k", "PX")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.match(tt.expected, tt.actual); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	get1 := &redisMock{commands: []models.RedisCommand{cmd("GET", "user:1")}}
+	get2 := &redisMock{commands: []models.RedisCommand{cmd("GET", "user:2")}}
+	multi := &redisMock{commands: []models.RedisCommand{cmd("MULTI"), cmd("INCR", "a"), cmd("EXEC")}}
+	multi2 := &redisMock{commands: []models.RedisCommand{cmd("MULTI"), cmd("INCR", "b"), cmd("EXEC")}}
+	mocks := []*redisMock{get1, get2, multi, multi2}
+
+	tests := []struct {
+		name     string
+		commands []models.RedisCommand
+		want     *redisMock
+	}{
+		{name: "key", commands: []models.RedisCommand{cmd("GET", "user:2")}, want: get2},
+		{name: "no match", commands: []models.RedisCommand{cmd("GET", "user:3")}},
+		{name: "transaction", commands: []models.RedisCommand{cmd("MULTI"), cmd("INCR", "b"), cmd("EXEC")}, want: multi2},
+		{name: "first command of a transaction", commands: []models.RedisCommand{cmd("MULTI")}, want: multi},
+	}
+	s := &session{noise: noise{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.bestMatch(mocks, tt.commands); got != tt.want {
+				t.Errorf("bestMatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRedisMock(t *testing.T) {
+	str := func(data string) models.Payload {
+		return models.Payload{Message: []models.OutputBinary{{Type: models.String, Data: data}}}
+	}
+	tests := []struct {
+		name     string
+		spec     models.MockSpec
+		commands []models.RedisCommand
+		replies  []string
+		wantErr  bool
+	}{
+		{
+			name: "decoded commands",
+			spec: models.MockSpec{
+				RedisCommands:  []models.RedisCommand{cmd("GET", "k")},
+				RedisResponses: []models.Payload{str("$1\r\nv\r\n")},
+			},
+			commands: []models.RedisCommand{cmd("GET", "k")},
+			replies:  []string{"$1\r\nv\r\n"},
+		},
+		{
+			name: "raw pipeline",
+			spec: models.MockSpec{
+				RedisRequests:  []models.Payload{str("*2\r\n$3\r\nGET\r\n$1\r\na\r\n*2\r\n$3\r\nGET\r\n$1\r\nb\r\n")},
+				RedisResponses: []models.Payload{str("$1\r\n1\r\n$-1\r\n")},
+			},
+			commands: []models.RedisCommand{cmd("GET", "a"), cmd("GET", "b")},
+			replies:  []string{"$1\r\n1\r\n", "$-1\r\n"},
+		},
+		{
+			name: "raw replies which can't be told apart",
+			spec: models.MockSpec{
+				RedisRequests:  []models.Payload{str("PING\r\nPING\r\n")},
+				RedisResponses: []models.Payload{str("+PONG\r\n")},
+			},
+			commands: []models.RedisCommand{cmd("PING"), cmd("PING")},
+			replies:  []string{"", "+PONG\r\n"},
+		},
+		{
+			name:    "incomplete raw command",
+			spec:    models.MockSpec{RedisRequests: []models.Payload{str("*2\r\n$3\r\nGET\r\n")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm, err := newRedisMock(&models.Mock{Kind: models.REDIS, Spec: tt.spec})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRedisMock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !sameCommands(rm.commands, tt.commands) {
+				t.Errorf("newRedisMock() commands = %v, want %v", rm.commands, tt.commands)
+			}
+			replies := make([]string, len(rm.replies))
+			for i, reply := range rm.replies {
+				replies[i] = string(reply)
+			}
+			if !reflect.DeepEqual(replies, tt.replies) {
+				t.Errorf("newRedisMock() replies = %q, want %q", replies, tt.replies)
+			}
+		})
+	}
+}
+
+// mockDb serves the redis mocks of a session, the lookups the session doesn't make are left unimplemented.
+type mockDb struct {
+	integrations.MockMemDb
+	mocks     []*models.Mock
+	used      []string
+	unmatched []string
+}
+
+func (db *mockDb) GetUnFilteredMocksByKind(models.Kind) ([]*models.Mock, error) {
+	return db.mocks, nil
+}
+
+func (db *mockDb) FlagMockAsUsed(mock models.Mock) error {
+	db.used = append(db.used, mock.Name)
+	return nil
+}
+
+func (db *mockDb) FlagCallAsUnmatched(_ models.Kind, call string) {
+	db.unmatched = append(db.unmatched, call)
+}
+
+func TestSessionReply(t *testing.T) {
+	str := func(data string) models.Payload {
+		return models.Payload{Message: []models.OutputBinary{{Type: models.String, Data: data}}}
+	}
+	db := &mockDb{mocks: []*models.Mock{
+		{
+			Name: "mock-0",
+			Kind: models.REDIS,
+			Spec: models.MockSpec{
+				RedisCommands:  []models.RedisCommand{cmd("SET", "k", "v", "EX", "60"), cmd("GET", "k")},
+				RedisResponses: []models.Payload{str("+OK\r\n"), str("$1\r\nv\r\n")},
+			},
+		},
+		{
+			Name: "mock-1",
+			Kind: models.REDIS,
+			Spec: models.MockSpec{
+				RedisCommands:  []models.RedisCommand{cmd("GET", "other")},
+				RedisResponses: []models.Payload{str("$-1\r\n")},
+			},
+		},
+	}}
+	s := &session{logger: zap.NewNop(), mockDb: db, noise: newNoise(map[string][]string{"set": {"ex"}})}
+
+	// the pipeline is replayed from the mock holding both commands, whatever the ttl
+	tests := []struct {
+		name     string
+		commands []models.RedisCommand
+		reply    string
+		ok       bool
+	}{
+		{name: "first command of the pipeline", commands: []models.RedisCommand{cmd("SET", "k", "v", "EX", "30"), cmd("GET", "k")}, reply: "+OK\r\n", ok: true},
+		{name: "second command of the pipeline", commands: []models.RedisCommand{cmd("GET", "k")}, reply: "$1\r\nv\r\n", ok: true},
+		{name: "another mock", commands: []models.RedisCommand{cmd("GET", "other")}, reply: "$-1\r\n", ok: true},
+		{name: "no mock", commands: []models.RedisCommand{cmd("GET", "missing")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, ok, err := s.reply(context.Background(), tt.commands)
+			if err != nil {
+				t.Fatalf("reply() error = %v", err)
+			}
+			if ok != tt.ok || string(reply) != tt.reply {
+				t.Errorf("reply() = %q, %v, want %q, %v", reply, ok, tt.reply, tt.ok)
+			}
+		})
+	}
+	if want := []string{"GET"}; !reflect.DeepEqual(db.unmatched, want) {
+		t.Errorf("unmatched calls = %v, want %v", db.unmatched, want)
+	}
+	if want := []string{"mock-0", "mock-1"}; !reflect.DeepEqual(db.used, want) {
+		t.Errorf("used mocks = %v, want %v", db.used, want)
+	}
+}
@@ -0,0 +1,205 @@
+//go:build linux
+
+package redis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// errIncomplete is returned when the buffer ends in the middle of a RESP message.
+var errIncomplete = errors.New("incomplete RESP message")
+
+// maxElements bounds the length of the aggregates, so that a corrupt length doesn't exhaust the memory.
+const maxElements = 1 << 24
+
+// push is a RESP3 out of band message, e.g. a pub/sub message or a key invalidation.
+type push []interface{}
+
+// parseValue parses the RESP2/RESP3 value at the start of the buffer and returns it along with its
+// length. The strings are returned as string, the integers as int64, the aggregates as []interface{}
+// (the maps as a list of keys and values) and the null as nil.
+func parseValue(buf []byte) (interface{}, int, error) {
+	if len(buf) == 0 {
+		return nil, 0, errIncomplete
+	}
+	line, n, err := readLine(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	typ, payload := buf[0], string(line[1:])
+
+	switch typ {
+	case '+', '-', ',', '(':
+		return payload, n, nil
+	case ':':
+		v, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid RESP integer %q", payload)
+		}
+		return v, n, nil
+	case '#':
+		return payload == "t", n, nil
+	case '_':
+		return nil, n, nil
+	case '$', '!', '=':
+		size, err := parseLength(payload)
+		if err != nil {
+			return nil, 0, err
+		}
+		if size < 0 {
+			return nil, n, nil
+		}
+		if len(buf) < n+size+2 {
+			return nil, 0, errIncomplete
+		}
+		if buf[n+size] != '\r' || buf[n+size+1] != '\n' {
+			return nil, 0, errors.New("RESP bulk string isn't terminated by CRLF")
+		}
+		return string(buf[n : n+size]), n + size + 2, nil
+	case '*', '~', '>', '%', '|':
+		count, err := parseLength(payload)
+		if err != nil {
+			return nil, 0, err
+		}
+		if count < 0 {
+			return nil, n, nil
+		}
+		if typ == '%' || typ == '|' {
+			count *= 2
+		}
+		elems := make([]interface{}, 0, min(count, 64))
+		for i := 0; i < count; i++ {
+			v, size, err := parseValue(buf[n:])
+			if err != nil {
+				return nil, 0, err
+			}
+			elems = append(elems, v)
+			n += size
+		}
+		switch typ {
+		case '>':
+			return push(elems), n, nil
+		case '|':
+			// the attributes are followed by the value they describe
+			v, size, err := parseValue(buf[n:])
+			if err != nil {
+				return nil, 0, err
+			}
+			return v, n + size, nil
+		}
+		return elems, n, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown RESP type %q", typ)
+	}
+}
+
+// parseCommand parses the command at the start of the buffer, which is either an array of bulk strings
+// or an inline command. An empty name is returned for the empty commands, which are ignored by the server.
+func parseCommand(buf []byte) (models.RedisCommand, int, error) {
+	if len(buf) == 0 {
+		return models.RedisCommand{}, 0, errIncomplete
+	}
+	if buf[0] != '*' {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return models.RedisCommand{}, 0, errIncomplete
+		}
+		return newCommand(strings.Fields(string(buf[:i]))), i + 1, nil
+	}
+
+	v, n, err := parseValue(buf)
+	if err != nil {
+		return models.RedisCommand{}, 0, err
+	}
+	elems, _ := v.([]interface{})
+	args := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		arg, ok := elem.(string)
+		if !ok {
+			return models.RedisCommand{}, 0, fmt.Errorf("invalid redis command argument %v", elem)
+		}
+		args = append(args, arg)
+	}
+	return newCommand(args), n, nil
+}
+
+// parseCommands parses the complete commands of the buffer. It returns the raw bytes of each command
+// along with the rest of the buffer, which holds an incomplete command.
+func parseCommands(buf []byte) ([]models.RedisCommand, [][]byte, []byte, error) {
+	var (
+		commands []models.RedisCommand
+		raws     [][]byte
+	)
+	for len(buf) > 0 {
+		cmd, n, err := parseCommand(buf)
+		if err == errIncomplete {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if cmd.Name != "" {
+			commands = append(commands, cmd)
+			raws = append(raws, buf[:n])
+		}
+		buf = buf[n:]
+	}
+	return commands, raws, buf, nil
+}
+
+func newCommand(fields []string) models.RedisCommand {
+	if len(fields) == 0 {
+		return models.RedisCommand{}
+	}
+	return models.RedisCommand{Name: strings.ToUpper(fields[0]), Args: fields[1:]}
+}
+
+func readLine(buf []byte) ([]byte, int, error) {
+	i := bytes.Index(buf, []byte("\r\n"))
+	if i < 0 {
+		return nil, 0, errIncomplete
+	}
+	return buf[:i], i + 2, nil
+}
+
+func parseLength(s string) (int, error) {
+	size, err := strconv.Atoi(s)
+	if err != nil || size < -1 || size > maxElements {
+		return 0, fmt.Errorf("invalid RESP length %q", s)
+	}
+	return size, nil
+}
+
+// isSubscription tells whether the command subscribes or unsubscribes the connection to channels.
+// The server replies once for every channel.
+func isSubscription(name string) bool {
+	switch name {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE":
+		return true
+	}
+	return false
+}
+
+// expectedReplies is the number of replies of the server to the command.
+func expectedReplies(cmd models.RedisCommand) int {
+	if isSubscription(cmd.Name) && len(cmd.Args) > 1 {
+		return len(cmd.Args)
+	}
+	return 1
+}
+
+// isSubscriptionPush tells whether the push message is the confirmation of a (un)subscription, with
+// which a RESP3 server replies to the subscription commands.
+func isSubscriptionPush(p push) bool {
+	if len(p) == 0 {
+		return false
+	}
+	kind, _ := p[0].(string)
+	return isSubscription(strings.ToUpper(kind))
+}
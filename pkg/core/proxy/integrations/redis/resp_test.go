@@ -0,0 +1,133 @@
+//go:build linux
+
+package redis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		buf     string
+		want    interface{}
+		n       int
+		wantErr error
+	}{
+		{name: "simple string", buf: "+OK\r\n", want: "OK", n: 5},
+		{name: "error", buf: "-ERR unknown\r\n", want: "ERR unknown", n: 14},
+		{name: "integer", buf: ":42\r\n", want: int64(42), n: 5},
+		{name: "bulk string", buf: "$5\r\nhello\r\n", want: "hello", n: 11},
+		{name: "bulk string holding CRLF", buf: "$4\r\na\r\nb\r\n", want: "a\r\nb", n: 10},
+		{name: "null bulk string", buf: "$-1\r\n", want: nil, n: 5},
+		{name: "array", buf: "*2\r\n$1\r\na\r\n:1\r\n", want: []interface{}{"a", int64(1)}, n: 15},
+		{name: "null", buf: "_\r\n", want: nil, n: 3},
+		{name: "boolean", buf: "#t\r\n", want: true, n: 4},
+		{name: "map", buf: "%1\r\n+k\r\n+v\r\n", want: []interface{}{"k", "v"}, n: 12},
+		{name: "push", buf: ">2\r\n+message\r\n+hi\r\n", want: push{"message", "hi"}, n: 19},
+		{name: "attribute before a value", buf: "|1\r\n+ttl\r\n:3\r\n+v\r\n", want: "v", n: 18},
+		{name: "value followed by another one", buf: "+OK\r\n+NEXT\r\n", want: "OK", n: 5},
+		{name: "empty", buf: "", wantErr: errIncomplete},
+		{name: "incomplete line", buf: "+OK", wantErr: errIncomplete},
+		{name: "incomplete bulk string", buf: "$5\r\nhel", wantErr: errIncomplete},
+		{name: "incomplete array", buf: "*2\r\n+a\r\n", wantErr: errIncomplete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := parseValue([]byte(tt.buf))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseValue() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) || n != tt.n {
+				t.Errorf("parseValue() = %#v, %d, want %#v, %d", got, n, tt.want, tt.n)
+			}
+		})
+	}
+}
+
+func TestParseValueInvalid(t *testing.T) {
+	for _, buf := range []string{
+		"?x\r\n",
+		":abc\r\n",
+		"$5\r\nhelloXX",
+		"*99999999999\r\n",
+		"$-2\r\n",
+	} {
+		if _, _, err := parseValue([]byte(buf)); err == nil || errors.Is(err, errIncomplete) {
+			t.Errorf("parseValue(%q) error = %v, want an invalid RESP error", buf, err)
+		}
+	}
+}
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		buf      string
+		commands []models.RedisCommand
+		rest     string
+	}{
+		{
+			name:     "array",
+			buf:      "*3\r\n$3\r\nset\r\n$1\r\nk\r\n$1\r\nv\r\n",
+			commands: []models.RedisCommand{cmd("SET", "k", "v")},
+		},
+		{
+			name:     "inline",
+			buf:      "PING\r\nget k\r\n",
+			commands: []models.RedisCommand{cmd("PING"), cmd("GET", "k")},
+		},
+		{
+			name:     "empty inline command",
+			buf:      "\r\nPING\r\n",
+			commands: []models.RedisCommand{cmd("PING")},
+		},
+		{
+			name:     "pipeline ending with an incomplete command",
+			buf:      "*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n",
+			commands: []models.RedisCommand{cmd("PING")},
+			rest:     "*2\r\n$3\r\nGET\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, raws, rest, err := parseCommands([]byte(tt.buf))
+			if err != nil {
+				t.Fatalf("parseCommands() error = %v", err)
+			}
+			if !sameCommands(commands, tt.commands) || len(raws) != len(commands) {
+				t.Errorf("parseCommands() = %v with %d raws, want %v", commands, len(raws), tt.commands)
+			}
+			if string(rest) != tt.rest {
+				t.Errorf("parseCommands() rest = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+
+	if _, _, _, err := parseCommands([]byte("*1\r\n:1\r\n")); err == nil {
+		t.Error("parseCommands() of a command with an integer argument didn't fail")
+	}
+}
+
+func TestExpectedReplies(t *testing.T) {
+	tests := []struct {
+		cmd  models.RedisCommand
+		want int
+	}{
+		{cmd: cmd("GET", "k"), want: 1},
+		{cmd: cmd("SUBSCRIBE", "a"), want: 1},
+		{cmd: cmd("SUBSCRIBE", "a", "b", "c"), want: 3},
+		{cmd: cmd("PUNSUBSCRIBE", "a*", "b*"), want: 2},
+	}
+	for _, tt := range tests {
+		if got := expectedReplies(tt.cmd); got != tt.want {
+			t.Errorf("expectedReplies(%v) = %d, want %d", tt.cmd, got, tt.want)
+		}
+	}
+}
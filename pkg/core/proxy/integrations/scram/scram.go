@@ -4,6 +4,7 @@
 package scram
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -135,3 +136,44 @@ func GenerateAuthMessage(firstRequest, firstResponse string, logger *zap.Logger)
 
 	return authMsg
 }
+
+// NewServerFirstMessage generates the server's first message for a client's first message, when there
+// is no recorded response to reuse. The client nonce is extended with a random one, and a random salt
+// is used along with the given iteration count.
+//
+// Returns:
+//   - The server's first message, e.g., "r=<client nonce><server nonce>,s=<base64 salt>,i=4096".
+//   - The raw salt, which is needed for generating the server's final message.
+//   - An error if the client nonce can't be extracted or the random bytes can't be read.
+func NewServerFirstMessage(clientFirstMsg string, itr int) (string, string, error) {
+	clientNonce, err := extractClientNonce(clientFirstMsg)
+	if err != nil {
+		return "", "", err
+	}
+	serverNonce := make([]byte, 18)
+	if _, err := rand.Read(serverNonce); err != nil {
+		return "", "", fmt.Errorf("failed to generate the server nonce: %v", err)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", fmt.Errorf("failed to generate the salt: %v", err)
+	}
+	msg := fmt.Sprintf("r=%s%s,s=%s,i=%d", clientNonce, base64.StdEncoding.EncodeToString(serverNonce), base64.StdEncoding.EncodeToString(salt), itr)
+	return msg, string(salt), nil
+}
+
+// BuildAuthMessage creates the authentication message from the whole conversation, i.e.,
+// client-first-message-bare + "," + server-first-message + "," + client-final-message-without-proof.
+// Unlike GenerateAuthMessage, the channel binding is taken from the client's final message as it is.
+func BuildAuthMessage(clientFirstMsg, serverFirstMsg, clientFinalMsg string) (string, error) {
+	// the gs2 header is made of the first two fields, e.g., "n,," or "y,,"
+	parts := strings.SplitN(clientFirstMsg, ",", 3)
+	if len(parts) < 3 {
+		return "", fmt.Errorf("no gs2 header found in the client first message")
+	}
+	proofIdx := strings.LastIndex(clientFinalMsg, ",p=")
+	if proofIdx == -1 {
+		return "", fmt.Errorf("no proof found in the client final message")
+	}
+	return parts[2] + "," + serverFirstMsg + "," + clientFinalMsg[:proofIdx], nil
+}
@@ -0,0 +1,73 @@
+//go:build linux
+
+package scram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewServerFirstMessage(t *testing.T) {
+	msg, salt, err := NewServerFirstMessage("n,,n=,r=clientnonce", 4096)
+	if err != nil {
+		t.Fatalf("NewServerFirstMessage() error = %v", err)
+	}
+	parts := strings.Split(msg, ",")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "r=clientnonce") || len(parts[0]) == len("r=clientnonce") || !strings.HasPrefix(parts[1], "s=") || parts[2] != "i=4096" {
+		t.Errorf("NewServerFirstMessage() = %q, want the client nonce extended, a salt and the iteration count", msg)
+	}
+	if len(salt) != 16 {
+		t.Errorf("NewServerFirstMessage() salt of %d bytes, want 16", len(salt))
+	}
+
+	other, _, err := NewServerFirstMessage("n,,n=,r=clientnonce", 4096)
+	if err != nil {
+		t.Fatalf("NewServerFirstMessage() error = %v", err)
+	}
+	if other == msg {
+		t.Error("NewServerFirstMessage() returned the same nonce and salt twice")
+	}
+
+	if _, _, err := NewServerFirstMessage("n,,n=", 4096); err == nil {
+		t.Error("NewServerFirstMessage() without a client nonce error = nil, want an error")
+	}
+}
+
+func TestBuildAuthMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		clientFirst string
+		serverFirst string
+		clientFinal string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "without channel binding",
+			clientFirst: "n,,n=,r=abc",
+			serverFirst: "r=abcdef,s=c2FsdA==,i=4096",
+			clientFinal: "c=biws,r=abcdef,p=cHJvb2Y=",
+			want:        "n=,r=abc,r=abcdef,s=c2FsdA==,i=4096,c=biws,r=abcdef",
+		},
+		{
+			name:        "channel binding supported by the client",
+			clientFirst: "y,,n=user,r=abc",
+			serverFirst: "r=abcdef,s=c2FsdA==,i=4096",
+			clientFinal: "c=eSws,r=abcdef,p=cHJvb2Y=",
+			want:        "n=user,r=abc,r=abcdef,s=c2FsdA==,i=4096,c=eSws,r=abcdef",
+		},
+		{name: "no gs2 header", clientFirst: "n=,r=abc", serverFirst: "r=abcdef", clientFinal: "c=biws,r=abcdef,p=cHJvb2Y=", wantErr: true},
+		{name: "no proof", clientFirst: "n,,n=,r=abc", serverFirst: "r=abcdef", clientFinal: "c=biws,r=abcdef", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildAuthMessage(tt.clientFirst, tt.serverFirst, tt.clientFinal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildAuthMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BuildAuthMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
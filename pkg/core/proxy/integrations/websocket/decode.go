@@ -0,0 +1,180 @@
+//go:build linux
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	proxyHttp "go.keploy.io/server/v2/pkg/core/proxy/integrations/http"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeWebSocket replays a recorded websocket session to the application. The handshake is answered with the
+// recorded response, then the messages of the server are sent as the ones of the client are received.
+func decodeWebSocket(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *models.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger.Debug("Into the websocket parser in test mode")
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+
+		buf, err := readHeaders(ctx, logger, clientConn, reqBuf)
+		if err != nil {
+			utils.LogError(logger, err, "failed to read the websocket handshake request")
+			errCh <- err
+			return
+		}
+		reqHeaders, frames := splitHeaders(buf)
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(reqHeaders)))
+		if err != nil {
+			utils.LogError(logger, err, "failed to parse the websocket handshake request")
+			errCh <- err
+			return
+		}
+
+		matchStart := time.Now()
+		mock, err := match(ctx, logger, req, mockDb)
+		integrations.MockMatch(mockDb, models.WebSocket, req.Host+req.URL.Path, mock != nil, err, matchStart)
+		if err != nil {
+			utils.LogError(logger, err, "error while matching websocket mocks")
+			errCh <- err
+			return
+		}
+		if mock == nil {
+			if !proxyHttp.IsPassThrough(logger, req, dstCfg.Port, opts) {
+				utils.LogError(logger, nil, "Didn't match any preExisting websocket mock", zap.Any("url", req.URL.String()))
+			}
+			if opts.FallBackOnMiss {
+				errCh <- passThrough(ctx, logger, clientConn, dstCfg, buf)
+				return
+			}
+			errCh <- nil
+			return
+		}
+
+		_, err = clientConn.Write(handshakeResponse(mock, req))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			utils.LogError(logger, err, "failed to write the websocket handshake response to the client")
+			errCh <- err
+			return
+		}
+		if mock.Spec.HTTPResp.StatusCode != http.StatusSwitchingProtocols {
+			errCh <- nil
+			return
+		}
+
+		s := &session{logger: logger, conn: clientConn, messages: mock.Spec.WebSocketMessages, mockDb: mockDb, call: req.Host + req.URL.Path}
+		err = s.replay(ctx, frames)
+		if err != nil && ctx.Err() == nil {
+			if err != io.EOF {
+				utils.LogError(logger, err, "failed to replay the websocket session")
+			}
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// handshakeResponse returns the recorded response to the handshake request. The accept key is computed from the
+// key of the request, and the extensions aren't negotiated, as the messages are sent uncompressed.
+func handshakeResponse(mock *models.Mock, req *http.Request) []byte {
+	resp := mock.Spec.HTTPResp
+	// the keys are canonicalized, so that the recorded ones are replaced whatever their case
+	header := http.Header{}
+	for key, values := range resp.Header {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	body := resp.Body
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		header.Set("Sec-WebSocket-Accept", pkg.WebSocketAccept(req.Header.Get("Sec-WebSocket-Key")))
+		header.Del("Sec-WebSocket-Extensions")
+		body = ""
+	} else if header.Get("Content-Length") != "" || body != "" {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	for key, values := range header {
+		for _, value := range values {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+		}
+	}
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	return b.Bytes()
+}
+
+// passThrough forwards the session to the destination server, when no mock matches its handshake.
+func passThrough(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *models.ConditionalDstCfg, reqBuf []byte) error {
+	var (
+		destConn net.Conn
+		err      error
+	)
+	if dstCfg.TLSCfg != nil {
+		destConn, err = tls.Dial("tcp", dstCfg.Addr, dstCfg.TLSCfg)
+	} else {
+		destConn, err = net.Dial("tcp", dstCfg.Addr)
+	}
+	if err != nil {
+		utils.LogError(logger, err, "failed to dial the destination server", zap.Any("server address", dstCfg.Addr))
+		return err
+	}
+	defer func() {
+		if err := destConn.Close(); err != nil {
+			logger.Debug("failed to close the destination connection", zap.Error(err))
+		}
+	}()
+
+	_, err = destConn.Write(reqBuf)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the websocket handshake request to the destination server")
+		return err
+	}
+	errCh := make(chan error, 2)
+	go func() {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		_, err := io.Copy(destConn, clientConn)
+		errCh <- err
+	}()
+	go func() {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		_, err := io.Copy(clientConn, destConn)
+		errCh <- err
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
@@ -0,0 +1,276 @@
+//go:build linux
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg"
+	proxyHttp "go.keploy.io/server/v2/pkg/core/proxy/integrations/http"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+var headerEnd = []byte("\r\n\r\n")
+
+// encodeWebSocket forwards the handshake and the frames of a websocket session, and records the whole session
+// as a single mock once it is closed. The messages are recorded in the order they were forwarded, with the
+// side which sent them.
+func encodeWebSocket(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	reqTimestampMock := time.Now()
+	reqBuf, err := readHeaders(ctx, logger, clientConn, reqBuf)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the websocket handshake request")
+		return err
+	}
+	_, err = destConn.Write(reqBuf)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the websocket handshake request to the destination server")
+		return err
+	}
+
+	respBuf, err := readHeaders(ctx, logger, destConn, nil)
+	if err == nil {
+		respBuf, err = readRejection(ctx, logger, destConn, respBuf)
+	}
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the websocket handshake response")
+		return err
+	}
+	_, err = clientConn.Write(respBuf)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the websocket handshake response to the client")
+		return err
+	}
+	resTimestampMock := time.Now()
+
+	rec, clientFrames, destFrames, err := newRecorder(logger, reqBuf, respBuf, mocks)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake")
+		return err
+	}
+	rec.reqTimestamp, rec.resTimestamp = reqTimestampMock, resTimestampMock
+
+	remoteAddr := destConn.RemoteAddr().(*net.TCPAddr)
+	if proxyHttp.IsPassThrough(logger, rec.req, uint(remoteAddr.Port), opts) {
+		logger.Debug("the websocket session is passed through, it won't be recorded", zap.Any("url", rec.req.URL.String()))
+		rec.disabled = true
+	}
+
+	// a rejected handshake ends the session, the response is recorded for the application to get it again
+	if rec.resp.StatusCode != http.StatusSwitchingProtocols {
+		rec.save()
+		return nil
+	}
+
+	rec.add(models.FromClient, clientFrames)
+	rec.add(models.FromServer, destFrames)
+
+	errCh := make(chan error, 2)
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	forward := func(src, dst net.Conn, origin models.OriginType) {
+		g.Go(func() error {
+			defer pUtil.Recover(logger, clientConn, destConn)
+			chunk := make([]byte, 32*1024)
+			for {
+				n, err := src.Read(chunk)
+				if err != nil && n == 0 {
+					if err != io.EOF {
+						utils.LogError(logger, err, "failed to read the websocket frames", zap.Any("origin", origin))
+					}
+					errCh <- err
+					return nil
+				}
+				// the frames are recorded before being forwarded, so that the messages are in the order of the session
+				rec.add(origin, chunk[:n])
+				_, err = dst.Write(chunk[:n])
+				if err != nil {
+					utils.LogError(logger, err, "failed to forward the websocket frames", zap.Any("origin", origin))
+					errCh <- err
+					return nil
+				}
+			}
+		})
+	}
+	forward(clientConn, destConn, models.FromClient)
+	forward(destConn, clientConn, models.FromServer)
+
+	select {
+	case <-ctx.Done():
+		rec.save()
+		return ctx.Err()
+	case err := <-errCh:
+		rec.save()
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// readHeaders reads from conn until buf holds the headers of a whole http message.
+func readHeaders(ctx context.Context, logger *zap.Logger, conn net.Conn, buf []byte) ([]byte, error) {
+	for !bytes.Contains(buf, headerEnd) {
+		data, err := pUtil.ReadBytes(ctx, logger, conn)
+		buf = append(buf, data...)
+		if err != nil && (err != io.EOF || !bytes.Contains(buf, headerEnd)) {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// readRejection reads the rest of the body of a response rejecting the handshake, i.e. of any response but
+// 101 Switching Protocols. The body of a rejection is expected to have a length.
+func readRejection(ctx context.Context, logger *zap.Logger, conn net.Conn, buf []byte) ([]byte, error) {
+	headers, body := splitHeaders(buf)
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(headers)), nil)
+	if err != nil {
+		return buf, err
+	}
+	for resp.StatusCode != http.StatusSwitchingProtocols && resp.ContentLength > int64(len(body)) {
+		data, err := pUtil.ReadBytes(ctx, logger, conn)
+		buf = append(buf, data...)
+		body = append(body, data...)
+		if err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// splitHeaders splits a buffer into the headers of the http message it starts with and the frames which follow them.
+func splitHeaders(buf []byte) ([]byte, []byte) {
+	i := bytes.Index(buf, headerEnd)
+	if i < 0 {
+		return buf, nil
+	}
+	return buf[:i+len(headerEnd)], buf[i+len(headerEnd):]
+}
+
+// recorder collects the messages of a websocket session.
+type recorder struct {
+	logger *zap.Logger
+	mocks  chan<- *models.Mock
+	req    *http.Request
+	resp   *http.Response
+	body   []byte
+
+	mu           sync.Mutex
+	parsers      map[models.OriginType]*pkg.WebSocketParser
+	messages     []models.WebSocketMessage
+	start        time.Time
+	reqTimestamp time.Time
+	resTimestamp time.Time
+	disabled     bool
+	saved        bool
+}
+
+// newRecorder parses the handshake of the session, and returns the frames which were read along with it.
+func newRecorder(logger *zap.Logger, reqBuf, respBuf []byte, mocks chan<- *models.Mock) (*recorder, []byte, []byte, error) {
+	reqHeaders, clientFrames := splitHeaders(reqBuf)
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(reqHeaders)))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, destFrames := splitHeaders(respBuf)
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(respBuf)), req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rec := &recorder{logger: logger, mocks: mocks, req: req, resp: resp, start: time.Now()}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		rec.body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return rec, nil, nil, nil
+	}
+	deflate := pkg.IsWebSocketDeflate(resp.Header.Get("Sec-WebSocket-Extensions"))
+	rec.parsers = map[models.OriginType]*pkg.WebSocketParser{
+		models.FromClient: pkg.NewWebSocketParser(models.FromClient, deflate),
+		models.FromServer: pkg.NewWebSocketParser(models.FromServer, deflate),
+	}
+	return rec, clientFrames, destFrames, nil
+}
+
+// add parses the frames sent by origin. The pings and pongs are left out, as the parties answer them by themselves.
+func (r *recorder) add(origin models.OriginType, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.disabled || len(data) == 0 {
+		return
+	}
+	msgs, err := r.parsers[origin].Feed(data, time.Since(r.start))
+	if err != nil {
+		r.logger.Debug("failed to parse the websocket frames, the session won't be recorded", zap.Error(err))
+		r.disabled = true
+		return
+	}
+	for _, msg := range msgs {
+		if msg.Type == models.WebSocketPing || msg.Type == models.WebSocketPong {
+			continue
+		}
+		r.messages = append(r.messages, msg)
+	}
+}
+
+// save sends the mock of the session, once.
+func (r *recorder) save() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.disabled || r.saved {
+		return
+	}
+	r.saved = true
+
+	resTimestampMock := r.resTimestamp
+	if len(r.messages) > 0 {
+		resTimestampMock = time.Now()
+	}
+	r.mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.WebSocket,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"name":      "WebSocket",
+				"type":      models.HTTPClient,
+				"operation": r.req.Method,
+			},
+			HTTPReq: &models.HTTPReq{
+				Method:     models.Method(r.req.Method),
+				ProtoMajor: r.req.ProtoMajor,
+				ProtoMinor: r.req.ProtoMinor,
+				URL:        r.req.URL.String(),
+				Header:     pkg.ToYamlHTTPHeader(r.req.Header),
+				URLParams:  pkg.URLParams(r.req),
+			},
+			HTTPResp: &models.HTTPResp{
+				StatusCode:   r.resp.StatusCode,
+				Header:       pkg.ToYamlHTTPHeader(r.resp.Header),
+				Body:         string(r.body),
+				BodyEncoding: models.EncodingOf(string(r.body)),
+			},
+			WebSocketMessages: append([]models.WebSocketMessage(nil), r.messages...),
+			Created:           time.Now().Unix(),
+			ReqTimestampMock:  r.reqTimestamp,
+			ResTimestampMock:  resTimestampMock,
+		},
+	}
+}
@@ -0,0 +1,213 @@
+//go:build linux
+
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// match finds the mock of the session of a handshake request, looking in the mocks of the current test case
+// first. The mocks with the same path are candidates, the ones with the same query too being preferred.
+func match(ctx context.Context, logger *zap.Logger, req *http.Request, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		filteredMocks, err := mockDb.GetFilteredMocksByKey(integrations.WebSocketKey(req.URL.Path))
+		if err != nil {
+			return nil, fmt.Errorf("error while getting filtered mocks %v", err)
+		}
+		unfilteredMocks, err := mockDb.GetUnFilteredMocksByKey(integrations.WebSocketKey(req.URL.Path))
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		if mock := bestMatch(logger, filteredMocks, req); mock != nil {
+			// the matched mock is shared with the mock store, so a copy is updated
+			updatedMock := *mock
+			updatedMock.TestModeInfo.IsFiltered = false
+			updatedMock.TestModeInfo.SortOrder = math.MaxInt
+			if !mockDb.UpdateUnFilteredMock(mock, &updatedMock) {
+				continue
+			}
+			return mock, nil
+		}
+		if mock := bestMatch(logger, unfilteredMocks, req); mock != nil {
+			if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+				logger.Debug("failed to flag the websocket mock as used", zap.Error(err))
+			}
+			return mock, nil
+		}
+		return nil, nil
+	}
+}
+
+// bestMatch returns the first mock of the path of the request, or the first one which also has its query.
+func bestMatch(logger *zap.Logger, mocks []*models.Mock, req *http.Request) *models.Mock {
+	var best *models.Mock
+	for _, mock := range mocks {
+		if mock.Kind != models.WebSocket || mock.Spec.HTTPReq == nil || mock.Spec.HTTPResp == nil {
+			continue
+		}
+		u, err := url.Parse(mock.Spec.HTTPReq.URL)
+		if err != nil {
+			logger.Debug("failed to parse the url of the websocket mock", zap.String("mock", mock.Name), zap.Error(err))
+			continue
+		}
+		if u.Path != req.URL.Path {
+			continue
+		}
+		if reflect.DeepEqual(u.Query(), req.URL.Query()) {
+			return mock
+		}
+		if best == nil {
+			best = mock
+		}
+	}
+	return best
+}
+
+// session replays the recorded messages of a websocket session.
+type session struct {
+	logger   *zap.Logger
+	conn     net.Conn
+	messages []models.WebSocketMessage
+	pos      int
+	closed   bool
+	// mockDb is told about the messages of the client which match no recorded one, as the call of the session
+	mockDb integrations.MockMemDb
+	call   string
+}
+
+// replay sends the messages of the server which preceded the first message of the client, then answers each
+// message of the client with the messages of the server which followed the matching recorded message.
+func (s *session) replay(ctx context.Context, frames []byte) error {
+	start := time.Now()
+	err := s.sendServerMessages()
+	if err != nil {
+		return err
+	}
+	parser := pkg.NewWebSocketParser(models.FromClient, false)
+	chunk := make([]byte, 32*1024)
+	for {
+		msgs, err := parser.Feed(frames, time.Since(start))
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			done, err := s.receive(msg)
+			if err != nil || done {
+				return err
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		n, err := s.conn.Read(chunk)
+		if err != nil && n == 0 {
+			return err
+		}
+		frames = chunk[:n]
+	}
+}
+
+// receive answers a message of the client, done being true once the session is closed.
+func (s *session) receive(msg models.WebSocketMessage) (bool, error) {
+	switch msg.Type {
+	case models.WebSocketPing:
+		return false, s.send(models.WebSocketMessage{Type: models.WebSocketPong, Data: msg.Data})
+	case models.WebSocketPong:
+		return false, nil
+	}
+
+	start := time.Now()
+	i := s.next(msg)
+	integrations.MockMatch(s.mockDb, models.WebSocket, s.call, i >= 0, nil, start)
+	if i >= 0 {
+		s.pos = i + 1
+		err := s.sendServerMessages()
+		if err != nil {
+			return false, err
+		}
+	} else {
+		s.logger.Warn("no recorded websocket message matches the message of the client", zap.String("session", s.call), zap.Any("message", msg))
+	}
+
+	if msg.Type != models.WebSocketClose {
+		return s.closed, nil
+	}
+	// the close message of the client is answered with its own status, unless the server closed the session first
+	if !s.closed {
+		err := s.send(models.WebSocketMessage{Type: models.WebSocketClose, CloseCode: msg.CloseCode})
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// next returns the index of the recorded message of the client which matches msg, -1 if none does. The messages
+// are matched in order, the ones of the client which have no counterpart being skipped.
+func (s *session) next(msg models.WebSocketMessage) int {
+	for i := s.pos; i < len(s.messages); i++ {
+		recorded := s.messages[i]
+		if recorded.Origin == models.FromClient && recorded.Type == msg.Type && sameContent(recorded, msg) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameContent tells whether two messages have the same content, the text messages holding JSON being compared
+// as JSON.
+func sameContent(recorded, msg models.WebSocketMessage) bool {
+	if recorded.Data == msg.Data && recorded.CloseCode == msg.CloseCode {
+		return true
+	}
+	if msg.Type != models.WebSocketText {
+		return false
+	}
+	var a, b interface{}
+	if json.Unmarshal([]byte(recorded.Data), &a) != nil || json.Unmarshal([]byte(msg.Data), &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sendServerMessages sends the recorded messages of the server up to the next message of the client.
+func (s *session) sendServerMessages() error {
+	for ; s.pos < len(s.messages) && s.messages[s.pos].Origin == models.FromServer; s.pos++ {
+		if s.closed {
+			continue
+		}
+		err := s.send(s.messages[s.pos])
+		if err != nil {
+			return err
+		}
+		s.closed = s.messages[s.pos].Type == models.WebSocketClose
+	}
+	return nil
+}
+
+func (s *session) send(msg models.WebSocketMessage) error {
+	frame, err := pkg.EncodeWebSocketFrame(msg, false)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,88 @@
+//go:build linux
+
+package websocket
+
+import (
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockDb records the calls flagged as unmatched.
+type mockDb struct {
+	integrations.MockMemDb
+	unmatched []string
+}
+
+func (db *mockDb) FlagCallAsUnmatched(_ models.Kind, call string) {
+	db.unmatched = append(db.unmatched, call)
+}
+
+func text(origin models.OriginType, data string) models.WebSocketMessage {
+	return models.WebSocketMessage{Origin: origin, Type: models.WebSocketText, Data: data}
+}
+
+func TestSessionNext(t *testing.T) {
+	messages := []models.WebSocketMessage{
+		text(models.FromClient, `{"op":"subscribe","id":1}`),
+		text(models.FromServer, "subscribed"),
+		text(models.FromClient, "ping"),
+		{Origin: models.FromClient, Type: models.WebSocketBinary, Data: "\x01\x02"},
+		text(models.FromClient, "bye"),
+	}
+	tests := []struct {
+		name string
+		pos  int
+		msg  models.WebSocketMessage
+		want int
+	}{
+		{name: "same JSON", msg: text(models.FromClient, `{"id":1,"op":"subscribe"}`), want: 0},
+		{name: "skipping the messages without a counterpart", msg: text(models.FromClient, "bye"), want: 4},
+		{name: "same content of another type", msg: models.WebSocketMessage{Type: models.WebSocketBinary, Data: "ping"}, want: -1},
+		{name: "other content of the same type", msg: text(models.FromClient, `{"op":"subscribe","id":2}`), want: -1},
+		{name: "message already replayed", pos: 3, msg: text(models.FromClient, "ping"), want: -1},
+		{name: "message of the server", msg: text(models.FromClient, "subscribed"), want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &session{messages: messages, pos: tt.pos}
+			if got := s.next(tt.msg); got != tt.want {
+				t.Errorf("next() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionReceiveUnmatched(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		_, _ = io.Copy(io.Discard, client)
+	}()
+
+	db := &mockDb{}
+	s := &session{
+		logger:   zap.NewNop(),
+		conn:     server,
+		messages: []models.WebSocketMessage{text(models.FromClient, "hello"), text(models.FromServer, "world")},
+		mockDb:   db,
+		call:     "localhost/ws",
+	}
+	for _, msg := range []models.WebSocketMessage{text(models.FromClient, "hi"), text(models.FromClient, "hello")} {
+		if _, err := s.receive(msg); err != nil {
+			t.Fatalf("receive(%q) error = %v", msg.Data, err)
+		}
+	}
+	if want := []string{"localhost/ws"}; !reflect.DeepEqual(db.unmatched, want) {
+		t.Errorf("unmatched calls = %v, want %v", db.unmatched, want)
+	}
+	if s.pos != 2 {
+		t.Errorf("position = %d, want 2 once the answer of the matched message is sent", s.pos)
+	}
+}
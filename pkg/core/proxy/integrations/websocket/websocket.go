@@ -0,0 +1,71 @@
+//go:build linux
+
+// Package websocket records and mocks the websocket sessions of the application, i.e. the http upgrade
+// handshake along with the messages exchanged after it.
+package websocket
+
+import (
+	"context"
+	"net"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("websocket", NewWebSocket)
+}
+
+type WebSocket struct {
+	logger *zap.Logger
+}
+
+func NewWebSocket(logger *zap.Logger) integrations.Integrations {
+	return &WebSocket{
+		logger: logger,
+	}
+}
+
+// MatchType tells whether the outgoing call is the handshake of a websocket session. The http integration
+// leaves these requests out, as the frames which follow the handshake aren't http.
+func (w *WebSocket) MatchType(_ context.Context, buf []byte) bool {
+	return pkg.IsWebSocketHandshake(buf)
+}
+
+func (w *WebSocket) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := w.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial websocket message")
+		return err
+	}
+
+	err = encodeWebSocket(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the websocket session into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (w *WebSocket) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *models.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := w.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial websocket message")
+		return err
+	}
+
+	err = decodeWebSocket(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the websocket session")
+		return err
+	}
+	return nil
+}
@@ -9,7 +9,9 @@ import (
 	"strings"
 	"sync"
 
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/metrics"
 	"go.uber.org/zap"
 )
 
@@ -17,15 +19,16 @@ type MockManager struct {
 	filtered      *TreeDb
 	unfiltered    *TreeDb
 	logger        *zap.Logger
-	consumedMocks sync.Map
+	consumedMu    sync.Mutex
+	consumedMocks []string // names of the mocks used since the last call of GetConsumedMocks, once per use
+	unmatched     []models.MockState
 }
 
 func NewMockManager(filtered, unfiltered *TreeDb, logger *zap.Logger) *MockManager {
 	return &MockManager{
-		filtered:      filtered,
-		unfiltered:    unfiltered,
-		logger:        logger,
-		consumedMocks: sync.Map{},
+		filtered:   filtered,
+		unfiltered: unfiltered,
+		logger:     logger,
 	}
 }
 
@@ -75,15 +78,50 @@ func (m *MockManager) GetUnFilteredMocks() ([]*models.Mock, error) {
 	return configMocks, nil
 }
 
+// GetFilteredMocksByKind returns the filtered mocks of a kind, in their sort order. The mocks aren't
+// copied, so they must not be modified.
+func (m *MockManager) GetFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error) {
+	return indexedMocks(m.filtered, integrations.KindKey(kind))
+}
+
+// GetUnFilteredMocksByKind returns the unfiltered mocks of a kind, in their sort order. The mocks aren't
+// copied, so they must not be modified.
+func (m *MockManager) GetUnFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error) {
+	return indexedMocks(m.unfiltered, integrations.KindKey(kind))
+}
+
+// GetFilteredMocksByKey returns the filtered mocks indexed under the key (see integrations.MockKeys),
+// in their sort order. The mocks aren't copied, so they must not be modified.
+func (m *MockManager) GetFilteredMocksByKey(key string) ([]*models.Mock, error) {
+	return indexedMocks(m.filtered, key)
+}
+
+// GetUnFilteredMocksByKey returns the unfiltered mocks indexed under the key (see integrations.MockKeys),
+// in their sort order. The mocks aren't copied, so they must not be modified.
+func (m *MockManager) GetUnFilteredMocksByKey(key string) ([]*models.Mock, error) {
+	return indexedMocks(m.unfiltered, key)
+}
+
+func indexedMocks(db *TreeDb, key string) ([]*models.Mock, error) {
+	values := db.getByIndex(key)
+	mocks := make([]*models.Mock, 0, len(values))
+	for _, v := range values {
+		mock, ok := v.(*models.Mock)
+		if !ok {
+			return nil, fmt.Errorf("expected mock instance, got %v", v)
+		}
+		mocks = append(mocks, mock)
+	}
+	return mocks, nil
+}
+
 func (m *MockManager) UpdateUnFilteredMock(old *models.Mock, new *models.Mock) bool {
 	updated := m.unfiltered.update(old.TestModeInfo, new.TestModeInfo, new)
 	if updated {
 		// mark the unfiltered mock as used for the current simulated test-case
-		go func() {
-			if err := m.FlagMockAsUsed(*old); err != nil {
-				m.logger.Error("failed to flag mock as used", zap.Error(err))
-			}
-		}()
+		if err := m.FlagMockAsUsed(*old); err != nil {
+			m.logger.Error("failed to flag mock as used", zap.Error(err))
+		}
 	}
 	return updated
 }
@@ -92,18 +130,33 @@ func (m *MockManager) FlagMockAsUsed(mock models.Mock) error {
 	if mock.Name == "" {
 		return fmt.Errorf("mock is empty")
 	}
-	m.consumedMocks.Store(mock.Name, true)
+	m.consumedMu.Lock()
+	m.consumedMocks = append(m.consumedMocks, mock.Name)
+	m.consumedMu.Unlock()
+	metrics.MockConsumed(mock.Kind)
 	return nil
 }
 
+// FlagCallAsUnmatched records a dependency call which matched no mock, to be reported by GetConsumedMocks.
+func (m *MockManager) FlagCallAsUnmatched(kind models.Kind, call string) {
+	name := strings.TrimSpace(string(kind) + " " + call)
+	m.consumedMu.Lock()
+	defer m.consumedMu.Unlock()
+	for i := range m.unmatched {
+		if m.unmatched[i].Name == name {
+			m.unmatched[i].Usage++
+			return
+		}
+	}
+	m.unmatched = append(m.unmatched, models.MockState{Name: name, Usage: 1, Unmatched: true, Kind: kind})
+}
+
 func (m *MockManager) DeleteFilteredMock(mock models.Mock) bool {
 	isDeleted := m.filtered.delete(mock.TestModeInfo)
 	if isDeleted {
-		go func() {
-			if err := m.FlagMockAsUsed(mock); err != nil {
-				m.logger.Error("failed to flag mock as used", zap.Error(err))
-			}
-		}()
+		if err := m.FlagMockAsUsed(mock); err != nil {
+			m.logger.Error("failed to flag mock as used", zap.Error(err))
+		}
 	}
 	return isDeleted
 }
@@ -111,31 +164,42 @@ func (m *MockManager) DeleteFilteredMock(mock models.Mock) bool {
 func (m *MockManager) DeleteUnFilteredMock(mock models.Mock) bool {
 	isDeleted := m.unfiltered.delete(mock.TestModeInfo)
 	if isDeleted {
-		go func() {
-			if err := m.FlagMockAsUsed(mock); err != nil {
-				m.logger.Error("failed to flag mock as used", zap.Error(err))
-			}
-		}()
+		if err := m.FlagMockAsUsed(mock); err != nil {
+			m.logger.Error("failed to flag mock as used", zap.Error(err))
+		}
 	}
 	return isDeleted
 }
 
-func (m *MockManager) GetConsumedMocks() []string {
-	var keys []string
-	m.consumedMocks.Range(func(key, _ interface{}) bool {
-		if _, ok := key.(string); ok {
-			keys = append(keys, key.(string))
-			m.consumedMocks.Delete(key)
+// GetConsumedMocks returns the mocks used since its last call, sorted by their number, along with the
+// number of times each one has been used, so that the dependency calls of a test case can be counted.
+// The calls which matched no mock follow them, flagged as unmatched.
+func (m *MockManager) GetConsumedMocks() []models.MockState {
+	m.consumedMu.Lock()
+	names, unmatched := m.consumedMocks, m.unmatched
+	m.consumedMocks, m.unmatched = nil, nil
+	m.consumedMu.Unlock()
+
+	var consumed []models.MockState
+	usage := map[string]int{}
+	for _, name := range names {
+		if _, ok := usage[name]; !ok {
+			consumed = append(consumed, models.MockState{Name: name})
 		}
-		return true
-	})
-	sort.Slice(keys, func(i, j int) bool {
-		numI, _ := strconv.Atoi(strings.Split(keys[i], "-")[1])
-		numJ, _ := strconv.Atoi(strings.Split(keys[j], "-")[1])
-		return numI < numJ
-	})
-	for key := range keys {
-		m.consumedMocks.Delete(key)
+		usage[name]++
+	}
+	for i := range consumed {
+		consumed[i].Usage = usage[consumed[i].Name]
 	}
-	return keys
+	sort.SliceStable(consumed, func(i, j int) bool {
+		return mockNumber(consumed[i].Name) < mockNumber(consumed[j].Name)
+	})
+	return append(consumed, unmatched...)
+}
+
+// mockNumber returns the number of a mock named like "mock-12".
+func mockNumber(name string) int {
+	parts := strings.Split(name, "-")
+	num, _ := strconv.Atoi(parts[len(parts)-1])
+	return num
 }
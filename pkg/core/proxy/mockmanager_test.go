@@ -0,0 +1,195 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+const benchmarkMocks = 40000
+
+// benchmarkMockManager returns a mock manager holding unfiltered postgres, mongo and http mocks, each
+// postgres mock running one of 100 queries.
+func benchmarkMockManager(b *testing.B) *MockManager {
+	b.Helper()
+	mocks := make([]*models.Mock, 0, benchmarkMocks)
+	for i := 0; i < benchmarkMocks; i++ {
+		mock := &models.Mock{Name: fmt.Sprintf("mock-%d", i)}
+		switch i % 3 {
+		case 0:
+			mock.Kind = models.Postgres
+			mock.Spec.PostgresRequests = []models.Backend{{}}
+			mock.Spec.PostgresRequests[0].Query.String = fmt.Sprintf("SELECT * FROM table_%d WHERE id = %d", i%100, i)
+		case 1:
+			mock.Kind = models.Mongo
+			mock.Spec.MongoRequests = []models.MongoRequest{{
+				Message: &models.MongoOpMessage{Sections: []string{fmt.Sprintf(`{ SectionSingle msg: {"find":"collection_%d"} }`, i%100)}},
+			}}
+		default:
+			mock.Kind = models.HTTP
+			mock.Spec.HTTPReq = &models.HTTPReq{Method: models.Method("GET"), URL: fmt.Sprintf("http://localhost/path/%d", i%100)}
+		}
+		mocks = append(mocks, mock)
+	}
+	m := NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), zap.NewNop())
+	m.SetUnFilteredMocks(mocks)
+	return m
+}
+
+func BenchmarkMockLookup(b *testing.B) {
+	m := benchmarkMockManager(b)
+	query := "SELECT * FROM table_42 WHERE id = 1"
+
+	// the lookup of the matchers before the mocks were indexed
+	b.Run("FullScan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mocks, err := m.GetUnFilteredMocks()
+			if err != nil {
+				b.Fatal(err)
+			}
+			var candidates []*models.Mock
+			for _, mock := range mocks {
+				if mock.Kind == models.Postgres {
+					candidates = append(candidates, mock)
+				}
+			}
+			if len(candidates) == 0 {
+				b.Fatal("no postgres mock found")
+			}
+		}
+	})
+
+	b.Run("ByKind", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mocks, err := m.GetUnFilteredMocksByKind(models.Postgres)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(mocks) == 0 {
+				b.Fatal("no postgres mock found")
+			}
+		}
+	})
+
+	b.Run("ByKey", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mocks, err := m.GetUnFilteredMocksByKey(integrations.SQLKey(models.Postgres, query))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(mocks) == 0 {
+				b.Fatal("no postgres mock found")
+			}
+		}
+	})
+}
+
+func mockNames(mocks []*models.Mock) []string {
+	var names []string
+	for _, mock := range mocks {
+		names = append(names, mock.Name)
+	}
+	return names
+}
+
+func TestMockManagerIndex(t *testing.T) {
+	query := func(name, q string) *models.Mock {
+		mock := &models.Mock{Name: name, Kind: models.Postgres, Spec: models.MockSpec{PostgresRequests: []models.Backend{{}}}}
+		mock.Spec.PostgresRequests[0].Query.String = q
+		return mock
+	}
+	users := integrations.SQLKey(models.Postgres, "SELECT * FROM users WHERE id = 7")
+	orders := integrations.SQLKey(models.Postgres, "SELECT * FROM orders")
+
+	m := NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), zap.NewNop())
+	m.SetUnFilteredMocks([]*models.Mock{
+		query("mock-0", "SELECT * FROM users WHERE id = 1"),
+		{Name: "mock-1", Kind: models.HTTP},
+		query("mock-2", "SELECT * FROM orders"),
+		query("mock-3", "SELECT * FROM users WHERE id = 2"),
+	})
+
+	lookup := func(key string) []string {
+		t.Helper()
+		mocks, err := m.GetUnFilteredMocksByKey(key)
+		if err != nil {
+			t.Fatalf("GetUnFilteredMocksByKey() error = %v", err)
+		}
+		return mockNames(mocks)
+	}
+	expect := func(key string, want ...string) {
+		t.Helper()
+		if got := lookup(key); !reflect.DeepEqual(got, want) {
+			t.Errorf("mocks indexed under %q = %v, want %v", key, got, want)
+		}
+	}
+
+	expect(users, "mock-0", "mock-3")
+	expect(orders, "mock-2")
+	expect(integrations.KindKey(models.Postgres), "mock-0", "mock-2", "mock-3")
+	expect(integrations.KindKey(models.HTTP), "mock-1")
+	expect(integrations.KindKey(models.Mongo))
+
+	byKind, err := m.GetUnFilteredMocksByKind(models.Postgres)
+	if err != nil || !reflect.DeepEqual(mockNames(byKind), []string{"mock-0", "mock-2", "mock-3"}) {
+		t.Errorf("GetUnFilteredMocksByKind() = %v, %v, want the postgres mocks", mockNames(byKind), err)
+	}
+
+	// a consumed mock is moved to the end of the sort order with its updated request
+	old := byKind[0]
+	updated := query("mock-0", "SELECT * FROM orders")
+	updated.TestModeInfo = models.TestModeInfo{ID: old.TestModeInfo.ID, SortOrder: 10}
+	if !m.UpdateUnFilteredMock(old, updated) {
+		t.Fatal("UpdateUnFilteredMock() = false, want the mock updated")
+	}
+	expect(users, "mock-3")
+	expect(orders, "mock-2", "mock-0")
+	expect(integrations.KindKey(models.Postgres), "mock-2", "mock-3", "mock-0")
+
+	if !m.DeleteUnFilteredMock(*byKind[1]) {
+		t.Fatal("DeleteUnFilteredMock() = false, want the mock deleted")
+	}
+	expect(orders, "mock-0")
+	if m.DeleteUnFilteredMock(*byKind[1]) {
+		t.Error("DeleteUnFilteredMock() of a deleted mock = true")
+	}
+
+	m.SetUnFilteredMocks(nil)
+	expect(orders)
+	expect(integrations.KindKey(models.Postgres))
+}
+
+func TestGetConsumedMocks(t *testing.T) {
+	m := NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), zap.NewNop())
+	for _, name := range []string{"mock-10", "mock-2", "mock-10", "mock-1"} {
+		if err := m.FlagMockAsUsed(models.Mock{Name: name, Kind: models.HTTP}); err != nil {
+			t.Fatalf("FlagMockAsUsed() error = %v", err)
+		}
+	}
+	if err := m.FlagMockAsUsed(models.Mock{}); err == nil {
+		t.Error("FlagMockAsUsed() of an unnamed mock error = nil, want an error")
+	}
+	m.FlagCallAsUnmatched(models.REDIS, "GET")
+	m.FlagCallAsUnmatched(models.HTTP, "DELETE /users/1")
+	m.FlagCallAsUnmatched(models.REDIS, "GET")
+
+	want := []models.MockState{
+		{Name: "mock-1", Usage: 1},
+		{Name: "mock-2", Usage: 1},
+		{Name: "mock-10", Usage: 2},
+		{Name: "Redis GET", Usage: 2, Unmatched: true, Kind: models.REDIS},
+		{Name: "Http DELETE /users/1", Usage: 1, Unmatched: true, Kind: models.HTTP},
+	}
+	if got := m.GetConsumedMocks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetConsumedMocks() = %+v, want %+v", got, want)
+	}
+	if got := m.GetConsumedMocks(); len(got) != 0 {
+		t.Errorf("GetConsumedMocks() after a call = %+v, want none", got)
+	}
+}
@@ -0,0 +1,222 @@
+//go:build linux
+
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// clientSilenceTimeout is how long the client may stay silent before the connection is assumed
+	// to be of a protocol in which the server speaks first.
+	clientSilenceTimeout = 500 * time.Millisecond
+	// serverGreetingTimeout is how long the server has to send its greeting once the client is silent.
+	serverGreetingTimeout = 2 * time.Second
+)
+
+// detectProtocol is the protocol of the rules of the protocol map for the destinations whose
+// protocol has to be detected from the greeting of the server.
+const detectProtocol = "detect"
+
+// hostCacheTTL is how long the addresses of the hosts of the protocol map are cached.
+const hostCacheTTL = time.Minute
+
+// wellKnownPorts are the ports for which the protocol is used when the protocol map of the
+// config has no rule for the destination.
+var wellKnownPorts = map[uint32]string{
+	3306: string(integrations.MYSQL),
+}
+
+// serverFirstPorts are the ports on which the server first protocols are commonly served besides
+// their well known ports (MariaDB, TiDB and ProxySQL), and for which the protocol is detected
+// when the protocol map of the config has no rule for the destination.
+var serverFirstPorts = map[uint32]bool{
+	3307: true,
+	4000: true,
+	6033: true,
+}
+
+// resolvedHost holds the addresses a host of the protocol map resolved to.
+type resolvedHost struct {
+	addrs   []string
+	expires time.Time
+}
+
+// configuredProtocol returns the protocol set for the destination by the protocol map of the config,
+// or by the well known ports. It returns an empty protocol if the connection has to be handled as
+// usual, along with whether the client has to be checked for waiting on the server to speak first.
+func (p *Proxy) configuredProtocol(ctx context.Context, rules []config.ProtocolRule, dstIP string, dstPort uint32) (string, bool) {
+	for _, rule := range rules {
+		if rule.Port != 0 && rule.Port != uint(dstPort) {
+			continue
+		}
+		if !p.hostMatches(ctx, rule.Host, dstIP) {
+			continue
+		}
+		if rule.Protocol == detectProtocol {
+			return "", true
+		}
+		if _, ok := p.Integrations[rule.Protocol]; !ok {
+			p.logger.Warn("ignoring the protocol rule for an unknown protocol", zap.Any("rule", rule))
+			continue
+		}
+		return rule.Protocol, false
+	}
+	return wellKnownPorts[dstPort], serverFirstPorts[dstPort]
+}
+
+// hostMatches checks whether the host of a rule, which is either an IP address or a hostname,
+// refers to the destination IP. An empty host matches every destination.
+func (p *Proxy) hostMatches(ctx context.Context, host, dstIP string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(dstIP)
+	if hostIP := net.ParseIP(host); hostIP != nil {
+		return hostIP.Equal(ip)
+	}
+	for _, addr := range p.lookupHost(ctx, host) {
+		if net.ParseIP(addr).Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupHost returns the addresses of a host of the protocol map, which are cached so that the
+// host isn't resolved for every connection.
+func (p *Proxy) lookupHost(ctx context.Context, host string) []string {
+	if cached, ok := p.hostCache.Load(host); ok && time.Now().Before(cached.(resolvedHost).expires) {
+		return cached.(resolvedHost).addrs
+	}
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		p.logger.Debug("failed to resolve the host of the protocol rule", zap.String("host", host), zap.Error(err))
+	}
+	p.hostCache.Store(host, resolvedHost{addrs: addrs, expires: time.Now().Add(hostCacheTTL)})
+	return addrs
+}
+
+// detectServerFirst detects the connections in which the client waits for the server to speak first.
+// While recording, the destination is dialed and its greeting is classified by the server first
+// integrations. While replaying, the integration is chosen by the kind of the recorded mocks.
+//
+// It returns the detected protocol along with the client connection, which keeps the bytes peeked
+// from it, and the destination connection (if dialed), which keeps the greeting for the parser.
+// An empty protocol means the connection has to be handled as usual, over the same destination
+// connection if it was dialed.
+func (p *Proxy) detectServerFirst(ctx context.Context, srcConn net.Conn, dstAddr string, mode models.Mode, mockDb *MockManager) (string, net.Conn, net.Conn, error) {
+	reader := bufio.NewReader(srcConn)
+	src := &util.Conn{
+		Conn:   srcConn,
+		Reader: reader,
+		Logger: p.logger,
+	}
+
+	err := srcConn.SetReadDeadline(time.Now().Add(clientSilenceTimeout))
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to set the read deadline for the client connection")
+		return "", src, nil, err
+	}
+	_, peekErr := reader.Peek(1)
+	err = srcConn.SetReadDeadline(time.Time{})
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to reset the read deadline for the client connection")
+		return "", src, nil, err
+	}
+
+	var netErr net.Error
+	if peekErr == nil || !errors.As(peekErr, &netErr) || !netErr.Timeout() {
+		// the client speaks first (or has closed the connection, which is handled later on)
+		return "", src, nil, nil
+	}
+
+	p.logger.Debug("the client is waiting for the server to speak first", zap.Any("destination", dstAddr))
+
+	if mode == models.MODE_TEST {
+		return p.serverFirstFromMocks(mockDb), src, nil, nil
+	}
+
+	dstConn, err := net.Dial("tcp", dstAddr)
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to dial the conn to destination server", zap.Any("server address", dstAddr))
+		return "", src, nil, err
+	}
+
+	greeting := make([]byte, 4096)
+	err = dstConn.SetReadDeadline(time.Now().Add(serverGreetingTimeout))
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to set the read deadline for the destination connection")
+		return "", src, dstConn, err
+	}
+	n, readErr := dstConn.Read(greeting)
+	err = dstConn.SetReadDeadline(time.Time{})
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to reset the read deadline for the destination connection")
+		return "", src, dstConn, err
+	}
+	greeting = greeting[:n]
+
+	if readErr == nil || readErr == io.EOF {
+		for name, parser := range p.Integrations {
+			sf, ok := parser.(integrations.ServerFirst)
+			if ok && sf.MatchGreeting(ctx, greeting) {
+				p.logger.Debug("detected the protocol from the greeting of the server", zap.String("protocol", name))
+				dst := &util.Conn{
+					Conn:   dstConn,
+					Reader: io.MultiReader(bytes.NewReader(greeting), dstConn),
+					Logger: p.logger,
+				}
+				return name, src, dst, nil
+			}
+		}
+	}
+
+	// The greeting isn't of a supported protocol (or the server is silent as well), so the connection
+	// is handled as usual, the greeting being kept for the parser.
+	p.logger.Debug("could not detect the protocol from the greeting of the server", zap.Any("destination", dstAddr), zap.Error(readErr))
+	dst := &util.Conn{
+		Conn:   dstConn,
+		Reader: io.MultiReader(bytes.NewReader(greeting), dstConn),
+		Logger: p.logger,
+	}
+	return "", src, dst, nil
+}
+
+// serverFirstFromMocks returns the server first integration for which mocks were recorded.
+func (p *Proxy) serverFirstFromMocks(mockDb *MockManager) string {
+	if mockDb == nil {
+		return ""
+	}
+	for name, parser := range p.Integrations {
+		sf, ok := parser.(integrations.ServerFirst)
+		if !ok {
+			continue
+		}
+		filtered, err := mockDb.GetFilteredMocksByKind(sf.MockKind())
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to get the filtered mocks")
+		}
+		unfiltered, err := mockDb.GetUnFilteredMocksByKind(sf.MockKind())
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to get the unfiltered mocks")
+		}
+		if len(filtered) > 0 || len(unfiltered) > 0 {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+//go:build linux
+
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// serverFirst is an integration of a protocol in which the server greets with "HELLO".
+type serverFirst struct {
+	integrations.Integrations
+}
+
+func (serverFirst) MatchGreeting(_ context.Context, greeting []byte) bool {
+	return string(greeting) == "HELLO"
+}
+
+func (serverFirst) MockKind() models.Kind {
+	return models.MySQL
+}
+
+// clientFirst is an integration of a protocol in which the client speaks first.
+type clientFirst struct {
+	integrations.Integrations
+}
+
+func testProxy() *Proxy {
+	return &Proxy{
+		logger:       zap.NewNop(),
+		Integrations: map[string]integrations.Integrations{"mysql": serverFirst{}, "http": clientFirst{}},
+	}
+}
+
+func TestConfiguredProtocol(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      []config.ProtocolRule
+		dstIP      string
+		dstPort    uint32
+		want       string
+		wantDetect bool
+	}{
+		{name: "well known port", dstIP: "10.0.0.1", dstPort: 3306, want: "mysql"},
+		{name: "server first port", dstIP: "10.0.0.1", dstPort: 4000, wantDetect: true},
+		{name: "other port", dstIP: "10.0.0.1", dstPort: 8080},
+		{name: "rule for the port", rules: []config.ProtocolRule{{Port: 9000, Protocol: "mysql"}}, dstIP: "10.0.0.1", dstPort: 9000, want: "mysql"},
+		{name: "rule for another port", rules: []config.ProtocolRule{{Port: 9000, Protocol: "mysql"}}, dstIP: "10.0.0.1", dstPort: 9001},
+		{name: "rule for the ip", rules: []config.ProtocolRule{{Host: "10.0.0.1", Protocol: "mysql"}}, dstIP: "10.0.0.1", dstPort: 9000, want: "mysql"},
+		{name: "rule for another ip", rules: []config.ProtocolRule{{Host: "10.0.0.2", Protocol: "mysql"}}, dstIP: "10.0.0.1", dstPort: 9000},
+		{name: "rule for the host", rules: []config.ProtocolRule{{Host: "localhost", Port: 9000, Protocol: "mysql"}}, dstIP: "127.0.0.1", dstPort: 9000, want: "mysql"},
+		{name: "detect rule", rules: []config.ProtocolRule{{Port: 9000, Protocol: "detect"}}, dstIP: "10.0.0.1", dstPort: 9000, wantDetect: true},
+		{name: "rule overriding a well known port", rules: []config.ProtocolRule{{Port: 3306, Protocol: "http"}}, dstIP: "10.0.0.1", dstPort: 3306, want: "http"},
+		{name: "first matching rule", rules: []config.ProtocolRule{{Port: 9000, Protocol: "http"}, {Port: 9000, Protocol: "mysql"}}, dstIP: "10.0.0.1", dstPort: 9000, want: "http"},
+		{name: "unknown protocol skipped", rules: []config.ProtocolRule{{Port: 9000, Protocol: "oracle"}, {Port: 9000, Protocol: "mysql"}}, dstIP: "10.0.0.1", dstPort: 9000, want: "mysql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, detect := testProxy().configuredProtocol(context.Background(), tt.rules, tt.dstIP, tt.dstPort)
+			if got != tt.want || detect != tt.wantDetect {
+				t.Errorf("configuredProtocol() = %q, %v, want %q, %v", got, detect, tt.want, tt.wantDetect)
+			}
+		})
+	}
+}
+
+func TestDetectServerFirst(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientSpeaks bool
+		greeting     string
+		want         string
+	}{
+		{name: "client speaks first", clientSpeaks: true},
+		{name: "known greeting", greeting: "HELLO", want: "mysql"},
+		{name: "unknown greeting", greeting: "220 smtp ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			defer ln.Close()
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_, _ = conn.Write([]byte(tt.greeting))
+				time.Sleep(time.Second)
+			}()
+
+			srcConn, client := net.Pipe()
+			defer srcConn.Close()
+			defer client.Close()
+			if tt.clientSpeaks {
+				go func() { _, _ = client.Write([]byte("GET / HTTP/1.1\r\n")) }()
+			}
+
+			got, src, dst, err := testProxy().detectServerFirst(context.Background(), srcConn, ln.Addr().String(), models.MODE_RECORD, nil)
+			if err != nil {
+				t.Fatalf("detectServerFirst() error = %v", err)
+			}
+			if dst != nil {
+				defer dst.Close()
+			}
+			if got != tt.want {
+				t.Errorf("detectServerFirst() protocol = %q, want %q", got, tt.want)
+			}
+
+			if tt.clientSpeaks {
+				if dst != nil {
+					t.Error("detectServerFirst() dialed the destination of a client speaking first")
+				}
+				buf := make([]byte, 3)
+				if _, err := io.ReadFull(src, buf); err != nil || string(buf) != "GET" {
+					t.Errorf("client connection read %q, %v, want the peeked request", buf, err)
+				}
+				return
+			}
+			if dst == nil {
+				t.Fatal("detectServerFirst() returned no destination connection")
+			}
+			buf := make([]byte, len(tt.greeting))
+			if _, err := io.ReadFull(dst, buf); err != nil || string(buf) != tt.greeting {
+				t.Errorf("destination connection read %q, %v, want the greeting %q", buf, err, tt.greeting)
+			}
+		})
+	}
+}
+
+func TestServerFirstFromMocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		mocks []*models.Mock
+		want  string
+	}{
+		{name: "mysql mocks", mocks: []*models.Mock{{Name: "mock-0", Kind: models.MySQL}}, want: "mysql"},
+		{name: "other mocks", mocks: []*models.Mock{{Name: "mock-0", Kind: models.HTTP}}},
+		{name: "no mocks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), zap.NewNop())
+			m.SetUnFilteredMocks(tt.mocks)
+			if got := testProxy().serverFirstFromMocks(m); got != tt.want {
+				t.Errorf("serverFirstFromMocks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := testProxy().serverFirstFromMocks(nil); got != "" {
+		t.Errorf("serverFirstFromMocks(nil) = %q, want none", got)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	pTls "go.keploy.io/server/v2/pkg/core/proxy/tls"
 	"go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/metrics"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 )
@@ -46,6 +47,13 @@ type Proxy struct {
 
 	sessions *core.Sessions
 
+	// hostCache holds the addresses of the hosts of the protocol map, as resolvedHost
+	hostCache sync.Map
+
+	// dnsCache holds the answers resolved for the recording session, keyed by question
+	dnsMutex *sync.RWMutex
+	dnsCache map[string]dnsAnswer
+
 	connMutex *sync.Mutex
 	ipMutex   *sync.Mutex
 
@@ -68,6 +76,8 @@ func New(logger *zap.Logger, info core.DestInfo, opts *config.Config) *Proxy {
 		IP6:          "::1",          //default: "::1" <-> ([4]uint32{0000, 0000, 0000, 0001})
 		ipMutex:      &sync.Mutex{},
 		connMutex:    &sync.Mutex{},
+		dnsMutex:     &sync.RWMutex{},
+		dnsCache:     make(map[string]dnsAnswer),
 		DestInfo:     info,
 		sessions:     core.NewSessions(),
 		MockManagers: sync.Map{},
@@ -274,13 +284,18 @@ func (p *Proxy) start(ctx context.Context, readyChan chan<- error) error {
 }
 
 // handleConnection function executes the actual outgoing network call and captures/forwards the request and response messages.
-func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
+func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) (err error) {
 	//checking how much time proxy takes to execute the flow.
 	start := time.Now()
 
+	// integration handling the connection, for the metrics
+	var integration string
 	defer func(start time.Time) {
 		duration := time.Since(start)
 		p.logger.Debug("time taken by proxy to execute the flow", zap.Any("Duration(ms)", duration.Milliseconds()))
+		if err != nil {
+			metrics.ConnectionError(integration)
+		}
 	}(start)
 
 	// making a new client connection id for each client connection
@@ -316,13 +331,15 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return err
 	}
 
-	var dstAddr string
+	var dstAddr, dstIP string
 
 	if destInfo.Version == 4 {
-		dstAddr = fmt.Sprintf("%v:%v", util.ToIP4AddressStr(destInfo.IPv4Addr), destInfo.Port)
+		dstIP = util.ToIP4AddressStr(destInfo.IPv4Addr)
+		dstAddr = fmt.Sprintf("%v:%v", dstIP, destInfo.Port)
 		p.logger.Debug("", zap.Any("DestIp4", destInfo.IPv4Addr), zap.Any("DestPort", destInfo.Port))
 	} else if destInfo.Version == 6 {
-		dstAddr = fmt.Sprintf("[%v]:%v", util.ToIPv6AddressStr(destInfo.IPv6Addr), destInfo.Port)
+		dstIP = util.ToIPv6AddressStr(destInfo.IPv6Addr)
+		dstAddr = fmt.Sprintf("[%v]:%v", dstIP, destInfo.Port)
 		p.logger.Debug("", zap.Any("DestIp6", destInfo.IPv6Addr), zap.Any("DestPort", destInfo.Port))
 	}
 
@@ -362,6 +379,8 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 	//check for global passthrough in test mode
 	if !rule.OutgoingOptions.Mocking && rule.Mode == models.MODE_TEST {
 
+		integration = "passthrough"
+		metrics.Connection(integration, rule.Mode)
 		dstConn, err = net.Dial("tcp", dstAddr)
 		if err != nil {
 			utils.LogError(p.logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
@@ -376,13 +395,34 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return nil
 	}
 
-	//checking for the destination port of "mysql"
-	if destInfo.Port == 3306 {
+	// the protocol is either set for the destination in the config, or detected from the greeting
+	// of the server for the destinations which may serve a server first protocol, in case the client
+	// waits for the server to speak first
+	protocol, detect := p.configuredProtocol(parserCtx, rule.OutgoingOptions.ProtocolMap, dstIP, destInfo.Port)
+	if detect {
+		var mockDb *MockManager
+		if m, ok := p.MockManagers.Load(destInfo.AppID); ok {
+			mockDb = m.(*MockManager)
+		}
+		protocol, srcConn, dstConn, err = p.detectServerFirst(parserCtx, srcConn, dstAddr, rule.Mode, mockDb)
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to detect the protocol of the connection")
+			return err
+		}
+	}
+
+	//checking for the protocols in which the server speaks first (e.g. "mysql")
+	if _, ok := p.Integrations[protocol].(integrations.ServerFirst); ok {
+		parser := p.Integrations[protocol]
+		integration = protocol
+		metrics.Connection(integration, rule.Mode)
 		if rule.Mode != models.MODE_TEST {
-			dstConn, err = net.Dial("tcp", dstAddr)
-			if err != nil {
-				utils.LogError(p.logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
-				return err
+			if dstConn == nil {
+				dstConn, err = net.Dial("tcp", dstAddr)
+				if err != nil {
+					utils.LogError(p.logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
+					return err
+				}
 			}
 
 			dstCfg := &models.ConditionalDstCfg{
@@ -391,7 +431,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 			rule.OutgoingOptions.DstCfg = dstCfg
 
 			// Record the outgoing message into a mock
-			err := p.Integrations["mysql"].RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
+			err := parser.RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
 			if err != nil {
 				utils.LogError(p.logger, err, "failed to record the outgoing message")
 				return err
@@ -406,7 +446,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		}
 
 		//mock the outgoing message
-		err := p.Integrations["mysql"].MockOutgoing(parserCtx, srcConn, &models.ConditionalDstCfg{Addr: dstAddr}, m.(*MockManager), rule.OutgoingOptions)
+		err := parser.MockOutgoing(parserCtx, srcConn, &models.ConditionalDstCfg{Addr: dstAddr}, m.(*MockManager), rule.OutgoingOptions)
 		if err != nil {
 			utils.LogError(p.logger, err, "failed to mock the outgoing message")
 			return err
@@ -483,6 +523,13 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 
 		addr := fmt.Sprintf("%v:%v", pTls.DstURL, destInfo.Port)
 		if rule.Mode != models.MODE_TEST {
+			// the connection dialed while detecting the protocol is replaced by a tls one
+			if dstConn != nil {
+				err = dstConn.Close()
+				if err != nil {
+					logger.Debug("failed to close the destination connection", zap.Error(err))
+				}
+			}
 			dstConn, err = tls.Dial("tcp", addr, cfg)
 			if err != nil {
 				utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
@@ -494,7 +541,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		dstCfg.Addr = addr
 
 	} else {
-		if rule.Mode != models.MODE_TEST {
+		if rule.Mode != models.MODE_TEST && dstConn == nil {
 			dstConn, err = net.Dial("tcp", dstAddr)
 			if err != nil {
 				utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
@@ -513,9 +560,11 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 
 	generic := true
 
-	//Checking for all the parsers.
-	for _, parser := range p.Integrations {
-		if parser.MatchType(parserCtx, initialBuf) {
+	//Checking for all the parsers, unless the protocol is set for the destination.
+	for name, parser := range p.Integrations {
+		if name == protocol || (protocol == "" && parser.MatchType(parserCtx, initialBuf)) {
+			integration = name
+			metrics.Connection(integration, rule.Mode)
 			if rule.Mode == models.MODE_RECORD {
 				err := parser.RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
 				if err != nil {
@@ -535,6 +584,8 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 
 	if generic {
 		logger.Debug("The external dependency is not supported. Hence using generic parser")
+		integration = "generic"
+		metrics.Connection(integration, rule.Mode)
 		if rule.Mode == models.MODE_RECORD {
 			err := p.Integrations["generic"].RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
 			if err != nil {
@@ -593,6 +644,9 @@ func (p *Proxy) Record(_ context.Context, id uint64, mocks chan<- *models.Mock,
 
 	p.MockManagers.Store(id, NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), p.logger))
 
+	// the answers cached by a previous session were recorded in its mocks, so they are resolved again
+	p.resetDNSCache()
+
 	////set the new proxy ip:port for a new session
 	//err := p.setProxyIP(opts.DnsIPv4Addr, opts.DnsIPv6Addr)
 	//if err != nil {
@@ -647,7 +701,7 @@ func (p *Proxy) SetMocks(_ context.Context, id uint64, filtered []*models.Mock,
 }
 
 // GetConsumedMocks returns the consumed filtered mocks for a given app id
-func (p *Proxy) GetConsumedMocks(_ context.Context, id uint64) ([]string, error) {
+func (p *Proxy) GetConsumedMocks(_ context.Context, id uint64) ([]models.MockState, error) {
 	m, ok := p.MockManagers.Load(id)
 	if !ok {
 		return nil, fmt.Errorf("mock manager not found to get consumed filtered mocks")
@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/emirpasic/gods/trees/redblacktree"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/models"
 )
 
@@ -30,43 +31,42 @@ var customComparator = func(a, b interface{}) int {
 }
 
 type TreeDb struct {
-	rbt   *redblacktree.Tree
-	mutex *sync.Mutex
+	rbt *redblacktree.Tree
+	// indexes holds a tree per index key of the mocks (see integrations.MockKeys), ordered like rbt.
+	indexes map[string]*redblacktree.Tree
+	// indexKeys holds the index keys of every object, so that they aren't computed again on removal.
+	indexKeys map[interface{}][]string
+	mutex     *sync.Mutex
 }
 
 func NewTreeDb(comparator func(a, b interface{}) int) *TreeDb {
 	return &TreeDb{
-		rbt:   redblacktree.NewWith(comparator),
-		mutex: &sync.Mutex{},
+		rbt:       redblacktree.NewWith(comparator),
+		indexes:   make(map[string]*redblacktree.Tree),
+		indexKeys: make(map[interface{}][]string),
+		mutex:     &sync.Mutex{},
 	}
 }
 
 func (db *TreeDb) insert(key interface{}, obj interface{}) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	db.rbt.Put(key, obj)
+	db.put(key, obj)
 }
 
 func (db *TreeDb) delete(key interface{}) bool {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	_, found := db.rbt.Get(key)
-	if !found {
-		return false
-	}
-	db.rbt.Remove(key)
-	return true
+	return db.remove(key)
 }
 
 func (db *TreeDb) update(oldKey interface{}, newKey interface{}, newObj interface{}) bool {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	_, found := db.rbt.Get(oldKey)
-	if !found {
+	if !db.remove(oldKey) {
 		return false
 	}
-	db.rbt.Remove(oldKey)
-	db.rbt.Put(newKey, newObj)
+	db.put(newKey, newObj)
 	return true
 }
 
@@ -74,6 +74,8 @@ func (db *TreeDb) deleteAll() {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 	db.rbt.Clear()
+	db.indexes = make(map[string]*redblacktree.Tree)
+	db.indexKeys = make(map[interface{}][]string)
 }
 
 func (db *TreeDb) getAll() []interface{} {
@@ -81,3 +83,56 @@ func (db *TreeDb) getAll() []interface{} {
 	defer db.mutex.Unlock()
 	return db.rbt.Values()
 }
+
+// getByIndex returns the objects indexed under the key, in the order of the tree.
+func (db *TreeDb) getByIndex(indexKey string) []interface{} {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	index, ok := db.indexes[indexKey]
+	if !ok {
+		return nil
+	}
+	return index.Values()
+}
+
+func (db *TreeDb) put(key interface{}, obj interface{}) {
+	db.unindex(key)
+	db.rbt.Put(key, obj)
+	mock, ok := obj.(*models.Mock)
+	if !ok {
+		return
+	}
+	indexKeys := integrations.MockKeys(mock)
+	for _, indexKey := range indexKeys {
+		index, ok := db.indexes[indexKey]
+		if !ok {
+			index = redblacktree.NewWith(db.rbt.Comparator)
+			db.indexes[indexKey] = index
+		}
+		index.Put(key, obj)
+	}
+	db.indexKeys[key] = indexKeys
+}
+
+func (db *TreeDb) remove(key interface{}) bool {
+	if _, found := db.rbt.Get(key); !found {
+		return false
+	}
+	db.rbt.Remove(key)
+	db.unindex(key)
+	return true
+}
+
+func (db *TreeDb) unindex(key interface{}) {
+	for _, indexKey := range db.indexKeys[key] {
+		index, ok := db.indexes[indexKey]
+		if !ok {
+			continue
+		}
+		index.Remove(key)
+		if index.Empty() {
+			delete(db.indexes, indexKey)
+		}
+	}
+	delete(db.indexKeys, key)
+}
@@ -44,7 +44,7 @@ type Proxy interface {
 	Record(ctx context.Context, id uint64, mocks chan<- *models.Mock, opts models.OutgoingOptions) error
 	Mock(ctx context.Context, id uint64, opts models.OutgoingOptions) error
 	SetMocks(ctx context.Context, id uint64, filtered []*models.Mock, unFiltered []*models.Mock) error
-	GetConsumedMocks(ctx context.Context, id uint64) ([]string, error)
+	GetConsumedMocks(ctx context.Context, id uint64) ([]models.MockState, error)
 }
 
 type ProxyOptions struct {
@@ -112,7 +112,7 @@ func (s *Sessions) Delete(id uint64) {
 	s.sessions.Delete(id)
 }
 
-func (s *Sessions) getAll() map[uint64]*Session {
+func (s *Sessions) GetAll() map[uint64]*Session {
 	sessions := map[uint64]*Session{}
 	s.sessions.Range(func(k, v interface{}) bool {
 		sessions[k.(uint64)] = v.(*Session)
@@ -122,7 +122,7 @@ func (s *Sessions) getAll() map[uint64]*Session {
 }
 
 func (s *Sessions) GetAllMC() []chan<- *models.Mock {
-	sessions := s.getAll()
+	sessions := s.GetAll()
 	var mc []chan<- *models.Mock
 	for _, session := range sessions {
 		mc = append(mc, session.MC)
@@ -0,0 +1,488 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/protocolbuffers/protoscope"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtoRegistry resolves the request and response message types of the gRPC methods from the
+// protobuf definitions provided by the user. A nil registry is valid and resolves nothing, in
+// which case the messages are stored as protoscope text.
+type ProtoRegistry struct {
+	// methods is keyed by the :path pseudo header of the call, i.e. /package.Service/Method
+	methods map[string]protoreflect.MethodDescriptor
+}
+
+// LoadProtoRegistry compiles the descriptor set or the .proto files referred to by the config.
+// It returns a nil registry if no protobuf definitions are configured.
+func LoadProtoRegistry(ctx context.Context, cfg config.Proto) (*ProtoRegistry, error) {
+	var files []protoreflect.FileDescriptor
+
+	if cfg.DescriptorSet != "" {
+		data, err := os.ReadFile(cfg.DescriptorSet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the descriptor set: %v", err)
+		}
+		set := &descriptorpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(data, set); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the descriptor set: %v", err)
+		}
+		registry, err := protodesc.NewFiles(set)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build the descriptors from the descriptor set: %v", err)
+		}
+		registry.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			files = append(files, fd)
+			return true
+		})
+	}
+
+	if cfg.Dir != "" {
+		var protoFiles []string
+		err := filepath.WalkDir(cfg.Dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != ".proto" {
+				return nil
+			}
+			rel, err := filepath.Rel(cfg.Dir, path)
+			if err != nil {
+				return err
+			}
+			protoFiles = append(protoFiles, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk the proto directory: %v", err)
+		}
+
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+				ImportPaths: append([]string{cfg.Dir}, cfg.ImportPaths...),
+			}),
+		}
+		compiled, err := compiler.Compile(ctx, protoFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile the proto files: %v", err)
+		}
+		for _, fd := range compiled {
+			files = append(files, fd)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	r := &ProtoRegistry{
+		methods: make(map[string]protoreflect.MethodDescriptor),
+	}
+	for _, fd := range files {
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				md := methods.Get(j)
+				r.methods[fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name())] = md
+			}
+		}
+	}
+	return r, nil
+}
+
+// InputType returns the request message descriptor of the method, or nil if it is unknown.
+func (r *ProtoRegistry) InputType(path string) protoreflect.MessageDescriptor {
+	if r == nil || r.methods[path] == nil {
+		return nil
+	}
+	return r.methods[path].Input()
+}
+
+// OutputType returns the response message descriptor of the method, or nil if it is unknown.
+func (r *ProtoRegistry) OutputType(path string) protoreflect.MessageDescriptor {
+	if r == nil || r.methods[path] == nil {
+		return nil
+	}
+	return r.methods[path].Output()
+}
+
+// ProtoRegistryCache holds the protobuf definitions configured by the user, since compiling them
+// for every connection is expensive. They are only reloaded when the config changes.
+type ProtoRegistryCache struct {
+	mutex    sync.Mutex
+	cfg      string
+	registry *ProtoRegistry
+}
+
+// Get returns the protobuf definitions of the config, loading them on first use. A failure to
+// load them is logged and the messages are then stored as protoscope text.
+func (c *ProtoRegistryCache) Get(ctx context.Context, logger *zap.Logger, cfg config.Proto) *ProtoRegistry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := fmt.Sprint(cfg.DescriptorSet, cfg.Dir, cfg.ImportPaths)
+	if key == c.cfg {
+		return c.registry
+	}
+
+	registry, err := LoadProtoRegistry(ctx, cfg)
+	if err != nil {
+		utils.LogError(logger, err, "failed to load the protobuf definitions, grpc messages will not be decoded as json")
+	}
+	c.cfg = key
+	c.registry = registry
+	return registry
+}
+
+// protoToJSON decodes the wire format message into its protobuf JSON representation.
+func protoToJSON(md protoreflect.MessageDescriptor, data []byte) (string, error) {
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return "", err
+	}
+	out, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	// protojson deliberately randomises the whitespace in its output, compact it to keep the mocks stable.
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, out); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// jsonToProto encodes the protobuf JSON representation of the message into the wire format.
+func jsonToProto(md protoreflect.MessageDescriptor, data string) ([]byte, error) {
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal([]byte(data), msg); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+// SplitLengthPrefixedMessages splits the buffer into complete length-prefixed messages and
+// returns the remaining bytes which do not form a complete message yet.
+func SplitLengthPrefixedMessages(buf []byte) ([][]byte, []byte) {
+	var msgs [][]byte
+	for len(buf) >= 5 {
+		end := 5 + int(binary.BigEndian.Uint32(buf[1:5]))
+		if len(buf) < end {
+			break
+		}
+		msgs = append(msgs, buf[:end:end])
+		buf = buf[end:]
+	}
+	// Keep the remaining bytes in a fresh slice, so that the next append does not overwrite the messages.
+	return msgs, append([]byte(nil), buf...)
+}
+
+// DecodeLengthPrefixedMessage decodes a length-prefixed message into its protobuf JSON format if the
+// descriptor of the message is known, and into protoscope text otherwise.
+func DecodeLengthPrefixedMessage(data []byte, md protoreflect.MessageDescriptor) models.GrpcLengthPrefixedMessage {
+	msg := models.GrpcLengthPrefixedMessage{}
+
+	// If the body is not length prefixed, we return the default value.
+	if len(data) < 5 {
+		return msg
+	}
+
+	// The first byte is the compression flag.
+	msg.CompressionFlag = uint(data[0])
+
+	// The next 4 bytes are message length.
+	msg.MessageLength = binary.BigEndian.Uint32(data[1:5])
+
+	// The payload could be empty. We only parse it if it is present.
+	if len(data) >= 5 {
+		// Compressed messages can't be decoded without the grpc-encoding of the stream.
+		if md != nil && msg.CompressionFlag == 0 {
+			if decoded, err := protoToJSON(md, data[5:]); err == nil {
+				msg.DecodedJSON = decoded
+				return msg
+			}
+		}
+
+		// Use protoscope to decode the message.
+		msg.DecodedData = protoscope.Write(data[5:], protoscope.WriterOptions{})
+	}
+
+	return msg
+}
+
+// EncodeLengthPrefixedMessage encodes the message back into the wire format. Messages stored
+// as JSON need the descriptor of the message, the others are encoded from their protoscope text.
+func EncodeLengthPrefixedMessage(msg models.GrpcLengthPrefixedMessage, md protoreflect.MessageDescriptor) ([]byte, error) {
+	var (
+		encodedData []byte
+		err         error
+	)
+	if msg.DecodedJSON != "" {
+		if md == nil {
+			return nil, fmt.Errorf("could not encode grpc msg stored as json: message descriptor not found")
+		}
+		encodedData, err = jsonToProto(md, msg.DecodedJSON)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode grpc msg using the message descriptor: %v", err)
+		}
+	} else {
+		scanner := protoscope.NewScanner(msg.DecodedData)
+		encodedData, err = scanner.Exec()
+		if err != nil {
+			return nil, fmt.Errorf("could not encode grpc msg using protoscope: %v", err)
+		}
+	}
+
+	// Note that the encoded length is present in the msg, but it is also equal to the len of encodedData.
+	// We should give the preference to the length of encodedData, since the mocks might have been altered.
+
+	// Reserve 1 byte for compression flag, 4 bytes for length capture.
+	payload := make([]byte, 1+4)
+	payload[0] = uint8(msg.CompressionFlag)
+	binary.BigEndian.PutUint32(payload[1:5], uint32(len(encodedData)))
+	payload = append(payload, encodedData...)
+
+	return payload, nil
+}
+
+// SimulateGRPC sends the recorded gRPC call of the test case to the application over HTTP/2 and
+// returns the response in the same form as it is recorded.
+func SimulateGRPC(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64, protos *ProtoRegistry) (*models.GrpcResp, error) {
+	if err := renderTemplatizedValues(logger, tc, testSet); err != nil {
+		return nil, err
+	}
+
+	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
+
+	pseudoHeaders := tc.GrpcReq.Headers.PseudoHeaders
+	scheme := pseudoHeaders[":scheme"]
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := pseudoHeaders[":path"]
+
+	reqMsgs := tc.GrpcReq.Messages()
+	payloads := make([][]byte, len(reqMsgs))
+	for i, msg := range reqMsgs {
+		payload, err := EncodeLengthPrefixedMessage(msg.GrpcLengthPrefixedMessage, protos.InputType(path))
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode the grpc request message")
+			return nil, err
+		}
+		payloads[i] = payload
+	}
+
+	// The request messages are written while the response is read, as a bidi stream depends on their order.
+	body, bodyWriter := io.Pipe()
+	answered := newMessageCounter()
+	defer answered.finish()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s://%s%s", scheme, pseudoHeaders[":authority"], path), body)
+	if err != nil {
+		utils.LogError(logger, err, "failed to create a grpc request from the yaml document")
+		return nil, err
+	}
+	for key, value := range tc.GrpcReq.Headers.OrdinaryHeaders {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("keploy-test-id", tc.Name)
+	req.Header.Set("keploy-test-set-id", testSet)
+	logger.Debug(fmt.Sprintf("Sending grpc request to user app:%v", req))
+
+	// gRPC is served over HTTP/2 without the upgrade dance (h2c) unless the call was made over TLS.
+	transport := &http2.Transport{}
+	if scheme == "http" {
+		transport.AllowHTTP = true
+		transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Timeout:   time.Second * time.Duration(apiTimeout),
+		Transport: transport,
+	}
+
+	go func() {
+		bodyWriter.CloseWithError(sendGrpcMessages(ctx, bodyWriter, tc, payloads, time.Now(), answered))
+	}()
+	httpResp, err := client.Do(req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to send testcase grpc request to app")
+		return nil, err
+	}
+	defer func() {
+		if err := httpResp.Body.Close(); err != nil {
+			utils.LogError(logger, err, "failed to close the grpc response body")
+		}
+	}()
+
+	// The trailers are only available once the body has been read completely.
+	var (
+		msgs    [][]byte
+		pending []byte
+		buf     = make([]byte, 32*1024)
+	)
+	for {
+		n, err := httpResp.Body.Read(buf)
+		if n > 0 {
+			var read [][]byte
+			read, pending = SplitLengthPrefixedMessages(append(pending, buf[:n]...))
+			msgs = append(msgs, read...)
+			answered.set(len(msgs))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			utils.LogError(logger, err, "failed reading grpc response body")
+			return nil, err
+		}
+	}
+
+	resp := models.NewGrpcStream(0).GrpcResp
+	headers := &resp.Headers
+	// A trailers-only response carries the status in the only HEADERS frame, which is recorded as the trailers.
+	if len(httpResp.Trailer) == 0 && len(msgs) == 0 && len(pending) == 0 && httpResp.Header.Get("grpc-status") != "" {
+		headers = &resp.Trailers
+	}
+	headers.PseudoHeaders[":status"] = strconv.Itoa(httpResp.StatusCode)
+	for key, values := range httpResp.Header {
+		headers.OrdinaryHeaders[strings.ToLower(key)] = strings.Join(values, ", ")
+	}
+	for key, values := range httpResp.Trailer {
+		resp.Trailers.OrdinaryHeaders[strings.ToLower(key)] = strings.Join(values, ", ")
+	}
+
+	md := protos.OutputType(path)
+	for i, msg := range msgs {
+		streamMsg := models.GrpcStreamMessage{GrpcLengthPrefixedMessage: DecodeLengthPrefixedMessage(msg, md)}
+		if i == 0 {
+			resp.Body = streamMsg.GrpcLengthPrefixedMessage
+		}
+		resp.Stream = append(resp.Stream, streamMsg)
+	}
+	// Unary responses are stored in the Body alone, as they are while recording.
+	if len(resp.Stream) <= 1 {
+		resp.Stream = nil
+	}
+
+	return &resp, nil
+}
+
+// sendGrpcMessages writes the request messages of the test case on the body of the request, then closes
+// it. The messages of a streaming call are sent as the recorded client sent them: not before their
+// recorded offset, and once the application has sent the response messages which the recorded server had
+// sent before receiving them.
+func sendGrpcMessages(ctx context.Context, body io.Writer, tc *models.TestCase, payloads [][]byte, start time.Time, answered *messageCounter) error {
+	reqMsgs := tc.GrpcReq.Messages()
+	for i, payload := range payloads {
+		if len(tc.GrpcResp.Stream) > 0 {
+			before := 0
+			for _, msg := range tc.GrpcResp.Stream {
+				if msg.AfterRequests <= i {
+					before++
+				}
+			}
+			if err := answered.wait(ctx, before); err != nil {
+				return err
+			}
+		}
+		if delay := time.Until(start.Add(reqMsgs[i].Offset)); delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-answered.done:
+				return nil
+			case <-time.After(delay):
+			}
+		}
+		if _, err := body.Write(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// messageCounter counts the response messages read from the application, for the request messages
+// waiting to be sent.
+type messageCounter struct {
+	mutex   sync.Mutex
+	count   int
+	changed chan struct{}
+	// done is closed once the response is over.
+	done chan struct{}
+}
+
+func newMessageCounter() *messageCounter {
+	return &messageCounter{changed: make(chan struct{}), done: make(chan struct{})}
+}
+
+// set records the number of response messages read so far and wakes up the waiting messages.
+func (c *messageCounter) set(count int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.count = count
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// finish releases the waiting messages, as no more response message will be read.
+func (c *messageCounter) finish() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
+
+// wait blocks until the given number of response messages was read, or the response is over.
+func (c *messageCounter) wait(ctx context.Context, count int) error {
+	for {
+		c.mutex.Lock()
+		changed := c.changed
+		reached := c.count >= count
+		c.mutex.Unlock()
+		if reached {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done:
+			return nil
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const greeterProto = `syntax = "proto3";
+package greet;
+
+message HelloRequest {
+  string name = 1;
+  int32 times = 2;
+}
+
+message HelloReply {
+  string message = 1;
+}
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply);
+}
+`
+
+func testProtoRegistry(t *testing.T) *ProtoRegistry {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "greet.proto"), []byte(greeterProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := LoadProtoRegistry(context.Background(), config.Proto{Dir: dir})
+	if err != nil {
+		t.Fatalf("LoadProtoRegistry() error = %v", err)
+	}
+	return registry
+}
+
+func TestProtoRegistry(t *testing.T) {
+	registry := testProtoRegistry(t)
+	if got := registry.InputType("/greet.Greeter/SayHello"); got == nil || got.FullName() != "greet.HelloRequest" {
+		t.Errorf("InputType() = %v, want greet.HelloRequest", got)
+	}
+	if got := registry.OutputType("/greet.Greeter/SayHello"); got == nil || got.FullName() != "greet.HelloReply" {
+		t.Errorf("OutputType() = %v, want greet.HelloReply", got)
+	}
+	if got := registry.InputType("/greet.Greeter/Unknown"); got != nil {
+		t.Errorf("InputType() of an unknown method = %v, want nil", got)
+	}
+	var none *ProtoRegistry
+	if got := none.OutputType("/greet.Greeter/SayHello"); got != nil {
+		t.Errorf("OutputType() of a nil registry = %v, want nil", got)
+	}
+
+	registry, err := LoadProtoRegistry(context.Background(), config.Proto{})
+	if err != nil || registry != nil {
+		t.Errorf("LoadProtoRegistry() without definitions = %v, %v, want nil, nil", registry, err)
+	}
+}
+
+func TestLengthPrefixedMessage(t *testing.T) {
+	md := testProtoRegistry(t).InputType("/greet.Greeter/SayHello")
+	// name: "bob", times: 2
+	payload := []byte{0x0a, 0x03, 'b', 'o', 'b', 0x10, 0x02}
+	prefixed := func(flag byte, payload []byte) []byte {
+		return append([]byte{flag, 0, 0, 0, byte(len(payload))}, payload...)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		withDesc bool
+		wantJSON string
+		wantData bool
+	}{
+		{name: "with the descriptor", data: prefixed(0, payload), withDesc: true, wantJSON: `{"name":"bob","times":2}`},
+		{name: "without the descriptor", data: prefixed(0, payload), wantData: true},
+		{name: "compressed", data: prefixed(1, payload), withDesc: true, wantData: true},
+		{name: "not of the type of the descriptor", data: prefixed(0, []byte{0x0a, 0x05, 'b'}), withDesc: true, wantData: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc := md
+			if !tt.withDesc {
+				desc = nil
+			}
+			msg := DecodeLengthPrefixedMessage(tt.data, desc)
+			if msg.DecodedJSON != tt.wantJSON || (msg.DecodedData != "") != tt.wantData {
+				t.Fatalf("DecodeLengthPrefixedMessage() = %+v, want the json %q or protoscope text %v", msg, tt.wantJSON, tt.wantData)
+			}
+			if msg.MessageLength != uint32(len(tt.data)-5) || msg.CompressionFlag != uint(tt.data[0]) {
+				t.Errorf("DecodeLengthPrefixedMessage() prefix = %d, %d, want %d, %d", msg.CompressionFlag, msg.MessageLength, tt.data[0], len(tt.data)-5)
+			}
+			encoded, err := EncodeLengthPrefixedMessage(msg, desc)
+			if err != nil {
+				t.Fatalf("EncodeLengthPrefixedMessage() error = %v", err)
+			}
+			// the fields of the messages encoded with their descriptor aren't written in a stable order
+			if got := DecodeLengthPrefixedMessage(encoded, desc); got != msg {
+				t.Errorf("EncodeLengthPrefixedMessage() = %x, decoded back as %+v, want %+v", encoded, got, msg)
+			}
+		})
+	}
+
+	if _, err := EncodeLengthPrefixedMessage(models.GrpcLengthPrefixedMessage{DecodedJSON: `{"name":"bob"}`}, nil); err == nil {
+		t.Error("EncodeLengthPrefixedMessage() of a json message without its descriptor didn't fail")
+	}
+}
+
+func TestSplitLengthPrefixedMessages(t *testing.T) {
+	one := []byte{0, 0, 0, 0, 1, 0x01}
+	two := []byte{0, 0, 0, 0, 2, 0x08, 0x01}
+	tests := []struct {
+		name     string
+		buf      []byte
+		wantMsgs [][]byte
+		wantRest []byte
+	}{
+		{name: "empty", wantRest: []byte{}},
+		{name: "whole messages", buf: append(append([]byte{}, one...), two...), wantMsgs: [][]byte{one, two}, wantRest: []byte{}},
+		{name: "partial prefix", buf: append(append([]byte{}, one...), 0, 0), wantMsgs: [][]byte{one}, wantRest: []byte{0, 0}},
+		{name: "partial payload", buf: two[:6], wantRest: two[:6]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, rest := SplitLengthPrefixedMessages(tt.buf)
+			if !reflect.DeepEqual(msgs, tt.wantMsgs) || !bytes.Equal(rest, tt.wantRest) {
+				t.Errorf("SplitLengthPrefixedMessages() = %x, %x, want %x, %x", msgs, rest, tt.wantMsgs, tt.wantRest)
+			}
+		})
+	}
+}
+
+// chanWriter hands every written payload over to the test.
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestSendGrpcMessages(t *testing.T) {
+	bidi := &models.TestCase{
+		GrpcReq: models.GrpcReq{Stream: []models.GrpcStreamMessage{{}, {}, {}}},
+		GrpcResp: models.GrpcResp{Stream: []models.GrpcStreamMessage{
+			{AfterRequests: 1},
+			{AfterRequests: 2},
+		}},
+	}
+	body := make(chanWriter, 3)
+	answered := newMessageCounter()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sendGrpcMessages(context.Background(), body, bidi, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, time.Now(), answered)
+	}()
+
+	expectWritten := func(want string) {
+		t.Helper()
+		select {
+		case got := <-body:
+			if got != want {
+				t.Fatalf("wrote %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q was not written", want)
+		}
+	}
+	expectBlocked := func() {
+		t.Helper()
+		select {
+		case got := <-body:
+			t.Fatalf("wrote %q before the response message it follows", got)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	expectWritten("a")
+	expectBlocked()
+	answered.set(1)
+	expectWritten("b")
+	expectBlocked()
+	answered.set(2)
+	expectWritten("c")
+	if err := <-errc; err != nil {
+		t.Fatalf("sendGrpcMessages() error = %v", err)
+	}
+}
+
+func TestSendGrpcMessagesOffset(t *testing.T) {
+	tc := &models.TestCase{GrpcReq: models.GrpcReq{Stream: []models.GrpcStreamMessage{{}, {Offset: time.Hour}}}}
+
+	t.Run("response over", func(t *testing.T) {
+		var body bytes.Buffer
+		answered := newMessageCounter()
+		answered.finish()
+		if err := sendGrpcMessages(context.Background(), &body, tc, [][]byte{[]byte("a"), []byte("b")}, time.Now(), answered); err != nil {
+			t.Fatalf("sendGrpcMessages() error = %v", err)
+		}
+		if body.String() != "a" {
+			t.Errorf("wrote %q, want only the messages due before the response ended", body.String())
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		var body bytes.Buffer
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := sendGrpcMessages(ctx, &body, tc, [][]byte{[]byte("a"), []byte("b")}, time.Now(), newMessageCounter())
+		if err != context.Canceled {
+			t.Fatalf("sendGrpcMessages() error = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestMessageCounter(t *testing.T) {
+	tests := []struct {
+		name    string
+		release func(c *messageCounter, cancel context.CancelFunc)
+		wantErr error
+	}{
+		{name: "count reached", release: func(c *messageCounter, _ context.CancelFunc) { c.set(1); c.set(2) }},
+		{name: "response over", release: func(c *messageCounter, _ context.CancelFunc) { c.finish(); c.finish() }},
+		{name: "context cancelled", release: func(_ *messageCounter, cancel context.CancelFunc) { cancel() }, wantErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newMessageCounter()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errc := make(chan error, 1)
+			go func() { errc <- c.wait(ctx, 2) }()
+			tt.release(c, cancel)
+			select {
+			case err := <-errc:
+				if err != tt.wantErr {
+					t.Errorf("wait() error = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("wait() did not return")
+			}
+		})
+	}
+}
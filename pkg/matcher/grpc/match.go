@@ -0,0 +1,203 @@
+// Package grpc for grpc matching
+package grpc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/k0kubun/pp/v3"
+	"go.uber.org/zap"
+
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+// Match compares the gRPC response of the application with the recorded one. The grpc-status is
+// compared as the status code, the headers and trailers as headers and every message as a body.
+func Match(tc *models.TestCase, actualResponse *models.GrpcResp, noiseConfig map[string]map[string][]string, ignoreOrdering bool, logger *zap.Logger) (bool, *models.Result) {
+	pass := true
+	hRes := &[]models.HeaderResult{}
+	res := &models.Result{
+		StatusCode: models.IntResult{
+			Normal:   false,
+			Expected: grpcStatus(tc.GrpcResp),
+			Actual:   grpcStatus(*actualResponse),
+		},
+	}
+	noise := tc.Noise
+	bodyNoise, headerNoise := matcherUtils.SplitNoise(noise, noiseConfig)
+
+	expMsgs, actMsgs := tc.GrpcResp.Messages(), actualResponse.Messages()
+	for i := 0; i < len(expMsgs) || i < len(actMsgs); i++ {
+		var exp, act models.GrpcLengthPrefixedMessage
+		if i < len(expMsgs) {
+			exp = expMsgs[i].GrpcLengthPrefixedMessage
+		}
+		if i < len(actMsgs) {
+			act = actMsgs[i].GrpcLengthPrefixedMessage
+		}
+		bodyRes := compareMessage(logger, exp, act, i < len(expMsgs) && i < len(actMsgs), bodyNoise, ignoreOrdering)
+		if matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") {
+			bodyRes.Normal = true
+		}
+		if !bodyRes.Normal {
+			pass = false
+		}
+		res.BodyResult = append(res.BodyResult, bodyRes)
+	}
+
+	if !matcherUtils.CompareHeaders(responseHeaders(tc.GrpcResp), responseHeaders(*actualResponse), hRes, headerNoise) {
+		pass = false
+	}
+
+	res.HeadersResult = *hRes
+	if res.StatusCode.Expected == res.StatusCode.Actual {
+		res.StatusCode.Normal = true
+	} else {
+		pass = false
+	}
+
+	if !pass {
+		logDiffs := matcherUtils.NewDiffsPrinter(tc.Name)
+
+		newLogger := pp.New()
+		newLogger.WithLineInfo = false
+		newLogger.SetColorScheme(models.GetFailingColorScheme())
+		var logs = ""
+
+		logs = logs + newLogger.Sprintf("Testrun failed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name)
+
+		// ------------ DIFFS RELATED CODE -----------
+		if !res.StatusCode.Normal {
+			logDiffs.PushStatusDiff(fmt.Sprint(res.StatusCode.Expected), fmt.Sprint(res.StatusCode.Actual))
+		}
+
+		for _, j := range res.HeadersResult {
+			if !j.Normal {
+				logDiffs.PushHeaderDiff(fmt.Sprint(j.Expected.Value), fmt.Sprint(j.Actual.Value), j.Expected.Key, headerNoise)
+			}
+		}
+
+		for _, j := range res.BodyResult {
+			if !j.Normal {
+				logDiffs.PushBodyDiff(joinMessages(res.BodyResult, true), joinMessages(res.BodyResult, false), bodyNoise)
+				break
+			}
+		}
+
+		_, err := newLogger.Printf(logs)
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+
+		err = logDiffs.Render()
+		if err != nil {
+			utils.LogError(logger, err, "failed to render the diffs")
+		}
+	} else {
+		newLogger := pp.New()
+		newLogger.WithLineInfo = false
+		newLogger.SetColorScheme(models.GetPassingColorScheme())
+		var log2 = ""
+		log2 += newLogger.Sprintf("Testrun passed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name)
+		_, err := newLogger.Printf(log2)
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+	}
+	return pass, res
+}
+
+// compareMessage compares a pair of response messages. The messages are compared as JSON when both
+// of them could be decoded using the protobuf definitions, and as protoscope text otherwise.
+func compareMessage(logger *zap.Logger, exp, act models.GrpcLengthPrefixedMessage, bothPresent bool, noise map[string][]string, ignoreOrdering bool) models.BodyResult {
+	if exp.DecodedJSON != "" && act.DecodedJSON != "" {
+		res := models.BodyResult{
+			Normal:   false,
+			Type:     models.BodyTypeJSON,
+			Expected: exp.DecodedJSON,
+			Actual:   act.DecodedJSON,
+		}
+		if exp.CompressionFlag != act.CompressionFlag {
+			return res
+		}
+		cleanExp, cleanAct := exp.DecodedJSON, act.DecodedJSON
+		validatedJSON, err := matcherUtils.ValidateAndMarshalJSON(logger, &cleanExp, &cleanAct)
+		if err != nil || !validatedJSON.IsIdentical() {
+			return res
+		}
+		jsonComparisonResult, err := matcherUtils.JSONDiffWithNoiseControl(validatedJSON, noise, ignoreOrdering)
+		res.Normal = err == nil && jsonComparisonResult.IsExact()
+		return res
+	}
+
+	res := models.BodyResult{
+		Normal:   false,
+		Type:     models.BodyTypePlain,
+		Expected: exp.DecodedData,
+		Actual:   act.DecodedData,
+	}
+	if (exp.DecodedJSON == "") != (act.DecodedJSON == "") {
+		// the protobuf definitions were only configured while recording or while replaying
+		logger.Warn("a grpc message stored as json can't be compared with protoscope text, the same protobuf definitions are needed to record and to replay")
+		res.Expected, res.Actual = exp.DecodedData+exp.DecodedJSON, act.DecodedData+act.DecodedJSON
+		return res
+	}
+	res.Normal = bothPresent && exp.CompressionFlag == act.CompressionFlag && res.Expected == res.Actual
+	return res
+}
+
+// grpcStatus returns the grpc-status of the response, which is sent in the trailers unless the
+// response is trailers-only. It returns -1 if the response carries no valid status.
+func grpcStatus(resp models.GrpcResp) int {
+	status, ok := resp.Trailers.OrdinaryHeaders["grpc-status"]
+	if !ok {
+		status, ok = resp.Headers.OrdinaryHeaders["grpc-status"]
+	}
+	if !ok {
+		return -1
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return -1
+	}
+	return code
+}
+
+// responseHeaders merges the ordinary headers and trailers of the response, the pseudo headers
+// are left out as they are fixed by gRPC.
+func responseHeaders(resp models.GrpcResp) http.Header {
+	headers := http.Header{}
+	for key, value := range resp.Headers.OrdinaryHeaders {
+		headers[key] = []string{value}
+	}
+	for key, value := range resp.Trailers.OrdinaryHeaders {
+		headers[key] = []string{value}
+	}
+	return headers
+}
+
+// joinMessages renders all the expected or actual messages as a single body for the diff. The
+// messages form a JSON array when all of them are JSON, so that the diff is computed per field.
+func joinMessages(results []models.BodyResult, expected bool) string {
+	isJSON := true
+	msgs := make([]string, 0, len(results))
+	for _, r := range results {
+		msg := r.Actual
+		if expected {
+			msg = r.Expected
+		}
+		if msg == "" {
+			continue
+		}
+		isJSON = isJSON && r.Type == models.BodyTypeJSON
+		msgs = append(msgs, msg)
+	}
+	if isJSON {
+		return "[" + strings.Join(msgs, ",") + "]"
+	}
+	return strings.Join(msgs, "\n")
+}
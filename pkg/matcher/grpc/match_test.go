@@ -0,0 +1,108 @@
+package grpc
+
+import (
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func grpcResp(status string, msgs ...models.GrpcLengthPrefixedMessage) models.GrpcResp {
+	resp := models.GrpcResp{
+		Headers:  models.GrpcHeaders{PseudoHeaders: map[string]string{":status": "200"}, OrdinaryHeaders: map[string]string{"content-type": "application/grpc"}},
+		Trailers: models.GrpcHeaders{OrdinaryHeaders: map[string]string{"grpc-status": status}},
+	}
+	if len(msgs) == 1 {
+		resp.Body = msgs[0]
+		return resp
+	}
+	for _, msg := range msgs {
+		resp.Stream = append(resp.Stream, models.GrpcStreamMessage{GrpcLengthPrefixedMessage: msg})
+	}
+	return resp
+}
+
+func scope(data string) models.GrpcLengthPrefixedMessage {
+	return models.GrpcLengthPrefixedMessage{DecodedData: data}
+}
+
+func json(data string) models.GrpcLengthPrefixedMessage {
+	return models.GrpcLengthPrefixedMessage{DecodedJSON: data}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    models.GrpcResp
+		actual      models.GrpcResp
+		noise       map[string][]string
+		noiseConfig map[string]map[string][]string
+		want        bool
+	}{
+		{name: "same unary response", expected: grpcResp("0", scope("1: 1")), actual: grpcResp("0", scope("1: 1")), want: true},
+		{name: "other status", expected: grpcResp("0", scope("1: 1")), actual: grpcResp("5", scope("1: 1"))},
+		{name: "other protoscope message", expected: grpcResp("0", scope("1: 1")), actual: grpcResp("0", scope("1: 2"))},
+		{name: "same stream", expected: grpcResp("0", scope("1: 1"), scope("1: 2")), actual: grpcResp("0", scope("1: 1"), scope("1: 2")), want: true},
+		{name: "missing stream message", expected: grpcResp("0", scope("1: 1"), scope("1: 2"), scope("1: 3")), actual: grpcResp("0", scope("1: 1"), scope("1: 2"))},
+		{name: "json fields in another order", expected: grpcResp("0", json(`{"a":1,"b":2}`)), actual: grpcResp("0", json(`{"b":2,"a":1}`)), want: true},
+		{name: "json field of the test case noise", expected: grpcResp("0", json(`{"a":1,"ts":"1"}`)), actual: grpcResp("0", json(`{"a":1,"ts":"2"}`)), noise: map[string][]string{"body.ts": {}}, want: true},
+		{name: "json field of the config noise", expected: grpcResp("0", json(`{"a":1,"ts":"1"}`)), actual: grpcResp("0", json(`{"a":1,"ts":"2"}`)), noiseConfig: map[string]map[string][]string{"body": {"ts": {}}}, want: true},
+		{name: "json against protoscope", expected: grpcResp("0", json(`{"a":1}`)), actual: grpcResp("0", scope("1: 1"))},
+		{name: "whole body noisy", expected: grpcResp("0", scope("1: 1")), actual: grpcResp("0", scope("1: 2")), noise: map[string][]string{"body": {}}, want: true},
+		{name: "noisy trailer", expected: withTrailer(grpcResp("0", scope("1: 1")), "x-id", "a"), actual: withTrailer(grpcResp("0", scope("1: 1")), "x-id", "b"), noise: map[string][]string{"header.x-id": {}}, want: true},
+		{name: "other trailer", expected: withTrailer(grpcResp("0", scope("1: 1")), "x-id", "a"), actual: withTrailer(grpcResp("0", scope("1: 1")), "x-id", "b")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &models.TestCase{Name: "test-1", Kind: models.GRPC_EXPORT, GrpcResp: tt.expected, Noise: tt.noise}
+			got, _ := Match(tc, &tt.actual, tt.noiseConfig, false, zap.NewNop())
+			if got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func withTrailer(resp models.GrpcResp, key, value string) models.GrpcResp {
+	resp.Trailers.OrdinaryHeaders[key] = value
+	return resp
+}
+
+func TestGrpcStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		resp models.GrpcResp
+		want int
+	}{
+		{name: "in the trailers", resp: models.GrpcResp{Trailers: models.GrpcHeaders{OrdinaryHeaders: map[string]string{"grpc-status": "14"}}}, want: 14},
+		{name: "trailers-only", resp: models.GrpcResp{Headers: models.GrpcHeaders{OrdinaryHeaders: map[string]string{"grpc-status": "5"}}}, want: 5},
+		{name: "missing", want: -1},
+		{name: "invalid", resp: models.GrpcResp{Trailers: models.GrpcHeaders{OrdinaryHeaders: map[string]string{"grpc-status": "ok"}}}, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grpcStatus(tt.resp); got != tt.want {
+				t.Errorf("grpcStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []models.BodyResult
+		want    string
+	}{
+		{name: "json", results: []models.BodyResult{{Type: models.BodyTypeJSON, Expected: `{"a":1}`}, {Type: models.BodyTypeJSON, Expected: `{"a":2}`}}, want: `[{"a":1},{"a":2}]`},
+		{name: "protoscope", results: []models.BodyResult{{Type: models.BodyTypePlain, Expected: "1: 1"}, {Type: models.BodyTypePlain, Expected: "1: 2"}}, want: "1: 1\n1: 2"},
+		{name: "missing message", results: []models.BodyResult{{Type: models.BodyTypeJSON, Expected: `{"a":1}`}, {Type: models.BodyTypePlain}}, want: `[{"a":1}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinMessages(tt.results, true); got != tt.want {
+				t.Errorf("joinMessages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
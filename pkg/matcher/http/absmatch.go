@@ -160,8 +160,8 @@ func CompareHTTPReq(tcs1, tcs2 *models.TestCase, _ models.GlobalNoise, ignoreOrd
 	reqHeaderNoise := map[string][]string{}
 	reqHeaderNoise["keploy-test-id"] = []string{}
 	reqHeaderNoise["keploy-test-set-id"] = []string{}
-	tcs1.HTTPReq.Header["Keploy-Test-Id"] = "dummyTest"
-	tcs1.HTTPReq.Header["Keploy-Test-Set-Id"] = "dummyTestSet"
+	tcs1.HTTPReq.Header.Set("Keploy-Test-Id", "dummyTest")
+	tcs1.HTTPReq.Header.Set("Keploy-Test-Set-Id", "dummyTestSet")
 
 	// compare http req headers
 	ok := matcher.CompareHeaders(pkg.ToHTTPHeader(tcs1.HTTPReq.Header), pkg.ToHTTPHeader(tcs2.HTTPReq.Header), &reqCompare.HeaderResult, reqHeaderNoise)
@@ -275,27 +275,7 @@ func CompareHTTPResp(tcs1, tcs2 *models.TestCase, noiseConfig models.GlobalNoise
 
 	noise := noise1
 
-	var (
-		bodyNoise   = noiseConfig["body"]
-		headerNoise = noiseConfig["header"]
-	)
-
-	if bodyNoise == nil {
-		bodyNoise = map[string][]string{}
-	}
-	if headerNoise == nil {
-		headerNoise = map[string][]string{}
-	}
-
-	for field, regexArr := range noise {
-		a := strings.Split(field, ".")
-		if len(a) > 1 && a[0] == "body" {
-			x := strings.Join(a[1:], ".")
-			bodyNoise[strings.ToLower(x)] = regexArr
-		} else if a[0] == "header" {
-			headerNoise[strings.ToLower(a[len(a)-1])] = regexArr
-		}
-	}
+	bodyNoise, headerNoise := matcher.SplitNoise(noise, noiseConfig)
 
 	// compare http resp headers
 	ok = matcher.CompareHeaders(pkg.ToHTTPHeader(tcs1.HTTPResp.Header), pkg.ToHTTPHeader(tcs2.HTTPResp.Header), &respCompare.HeadersResult, headerNoise)
@@ -438,8 +418,8 @@ func CompareCurl(curl1, curl2 string, logger *zap.Logger) bool {
 	curlHeaderNoise := map[string][]string{}
 	curlHeaderNoise["keploy-test-id"] = []string{}
 	curlHeaderNoise["keploy-test-set-id"] = []string{}
-	headers1["Keploy-Test-Id"] = "dummyTest"
-	headers1["Keploy-Test-Set-Id"] = "dummyTestSet"
+	headers1.Set("Keploy-Test-Id", "dummyTest")
+	headers1.Set("Keploy-Test-Set-Id", "dummyTestSet")
 
 	hres := []models.HeaderResult{}
 	ok := matcher.CompareHeaders(pkg.ToHTTPHeader(headers1), pkg.ToHTTPHeader(headers2), &hres, curlHeaderNoise)
@@ -450,9 +430,9 @@ func CompareCurl(curl1, curl2 string, logger *zap.Logger) bool {
 	return true
 }
 
-func parseCurlString(curlString string) (method, url string, headers map[string]string, data string) {
+func parseCurlString(curlString string) (method, url string, headers models.HTTPHeader, data string) {
 	lines := strings.Split(curlString, "\\")
-	headers = make(map[string]string)
+	headers = make(models.HTTPHeader)
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "--request") {
@@ -462,7 +442,8 @@ func parseCurlString(curlString string) (method, url string, headers map[string]
 		} else if strings.HasPrefix(line, "--header") {
 			headerParts := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "--header")), ":", 2)
 			if len(headerParts) == 2 {
-				headers[strings.TrimSpace(headerParts[0])] = strings.TrimSpace(headerParts[1])
+				key := strings.TrimSpace(headerParts[0])
+				headers[key] = append(headers[key], strings.TrimSpace(headerParts[1]))
 			}
 		} else if strings.HasPrefix(line, "--data") {
 			data = strings.TrimSpace(strings.TrimPrefix(line, "--data"))
@@ -0,0 +1,382 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Body is an http body along with its Content-Type header.
+type Body struct {
+	ContentType string
+	Data        string
+}
+
+// BodyComparator compares the bodies of a content type. A body is flattened into the values of its
+// paths (e.g. "order.id"), and two bodies match when all their paths, except for the noisy ones, have
+// the same values.
+type BodyComparator interface {
+	// Type is reported as the type of the body in the test results.
+	Type() models.BodyType
+	// Flatten returns the values of each path of the body.
+	Flatten(body Body) (map[string][]string, error)
+}
+
+var bodyComparators = map[string]BodyComparator{}
+
+// RegisterBodyComparator sets the comparator used for the bodies of a media type, e.g. "application/xml".
+func RegisterBodyComparator(mediaType string, c BodyComparator) {
+	bodyComparators[strings.ToLower(mediaType)] = c
+}
+
+func init() {
+	RegisterBodyComparator("application/json", jsonComparator{})
+	RegisterBodyComparator("application/xml", xmlComparator{})
+	RegisterBodyComparator("text/xml", xmlComparator{})
+	RegisterBodyComparator("application/x-www-form-urlencoded", formComparator{})
+	RegisterBodyComparator("multipart/form-data", multipartComparator{})
+	RegisterBodyComparator("multipart/mixed", multipartComparator{})
+}
+
+// comparatorFor returns the comparator of the media type of the Content-Type header. The media types with
+// a structured syntax suffix, such as application/soap+xml, use the comparator of the suffix.
+func comparatorFor(contentType string) (BodyComparator, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	if c, ok := bodyComparators[mediaType]; ok {
+		return c, true
+	}
+	if i := strings.LastIndex(mediaType, "+"); i != -1 {
+		c, ok := bodyComparators["application/"+mediaType[i+1:]]
+		return c, ok
+	}
+	return nil, false
+}
+
+// contentType returns the Content-Type header, whatever the case of its name.
+func contentType(header models.HTTPHeader) string {
+	for k := range header {
+		if strings.EqualFold(k, "Content-Type") {
+			return header.Get(k)
+		}
+	}
+	return ""
+}
+
+// sameMultipartType tells whether the Content-Type headers of two multipart bodies only differ by their
+// boundary.
+func sameMultipartType(exp, act string) bool {
+	expType, expParams, err := mime.ParseMediaType(exp)
+	if err != nil {
+		return false
+	}
+	actType, actParams, err := mime.ParseMediaType(act)
+	if err != nil || expType != actType || len(expParams) != len(actParams) {
+		return false
+	}
+	for k, v := range expParams {
+		if k != "boundary" && actParams[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// withNoise returns a copy of the noise with the key added, leaving the noise of the config unchanged.
+func withNoise(noise map[string][]string, key string) map[string][]string {
+	n := make(map[string][]string, len(noise)+1)
+	for k, v := range noise {
+		n[k] = v
+	}
+	n[key] = []string{}
+	return n
+}
+
+// bodyDiff holds the values of the paths which differ between two bodies.
+type bodyDiff struct {
+	expected map[string]interface{}
+	actual   map[string]interface{}
+}
+
+func (d bodyDiff) empty() bool {
+	return len(d.expected) == 0 && len(d.actual) == 0
+}
+
+// strings returns the differences as json objects keyed by path, so that they are rendered like the
+// differences of json bodies.
+func (d bodyDiff) strings() (string, string) {
+	exp, err := json.Marshal(d.expected)
+	if err != nil {
+		return fmt.Sprint(d.expected), fmt.Sprint(d.actual)
+	}
+	act, err := json.Marshal(d.actual)
+	if err != nil {
+		return fmt.Sprint(d.expected), fmt.Sprint(d.actual)
+	}
+	return string(exp), string(act)
+}
+
+// compareBodies flattens both bodies with the comparator and returns the differences of their paths.
+func compareBodies(c BodyComparator, exp, act Body, noise map[string][]string, ignoreOrdering bool) (bodyDiff, error) {
+	diff := bodyDiff{expected: map[string]interface{}{}, actual: map[string]interface{}{}}
+	expPaths, err := c.Flatten(exp)
+	if err != nil {
+		return diff, fmt.Errorf("failed to parse the expected body: %w", err)
+	}
+	actPaths, err := c.Flatten(act)
+	if err != nil {
+		return diff, fmt.Errorf("failed to parse the actual body: %w", err)
+	}
+	noise = normalizeNoise(noise)
+
+	for path, expValues := range expPaths {
+		actValues, ok := actPaths[path]
+		if ok && equalValues(expValues, actValues, ignoreOrdering) {
+			continue
+		}
+		if isNoisyPath(path, expValues, noise) {
+			continue
+		}
+		diff.expected[path] = pathValue(expValues)
+		if ok {
+			diff.actual[path] = pathValue(actValues)
+		}
+	}
+	for path, actValues := range actPaths {
+		if _, ok := expPaths[path]; ok || isNoisyPath(path, nil, noise) {
+			continue
+		}
+		diff.actual[path] = pathValue(actValues)
+	}
+	return diff, nil
+}
+
+func equalValues(exp, act []string, ignoreOrdering bool) bool {
+	if len(exp) != len(act) {
+		return false
+	}
+	if ignoreOrdering {
+		exp = append([]string(nil), exp...)
+		act = append([]string(nil), act...)
+		sort.Strings(exp)
+		sort.Strings(act)
+	}
+	for i := range exp {
+		if exp[i] != act[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func pathValue(values []string) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// normalizeNoise lowercases the noisy paths and turns the XPath-style ones, e.g. /order/item/@sku, into
+// dot-delimited paths.
+func normalizeNoise(noise map[string][]string) map[string][]string {
+	normalized := make(map[string][]string, len(noise))
+	for path, regexArr := range noise {
+		path = strings.ToLower(strings.Trim(path, "/"))
+		normalized[strings.ReplaceAll(path, "/", ".")] = regexArr
+	}
+	return normalized
+}
+
+// isNoisyPath tells whether the path, or one of its parents, is noisy. A noisy path with regular
+// expressions is only ignored if its expected values match one of them.
+func isNoisyPath(path string, expValues []string, noise map[string][]string) bool {
+	path = strings.ToLower(path)
+	for {
+		if regexArr, ok := noise[path]; ok {
+			if len(regexArr) == 0 {
+				return true
+			}
+			for _, v := range expValues {
+				if isNoisy, _ := matcherUtils.MatchesAnyRegex(v, regexArr); !isNoisy {
+					return false
+				}
+			}
+			return len(expValues) > 0
+		}
+		i := strings.LastIndex(path, ".")
+		if i == -1 {
+			return false
+		}
+		path = path[:i]
+	}
+}
+
+// jsonComparator compares json bodies. It is used for the json parts of multipart bodies, as the json
+// bodies are diffed by JSONDiffWithNoiseControl.
+type jsonComparator struct{}
+
+func (jsonComparator) Type() models.BodyType { return models.BodyTypeJSON }
+
+func (jsonComparator) Flatten(body Body) (map[string][]string, error) {
+	var result interface{}
+	if err := json.Unmarshal([]byte(body.Data), &result); err != nil {
+		return nil, err
+	}
+	return matcherUtils.Flatten(result), nil
+}
+
+// xmlComparator compares xml bodies regardless of the order of the attributes, of the namespace prefixes
+// and of the whitespace around the text. The path of an element is made of the names of its ancestors,
+// e.g. "order.id", and the path of an attribute ends with its name prefixed by "@", e.g. "order.@id".
+type xmlComparator struct{}
+
+func (xmlComparator) Type() models.BodyType { return models.BodyTypeXML }
+
+func (xmlComparator) Flatten(body Body) (map[string][]string, error) {
+	paths := map[string][]string{}
+	var (
+		stack []string
+		texts []*strings.Builder
+	)
+	dec := xml.NewDecoder(strings.NewReader(body.Data))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			texts = append(texts, &strings.Builder{})
+			path := strings.Join(stack, ".")
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				attrPath := path + ".@" + attr.Name.Local
+				paths[attrPath] = append(paths[attrPath], attr.Value)
+			}
+		case xml.CharData:
+			if len(texts) == 0 {
+				continue
+			}
+			if text := strings.TrimSpace(string(t)); text != "" {
+				b := texts[len(texts)-1]
+				if b.Len() > 0 {
+					b.WriteString(" ")
+				}
+				b.WriteString(text)
+			}
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("unexpected end element")
+			}
+			path := strings.Join(stack, ".")
+			paths[path] = append(paths[path], texts[len(texts)-1].String())
+			stack, texts = stack[:len(stack)-1], texts[:len(texts)-1]
+		}
+	}
+	if len(stack) != 0 {
+		return nil, errors.New("unexpected end of the xml document")
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no xml element found")
+	}
+	return paths, nil
+}
+
+// formComparator compares url-encoded forms by key, regardless of the order of the keys.
+type formComparator struct{}
+
+func (formComparator) Type() models.BodyType { return models.BodyTypeForm }
+
+func (formComparator) Flatten(body Body) (map[string][]string, error) {
+	values, err := url.ParseQuery(body.Data)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// multipartComparator compares multipart bodies by the name of their parts, regardless of the boundary.
+// The parts are compared with the comparator of their Content-Type, so the path of a field of a json part
+// is prefixed by the name of the part, e.g. "metadata.id".
+type multipartComparator struct{}
+
+func (multipartComparator) Type() models.BodyType { return models.BodyTypeMultipart }
+
+func (multipartComparator) Flatten(body Body) (map[string][]string, error) {
+	_, params, err := mime.ParseMediaType(body.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if params["boundary"] == "" {
+		return nil, errors.New("no boundary in the multipart content type")
+	}
+
+	paths := map[string][]string{}
+	reader := multipart.NewReader(strings.NewReader(body.Data), params["boundary"])
+	for i := 0; ; i++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var data bytes.Buffer
+		if _, err := io.Copy(&data, part); err != nil {
+			return nil, err
+		}
+
+		name := part.FormName()
+		if name == "" {
+			name = part.FileName()
+		}
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		if filename := part.FileName(); filename != "" && filename != name {
+			paths[name+".@filename"] = append(paths[name+".@filename"], filename)
+		}
+
+		partBody := Body{ContentType: part.Header.Get("Content-Type"), Data: data.String()}
+		c, ok := comparatorFor(partBody.ContentType)
+		if !ok {
+			paths[name] = append(paths[name], partBody.Data)
+			continue
+		}
+		partPaths, err := c.Flatten(partBody)
+		if err != nil {
+			paths[name] = append(paths[name], partBody.Data)
+			continue
+		}
+		for path, values := range partPaths {
+			if path != "" {
+				path = name + "." + path
+			} else {
+				path = name
+			}
+			paths[path] = append(paths[path], values...)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,320 @@
+package http
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestComparatorFor(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        BodyComparator
+	}{
+		{contentType: "application/json", want: jsonComparator{}},
+		{contentType: "application/xml; charset=utf-8", want: xmlComparator{}},
+		{contentType: "TEXT/XML", want: xmlComparator{}},
+		{contentType: "application/soap+xml", want: xmlComparator{}},
+		{contentType: "application/problem+json", want: jsonComparator{}},
+		{contentType: "application/x-www-form-urlencoded", want: formComparator{}},
+		{contentType: "multipart/form-data; boundary=abc", want: multipartComparator{}},
+		{contentType: "multipart/mixed; boundary=abc", want: multipartComparator{}},
+		{contentType: "text/plain"},
+		{contentType: "application/vnd.custom+yaml"},
+		{contentType: ""},
+		{contentType: "invalid;;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			got, ok := comparatorFor(tt.contentType)
+			if ok != (tt.want != nil) || got != tt.want {
+				t.Errorf("comparatorFor() = %T, %v, want %T", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestXMLComparator(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			name: "elements and attributes",
+			data: `<?xml version="1.0"?><order id="7" status="new"><item sku="a1">Pen</item><item sku="b2">Ink</item></order>`,
+			want: map[string][]string{
+				"order":           {""},
+				"order.@id":       {"7"},
+				"order.@status":   {"new"},
+				"order.item":      {"Pen", "Ink"},
+				"order.item.@sku": {"a1", "b2"},
+			},
+		},
+		{
+			name: "namespaces and whitespace",
+			data: "<soap:Envelope xmlns:soap=\"http://schemas.xmlsoap.org/soap/envelope/\">\n  <soap:Body>\n    <total>  42  </total>\n  </soap:Body>\n</soap:Envelope>",
+			want: map[string][]string{
+				"Envelope":            {""},
+				"Envelope.Body":       {""},
+				"Envelope.Body.total": {"42"},
+			},
+		},
+		{name: "unclosed element", data: "<order><id>1</id>", wantErr: true},
+		{name: "no element", data: "just text", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := xmlComparator{}.Flatten(Body{ContentType: "application/xml", Data: tt.data})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Flatten() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flatten() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormComparator(t *testing.T) {
+	got, err := formComparator{}.Flatten(Body{Data: "b=2&a=1&a=3&c="})
+	if err != nil {
+		t.Fatalf("Flatten() error = %v", err)
+	}
+	want := map[string][]string{"a": {"1", "3"}, "b": {"2"}, "c": {""}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Flatten() = %v, want %v", got, want)
+	}
+	if _, err := (formComparator{}).Flatten(Body{Data: "a=%zz"}); err == nil {
+		t.Error("Flatten() of an invalid form error = nil, want an error")
+	}
+}
+
+func multipartBody(boundary string, parts ...string) Body {
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString("--" + boundary + "\r\n" + part + "\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return Body{ContentType: "multipart/form-data; boundary=" + boundary, Data: b.String()}
+}
+
+func TestMultipartComparator(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    Body
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			name: "fields, json and files",
+			body: multipartBody("XyZ",
+				"Content-Disposition: form-data; name=\"title\"\r\n\r\nreport",
+				"Content-Disposition: form-data; name=\"metadata\"\r\nContent-Type: application/json\r\n\r\n{\"id\":\"7\",\"owner\":{\"name\":\"ann\"}}",
+				"Content-Disposition: form-data; name=\"file\"; filename=\"report.csv\"\r\nContent-Type: text/csv\r\n\r\na,b\n1,2",
+			),
+			want: map[string][]string{
+				"title":               {"report"},
+				"metadata.id":         {"7"},
+				"metadata.owner.name": {"ann"},
+				"file":                {"a,b\n1,2"},
+				"file.@filename":      {"report.csv"},
+			},
+		},
+		{
+			name: "invalid json part",
+			body: multipartBody("XyZ", "Content-Disposition: form-data; name=\"metadata\"\r\nContent-Type: application/json\r\n\r\n{oops"),
+			want: map[string][]string{"metadata": {"{oops"}},
+		},
+		{
+			name: "unnamed parts",
+			body: multipartBody("XyZ", "Content-Type: text/plain\r\n\r\nfirst", "Content-Type: text/plain\r\n\r\nsecond"),
+			want: map[string][]string{"0": {"first"}, "1": {"second"}},
+		},
+		{name: "no boundary", body: Body{ContentType: "multipart/form-data", Data: "--XyZ--\r\n"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := multipartComparator{}.Flatten(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Flatten() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flatten() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareBodies(t *testing.T) {
+	xmlBody := func(id, ts string) Body {
+		return Body{ContentType: "application/xml", Data: `<order id="` + id + `"><ts>` + ts + `</ts><total>10</total></order>`}
+	}
+	tests := []struct {
+		name           string
+		exp            Body
+		act            Body
+		noise          map[string][]string
+		ignoreOrdering bool
+		wantExpected   map[string]interface{}
+		wantActual     map[string]interface{}
+	}{
+		{name: "same", exp: xmlBody("7", "1"), act: xmlBody("7", "1")},
+		{
+			name:         "changed element",
+			exp:          xmlBody("7", "1"),
+			act:          xmlBody("7", "2"),
+			wantExpected: map[string]interface{}{"order.ts": "1"},
+			wantActual:   map[string]interface{}{"order.ts": "2"},
+		},
+		{name: "noisy element", exp: xmlBody("7", "1"), act: xmlBody("7", "2"), noise: map[string][]string{"order.ts": {}}},
+		{name: "xpath noise", exp: xmlBody("7", "1"), act: xmlBody("8", "1"), noise: map[string][]string{"/order/@id": {}}},
+		{name: "noisy parent", exp: xmlBody("7", "1"), act: xmlBody("8", "2"), noise: map[string][]string{"ORDER": {}}},
+		{name: "noise matching the expected value", exp: xmlBody("7", "1"), act: xmlBody("7", "2"), noise: map[string][]string{"order.ts": {`^\d$`}}},
+		{
+			name:         "noise not matching the expected value",
+			exp:          xmlBody("7", "x"),
+			act:          xmlBody("7", "2"),
+			noise:        map[string][]string{"order.ts": {`^\d$`}},
+			wantExpected: map[string]interface{}{"order.ts": "x"},
+			wantActual:   map[string]interface{}{"order.ts": "2"},
+		},
+		{
+			name:         "missing and added keys",
+			exp:          Body{Data: "a=1&b=2"},
+			act:          Body{Data: "a=1&c=3"},
+			wantExpected: map[string]interface{}{"b": "2"},
+			wantActual:   map[string]interface{}{"c": "3"},
+		},
+		{
+			name:         "ordering",
+			exp:          Body{Data: "a=1&a=2"},
+			act:          Body{Data: "a=2&a=1"},
+			wantExpected: map[string]interface{}{"a": []string{"1", "2"}},
+			wantActual:   map[string]interface{}{"a": []string{"2", "1"}},
+		},
+		{name: "ordering ignored", exp: Body{Data: "a=1&a=2"}, act: Body{Data: "a=2&a=1"}, ignoreOrdering: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := comparatorFor(tt.exp.ContentType)
+			if !ok {
+				c = formComparator{}
+			}
+			diff, err := compareBodies(c, tt.exp, tt.act, tt.noise, tt.ignoreOrdering)
+			if err != nil {
+				t.Fatalf("compareBodies() error = %v", err)
+			}
+			if tt.wantExpected == nil {
+				tt.wantExpected = map[string]interface{}{}
+			}
+			if tt.wantActual == nil {
+				tt.wantActual = map[string]interface{}{}
+			}
+			if !reflect.DeepEqual(diff.expected, tt.wantExpected) || !reflect.DeepEqual(diff.actual, tt.wantActual) {
+				t.Errorf("compareBodies() = %v, %v, want %v, %v", diff.expected, diff.actual, tt.wantExpected, tt.wantActual)
+			}
+			if diff.empty() != (len(tt.wantExpected) == 0 && len(tt.wantActual) == 0) {
+				t.Errorf("empty() = %v", diff.empty())
+			}
+		})
+	}
+}
+
+func TestSameMultipartType(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+		act  string
+		want bool
+	}{
+		{name: "other boundary", exp: "multipart/form-data; boundary=a", act: "multipart/form-data; boundary=b", want: true},
+		{name: "other type", exp: "multipart/form-data; boundary=a", act: "multipart/mixed; boundary=a"},
+		{name: "other charset", exp: "multipart/form-data; boundary=a; charset=utf-8", act: "multipart/form-data; boundary=b; charset=latin1"},
+		{name: "missing parameter", exp: "multipart/form-data; boundary=a; charset=utf-8", act: "multipart/form-data; boundary=b"},
+		{name: "invalid", exp: "multipart/form-data; boundary=a", act: ";"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameMultipartType(tt.exp, tt.act); got != tt.want {
+				t.Errorf("sameMultipartType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchStructuredBody(t *testing.T) {
+	resp := func(contentType, body string) models.HTTPResp {
+		return models.HTTPResp{StatusCode: 200, Header: models.HTTPHeader{"Content-Type": {contentType}}, Body: body}
+	}
+	tests := []struct {
+		name     string
+		expected models.HTTPResp
+		actual   models.HTTPResp
+		noise    map[string][]string
+		want     bool
+		wantType models.BodyType
+	}{
+		{
+			name:     "xml attributes in another order",
+			expected: resp("application/xml", `<order id="7" status="new"/>`),
+			actual:   resp("application/xml", `<order status="new" id="7"></order>`),
+			want:     true,
+			wantType: models.BodyTypeXML,
+		},
+		{
+			name:     "noisy xml element",
+			expected: resp("text/xml", `<order><ts>1</ts></order>`),
+			actual:   resp("text/xml", `<order><ts>2</ts></order>`),
+			noise:    map[string][]string{"body.order.ts": {}},
+			want:     true,
+			wantType: models.BodyTypeXML,
+		},
+		{
+			name:     "form keys in another order",
+			expected: resp("application/x-www-form-urlencoded", "a=1&b=2"),
+			actual:   resp("application/x-www-form-urlencoded", "b=2&a=1"),
+			want:     true,
+			wantType: models.BodyTypeForm,
+		},
+		{
+			name:     "multipart with another boundary",
+			expected: resp("multipart/form-data; boundary=a", multipartBody("a", "Content-Disposition: form-data; name=\"x\"\r\n\r\n1").Data),
+			actual:   resp("multipart/form-data; boundary=b", multipartBody("b", "Content-Disposition: form-data; name=\"x\"\r\n\r\n1").Data),
+			want:     true,
+			wantType: models.BodyTypeMultipart,
+		},
+		{
+			name:     "multipart field changed",
+			expected: resp("multipart/form-data; boundary=a", multipartBody("a", "Content-Disposition: form-data; name=\"x\"\r\n\r\n1").Data),
+			actual:   resp("multipart/form-data; boundary=b", multipartBody("b", "Content-Disposition: form-data; name=\"x\"\r\n\r\n2").Data),
+			wantType: models.BodyTypeMultipart,
+		},
+		{
+			name:     "malformed xml compared as text",
+			expected: resp("application/xml", "<order>"),
+			actual:   resp("application/xml", "<order>"),
+			want:     true,
+			wantType: models.BodyTypePlain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &models.TestCase{Name: "test-1", Kind: models.HTTP, HTTPResp: tt.expected, Noise: tt.noise}
+			got, res := Match(tc, &tt.actual, nil, false, zap.NewNop())
+			if got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+			if res.BodyResult[0].Type != tt.wantType {
+				t.Errorf("body type = %q, want %q", res.BodyResult[0].Type, tt.wantType)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package http
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -19,8 +20,18 @@ import (
 
 func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map[string]map[string][]string, ignoreOrdering bool, logger *zap.Logger) (bool, *models.Result) {
 	bodyType := models.BodyTypePlain
-	if json.Valid([]byte(actualResponse.Body)) {
+	expBody := Body{ContentType: contentType(tc.HTTPResp.Header), Data: tc.HTTPResp.Body}
+	actBody := Body{ContentType: contentType(actualResponse.Header), Data: actualResponse.Body}
+	comparator, hasComparator := comparatorFor(actBody.ContentType)
+	// the binary bodies are compared byte by byte, and shown in base64 in the results
+	binaryBody := tc.HTTPResp.BodyEncoding.IsBinary() || models.EncodingOf(actualResponse.Body).IsBinary()
+	if binaryBody {
+		bodyType = models.BodyTypeBinary
+		hasComparator = false
+	} else if json.Valid([]byte(actualResponse.Body)) {
 		bodyType = models.BodyTypeJSON
+	} else if hasComparator {
+		bodyType = comparator.Type()
 	}
 	pass := true
 	hRes := &[]models.HeaderResult{}
@@ -37,34 +48,23 @@ func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map
 			Actual:   actualResponse.Body,
 		}},
 	}
-	noise := tc.Noise
-
-	var (
-		bodyNoise   = noiseConfig["body"]
-		headerNoise = noiseConfig["header"]
-	)
-
-	if bodyNoise == nil {
-		bodyNoise = map[string][]string{}
-	}
-	if headerNoise == nil {
-		headerNoise = map[string][]string{}
+	if binaryBody {
+		res.BodyResult[0].Expected = base64.StdEncoding.EncodeToString([]byte(tc.HTTPResp.Body))
+		res.BodyResult[0].Actual = base64.StdEncoding.EncodeToString([]byte(actualResponse.Body))
 	}
+	noise := tc.Noise
 
-	for field, regexArr := range noise {
-		a := strings.Split(field, ".")
-		if len(a) > 1 && a[0] == "body" {
-			x := strings.Join(a[1:], ".")
-			bodyNoise[strings.ToLower(x)] = regexArr
-		} else if a[0] == "header" {
-			headerNoise[strings.ToLower(a[len(a)-1])] = regexArr
-		}
-	}
+	bodyNoise, headerNoise := matcherUtils.SplitNoise(noise, noiseConfig)
 
 	// stores the json body after removing the noise
 	cleanExp, cleanAct := tc.HTTPResp.Body, actualResponse.Body
 	var jsonComparisonResult matcherUtils.JSONComparisonResult
-	if !matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") && bodyType == models.BodyTypeJSON {
+	var structuredDiff bodyDiff
+	// the streamed responses are compared event by event
+	streamed := len(tc.HTTPResp.Stream) > 0 || len(actualResponse.Stream) > 0
+	if streamed {
+		pass, res.BodyResult = matchStream(logger, tc.HTTPResp.Stream, actualResponse.Stream, noise, bodyNoise, ignoreOrdering)
+	} else if !matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") && bodyType == models.BodyTypeJSON {
 		//validate the stored json
 		validatedJSON, err := matcherUtils.ValidateAndMarshalJSON(logger, &cleanExp, &cleanAct)
 		if err != nil {
@@ -83,13 +83,31 @@ func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map
 		// debug log for cleanExp and cleanAct
 		logger.Debug("cleanExp", zap.Any("", cleanExp))
 		logger.Debug("cleanAct", zap.Any("", cleanAct))
+	} else if !matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") && hasComparator && bodyType != models.BodyTypeJSON {
+		diff, err := compareBodies(comparator, expBody, actBody, bodyNoise, ignoreOrdering)
+		if err != nil {
+			// the body doesn't follow its content type, so it is compared as plain text
+			logger.Debug("failed to compare the structured bodies", zap.Any("type", bodyType), zap.Error(err))
+			res.BodyResult[0].Type = models.BodyTypePlain
+			pass = tc.HTTPResp.Body == actualResponse.Body
+		} else {
+			structuredDiff = diff
+			pass = diff.empty()
+		}
 	} else {
 		if !matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") && tc.HTTPResp.Body != actualResponse.Body {
 			pass = false
 		}
 	}
 
-	res.BodyResult[0].Normal = pass
+	if !streamed {
+		res.BodyResult[0].Normal = pass
+	}
+
+	if bodyType == models.BodyTypeMultipart && sameMultipartType(expBody.ContentType, actBody.ContentType) {
+		// the boundaries of the multipart bodies are random, so they aren't compared
+		headerNoise = withNoise(headerNoise, "content-type")
+	}
 
 	if !matcherUtils.CompareHeaders(pkg.ToHTTPHeader(tc.HTTPResp.Header), pkg.ToHTTPHeader(actualResponse.Header), hRes, headerNoise) {
 
@@ -138,8 +156,17 @@ func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map
 				logDiffs.PushHeaderDiff(fmt.Sprint(j), fmt.Sprint(actualHeader[i]), i, headerNoise)
 			}
 		}
-		if !res.BodyResult[0].Normal {
-			if json.Valid([]byte(actualResponse.Body)) {
+		if streamed {
+			for _, j := range res.BodyResult {
+				if !j.Normal {
+					logDiffs.PushBodyDiff(joinEvents(res.BodyResult, true), joinEvents(res.BodyResult, false), bodyNoise)
+					break
+				}
+			}
+		} else if !res.BodyResult[0].Normal {
+			if binaryBody {
+				logDiffs.PushBodyDiff(res.BodyResult[0].Expected, res.BodyResult[0].Actual, bodyNoise)
+			} else if json.Valid([]byte(actualResponse.Body)) {
 				patch, err := jsondiff.Compare(tc.HTTPResp.Body, actualResponse.Body)
 				if err != nil {
 					logger.Warn("failed to compute json diff", zap.Error(err))
@@ -184,6 +211,9 @@ func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map
 					}
 					logDiffs.PushBodyDiff(fmt.Sprint(op.OldValue), fmt.Sprint(op.Value), bodyNoise)
 				}
+			} else if !structuredDiff.empty() {
+				exp, act := structuredDiff.strings()
+				logDiffs.PushBodyDiff(exp, act, bodyNoise)
 			} else {
 				logDiffs.PushBodyDiff(fmt.Sprint(tc.HTTPResp.Body), fmt.Sprint(actualResponse.Body), bodyNoise)
 			}
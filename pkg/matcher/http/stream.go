@@ -0,0 +1,184 @@
+package http
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// streamNoise is the noise of an event of a streamed response.
+type streamNoise struct {
+	event  bool
+	fields map[string]bool
+	data   map[string][]string
+}
+
+// streamNoiseOf returns the noise of the event at index i. The body noise applies to the data of every event,
+// the noise of a single event is set with stream.<i>, stream.<i>.<event|id|retry> and stream.<i>.data.<path>,
+// i being * for every event.
+func streamNoiseOf(noise map[string][]string, bodyNoise map[string][]string, i int) streamNoise {
+	n := streamNoise{fields: map[string]bool{}, data: map[string][]string{}}
+	for path, regexArr := range bodyNoise {
+		n.data[path] = regexArr
+	}
+	for field, regexArr := range noise {
+		a := strings.Split(strings.ToLower(field), ".")
+		if a[0] != "stream" {
+			continue
+		}
+		if len(a) == 1 {
+			n.event = true
+			continue
+		}
+		if a[1] != "*" && a[1] != strconv.Itoa(i) {
+			continue
+		}
+		switch {
+		case len(a) == 2:
+			n.event = true
+		case a[2] == "data" && len(a) == 3:
+			n.fields["data"] = true
+		case a[2] == "data":
+			n.data[strings.Join(a[3:], ".")] = regexArr
+		default:
+			n.fields[a[2]] = true
+		}
+	}
+	return n
+}
+
+// matchStream compares the events of the streamed responses one by one. The data of the events are compared as
+// JSON when both of them are JSON, and as text otherwise. The offsets of the events aren't compared, as they
+// depend on the load of the application.
+func matchStream(logger *zap.Logger, exp, act []models.HTTPStreamEvent, noise map[string][]string, bodyNoise map[string][]string, ignoreOrdering bool) (bool, []models.BodyResult) {
+	pass := true
+	wholeBody := matcherUtils.Contains(matcherUtils.MapToArray(noise), "body")
+	var results []models.BodyResult
+	for i := 0; i < len(exp) || i < len(act); i++ {
+		res := models.BodyResult{Type: models.BodyTypePlain}
+		if i < len(exp) {
+			res.Expected = exp[i].String()
+		}
+		if i < len(act) {
+			res.Actual = act[i].String()
+		}
+		if i >= len(exp) || i >= len(act) {
+			res.Normal = wholeBody
+			pass = pass && res.Normal
+			results = append(results, res)
+			continue
+		}
+
+		// the events carrying JSON data alone are shown as JSON
+		if json.Valid([]byte(res.Expected)) && json.Valid([]byte(res.Actual)) {
+			res.Type = models.BodyTypeJSON
+		}
+		n := streamNoiseOf(noise, bodyNoise, i)
+		res.Normal = wholeBody || n.event || (sameEventFields(exp[i], act[i], n.fields) && sameEventData(logger, exp[i].Data, act[i].Data, n, ignoreOrdering))
+		pass = pass && res.Normal
+		results = append(results, res)
+	}
+	return pass, results
+}
+
+// sameEventFields compares the fields of a pair of events other than their data.
+func sameEventFields(exp, act models.HTTPStreamEvent, noisy map[string]bool) bool {
+	return (noisy["event"] || exp.Event == act.Event) &&
+		(noisy["id"] || exp.ID == act.ID) &&
+		(noisy["retry"] || exp.Retry == act.Retry)
+}
+
+// sameEventData compares the data of a pair of events.
+func sameEventData(logger *zap.Logger, exp, act string, n streamNoise, ignoreOrdering bool) bool {
+	if n.fields["data"] {
+		return true
+	}
+	if !json.Valid([]byte(exp)) || !json.Valid([]byte(act)) {
+		return exp == act
+	}
+	validatedJSON, err := matcherUtils.ValidateAndMarshalJSON(logger, &exp, &act)
+	if err != nil || !validatedJSON.IsIdentical() {
+		return false
+	}
+	jsonComparisonResult, err := matcherUtils.JSONDiffWithNoiseControl(validatedJSON, n.data, ignoreOrdering)
+	return err == nil && jsonComparisonResult.IsExact()
+}
+
+// joinEvents renders all the expected or actual events as a single body for the diff. The events form a
+// JSON array when all of them are JSON, so that the diff is computed per field.
+func joinEvents(results []models.BodyResult, expected bool) string {
+	isJSON := true
+	events := make([]string, 0, len(results))
+	for _, res := range results {
+		event := res.Actual
+		if expected {
+			event = res.Expected
+		}
+		if event == "" {
+			continue
+		}
+		isJSON = isJSON && res.Type == models.BodyTypeJSON
+		events = append(events, event)
+	}
+	if isJSON {
+		return "[" + strings.Join(events, ",") + "]"
+	}
+	return strings.Join(events, "\n\n")
+}
+
+// FlattenHTTPStream flattens the events of a streamed response the way FlattenHTTPResponse flattens a body, the
+// fields of the data of the event at index i being under stream.<i>.data. The data which isn't JSON is left out.
+func FlattenHTTPStream(stream []models.HTTPStreamEvent) (map[string][]string, error) {
+	m := map[string][]string{}
+	for i, event := range stream {
+		prefix := "stream." + strconv.Itoa(i)
+		m[prefix+".event"] = []string{event.Event}
+		m[prefix+".id"] = []string{event.ID}
+		m[prefix+".retry"] = []string{event.Retry}
+		if !json.Valid([]byte(event.Data)) {
+			continue
+		}
+		data := map[string][]string{}
+		err := matcherUtils.AddHTTPBodyToMap(event.Data, data)
+		if err != nil {
+			return m, err
+		}
+		for k, v := range data {
+			m[prefix+".data"+strings.TrimPrefix(k, "body")] = v
+		}
+	}
+	return m, nil
+}
+
+// WebSocketResponse returns the response expected from the session of a websocket testcase: the handshake
+// response, with the messages of the server as its events. The extensions aren't negotiated on replay, so
+// their header is left out.
+func WebSocketResponse(tc *models.TestCase) models.HTTPResp {
+	resp := tc.HTTPResp
+	resp.Header = models.HTTPHeader{}
+	for key, values := range tc.HTTPResp.Header {
+		if !strings.EqualFold(key, "Sec-WebSocket-Extensions") {
+			resp.Header[key] = values
+		}
+	}
+	resp.Stream = nil
+	for _, msg := range tc.WebSocket {
+		if msg.Origin == models.FromServer {
+			resp.Stream = append(resp.Stream, msg.StreamEvent())
+		}
+	}
+	return resp
+}
+
+// MatchWebSocket compares the session of a websocket testcase with the replayed one. The messages of the server
+// are compared as the events of a stream, so that the noise of the event at index i is set with stream.<i>.
+func MatchWebSocket(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map[string]map[string][]string, ignoreOrdering bool, logger *zap.Logger) (bool, *models.Result) {
+	expected := *tc
+	expected.HTTPResp = WebSocketResponse(tc)
+	return Match(&expected, actualResponse, noiseConfig, ignoreOrdering, logger)
+}
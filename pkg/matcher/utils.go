@@ -10,6 +10,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -707,6 +708,27 @@ func checkKey(res *[]models.HeaderResult, key string) bool {
 	return true
 }
 
+// sameHeaderValues tells whether two headers have the same values. A header sent on several lines is the
+// same as the comma-separated list of its values on a single line (RFC 9110, section 5.3), which is also how
+// the headers were recorded before they could have several values.
+func sameHeaderValues(exp []string, act []string) bool {
+	if slices.Equal(exp, act) {
+		return true
+	}
+	return slices.Equal(headerList(exp), headerList(act))
+}
+
+// headerList splits the values of a header into the elements of their comma-separated lists.
+func headerList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, elem := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(elem))
+		}
+	}
+	return list
+}
+
 func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult, noise map[string][]string) bool {
 	if res == nil {
 		return false
@@ -716,7 +738,7 @@ func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult,
 	for k, v := range h1 {
 		regexArr, isNoisy := CheckStringExist(strings.ToLower(k), noise)
 		if isNoisy && len(regexArr) != 0 {
-			isNoisy, _ = MatchesAnyRegex(v[0], regexArr)
+			isNoisy, _ = MatchesAnyRegex(strings.Join(v, ", "), regexArr)
 		}
 		isNoisy = isNoisy || isHeaderNoisy
 		val, ok := h2[k]
@@ -739,7 +761,7 @@ func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult,
 				match = false
 				continue
 			}
-			if len(v) != len(val) {
+			if !sameHeaderValues(v, val) {
 				if checkKey(res, k) {
 					*res = append(*res, models.HeaderResult{
 						Normal: false,
@@ -756,25 +778,6 @@ func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult,
 				match = false
 				continue
 			}
-			for i, e := range v {
-				if val[i] != e {
-					if checkKey(res, k) {
-						*res = append(*res, models.HeaderResult{
-							Normal: false,
-							Expected: models.Header{
-								Key:   k,
-								Value: v,
-							},
-							Actual: models.Header{
-								Key:   k,
-								Value: val,
-							},
-						})
-					}
-					match = false
-					continue
-				}
-			}
 		}
 		if checkKey(res, k) {
 			*res = append(*res, models.HeaderResult{
@@ -793,7 +796,7 @@ func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult,
 	for k, v := range h2 {
 		regexArr, isNoisy := CheckStringExist(strings.ToLower(k), noise)
 		if isNoisy && len(regexArr) != 0 {
-			isNoisy, _ = MatchesAnyRegex(v[0], regexArr)
+			isNoisy, _ = MatchesAnyRegex(strings.Join(v, ", "), regexArr)
 		}
 		isNoisy = isNoisy || isHeaderNoisy
 		val, ok := h1[k]
@@ -832,6 +835,28 @@ func CompareHeaders(h1 http.Header, h2 http.Header, res *[]models.HeaderResult,
 	return match
 }
 
+// SplitNoise returns the body and header noise used to compare a test case: the noise of the config along
+// with the body.<path> and header.<name> entries of the noise of the test case. The maps returned are new,
+// as the noise config is shared by the test cases compared concurrently.
+func SplitNoise(noise map[string][]string, noiseConfig map[string]map[string][]string) (map[string][]string, map[string][]string) {
+	bodyNoise, headerNoise := map[string][]string{}, map[string][]string{}
+	for field, regexArr := range noiseConfig["body"] {
+		bodyNoise[field] = regexArr
+	}
+	for field, regexArr := range noiseConfig["header"] {
+		headerNoise[field] = regexArr
+	}
+	for field, regexArr := range noise {
+		a := strings.Split(field, ".")
+		if len(a) > 1 && a[0] == "body" {
+			bodyNoise[strings.ToLower(strings.Join(a[1:], "."))] = regexArr
+		} else if a[0] == "header" {
+			headerNoise[strings.ToLower(a[len(a)-1])] = regexArr
+		}
+	}
+	return bodyNoise, headerNoise
+}
+
 func MapToArray(mp map[string][]string) []string {
 	var result []string
 	for k := range mp {
@@ -0,0 +1,52 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitNoise(t *testing.T) {
+	tests := []struct {
+		name        string
+		noise       map[string][]string
+		noiseConfig map[string]map[string][]string
+		wantBody    map[string][]string
+		wantHeader  map[string][]string
+	}{
+		{
+			name:       "no noise",
+			wantBody:   map[string][]string{},
+			wantHeader: map[string][]string{},
+		},
+		{
+			name:       "noise of the test case",
+			noise:      map[string][]string{"body.Data.ID": {}, "header.Date": {"^Mon"}, "body": {}},
+			wantBody:   map[string][]string{"data.id": {}},
+			wantHeader: map[string][]string{"date": {"^Mon"}},
+		},
+		{
+			name:        "noise of the config overridden by the test case",
+			noise:       map[string][]string{"body.ts": {"[0-9]+"}},
+			noiseConfig: map[string]map[string][]string{"body": {"ts": {}, "id": {}}, "header": {"etag": {}}},
+			wantBody:    map[string][]string{"ts": {"[0-9]+"}, "id": {}},
+			wantHeader:  map[string][]string{"etag": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := map[string]int{"body": len(tt.noiseConfig["body"]), "header": len(tt.noiseConfig["header"])}
+			body, header := SplitNoise(tt.noise, tt.noiseConfig)
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("body noise = %v, want %v", body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(header, tt.wantHeader) {
+				t.Errorf("header noise = %v, want %v", header, tt.wantHeader)
+			}
+			for key, n := range before {
+				if len(tt.noiseConfig[key]) != n {
+					t.Errorf("noise config %s = %v, want it left untouched", key, tt.noiseConfig[key])
+				}
+			}
+		})
+	}
+}
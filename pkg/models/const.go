@@ -40,30 +40,30 @@ var IsAnsiDisabled = false
 
 var HighlightString = func(a ...interface{}) string {
 	if IsAnsiDisabled {
-		return fmt.Sprint(a)
+		return fmt.Sprint(a...)
 	}
-	return color.New(orangeColorSGR...).SprintFunc()(a)
+	return color.New(orangeColorSGR...).SprintFunc()(a...)
 }
 
 var HighlightPassingString = func(a ...interface{}) string {
 	if IsAnsiDisabled {
-		return fmt.Sprint(a)
+		return fmt.Sprint(a...)
 	}
-	return color.New(color.FgGreen).SprintFunc()(a)
+	return color.New(color.FgGreen).SprintFunc()(a...)
 }
 
 var HighlightFailingString = func(a ...interface{}) string {
 	if IsAnsiDisabled {
-		return fmt.Sprint(a)
+		return fmt.Sprint(a...)
 	}
-	return color.New(color.FgRed).SprintFunc()(a)
+	return color.New(color.FgRed).SprintFunc()(a...)
 }
 
 var HighlightGrayString = func(a ...interface{}) string {
 	if IsAnsiDisabled {
-		return fmt.Sprint(a)
+		return fmt.Sprint(a...)
 	}
-	return color.New(color.FgHiBlack).SprintFunc()(a)
+	return color.New(color.FgHiBlack).SprintFunc()(a...)
 }
 
 var defaultColorScheme = pp.ColorScheme{
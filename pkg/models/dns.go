@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// DNSSchema is the yaml schema of a DNS mock, i.e. a question asked by the application and the
+// answer of the resolver.
+type DNSSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          DNSReq            `json:"req" yaml:"req"`
+	Response         DNSResp           `json:"resp" yaml:"resp"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+type DNSReq struct {
+	Name  string `json:"name" yaml:"name"`   // fully qualified domain name, e.g. "_grpc._tcp.users.svc."
+	Qtype string `json:"qtype" yaml:"qtype"` // type of the record asked for, e.g. "SRV"
+}
+
+type DNSResp struct {
+	Rcode   string   `json:"rcode" yaml:"rcode"`                         // response code, e.g. "NOERROR" or "NXDOMAIN"
+	Answers []string `json:"answers,omitempty" yaml:"answers,omitempty"` // resource records in the zone file format
+}
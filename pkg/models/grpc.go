@@ -9,6 +9,9 @@ type GrpcSpec struct {
 	GrpcResp         GrpcResp  `json:"grpcResp" yaml:"grpcResp"`
 	ReqTimestampMock time.Time `json:"reqTimestampMock" yaml:"reqTimestampMock,omitempty"`
 	ResTimestampMock time.Time `json:"resTimestampMock" yaml:"resTimestampMock,omitempty"`
+	// Created and Assertions are only set for the gRPC test cases.
+	Created    int64                  `json:"created" yaml:"created,omitempty"`
+	Assertions map[string]interface{} `json:"assertions" yaml:"assertions,omitempty"`
 }
 
 type GrpcHeaders struct {
@@ -19,18 +22,61 @@ type GrpcHeaders struct {
 type GrpcLengthPrefixedMessage struct {
 	CompressionFlag uint   `json:"compression_flag" yaml:"compression_flag"`
 	MessageLength   uint32 `json:"message_length" yaml:"message_length"`
-	DecodedData     string `json:"decoded_data" yaml:"decoded_data"`
+	DecodedData     string `json:"decoded_data,omitempty" yaml:"decoded_data,omitempty"`
+	// DecodedJSON holds the message in the protobuf JSON format. It is only set when the
+	// descriptor of the message is known, in which case DecodedData is left empty.
+	DecodedJSON string `json:"decoded_json,omitempty" yaml:"decoded_json,omitempty"`
+}
+
+// GrpcStreamMessage is a single length-prefixed message sent on a streaming rpc.
+type GrpcStreamMessage struct {
+	GrpcLengthPrefixedMessage `yaml:",inline"`
+	// Offset is the time elapsed between the opening of the stream and this message.
+	Offset time.Duration `json:"offset" yaml:"offset"`
+	// AfterRequests is the number of request messages received by the server before it
+	// sent this message. It is only set for response messages.
+	AfterRequests int `json:"after_requests,omitempty" yaml:"after_requests,omitempty"`
 }
 
 type GrpcReq struct {
 	Headers GrpcHeaders               `json:"headers" yaml:"headers"`
 	Body    GrpcLengthPrefixedMessage `json:"body" yaml:"body"`
+	// Stream holds every message of a client-streaming or bidi call in the order they were sent.
+	// It is left empty for unary requests, whose only message is stored in Body.
+	Stream []GrpcStreamMessage `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// Timestamp is only set for the gRPC test cases, the mocks keep it in the GrpcSpec.
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp,omitempty"`
+}
+
+// Messages returns the ordered request messages, falling back to Body for unary requests.
+func (req GrpcReq) Messages() []GrpcStreamMessage {
+	if len(req.Stream) > 0 {
+		return req.Stream
+	}
+	return []GrpcStreamMessage{{GrpcLengthPrefixedMessage: req.Body}}
 }
 
 type GrpcResp struct {
 	Headers  GrpcHeaders               `json:"headers" yaml:"headers"`
 	Body     GrpcLengthPrefixedMessage `json:"body" yaml:"body"`
 	Trailers GrpcHeaders               `json:"trailers" yaml:"trailers"`
+	// Stream holds every message of a server-streaming or bidi call in the order they were sent.
+	// It is left empty for unary responses, whose only message is stored in Body.
+	Stream []GrpcStreamMessage `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// Timestamp is only set for the gRPC test cases, the mocks keep it in the GrpcSpec.
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp,omitempty"`
+}
+
+// Messages returns the ordered response messages, falling back to Body for unary responses.
+// A trailers-only response (no response headers were sent) carries no message at all.
+func (resp GrpcResp) Messages() []GrpcStreamMessage {
+	if len(resp.Stream) > 0 {
+		return resp.Stream
+	}
+	if len(resp.Headers.PseudoHeaders) == 0 && len(resp.Headers.OrdinaryHeaders) == 0 {
+		return nil
+	}
+	return []GrpcStreamMessage{{GrpcLengthPrefixedMessage: resp.Body}}
 }
 
 // GrpcStream is a helper function to combine the request-response model in a single struct.
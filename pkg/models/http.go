@@ -1,7 +1,15 @@
 package models
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Method string
@@ -12,11 +20,13 @@ type HTTPReq struct {
 	ProtoMinor int               `json:"proto_minor" yaml:"proto_minor"` // e.g. 0
 	URL        string            `json:"url" yaml:"url"`
 	URLParams  map[string]string `json:"url_params" yaml:"url_params,omitempty"`
-	Header     map[string]string `json:"header" yaml:"header"`
+	Header     HTTPHeader        `json:"header" yaml:"header"`
 	Body       string            `json:"body" yaml:"body"`
-	Binary     string            `json:"binary" yaml:"binary,omitempty"`
-	Form       []FormData        `json:"form" yaml:"form,omitempty"`
-	Timestamp  time.Time         `json:"timestamp" yaml:"timestamp"`
+	// BodyEncoding is the encoding of the body in the yaml files, the body itself always holds the raw bytes.
+	BodyEncoding BodyEncoding `json:"body_encoding,omitempty" yaml:"body_encoding,omitempty"`
+	Binary       string       `json:"binary" yaml:"binary,omitempty"`
+	Form         []FormData   `json:"form" yaml:"form,omitempty"`
+	Timestamp    time.Time    `json:"timestamp" yaml:"timestamp"`
 }
 
 type HTTPSchema struct {
@@ -37,12 +47,269 @@ type FormData struct {
 }
 
 type HTTPResp struct {
-	StatusCode    int               `json:"status_code" yaml:"status_code"` // e.g. 200
-	Header        map[string]string `json:"header" yaml:"header"`
-	Body          string            `json:"body" yaml:"body"`
-	StatusMessage string            `json:"status_message" yaml:"status_message"`
-	ProtoMajor    int               `json:"proto_major" yaml:"proto_major"`
-	ProtoMinor    int               `json:"proto_minor" yaml:"proto_minor"`
-	Binary        string            `json:"binary" yaml:"binary,omitempty"`
-	Timestamp     time.Time         `json:"timestamp" yaml:"timestamp"`
+	StatusCode int        `json:"status_code" yaml:"status_code"` // e.g. 200
+	Header     HTTPHeader `json:"header" yaml:"header"`
+	Body       string     `json:"body" yaml:"body"`
+	// BodyEncoding is the encoding of the body in the yaml files, the body itself always holds the raw bytes.
+	BodyEncoding BodyEncoding `json:"body_encoding,omitempty" yaml:"body_encoding,omitempty"`
+	// Stream holds the events of a streamed response, i.e. server-sent events or newline-delimited JSON, in the
+	// order they were sent. The body is left empty for the streamed responses.
+	Stream []HTTPStreamEvent `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// StreamCutOff tells whether the stream was cut off by its client while being recorded, in which case only
+	// the recorded events are read on replay, as the application doesn't end the stream.
+	StreamCutOff  bool      `json:"stream_cut_off,omitempty" yaml:"stream_cut_off,omitempty"`
+	StatusMessage string    `json:"status_message" yaml:"status_message"`
+	ProtoMajor    int       `json:"proto_major" yaml:"proto_major"`
+	ProtoMinor    int       `json:"proto_minor" yaml:"proto_minor"`
+	Binary        string    `json:"binary" yaml:"binary,omitempty"`
+	Timestamp     time.Time `json:"timestamp" yaml:"timestamp"`
+}
+
+// HTTPStreamEvent is a single event of a streamed http response. The lines of newline-delimited streams only
+// carry data, the other fields are the ones of the server-sent events.
+type HTTPStreamEvent struct {
+	// Offset is the time elapsed between the request and this event.
+	Offset time.Duration `json:"offset" yaml:"offset"`
+	Event  string        `json:"event,omitempty" yaml:"event,omitempty"`
+	ID     string        `json:"id,omitempty" yaml:"id,omitempty"`
+	Retry  string        `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Data   string        `json:"data" yaml:"data"`
+}
+
+// String returns the event as sent on a server-sent events stream, the data alone if it carries no other field.
+func (e HTTPStreamEvent) String() string {
+	if e.Event == "" && e.ID == "" && e.Retry == "" {
+		return e.Data
+	}
+	var lines []string
+	if e.Event != "" {
+		lines = append(lines, "event: "+e.Event)
+	}
+	if e.ID != "" {
+		lines = append(lines, "id: "+e.ID)
+	}
+	if e.Retry != "" {
+		lines = append(lines, "retry: "+e.Retry)
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		lines = append(lines, "data: "+line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HTTPHeader holds the values of the http headers, a header sent on several lines having several values. The
+// headers with a single value are written as strings in the yaml files, as they were before they could have
+// several values, so that the existing files are read as they are.
+type HTTPHeader map[string][]string
+
+// Get returns the first value of a header, "" if it isn't set.
+func (h HTTPHeader) Get(key string) string {
+	if values := h[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values of a header by a single value.
+func (h HTTPHeader) Set(key string, value string) {
+	h[key] = []string{value}
+}
+
+func (h HTTPHeader) MarshalYAML() (interface{}, error) {
+	if h == nil {
+		return map[string]string{}, nil
+	}
+	out := make(map[string]interface{}, len(h))
+	for key, values := range h {
+		if len(values) == 1 {
+			out[key] = values[0]
+			continue
+		}
+		out[key] = values
+	}
+	return out, nil
+}
+
+func (h *HTTPHeader) UnmarshalYAML(node *yaml.Node) error {
+	var raw map[string]yaml.Node
+	err := node.Decode(&raw)
+	if err != nil {
+		return err
+	}
+	*h = make(HTTPHeader, len(raw))
+	for key, value := range raw {
+		if value.Kind == yaml.SequenceNode {
+			var values []string
+			err := value.Decode(&values)
+			if err != nil {
+				return fmt.Errorf("failed to decode the values of the header %s: %w", key, err)
+			}
+			(*h)[key] = values
+			continue
+		}
+		(*h)[key] = []string{value.Value}
+	}
+	return nil
+}
+
+func (h *HTTPHeader) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	*h = make(HTTPHeader, len(raw))
+	for key, value := range raw {
+		var values []string
+		if json.Unmarshal(value, &values) == nil {
+			(*h)[key] = values
+			continue
+		}
+		var single string
+		err := json.Unmarshal(value, &single)
+		if err != nil {
+			return fmt.Errorf("failed to decode the values of the header %s: %w", key, err)
+		}
+		(*h)[key] = []string{single}
+	}
+	return nil
+}
+
+// BodyEncoding is the encoding of a body in the yaml files.
+type BodyEncoding string
+
+const (
+	// BodyEncodingUTF8 is the encoding of the text bodies, also assumed when the encoding is empty.
+	BodyEncodingUTF8 BodyEncoding = "utf-8"
+	// BodyEncodingBase64 is the encoding of the binary bodies, such as the compressed, protobuf or image payloads.
+	BodyEncodingBase64 BodyEncoding = "base64"
+)
+
+// EncodingOf returns the encoding of a body: base64 for the binary bodies, i.e. the ones which aren't valid
+// utf-8 or which hold control characters, and none, meaning utf-8, for the text bodies.
+func EncodingOf(body string) BodyEncoding {
+	if !utf8.ValidString(body) {
+		return BodyEncodingBase64
+	}
+	for _, r := range body {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return BodyEncodingBase64
+		}
+	}
+	return ""
+}
+
+// IsBinary tells whether a body is written in base64.
+func (e BodyEncoding) IsBinary() bool {
+	return e == BodyEncodingBase64
+}
+
+// encodeBody returns a body as written in the yaml files, along with its encoding.
+func encodeBody(body string, encoding BodyEncoding) (string, BodyEncoding) {
+	if encoding.IsBinary() || EncodingOf(body).IsBinary() {
+		return base64.StdEncoding.EncodeToString([]byte(body)), BodyEncodingBase64
+	}
+	return body, encoding
+}
+
+// decodeBody returns the raw bytes of a body read from the yaml files.
+func decodeBody(body string, encoding BodyEncoding) (string, error) {
+	if !encoding.IsBinary() {
+		return body, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the base64 body: %w", err)
+	}
+	return string(raw), nil
+}
+
+// the bodies are encoded the same way in the yaml files and in json, which the templates are rendered on
+
+func (req HTTPReq) MarshalYAML() (interface{}, error) {
+	type plain HTTPReq
+	p := plain(req)
+	p.Body, p.BodyEncoding = encodeBody(req.Body, req.BodyEncoding)
+	return p, nil
+}
+
+func (req *HTTPReq) UnmarshalYAML(node *yaml.Node) error {
+	type plain HTTPReq
+	var p plain
+	err := node.Decode(&p)
+	if err != nil {
+		return err
+	}
+	p.Body, err = decodeBody(p.Body, p.BodyEncoding)
+	if err != nil {
+		return err
+	}
+	*req = HTTPReq(p)
+	return nil
+}
+
+func (req HTTPReq) MarshalJSON() ([]byte, error) {
+	type plain HTTPReq
+	p := plain(req)
+	p.Body, p.BodyEncoding = encodeBody(req.Body, req.BodyEncoding)
+	return json.Marshal(p)
+}
+
+func (req *HTTPReq) UnmarshalJSON(data []byte) error {
+	type plain HTTPReq
+	var p plain
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return err
+	}
+	p.Body, err = decodeBody(p.Body, p.BodyEncoding)
+	if err != nil {
+		return err
+	}
+	*req = HTTPReq(p)
+	return nil
+}
+
+func (resp HTTPResp) MarshalYAML() (interface{}, error) {
+	type plain HTTPResp
+	p := plain(resp)
+	p.Body, p.BodyEncoding = encodeBody(resp.Body, resp.BodyEncoding)
+	return p, nil
+}
+
+func (resp *HTTPResp) UnmarshalYAML(node *yaml.Node) error {
+	type plain HTTPResp
+	var p plain
+	err := node.Decode(&p)
+	if err != nil {
+		return err
+	}
+	p.Body, err = decodeBody(p.Body, p.BodyEncoding)
+	if err != nil {
+		return err
+	}
+	*resp = HTTPResp(p)
+	return nil
+}
+
+func (resp HTTPResp) MarshalJSON() ([]byte, error) {
+	type plain HTTPResp
+	p := plain(resp)
+	p.Body, p.BodyEncoding = encodeBody(resp.Body, resp.BodyEncoding)
+	return json.Marshal(p)
+}
+
+func (resp *HTTPResp) UnmarshalJSON(data []byte) error {
+	type plain HTTPResp
+	var p plain
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return err
+	}
+	p.Body, err = decodeBody(p.Body, p.BodyEncoding)
+	if err != nil {
+		return err
+	}
+	*resp = HTTPResp(p)
+	return nil
 }
@@ -0,0 +1,226 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestHTTPHeaderYAML(t *testing.T) {
+	tests := []struct {
+		name   string
+		header HTTPHeader
+		yaml   string // a part of the yaml document
+	}{
+		{name: "single value", header: HTTPHeader{"Content-Type": {"application/json"}}, yaml: "Content-Type: application/json"},
+		{
+			name:   "multiple values",
+			header: HTTPHeader{"Set-Cookie": {"a=1; Path=/", "b=2; Path=/"}},
+			yaml:   "Set-Cookie:\n    - a=1; Path=/\n    - b=2; Path=/",
+		},
+		{
+			name:   "values holding commas",
+			header: HTTPHeader{"Www-Authenticate": {`Basic realm="a, b"`, `Bearer realm="c"`}, "Accept": {"text/html"}},
+			yaml:   "Accept: text/html",
+		},
+		{name: "empty", header: HTTPHeader{}, yaml: "{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := yaml.Marshal(tt.header)
+			if err != nil {
+				t.Fatalf("yaml.Marshal() error = %v", err)
+			}
+			if !strings.Contains(string(out), tt.yaml) {
+				t.Errorf("yaml.Marshal() = %s, want it to contain %s", out, tt.yaml)
+			}
+			var got HTTPHeader
+			if err := yaml.Unmarshal(out, &got); err != nil {
+				t.Fatalf("yaml.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.header) {
+				t.Errorf("yaml.Unmarshal() = %v, want %v", got, tt.header)
+			}
+		})
+	}
+}
+
+// TestHTTPHeaderDecode checks that the headers of the files written before the repeated headers were kept,
+// which hold a single value by header, are still read.
+func TestHTTPHeaderDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		json    string
+		want    HTTPHeader
+		wantErr bool
+	}{
+		{
+			name: "single values",
+			yaml: "Content-Type: application/json\nContent-Length: \"42\"\n",
+			json: `{"Content-Type":"application/json","Content-Length":"42"}`,
+			want: HTTPHeader{"Content-Type": {"application/json"}, "Content-Length": {"42"}},
+		},
+		{
+			name: "single and multiple values",
+			yaml: "Accept: text/html\nSet-Cookie:\n  - a=1\n  - b=2\n",
+			json: `{"Accept":"text/html","Set-Cookie":["a=1","b=2"]}`,
+			want: HTTPHeader{"Accept": {"text/html"}, "Set-Cookie": {"a=1", "b=2"}},
+		},
+		{
+			name:    "invalid values",
+			yaml:    "Accept:\n  - a: b\n",
+			json:    `{"Accept":{"a":"b"}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromYAML HTTPHeader
+			err := yaml.Unmarshal([]byte(tt.yaml), &fromYAML)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("yaml.Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var fromJSON HTTPHeader
+			err = json.Unmarshal([]byte(tt.json), &fromJSON)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(fromYAML, tt.want) {
+				t.Errorf("yaml.Unmarshal() = %v, want %v", fromYAML, tt.want)
+			}
+			if !reflect.DeepEqual(fromJSON, tt.want) {
+				t.Errorf("json.Unmarshal() = %v, want %v", fromJSON, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodingOf(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want BodyEncoding
+	}{
+		{name: "empty", body: "", want: ""},
+		{name: "json", body: `{"a":"é"}`, want: ""},
+		{name: "text with whitespaces", body: "a\tb\r\nc\n", want: ""},
+		{name: "invalid utf-8", body: "\xff\xfe", want: BodyEncodingBase64},
+		{name: "control characters", body: "\x00\x01abc", want: BodyEncodingBase64},
+		{name: "gzip", body: "\x1f\x8b\x08\x00", want: BodyEncodingBase64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodingOf(tt.body); got != tt.want {
+				t.Errorf("EncodingOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPBodyEncoding(t *testing.T) {
+	binary := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	tests := []struct {
+		name     string
+		body     string
+		encoding BodyEncoding
+		written  string // the body as written in the files
+	}{
+		{name: "text", body: `{"a":1}`, written: `{"a":1}`},
+		{name: "binary", body: binary, encoding: BodyEncodingBase64, written: "iVBORw0KGgoAAAANSUhEUg=="},
+		{name: "binary without its encoding", body: binary, written: "iVBORw0KGgoAAAANSUhEUg=="},
+		{name: "text marked as binary", body: "abc", encoding: BodyEncodingBase64, written: "YWJj"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := HTTPReq{Method: "POST", Header: HTTPHeader{"Content-Type": {"image/png"}}, Body: tt.body, BodyEncoding: tt.encoding}
+			resp := HTTPResp{StatusCode: 200, Header: HTTPHeader{}, Body: tt.body, BodyEncoding: tt.encoding}
+
+			out, err := yaml.Marshal(req)
+			if err != nil {
+				t.Fatalf("yaml.Marshal() error = %v", err)
+			}
+			var plain struct {
+				Body string `yaml:"body"`
+			}
+			if err := yaml.Unmarshal(out, &plain); err != nil {
+				t.Fatal(err)
+			}
+			if plain.Body != tt.written {
+				t.Errorf("yaml.Marshal() body = %q, want %q", plain.Body, tt.written)
+			}
+
+			var gotReq HTTPReq
+			if err := yaml.Unmarshal(out, &gotReq); err != nil {
+				t.Fatalf("yaml.Unmarshal() error = %v", err)
+			}
+			if gotReq.Body != tt.body {
+				t.Errorf("yaml round trip of the request body = %q, want %q", gotReq.Body, tt.body)
+			}
+
+			out, err = yaml.Marshal(resp)
+			if err != nil {
+				t.Fatalf("yaml.Marshal() error = %v", err)
+			}
+			var gotResp HTTPResp
+			if err := yaml.Unmarshal(out, &gotResp); err != nil {
+				t.Fatalf("yaml.Unmarshal() error = %v", err)
+			}
+			if gotResp.Body != tt.body {
+				t.Errorf("yaml round trip of the response body = %q, want %q", gotResp.Body, tt.body)
+			}
+
+			data, err := json.Marshal(req)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			var gotJSON HTTPReq
+			if err := json.Unmarshal(data, &gotJSON); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if gotJSON.Body != tt.body {
+				t.Errorf("json round trip of the request body = %q, want %q", gotJSON.Body, tt.body)
+			}
+		})
+	}
+
+	var req HTTPReq
+	if err := yaml.Unmarshal([]byte("body: not base64!\nbody_encoding: base64\n"), &req); err == nil {
+		t.Error("yaml.Unmarshal() of an invalid base64 body didn't fail")
+	}
+}
+
+func TestHTTPStreamYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		resp HTTPResp
+	}{
+		{name: "ended by the application", resp: HTTPResp{StatusCode: 200, Header: HTTPHeader{}, Stream: []HTTPStreamEvent{{Offset: time.Second, Data: "a"}}}},
+		{name: "cut off by the client", resp: HTTPResp{StatusCode: 200, Header: HTTPHeader{}, Stream: []HTTPStreamEvent{{Data: "a"}, {Event: "update", Data: "b"}}, StreamCutOff: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := yaml.Marshal(tt.resp)
+			if err != nil {
+				t.Fatalf("yaml.Marshal() error = %v", err)
+			}
+			if got := strings.Contains(string(out), "stream_cut_off"); got != tt.resp.StreamCutOff {
+				t.Errorf("yaml.Marshal() = %s, want stream_cut_off written only when set", out)
+			}
+			var got HTTPResp
+			if err := yaml.Unmarshal(out, &got); err != nil {
+				t.Fatalf("yaml.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Stream, tt.resp.Stream) || got.StreamCutOff != tt.resp.StreamCutOff {
+				t.Errorf("yaml round trip = %+v, cut off %v, want %+v, cut off %v", got.Stream, got.StreamCutOff, tt.resp.Stream, tt.resp.StreamCutOff)
+			}
+		})
+	}
+}
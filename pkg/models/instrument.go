@@ -16,11 +16,19 @@ type HookOptions struct {
 type OutgoingOptions struct {
 	Rules         []config.BypassRule
 	MongoPassword string
+	// PostgresPassword is used to simulate the SCRAM-SHA-256 auth of postgres, which can't be replayed from the mocks.
+	PostgresPassword string
 	// TODO: role of SQLDelay should be mentioned in the comments.
 	SQLDelay       time.Duration // This is the same as Application delay.
 	FallBackOnMiss bool          // this enables to pass the request to the actual server if no mock is found during test mode.
 	Mocking        bool          // used to enable/disable mocking
 	DstCfg         *ConditionalDstCfg
+	Proto          config.Proto // protobuf definitions used to decode and match the gRPC messages
+	Redis          config.Redis // noise of the redis commands
+	// Noise holds the body noise of the test set, by the lower-cased path of the field. It is also ignored
+	// in the messages of the gRPC mocks decoded as JSON.
+	Noise       map[string][]string
+	ProtocolMap []config.ProtocolRule
 }
 
 type ConditionalDstCfg struct {
@@ -31,6 +39,7 @@ type ConditionalDstCfg struct {
 
 type IncomingOptions struct {
 	Filters []config.Filter
+	Proto   config.Proto
 }
 
 type SetupOptions struct {
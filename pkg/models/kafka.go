@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// KafkaSchema is the yaml schema of a kafka mock, i.e. a request of the client along with the
+// response of the broker.
+type KafkaSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          KafkaRequest      `json:"request" yaml:"request"`
+	Response         *KafkaResponse    `json:"response,omitempty" yaml:"response,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+type KafkaRequestHeader struct {
+	APIKey        int16  `json:"api_key" yaml:"api_key"`
+	APIName       string `json:"api_name,omitempty" yaml:"api_name,omitempty"`
+	APIVersion    int16  `json:"api_version" yaml:"api_version"`
+	CorrelationID int32  `json:"correlation_id" yaml:"correlation_id"`
+	ClientID      string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+}
+
+type KafkaRequest struct {
+	Header KafkaRequestHeader `json:"header" yaml:"header"`
+	// Body is the decoded body of the request, it is empty for the APIs which are not decoded.
+	Body map[string]interface{} `json:"body,omitempty" yaml:"body,omitempty"`
+	// RawBody is the base64 encoded body of the requests which are not decoded.
+	RawBody string `json:"raw_body,omitempty" yaml:"raw_body,omitempty"`
+}
+
+type KafkaResponse struct {
+	CorrelationID int32 `json:"correlation_id" yaml:"correlation_id"`
+	// Body is the decoded body of the response, it is there for readability as the payload is replayed.
+	Body map[string]interface{} `json:"body,omitempty" yaml:"body,omitempty"`
+	// Payload is the base64 encoded response frame, which is replayed with the correlation id of the request.
+	Payload string `json:"payload" yaml:"payload"`
+}
@@ -21,29 +21,45 @@ type TestModeInfo struct {
 	SortOrder  int  `json:"sortOrder,omitempty" bson:"SortOrder,omitempty"`
 }
 
+// MockState is a mock consumed while replaying a test case.
+type MockState struct {
+	Name  string `json:"name"`
+	Usage int    `json:"usage"` // number of times the mock has been used
+	// Unmatched is set for the dependency calls which matched no mock, in which case Name describes the
+	// call, e.g. "Http POST api.stripe.com/v1/charges", and Usage is the number of such calls.
+	Unmatched bool `json:"unmatched,omitempty"`
+	Kind      Kind `json:"kind,omitempty"`
+}
+
 func (m *Mock) GetKind() string {
 	return string(m.Kind)
 }
 
 type MockSpec struct {
-	Metadata          map[string]string `json:"Metadata,omitempty" bson:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	GenericRequests   []Payload         `json:"RequestBin,omitempty" bson:"generic_requests,omitempty"`
-	GenericResponses  []Payload         `json:"ResponseBin,omitempty" bson:"generic_responses,omitempty"`
-	RedisRequests     []Payload         `json:"redisRequests,omitempty" bson:"redis_requests,omitempty"`
-	RedisResponses    []Payload         `json:"redisResponses,omitempty" bson:"redis_responses,omitempty"`
-	HTTPReq           *HTTPReq          `json:"Req,omitempty" bson:"http_req,omitempty"`
-	HTTPResp          *HTTPResp         `json:"Res,omitempty" bson:"http_resp,omitempty"`
-	Created           int64             `json:"Created,omitempty" bson:"created,omitempty"`
-	MongoRequests     []MongoRequest    `json:"MongoRequests,omitempty" bson:"mongo_requests,omitempty"`
-	MongoResponses    []MongoResponse   `json:"MongoResponses,omitempty" bson:"mongo_responses,omitempty"`
-	PostgresRequests  []Backend         `json:"postgresRequests,omitempty" bson:"postgres_requests,omitempty"`
-	PostgresResponses []Frontend        `json:"postgresResponses,omitempty" bson:"postgres_responses,omitempty"`
-	GRPCReq           *GrpcReq          `json:"gRPCRequest,omitempty" bson:"grpc_req,omitempty"`
-	GRPCResp          *GrpcResp         `json:"grpcResponse,omitempty" bson:"grpc_resp,omitempty"`
-	MySQLRequests     []mysql.Request   `json:"MySqlRequests,omitempty" bson:"my_sql_requests,omitempty"`
-	MySQLResponses    []mysql.Response  `json:"MySqlResponses,omitempty" bson:"my_sql_responses,omitempty"`
-	ReqTimestampMock  time.Time         `json:"ReqTimestampMock,omitempty" bson:"req_timestamp_mock,omitempty"`
-	ResTimestampMock  time.Time         `json:"ResTimestampMock,omitempty" bson:"res_timestamp_mock,omitempty"`
+	Metadata          map[string]string  `json:"Metadata,omitempty" bson:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	GenericRequests   []Payload          `json:"RequestBin,omitempty" bson:"generic_requests,omitempty"`
+	GenericResponses  []Payload          `json:"ResponseBin,omitempty" bson:"generic_responses,omitempty"`
+	RedisRequests     []Payload          `json:"redisRequests,omitempty" bson:"redis_requests,omitempty"`
+	RedisResponses    []Payload          `json:"redisResponses,omitempty" bson:"redis_responses,omitempty"`
+	RedisCommands     []RedisCommand     `json:"redisCommands,omitempty" bson:"redis_commands,omitempty"`
+	HTTPReq           *HTTPReq           `json:"Req,omitempty" bson:"http_req,omitempty"`
+	HTTPResp          *HTTPResp          `json:"Res,omitempty" bson:"http_resp,omitempty"`
+	Created           int64              `json:"Created,omitempty" bson:"created,omitempty"`
+	MongoRequests     []MongoRequest     `json:"MongoRequests,omitempty" bson:"mongo_requests,omitempty"`
+	MongoResponses    []MongoResponse    `json:"MongoResponses,omitempty" bson:"mongo_responses,omitempty"`
+	PostgresRequests  []Backend          `json:"postgresRequests,omitempty" bson:"postgres_requests,omitempty"`
+	PostgresResponses []Frontend         `json:"postgresResponses,omitempty" bson:"postgres_responses,omitempty"`
+	GRPCReq           *GrpcReq           `json:"gRPCRequest,omitempty" bson:"grpc_req,omitempty"`
+	GRPCResp          *GrpcResp          `json:"grpcResponse,omitempty" bson:"grpc_resp,omitempty"`
+	MySQLRequests     []mysql.Request    `json:"MySqlRequests,omitempty" bson:"my_sql_requests,omitempty"`
+	MySQLResponses    []mysql.Response   `json:"MySqlResponses,omitempty" bson:"my_sql_responses,omitempty"`
+	DNSReq            *DNSReq            `json:"DNSReq,omitempty" bson:"dns_req,omitempty"`
+	DNSResp           *DNSResp           `json:"DNSResp,omitempty" bson:"dns_resp,omitempty"`
+	KafkaRequest      *KafkaRequest      `json:"KafkaRequest,omitempty" bson:"kafka_request,omitempty"`
+	KafkaResponse     *KafkaResponse     `json:"KafkaResponse,omitempty" bson:"kafka_response,omitempty"`
+	WebSocketMessages []WebSocketMessage `json:"WebSocketMessages,omitempty" bson:"websocket_messages,omitempty"`
+	ReqTimestampMock  time.Time          `json:"ReqTimestampMock,omitempty" bson:"req_timestamp_mock,omitempty"`
+	ResTimestampMock  time.Time          `json:"ResTimestampMock,omitempty" bson:"res_timestamp_mock,omitempty"`
 }
 
 // OutputBinary store the encoded binary output of the egress calls as base64-encoded strings
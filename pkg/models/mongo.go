@@ -59,6 +59,8 @@ type MongoHeader struct {
 	RequestID  int32              `json:"requestId" yaml:"requestId" bson:"request_id"`
 	ResponseTo int32              `json:"responseTo" yaml:"responseTo" bson:"response_to"`
 	Opcode     wiremessage.OpCode `json:"Opcode" yaml:"Opcode" bson:"opcode"`
+	// CompressorID is set when the message was sent as OP_COMPRESSED, Opcode is then the original opcode.
+	CompressorID wiremessage.CompressorID `json:"compressorId,omitempty" yaml:"compressorId,omitempty" bson:"compressor_id,omitempty"`
 }
 
 type MongoRequest struct {
@@ -6,8 +6,16 @@ import (
 
 type RedisSchema struct {
 	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
-	RedisRequests    []Payload         `json:"RequestBin,omitempty"`
+	RedisRequests    []Payload         `json:"RequestBin,omitempty" yaml:"redisrequests,omitempty"`
+	RedisCommands    []RedisCommand    `json:"commands,omitempty" yaml:"commands,omitempty"`
 	RedisResponses   []Payload         `json:"ResponseBin,omitempty"`
 	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty"`
 	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty"`
 }
+
+// RedisCommand is a command of the client decoded from RESP. The responses of the mock hold the
+// replies of the commands in the same order.
+type RedisCommand struct {
+	Name string   `json:"name" yaml:"name"`
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Kind string
 type BodyType string
 type Version string
@@ -22,18 +24,24 @@ func GetVersion() (V1 Version) {
 
 // mocks types
 const (
-	HTTP           Kind     = "Http"
-	GENERIC        Kind     = "Generic"
-	REDIS          Kind     = "Redis"
-	MySQL          Kind     = "MySQL"
-	Postgres       Kind     = "Postgres"
-	GRPC_EXPORT    Kind     = "gRPC"
-	Mongo          Kind     = "Mongo"
-	BodyTypeUtf8   BodyType = "utf-8"
-	BodyTypeBinary BodyType = "binary"
-	BodyTypePlain  BodyType = "PLAIN"
-	BodyTypeJSON   BodyType = "JSON"
-	BodyTypeError  BodyType = "ERROR"
+	HTTP              Kind     = "Http"
+	GENERIC           Kind     = "Generic"
+	REDIS             Kind     = "Redis"
+	MySQL             Kind     = "MySQL"
+	Postgres          Kind     = "Postgres"
+	GRPC_EXPORT       Kind     = "gRPC"
+	Mongo             Kind     = "Mongo"
+	DNS               Kind     = "DNS"
+	KAFKA             Kind     = "Kafka"
+	WebSocket         Kind     = "WebSocket"
+	BodyTypeUtf8      BodyType = "utf-8"
+	BodyTypeBinary    BodyType = "binary"
+	BodyTypePlain     BodyType = "PLAIN"
+	BodyTypeJSON      BodyType = "JSON"
+	BodyTypeError     BodyType = "ERROR"
+	BodyTypeXML       BodyType = "XML"
+	BodyTypeForm      BodyType = "FORM"
+	BodyTypeMultipart BodyType = "MULTIPART"
 )
 
 type TestCase struct {
@@ -48,17 +56,35 @@ type TestCase struct {
 	AllKeys  map[string][]string `json:"all_keys" bson:"all_keys"`
 	GrpcResp GrpcResp            `json:"grpcResp" bson:"grpcResp"`
 	GrpcReq  GrpcReq             `json:"grpcReq" bson:"grpcReq"`
-	Anchors  map[string][]string `json:"anchors" bson:"anchors"`
-	Noise    map[string][]string `json:"noise" bson:"noise"`
-	Mocks    []*Mock             `json:"mocks" bson:"mocks"`
-	Type     string              `json:"type" bson:"type"`
-	Curl     string              `json:"curl" bson:"curl"`
+	// WebSocket holds the messages of a websocket testcase, the handshake being in HTTPReq and HTTPResp.
+	WebSocket []WebSocketMessage  `json:"websocket,omitempty" bson:"websocket,omitempty"`
+	Anchors   map[string][]string `json:"anchors" bson:"anchors"`
+	Noise     map[string][]string `json:"noise" bson:"noise"`
+	Mocks     []*Mock             `json:"mocks" bson:"mocks"`
+	Type      string              `json:"type" bson:"type"`
+	Curl      string              `json:"curl" bson:"curl"`
 }
 
 func (tc *TestCase) GetKind() string {
 	return string(tc.Kind)
 }
 
+// RequestTimestamp returns the time at which the request of the testcase was captured.
+func (tc *TestCase) RequestTimestamp() time.Time {
+	if tc.Kind == GRPC_EXPORT {
+		return tc.GrpcReq.Timestamp
+	}
+	return tc.HTTPReq.Timestamp
+}
+
+// ResponseTimestamp returns the time at which the response of the testcase was captured.
+func (tc *TestCase) ResponseTimestamp() time.Time {
+	if tc.Kind == GRPC_EXPORT {
+		return tc.GrpcResp.Timestamp
+	}
+	return tc.HTTPResp.Timestamp
+}
+
 type NoiseParams struct {
 	TestCaseID string              `json:"testCaseID"`
 	EditedBy   string              `json:"editedBy"`
@@ -36,6 +36,8 @@ type TestResult struct {
 	TestCaseID   string     `json:"testCaseID" yaml:"test_case_id"`
 	Req          HTTPReq    `json:"req" yaml:"req,omitempty"`
 	Res          HTTPResp   `json:"resp" yaml:"resp,omitempty"`
+	GrpcReq      GrpcReq    `json:"grpcReq" yaml:"grpc_req,omitempty"`
+	GrpcRes      GrpcResp   `json:"grpcResp" yaml:"grpc_resp,omitempty"`
 	Noise        Noise      `json:"noise" yaml:"noise,omitempty"`
 	Result       Result     `json:"result" yaml:"result"`
 }
@@ -133,6 +135,7 @@ const (
 	TestStatusFailed  TestStatus = "FAILED"
 	TestStatusPassed  TestStatus = "PASSED"
 	TestStatusIgnored TestStatus = "IGNORED"
+	TestStatusFlaky   TestStatus = "FLAKY" // failed, then passed when rerun
 )
 
 type (
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebSocketSchema is the yaml schema of a websocket session, i.e. the handshake along with the messages which
+// followed it. It is the schema of both the websocket mocks and the websocket testcases.
+type WebSocketSchema struct {
+	Metadata         map[string]string      `json:"metadata" yaml:"metadata"`
+	Request          HTTPReq                `json:"req" yaml:"req"`
+	Response         HTTPResp               `json:"resp" yaml:"resp"`
+	Messages         []WebSocketMessage     `json:"messages" yaml:"messages"`
+	Assertions       map[string]interface{} `json:"assertions" yaml:"assertions,omitempty"`
+	Created          int64                  `json:"created" yaml:"created,omitempty"`
+	ReqTimestampMock time.Time              `json:"reqTimestampMock" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time              `json:"resTimestampMock" yaml:"resTimestampMock,omitempty"`
+}
+
+type WebSocketMessageType string
+
+// constants for the types of the websocket messages. The ping and pong messages are answered by the
+// parties themselves, so they aren't recorded.
+const (
+	WebSocketText   WebSocketMessageType = "text"
+	WebSocketBinary WebSocketMessageType = "binary"
+	WebSocketClose  WebSocketMessageType = "close"
+	WebSocketPing   WebSocketMessageType = "ping"
+	WebSocketPong   WebSocketMessageType = "pong"
+)
+
+// WebSocketMessage is a whole message of a websocket session, the frames of a fragmented message being joined.
+type WebSocketMessage struct {
+	Origin OriginType           `json:"origin" yaml:"origin"`
+	Type   WebSocketMessageType `json:"type" yaml:"type"`
+	// Data is the text of the text messages, the base64 encoded payload of the binary messages and the reason
+	// of the close messages.
+	Data string `json:"data" yaml:"data"`
+	// CloseCode is the status code of a close message.
+	CloseCode int `json:"close_code,omitempty" yaml:"close_code,omitempty"`
+	// Offset is the time elapsed between the handshake and this message.
+	Offset time.Duration `json:"offset" yaml:"offset"`
+}
+
+// StreamEvent returns the message as an event of a streamed response, so that the messages of the server
+// are compared the way the events of a stream are. The type of the message is the event, unless it is text.
+func (m WebSocketMessage) StreamEvent() HTTPStreamEvent {
+	event := HTTPStreamEvent{Offset: m.Offset, Data: m.Data}
+	switch m.Type {
+	case WebSocketText:
+	case WebSocketClose:
+		event.Event = string(m.Type)
+		event.Data = strings.TrimSpace(strconv.Itoa(m.CloseCode) + " " + m.Data)
+	default:
+		event.Event = string(m.Type)
+	}
+	return event
+}
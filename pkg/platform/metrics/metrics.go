@@ -0,0 +1,195 @@
+// Package metrics provides the prometheus metrics of the proxy and the replayer, along with the local
+// HTTP endpoint serving them.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+const namespace = "keploy"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	connections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "proxy",
+		Name:      "connections_total",
+		Help:      "Outgoing connections of the application handled by the proxy, per integration and mode.",
+	}, []string{"integration", "mode"})
+
+	connectionErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "proxy",
+		Name:      "connection_errors_total",
+		Help:      "Outgoing connections dropped by the proxy because of an error, per integration.",
+	}, []string{"integration"})
+
+	mockMatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "proxy",
+		Name:      "mock_matches_total",
+		Help:      "Lookups of a mock for an outgoing request, per kind of mock and result (hit/miss).",
+	}, []string{"kind", "result"})
+
+	mockMatchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "proxy",
+		Name:      "mock_match_duration_seconds",
+		Help:      "Time taken to look up the mock of an outgoing request, per kind of mock.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 9), // 100µs to ~6.5s
+	}, []string{"kind"})
+
+	mocksConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "proxy",
+		Name:      "mocks_consumed_total",
+		Help:      "Mocks used to answer the outgoing requests, per kind of mock.",
+	}, []string{"kind"})
+
+	testSetMocks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "replay",
+		Name:      "mocks",
+		Help:      "Mocks of the last run of a test set, per state (consumed/unused).",
+	}, []string{"test_set", "state"})
+
+	tests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "replay",
+		Name:      "tests_total",
+		Help:      "Test cases run, per test set and status.",
+	}, []string{"test_set", "status"})
+
+	testCaseDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "replay",
+		Name:      "test_case_duration_seconds",
+		Help:      "Time taken to replay a test case and compare its response.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12), // 5ms to ~10s
+	})
+
+	testSetDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "replay",
+		Name:      "test_set_duration_seconds",
+		Help:      "Time taken to run a test set, per status of the test set.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12), // 500ms to ~17m
+	}, []string{"status"})
+
+	// loadedMocks holds the number of mocks loaded for each test set, to count the unused ones
+	loadedMocks   = map[string]int{}
+	loadedMocksMu sync.Mutex
+)
+
+func init() {
+	registry.MustRegister(connections, connectionErrors, mockMatches, mockMatchDuration, mocksConsumed, testSetMocks, tests, testCaseDuration, testSetDuration)
+}
+
+// Serve exposes the metrics at /metrics on the given address and port until the context is done. The
+// metrics are only served on the loopback interface if no address is given.
+func Serve(ctx context.Context, logger *zap.Logger, host string, port uint32) error {
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn("the metrics server stopped", zap.Error(err))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			logger.Debug("failed to shutdown the metrics server", zap.Error(err))
+		}
+	}()
+	logger.Info("serving the prometheus metrics", zap.String("address", fmt.Sprintf("http://%s/metrics", addr)))
+	return nil
+}
+
+// Connection counts an outgoing connection handled by the proxy.
+func Connection(integration string, mode models.Mode) {
+	connections.WithLabelValues(integration, string(mode)).Inc()
+}
+
+// ConnectionError counts an outgoing connection dropped by the proxy.
+func ConnectionError(integration string) {
+	if integration == "" {
+		integration = "unknown"
+	}
+	connectionErrors.WithLabelValues(integration).Inc()
+}
+
+// MockMatch records the result and the duration of the lookup of a mock, started at start.
+func MockMatch(kind models.Kind, matched bool, start time.Time) {
+	result := "miss"
+	if matched {
+		result = "hit"
+	}
+	mockMatches.WithLabelValues(string(kind), result).Inc()
+	mockMatchDuration.WithLabelValues(string(kind)).Observe(time.Since(start).Seconds())
+}
+
+// MockConsumed counts a use of a mock.
+func MockConsumed(kind models.Kind) {
+	mocksConsumed.WithLabelValues(string(kind)).Inc()
+}
+
+// TestSetMocksLoaded sets the number of mocks loaded for the run of a test set, all of them unused yet.
+func TestSetMocksLoaded(testSet string, loaded int) {
+	loadedMocksMu.Lock()
+	loadedMocks[testSet] = loaded
+	loadedMocksMu.Unlock()
+	testSetMocks.WithLabelValues(testSet, "consumed").Set(0)
+	testSetMocks.WithLabelValues(testSet, "unused").Set(float64(loaded))
+}
+
+// TestSetMocksConsumed sets the number of distinct mocks consumed by the run of a test set.
+func TestSetMocksConsumed(testSet string, consumed int) {
+	loadedMocksMu.Lock()
+	unused := max(loadedMocks[testSet]-consumed, 0)
+	loadedMocksMu.Unlock()
+	testSetMocks.WithLabelValues(testSet, "consumed").Set(float64(consumed))
+	testSetMocks.WithLabelValues(testSet, "unused").Set(float64(unused))
+}
+
+// TestCase records the status of a test case and the time taken to replay it.
+func TestCase(testSet string, status models.TestStatus, duration time.Duration) {
+	tests.WithLabelValues(testSet, string(status)).Inc()
+	if duration > 0 {
+		testCaseDuration.Observe(duration.Seconds())
+	}
+}
+
+// TestSet records the time taken to run a test set.
+func TestSet(status models.TestSetStatus, duration time.Duration) {
+	testSetDuration.WithLabelValues(string(status)).Observe(duration.Seconds())
+}
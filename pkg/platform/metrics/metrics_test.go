@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestConnection(t *testing.T) {
+	testMode := connections.WithLabelValues("test-connection", string(models.MODE_TEST))
+	recordMode := connections.WithLabelValues("test-connection", string(models.MODE_RECORD))
+	dropped := connectionErrors.WithLabelValues("test-connection")
+	unknown := connectionErrors.WithLabelValues("unknown")
+	tests := []struct {
+		name    string
+		counter prometheus.Counter
+		want    float64
+	}{
+		{name: "test mode", counter: testMode, want: 2},
+		{name: "record mode", counter: recordMode, want: 1},
+		{name: "error", counter: dropped, want: 1},
+		{name: "error without integration", counter: unknown, want: 1},
+	}
+	before := make([]float64, len(tests))
+	for i, tt := range tests {
+		before[i] = testutil.ToFloat64(tt.counter)
+	}
+
+	Connection("test-connection", models.MODE_TEST)
+	Connection("test-connection", models.MODE_TEST)
+	Connection("test-connection", models.MODE_RECORD)
+	ConnectionError("test-connection")
+	ConnectionError("")
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(tt.counter) - before[i]; got != tt.want {
+				t.Errorf("got %v more, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockMatch(t *testing.T) {
+	const kind models.Kind = "TestMockMatch"
+	hits, misses, consumed := mockMatches.WithLabelValues(string(kind), "hit"), mockMatches.WithLabelValues(string(kind), "miss"), mocksConsumed.WithLabelValues(string(kind))
+	hitsBefore, missesBefore, consumedBefore := testutil.ToFloat64(hits), testutil.ToFloat64(misses), testutil.ToFloat64(consumed)
+	samplesBefore := sampleCount(t, `keploy_proxy_mock_match_duration_seconds_count{kind="TestMockMatch"}`)
+
+	MockMatch(kind, true, time.Now())
+	MockMatch(kind, true, time.Now())
+	MockMatch(kind, false, time.Now().Add(-time.Second))
+	MockConsumed(kind)
+
+	if got := testutil.ToFloat64(hits) - hitsBefore; got != 2 {
+		t.Errorf("hits = %v more, want 2", got)
+	}
+	if got := testutil.ToFloat64(misses) - missesBefore; got != 1 {
+		t.Errorf("misses = %v more, want 1", got)
+	}
+	if got := testutil.ToFloat64(consumed) - consumedBefore; got != 1 {
+		t.Errorf("consumed = %v more, want 1", got)
+	}
+	if got := sampleCount(t, `keploy_proxy_mock_match_duration_seconds_count{kind="TestMockMatch"}`) - samplesBefore; got != 3 {
+		t.Errorf("match duration samples = %d more, want 3", got)
+	}
+}
+
+// sampleCount scrapes the metrics and returns the value of the series, 0 if it isn't there yet.
+func sampleCount(t *testing.T, series string) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, series+" "); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				t.Fatalf("the value of %s isn't a count: %s", series, value)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func TestTestSetMocks(t *testing.T) {
+	tests := []struct {
+		name         string
+		loaded       int
+		consumed     int
+		wantConsumed float64
+		wantUnused   float64
+	}{
+		{name: "some consumed", loaded: 5, consumed: 2, wantConsumed: 2, wantUnused: 3},
+		{name: "all consumed", loaded: 2, consumed: 2, wantConsumed: 2},
+		{name: "more consumed than loaded", loaded: 1, consumed: 3, wantConsumed: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSet := "test-set-" + tt.name
+			TestSetMocksLoaded(testSet, tt.loaded)
+			if got := testutil.ToFloat64(testSetMocks.WithLabelValues(testSet, "unused")); got != float64(tt.loaded) {
+				t.Errorf("unused after loading = %v, want %v", got, tt.loaded)
+			}
+			TestSetMocksConsumed(testSet, tt.consumed)
+			if got := testutil.ToFloat64(testSetMocks.WithLabelValues(testSet, "consumed")); got != tt.wantConsumed {
+				t.Errorf("consumed = %v, want %v", got, tt.wantConsumed)
+			}
+			if got := testutil.ToFloat64(testSetMocks.WithLabelValues(testSet, "unused")); got != tt.wantUnused {
+				t.Errorf("unused = %v, want %v", got, tt.wantUnused)
+			}
+		})
+	}
+}
+
+func TestTestCase(t *testing.T) {
+	passed := tests.WithLabelValues("test-set-TestTestCase", string(models.TestStatusPassed))
+	failed := tests.WithLabelValues("test-set-TestTestCase", string(models.TestStatusFailed))
+	passedBefore, failedBefore := testutil.ToFloat64(passed), testutil.ToFloat64(failed)
+	samplesBefore := sampleCount(t, "keploy_replay_test_case_duration_seconds_count")
+
+	TestCase("test-set-TestTestCase", models.TestStatusPassed, time.Second)
+	TestCase("test-set-TestTestCase", models.TestStatusPassed, 0)
+	TestCase("test-set-TestTestCase", models.TestStatusFailed, time.Second)
+
+	if got := testutil.ToFloat64(passed) - passedBefore; got != 2 {
+		t.Errorf("passed = %v more, want 2", got)
+	}
+	if got := testutil.ToFloat64(failed) - failedBefore; got != 1 {
+		t.Errorf("failed = %v more, want 1", got)
+	}
+	if got := sampleCount(t, "keploy_replay_test_case_duration_seconds_count") - samplesBefore; got != 2 {
+		t.Errorf("test case duration samples = %d more, want 2, the ones without a duration left out", got)
+	}
+}
+
+func TestServe(t *testing.T) {
+	// pick a free port, as Serve doesn't return the one it listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Serve(ctx, zap.NewNop(), "", uint32(port)); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	Connection("test-serve", models.MODE_RECORD)
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatalf("failed to get the metrics: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `keploy_proxy_connections_total{integration="test-serve",mode="record"}`; !strings.Contains(string(body), want) {
+		t.Errorf("the metrics don't contain %s:\n%s", want, body)
+	}
+
+	if err := Serve(ctx, zap.NewNop(), "", uint32(port)); err == nil {
+		t.Error("Serve() on a port in use succeeded, want an error")
+	}
+}
@@ -0,0 +1,189 @@
+package mockdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mohae/deepcopy"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// mockFile holds the mocks decoded from a mock file, so that the file is parsed once per test set
+// instead of once per test case. It is parsed again when the modification time or the size of the
+// file changes.
+type mockFile struct {
+	modTime time.Time
+	size    int64
+	// tcsMocks are the mocks of the test cases with both the timestamps, sorted by the request timestamp.
+	tcsMocks []*models.Mock
+	// untimedTcsMocks are the mocks of the test cases missing a timestamp, which match every test case.
+	untimedTcsMocks []*models.Mock
+	// configMocks are the mocks shared by the test cases, sorted by the request timestamp.
+	configMocks []*models.Mock
+}
+
+func newMockFile(mocks []*models.Mock, info os.FileInfo) *mockFile {
+	f := &mockFile{modTime: info.ModTime(), size: info.Size()}
+
+	sort.SliceStable(mocks, func(i, j int) bool {
+		return mocks[i].Spec.ReqTimestampMock.Before(mocks[j].Spec.ReqTimestampMock)
+	})
+	for _, mock := range mocks {
+		if !isTcsMock(mock) {
+			f.configMocks = append(f.configMocks, mock)
+			continue
+		}
+		if mock.Spec.ReqTimestampMock == (time.Time{}) || mock.Spec.ResTimestampMock == (time.Time{}) {
+			f.untimedTcsMocks = append(f.untimedTcsMocks, mock)
+			continue
+		}
+		f.tcsMocks = append(f.tcsMocks, mock)
+	}
+	return f
+}
+
+// isTcsMock tells whether the mock belongs to the test case during which it was recorded. The mocks of
+// the kinds below are always shared, as the connections of their clients are reused across test cases.
+func isTcsMock(mock *models.Mock) bool {
+	switch mock.Kind {
+	case "Generic", "Postgres", "Http", "Redis", "MySQL":
+		return false
+	}
+	return mock.Spec.Metadata["type"] != "config"
+}
+
+// tcsMocksIn returns copies of the mocks of the test cases recorded between afterTime and beforeTime,
+// along with the ones missing a timestamp, sorted by the request timestamp. All the mocks are returned
+// if the window isn't set.
+func (f *mockFile) tcsMocksIn(afterTime, beforeTime time.Time) []*models.Mock {
+	if afterTime == (time.Time{}) || beforeTime == (time.Time{}) {
+		all := make([]*models.Mock, 0, len(f.tcsMocks)+len(f.untimedTcsMocks))
+		all = append(all, copyMocks(f.untimedTcsMocks)...)
+		all = append(all, copyMocks(f.tcsMocks)...)
+		sort.SliceStable(all, func(i, j int) bool {
+			return all[i].Spec.ReqTimestampMock.Before(all[j].Spec.ReqTimestampMock)
+		})
+		return all
+	}
+
+	var inWindow []*models.Mock
+	start := sort.Search(len(f.tcsMocks), func(i int) bool {
+		return f.tcsMocks[i].Spec.ReqTimestampMock.After(afterTime)
+	})
+	for _, mock := range f.tcsMocks[start:] {
+		if !mock.Spec.ReqTimestampMock.Before(beforeTime) {
+			break
+		}
+		if mock.Spec.ResTimestampMock.Before(beforeTime) {
+			inWindow = append(inWindow, mock)
+		}
+	}
+
+	mocks := make([]*models.Mock, 0, len(inWindow)+len(f.untimedTcsMocks))
+	mocks = append(mocks, copyMocks(f.untimedTcsMocks)...)
+	mocks = append(mocks, copyMocks(inWindow)...)
+	for _, mock := range mocks {
+		mock.TestModeInfo.IsFiltered = true
+	}
+	sort.SliceStable(mocks, func(i, j int) bool {
+		return mocks[i].Spec.ReqTimestampMock.Before(mocks[j].Spec.ReqTimestampMock)
+	})
+	return mocks
+}
+
+// copyMocks deep copies the mocks, so that the callers can update them, down to their requests and
+// responses, without altering the cached ones.
+func copyMocks(mocks []*models.Mock) []*models.Mock {
+	copies := make([]*models.Mock, len(mocks))
+	for i, mock := range mocks {
+		copies[i] = deepcopy.Copy(mock).(*models.Mock)
+	}
+	return copies
+}
+
+// loadMockFile returns the mocks of the test set, parsing the mock file only if it changed since it was
+// last parsed. It returns nil if the test set has no mock file.
+func (ys *MockYaml) loadMockFile(ctx context.Context, testSetID string) (*mockFile, error) {
+	mockFileName := "mocks"
+	if ys.MockName != "" {
+		mockFileName = ys.MockName
+	}
+	path := filepath.Join(ys.MockPath, testSetID)
+	mockPath, err := yaml.ValidatePath(path + "/" + mockFileName + ".yaml")
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(mockPath)
+	if err != nil {
+		return nil, nil
+	}
+
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	if f, ok := ys.mockFiles[mockPath]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+		return f, nil
+	}
+
+	mocks, err := ys.readMocks(ctx, path, mockFileName)
+	if err != nil {
+		return nil, err
+	}
+	f := newMockFile(mocks, info)
+	ys.mockFiles[mockPath] = f
+	return f, nil
+}
+
+// invalidate drops the cached mocks of the test set, after its mock file has been written.
+func (ys *MockYaml) invalidate(testSetID string) {
+	mockFileName := "mocks"
+	if ys.MockName != "" {
+		mockFileName = ys.MockName
+	}
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	delete(ys.mockFiles, filepath.Join(ys.MockPath, testSetID, mockFileName+".yaml"))
+}
+
+// ReleaseMocks drops the parsed mocks of the test set kept in memory, once the test set has been run.
+func (ys *MockYaml) ReleaseMocks(testSetID string) {
+	ys.invalidate(testSetID)
+}
+
+// readMocks reads and decodes all the mocks of a mock file.
+func (ys *MockYaml) readMocks(ctx context.Context, path, mockFileName string) ([]*models.Mock, error) {
+	data, err := yaml.ReadFile(ctx, ys.Logger, path, mockFileName)
+	if err != nil {
+		utils.LogError(ys.Logger, err, "failed to read the mocks from config yaml", zap.Any("session", filepath.Base(path)))
+		return nil, err
+	}
+	var mockYamls []*yaml.NetworkTrafficDoc
+	dec := yamlLib.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc *yaml.NetworkTrafficDoc
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
+		}
+		mockYamls = append(mockYamls, doc)
+	}
+	mocks, err := decodeMocks(mockYamls, ys.Logger)
+	if err != nil {
+		utils.LogError(ys.Logger, err, "failed to decode the config mocks from yaml docs", zap.Any("session", filepath.Base(path)))
+		return nil, err
+	}
+	return mocks, nil
+}
@@ -0,0 +1,136 @@
+package mockdb
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// fileInfo is the info of a mock file which was never written.
+type fileInfo struct {
+	os.FileInfo
+}
+
+func (fileInfo) ModTime() time.Time { return time.Time{} }
+func (fileInfo) Size() int64        { return 0 }
+
+func names(mocks []*models.Mock) []string {
+	var names []string
+	for _, mock := range mocks {
+		names = append(names, mock.Name)
+	}
+	return names
+}
+
+func TestTcsMocksIn(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+	mock := func(name string, kind models.Kind, req, resp int) *models.Mock {
+		m := &models.Mock{Name: name, Kind: kind}
+		if req >= 0 {
+			m.Spec.ReqTimestampMock, m.Spec.ResTimestampMock = at(req), at(resp)
+		}
+		return m
+	}
+	f := newMockFile([]*models.Mock{
+		mock("mock-3", models.Mongo, 30, 31),
+		mock("mock-1", models.Mongo, 10, 11),
+		mock("mock-2", models.Mongo, 20, 25),
+		mock("mock-4", models.Mongo, -1, -1),
+		mock("mock-5", models.Postgres, 12, 13),
+		{Name: "mock-6", Kind: models.Mongo, Spec: models.MockSpec{Metadata: map[string]string{"type": "config"}, ReqTimestampMock: at(12), ResTimestampMock: at(13)}},
+	}, fileInfo{})
+
+	if got := names(f.configMocks); !reflect.DeepEqual(got, []string{"mock-5", "mock-6"}) {
+		t.Errorf("config mocks = %v, want the shared ones", got)
+	}
+
+	tests := []struct {
+		name         string
+		after        time.Time
+		before       time.Time
+		want         []string
+		wantFiltered bool
+	}{
+		{name: "no window", want: []string{"mock-4", "mock-1", "mock-2", "mock-3"}},
+		{name: "window", after: at(9), before: at(26), want: []string{"mock-4", "mock-1", "mock-2"}, wantFiltered: true},
+		{name: "response after the window", after: at(9), before: at(24), want: []string{"mock-4", "mock-1"}, wantFiltered: true},
+		{name: "request at the start of the window", after: at(10), before: at(26), want: []string{"mock-4", "mock-2"}, wantFiltered: true},
+		{name: "empty window", after: at(40), before: at(50), want: []string{"mock-4"}, wantFiltered: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.tcsMocksIn(tt.after, tt.before)
+			if !reflect.DeepEqual(names(got), tt.want) {
+				t.Fatalf("tcsMocksIn() = %v, want %v", names(got), tt.want)
+			}
+			for _, m := range got {
+				if m.TestModeInfo.IsFiltered != tt.wantFiltered {
+					t.Errorf("mock %s filtered = %v, want %v", m.Name, m.TestModeInfo.IsFiltered, tt.wantFiltered)
+				}
+			}
+		})
+	}
+}
+
+func TestMockFileCache(t *testing.T) {
+	ctx := context.Background()
+	ys := New(zap.NewNop(), t.TempDir(), "")
+	insert := func(host string) {
+		t.Helper()
+		mock := &models.Mock{
+			Version: models.GetVersion(),
+			Kind:    models.DNS,
+			Spec: models.MockSpec{
+				DNSReq:  &models.DNSReq{Name: host, Qtype: "A"},
+				DNSResp: &models.DNSResp{Rcode: "NOERROR"},
+			},
+		}
+		if err := ys.InsertMock(ctx, mock, "test-set-0"); err != nil {
+			t.Fatalf("InsertMock() error = %v", err)
+		}
+	}
+	get := func() []*models.Mock {
+		t.Helper()
+		mocks, err := ys.GetFilteredMocks(ctx, "test-set-0", time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("GetFilteredMocks() error = %v", err)
+		}
+		return mocks
+	}
+
+	if got := get(); len(got) != 0 {
+		t.Fatalf("GetFilteredMocks() without a mock file = %v, want none", names(got))
+	}
+
+	insert("a.example.com.")
+	first := get()
+	if !reflect.DeepEqual(names(first), []string{"mock-0"}) {
+		t.Fatalf("GetFilteredMocks() = %v, want the inserted mock", names(first))
+	}
+
+	// the returned mocks are copies, which can be updated without altering the cached ones
+	first[0].Spec.DNSReq.Name = "changed."
+	if got := get(); got[0].Spec.DNSReq.Name != "a.example.com." {
+		t.Errorf("cached mock request = %q, want it unchanged", got[0].Spec.DNSReq.Name)
+	}
+	if len(ys.mockFiles) != 1 {
+		t.Errorf("%d mock files cached, want 1", len(ys.mockFiles))
+	}
+
+	// writing the mock file drops it from the cache
+	insert("b.example.com.")
+	if got := get(); !reflect.DeepEqual(names(got), []string{"mock-0", "mock-1"}) {
+		t.Errorf("GetFilteredMocks() after an insert = %v, want both mocks", names(got))
+	}
+
+	ys.ReleaseMocks("test-set-0")
+	if len(ys.mockFiles) != 0 {
+		t.Errorf("%d mock files cached after the release, want none", len(ys.mockFiles))
+	}
+}
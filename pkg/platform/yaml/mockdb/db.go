@@ -9,7 +9,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -25,6 +25,8 @@ type MockYaml struct {
 	MockName  string
 	Logger    *zap.Logger
 	idCounter int64
+	mu        sync.Mutex
+	mockFiles map[string]*mockFile // parsed mock files by their path
 }
 
 func New(Logger *zap.Logger, mockPath string, mockName string) *MockYaml {
@@ -33,6 +35,7 @@ func New(Logger *zap.Logger, mockPath string, mockName string) *MockYaml {
 		MockName:  mockName,
 		Logger:    Logger,
 		idCounter: -1,
+		mockFiles: make(map[string]*mockFile),
 	}
 }
 
@@ -96,6 +99,7 @@ func (ys *MockYaml) UpdateMocks(ctx context.Context, testSetID string, mockNames
 	if err != nil {
 		return err
 	}
+	ys.invalidate(testSetID)
 
 	// write the new mocks to the new yaml file
 	for _, newMock := range newMocks {
@@ -137,153 +141,30 @@ func (ys *MockYaml) InsertMock(ctx context.Context, mock *models.Mock, testSetID
 	if err != nil {
 		return err
 	}
+	ys.invalidate(testSetID)
 	return nil
 }
 
+// GetFilteredMocks returns the mocks of the test cases recorded between afterTime and beforeTime. The
+// mock file is parsed once and kept in memory until it changes.
 func (ys *MockYaml) GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error) {
-
-	var tcsMocks = make([]*models.Mock, 0)
-	var filteredTcsMocks = make([]*models.Mock, 0)
-	mockFileName := "mocks"
-	if ys.MockName != "" {
-		mockFileName = ys.MockName
-	}
-
-	path := filepath.Join(ys.MockPath, testSetID)
-	mockPath, err := yaml.ValidatePath(path + "/" + mockFileName + ".yaml")
-	if err != nil {
-		return nil, err
-	}
-
-	if _, err := os.Stat(mockPath); err == nil {
-		var mockYamls []*yaml.NetworkTrafficDoc
-		data, err := yaml.ReadFile(ctx, ys.Logger, path, mockFileName)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to read the mocks from config yaml", zap.Any("session", filepath.Base(path)))
-			return nil, err
-		}
-		dec := yamlLib.NewDecoder(bytes.NewReader(data))
-		for {
-			var doc *yaml.NetworkTrafficDoc
-			err := dec.Decode(&doc)
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
-			}
-			mockYamls = append(mockYamls, doc)
-		}
-		mocks, err := decodeMocks(mockYamls, ys.Logger)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to decode the config mocks from yaml docs", zap.Any("session", filepath.Base(path)))
-			return nil, err
-		}
-
-		for _, mock := range mocks {
-			isFilteredMock := true
-			switch mock.Kind {
-			case "Generic":
-				isFilteredMock = false
-			case "Postgres":
-				isFilteredMock = false
-			case "Http":
-				isFilteredMock = false
-			case "Redis":
-				isFilteredMock = false
-			case "MySQL":
-				isFilteredMock = false
-			}
-			if mock.Spec.Metadata["type"] != "config" && isFilteredMock {
-				tcsMocks = append(tcsMocks, mock)
-			}
-		}
+	f, err := ys.loadMockFile(ctx, testSetID)
+	if err != nil || f == nil {
+		return make([]*models.Mock, 0), err
 	}
-	filteredTcsMocks, _ = ys.filterByTimeStamp(ctx, tcsMocks, afterTime, beforeTime, ys.Logger)
-
-	sort.SliceStable(filteredTcsMocks, func(i, j int) bool {
-		return filteredTcsMocks[i].Spec.ReqTimestampMock.Before(filteredTcsMocks[j].Spec.ReqTimestampMock)
-	})
-
-	return filteredTcsMocks, nil
+	return f.tcsMocksIn(afterTime, beforeTime), nil
 }
 
+// GetUnFilteredMocks returns the mocks shared by the test cases, the ones recorded between afterTime and
+// beforeTime coming first.
 func (ys *MockYaml) GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error) {
-
-	var configMocks = make([]*models.Mock, 0)
-
-	mockName := "mocks"
-	if ys.MockName != "" {
-		mockName = ys.MockName
+	f, err := ys.loadMockFile(ctx, testSetID)
+	if err != nil || f == nil {
+		return make([]*models.Mock, 0), err
 	}
-
-	path := filepath.Join(ys.MockPath, testSetID)
-
-	mockPath, err := yaml.ValidatePath(path + "/" + mockName + ".yaml")
-	if err != nil {
-		return nil, err
-	}
-
-	if _, err := os.Stat(mockPath); err == nil {
-		var mockYamls []*yaml.NetworkTrafficDoc
-		data, err := yaml.ReadFile(ctx, ys.Logger, path, mockName)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to read the mocks from config yaml", zap.Any("session", filepath.Base(path)))
-			return nil, err
-		}
-		dec := yamlLib.NewDecoder(bytes.NewReader(data))
-		for {
-			var doc *yaml.NetworkTrafficDoc
-			err := dec.Decode(&doc)
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
-			}
-			mockYamls = append(mockYamls, doc)
-		}
-		mocks, err := decodeMocks(mockYamls, ys.Logger)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to decode the config mocks from yaml docs", zap.Any("session", filepath.Base(path)))
-			return nil, err
-		}
-		for _, mock := range mocks {
-			isUnFilteredMock := false
-			switch mock.Kind {
-			case "Generic":
-				isUnFilteredMock = true
-			case "Postgres":
-				isUnFilteredMock = true
-			case "Http":
-				isUnFilteredMock = true
-			case "Redis":
-				isUnFilteredMock = true
-			case "MySQL":
-				isUnFilteredMock = true
-			}
-			if mock.Spec.Metadata["type"] == "config" || isUnFilteredMock {
-				configMocks = append(configMocks, mock)
-			}
-		}
-	}
-
-	filteredMocks, unfilteredMocks := ys.filterByTimeStamp(ctx, configMocks, afterTime, beforeTime, ys.Logger)
-
-	sort.SliceStable(filteredMocks, func(i, j int) bool {
-		return filteredMocks[i].Spec.ReqTimestampMock.Before(filteredMocks[j].Spec.ReqTimestampMock)
-	})
-
-	sort.SliceStable(unfilteredMocks, func(i, j int) bool {
-		return unfilteredMocks[i].Spec.ReqTimestampMock.Before(unfilteredMocks[j].Spec.ReqTimestampMock)
-	})
-
-	// if len(unfilteredMocks) > 10 {
-	// 	unfilteredMocks = unfilteredMocks[:10]
-	// }
-
+	// the config mocks are already sorted by their request timestamp, which the split preserves
+	filteredMocks, unfilteredMocks := ys.filterByTimeStamp(ctx, copyMocks(f.configMocks), afterTime, beforeTime, ys.Logger)
 	mocks := append(filteredMocks, unfilteredMocks...)
-
 	return mocks, nil
 }
 
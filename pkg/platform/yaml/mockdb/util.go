@@ -77,6 +77,21 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			utils.LogError(logger, err, "failed to marshal the http input-output as yaml")
 			return nil, err
 		}
+	case models.WebSocket:
+		webSocketSpec := models.WebSocketSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.HTTPReq,
+			Response:         *mock.Spec.HTTPResp,
+			Messages:         mock.Spec.WebSocketMessages,
+			Created:          mock.Spec.Created,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(webSocketSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the websocket session as yaml")
+			return nil, err
+		}
 	case models.GENERIC:
 		genericSpec := models.GenericSchema{
 			Metadata:         mock.Spec.Metadata,
@@ -94,6 +109,7 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 		redisSpec := models.RedisSchema{
 			Metadata:         mock.Spec.Metadata,
 			RedisRequests:    mock.Spec.RedisRequests,
+			RedisCommands:    mock.Spec.RedisCommands,
 			RedisResponses:   mock.Spec.RedisResponses,
 			ReqTimestampMock: mock.Spec.ReqTimestampMock,
 			ResTimestampMock: mock.Spec.ResTimestampMock,
@@ -119,6 +135,32 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			utils.LogError(logger, err, "failed to marshal the postgres input-output as yaml")
 			return nil, err
 		}
+	case models.DNS:
+		dnsSpec := models.DNSSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.DNSReq,
+			Response:         *mock.Spec.DNSResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(dnsSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the dns query-answer as yaml")
+			return nil, err
+		}
+	case models.KAFKA:
+		kafkaSpec := models.KafkaSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.KafkaRequest,
+			Response:         mock.Spec.KafkaResponse,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(kafkaSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the kafka request-response as yaml")
+			return nil, err
+		}
 	case models.GRPC_EXPORT:
 		gRPCSpec := models.GrpcSpec{
 			GrpcReq:          *mock.Spec.GRPCReq,
@@ -213,6 +255,22 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 				ReqTimestampMock: httpSpec.ReqTimestampMock,
 				ResTimestampMock: httpSpec.ResTimestampMock,
 			}
+		case models.WebSocket:
+			webSocketSpec := models.WebSocketSchema{}
+			err := m.Spec.Decode(&webSocketSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into websocket mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:          webSocketSpec.Metadata,
+				HTTPReq:           &webSocketSpec.Request,
+				HTTPResp:          &webSocketSpec.Response,
+				WebSocketMessages: webSocketSpec.Messages,
+				Created:           webSocketSpec.Created,
+				ReqTimestampMock:  webSocketSpec.ReqTimestampMock,
+				ResTimestampMock:  webSocketSpec.ResTimestampMock,
+			}
 		case models.Mongo:
 			mongoSpec := models.MongoSpec{}
 			err := m.Spec.Decode(&mongoSpec)
@@ -239,6 +297,34 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 				ReqTimestampMock: grpcSpec.ReqTimestampMock,
 				ResTimestampMock: grpcSpec.ResTimestampMock,
 			}
+		case models.DNS:
+			dnsSpec := models.DNSSchema{}
+			err := m.Spec.Decode(&dnsSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into dns mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         dnsSpec.Metadata,
+				DNSReq:           &dnsSpec.Request,
+				DNSResp:          &dnsSpec.Response,
+				ReqTimestampMock: dnsSpec.ReqTimestampMock,
+				ResTimestampMock: dnsSpec.ResTimestampMock,
+			}
+		case models.KAFKA:
+			kafkaSpec := models.KafkaSchema{}
+			err := m.Spec.Decode(&kafkaSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into kafka mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         kafkaSpec.Metadata,
+				KafkaRequest:     &kafkaSpec.Request,
+				KafkaResponse:    kafkaSpec.Response,
+				ReqTimestampMock: kafkaSpec.ReqTimestampMock,
+				ResTimestampMock: kafkaSpec.ResTimestampMock,
+			}
 		case models.GENERIC:
 			genericSpec := models.GenericSchema{}
 			err := m.Spec.Decode(&genericSpec)
@@ -263,6 +349,7 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 			mock.Spec = models.MockSpec{
 				Metadata:         redisSpec.Metadata,
 				RedisRequests:    redisSpec.RedisRequests,
+				RedisCommands:    redisSpec.RedisCommands,
 				RedisResponses:   redisSpec.RedisResponses,
 				ReqTimestampMock: redisSpec.ReqTimestampMock,
 				ResTimestampMock: redisSpec.ResTimestampMock,
@@ -367,6 +454,15 @@ func decodeMySQLMessage(_ context.Context, logger *zap.Logger, yamlSpec *mysql.S
 			}
 			req.Message = msg
 
+		case mysql.PlainPassword:
+			var msg string
+			err := v.Message.Decode(&msg)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal yaml document into mysql (string) plain_password")
+				return nil, err
+			}
+			req.Message = msg
+
 		// command phase
 
 		// utility packets
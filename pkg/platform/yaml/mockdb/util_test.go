@@ -0,0 +1,91 @@
+package mockdb
+
+import (
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// roundTrip writes the mock as in the mocks file and reads it back.
+func roundTrip(t *testing.T, mock *models.Mock) *models.Mock {
+	t.Helper()
+	doc, err := EncodeMock(mock, zap.NewNop())
+	if err != nil {
+		t.Fatalf("EncodeMock() error = %v", err)
+	}
+	data, err := yamlLib.Marshal(doc)
+	if err != nil {
+		t.Fatalf("yaml.Marshal() error = %v", err)
+	}
+	var read yaml.NetworkTrafficDoc
+	if err := yamlLib.Unmarshal(data, &read); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	mocks, err := decodeMocks([]*yaml.NetworkTrafficDoc{&read}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("decodeMocks() error = %v", err)
+	}
+	if len(mocks) != 1 {
+		t.Fatalf("decodeMocks() = %d mocks, want 1", len(mocks))
+	}
+	return mocks[0]
+}
+
+func TestDNSMockRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.DNSReq
+		resp models.DNSResp
+	}{
+		{
+			name: "srv",
+			req:  models.DNSReq{Name: "_grpc._tcp.users.svc.", Qtype: "SRV"},
+			resp: models.DNSResp{Rcode: "NOERROR", Answers: []string{
+				"_grpc._tcp.users.svc.\t30\tIN\tSRV\t0 50 8080 users-0.users.svc.",
+				"_grpc._tcp.users.svc.\t30\tIN\tSRV\t0 50 8080 users-1.users.svc.",
+			}},
+		},
+		{
+			name: "txt",
+			req:  models.DNSReq{Name: "example.com.", Qtype: "TXT"},
+			resp: models.DNSResp{Rcode: "NOERROR", Answers: []string{"example.com.\t300\tIN\tTXT\t\"v=spf1 -all\""}},
+		},
+		{
+			name: "no answer",
+			req:  models.DNSReq{Name: "missing.example.com.", Qtype: "A"},
+			resp: models.DNSResp{Rcode: "NXDOMAIN"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, resp := tt.req, tt.resp
+			mock := &models.Mock{
+				Version: models.GetVersion(),
+				Name:    "mock-0",
+				Kind:    models.DNS,
+				Spec: models.MockSpec{
+					Metadata: map[string]string{"type": "config"},
+					DNSReq:   &req,
+					DNSResp:  &resp,
+				},
+			}
+			got := roundTrip(t, mock)
+			if got.Kind != models.DNS || got.Spec.DNSReq == nil || got.Spec.DNSResp == nil {
+				t.Fatalf("decoded mock = %+v, want a dns mock", got)
+			}
+			if !reflect.DeepEqual(*got.Spec.DNSReq, tt.req) {
+				t.Errorf("decoded request = %+v, want %+v", *got.Spec.DNSReq, tt.req)
+			}
+			if !reflect.DeepEqual(*got.Spec.DNSResp, tt.resp) {
+				t.Errorf("decoded response = %+v, want %+v", *got.Spec.DNSResp, tt.resp)
+			}
+			if got.Spec.Metadata["type"] != "config" {
+				t.Errorf("decoded metadata = %v, want the config type", got.Spec.Metadata)
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"go.keploy.io/server/v2/pkg/models"
@@ -36,6 +38,21 @@ func (fe *TestReport) GetAllTestRunIDs(ctx context.Context) ([]string, error) {
 	return yaml.ReadSessionIndices(ctx, fe.Path, fe.Logger)
 }
 
+// GetAllTestSetIDs returns the test sets which have a report in the test run.
+func (fe *TestReport) GetAllTestSetIDs(_ context.Context, testRunID string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(fe.Path, testRunID))
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to read the test run %s. error: %s", utils.Emoji, testRunID, err.Error())
+	}
+	var testSetIDs []string
+	for _, entry := range entries {
+		if testSetID, ok := strings.CutSuffix(entry.Name(), "-report.yaml"); ok && !entry.IsDir() {
+			testSetIDs = append(testSetIDs, testSetID)
+		}
+	}
+	return testSetIDs, nil
+}
+
 func (fe *TestReport) InsertTestCaseResult(_ context.Context, testRunID string, testSetID string, result *models.TestResult) error {
 	fe.m.Lock()
 	defer fe.m.Unlock()
@@ -52,6 +69,8 @@ func (fe *TestReport) InsertTestCaseResult(_ context.Context, testRunID string,
 }
 
 func (fe *TestReport) GetTestCaseResults(_ context.Context, testRunID string, testSetID string) ([]models.TestResult, error) {
+	fe.m.Lock()
+	defer fe.m.Unlock()
 	testRun, ok := fe.tests[testRunID]
 	if !ok {
 		return []models.TestResult{}, fmt.Errorf("%s found no test results for test report with id: %s", utils.Emoji, testRunID)
@@ -97,7 +97,7 @@ func (ts *TestYaml) GetTestCases(ctx context.Context, testSetID string) ([]*mode
 		tcs = append(tcs, tc)
 	}
 	sort.SliceStable(tcs, func(i, j int) bool {
-		return tcs[i].HTTPReq.Timestamp.Before(tcs[j].HTTPReq.Timestamp)
+		return tcs[i].RequestTimestamp().Before(tcs[j].RequestTimestamp())
 	})
 	return tcs, nil
 }
@@ -20,22 +20,25 @@ import (
 
 func EncodeTestcase(tc models.TestCase, logger *zap.Logger) (*yaml.NetworkTrafficDoc, error) {
 
-	curl := pkg.MakeCurlCommand(tc.HTTPReq)
 	doc := &yaml.NetworkTrafficDoc{
 		Version: tc.Version,
 		Kind:    tc.Kind,
 		Name:    tc.Name,
-		Curl:    curl,
-	}
-	// find noisy fields
-	m, err := FlattenHTTPResponse(pkg.ToHTTPHeader(tc.HTTPResp.Header), tc.HTTPResp.Body)
-	if err != nil {
-		msg := "error in flattening http response"
-		utils.LogError(logger, err, msg)
 	}
 	noise := tc.Noise
 
-	if tc.Name == "" {
+	// the curl command and the noisy fields are only derived for the http testcases
+	if tc.Kind == models.HTTP {
+		doc.Curl = pkg.MakeCurlCommand(tc.HTTPReq)
+	}
+
+	if tc.Name == "" && (tc.Kind == models.HTTP || tc.Kind == models.WebSocket) {
+		// find noisy fields
+		m, err := FlattenHTTPResponse(pkg.ToHTTPHeader(tc.HTTPResp.Header), tc.HTTPResp.Body)
+		if err != nil {
+			msg := "error in flattening http response"
+			utils.LogError(logger, err, msg)
+		}
 		noiseFieldsFound := FindNoisyFields(m, func(_ string, vals []string) bool {
 			// check if k is date
 			for _, v := range vals {
@@ -67,6 +70,33 @@ func EncodeTestcase(tc models.TestCase, logger *zap.Logger) (*yaml.NetworkTraffi
 			utils.LogError(logger, err, "failed to encode testcase into a yaml doc")
 			return nil, err
 		}
+	case models.WebSocket:
+		err := doc.Spec.Encode(models.WebSocketSchema{
+			Request:  tc.HTTPReq,
+			Response: tc.HTTPResp,
+			Messages: tc.WebSocket,
+			Created:  tc.Created,
+			Assertions: map[string]interface{}{
+				"noise": noise,
+			},
+		})
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode the websocket testcase into a yaml doc")
+			return nil, err
+		}
+	case models.GRPC_EXPORT:
+		err := doc.Spec.Encode(models.GrpcSpec{
+			GrpcReq:  tc.GrpcReq,
+			GrpcResp: tc.GrpcResp,
+			Created:  tc.Created,
+			Assertions: map[string]interface{}{
+				"noise": noise,
+			},
+		})
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode the gRPC testcase into a yaml doc")
+			return nil, err
+		}
 	default:
 		utils.LogError(logger, nil, "failed to marshal the testcase into yaml due to invalid kind of testcase")
 		return nil, errors.New("type of testcases is invalid")
@@ -243,20 +273,19 @@ func Decode(yamlTestcase *yaml.NetworkTrafficDoc, logger *zap.Logger) (*models.T
 		tc.Created = httpSpec.Created
 		tc.HTTPReq = httpSpec.Request
 		tc.HTTPResp = httpSpec.Response
-		tc.Noise = map[string][]string{}
-		switch reflect.ValueOf(httpSpec.Assertions["noise"]).Kind() {
-		case reflect.Map:
-			for k, v := range httpSpec.Assertions["noise"].(map[string]interface{}) {
-				tc.Noise[k] = []string{}
-				for _, val := range v.([]interface{}) {
-					tc.Noise[k] = append(tc.Noise[k], val.(string))
-				}
-			}
-		case reflect.Slice:
-			for _, v := range httpSpec.Assertions["noise"].([]interface{}) {
-				tc.Noise[v.(string)] = []string{}
-			}
+		tc.Noise = decodeNoise(httpSpec.Assertions["noise"])
+	case models.WebSocket:
+		webSocketSpec := models.WebSocketSchema{}
+		err := yamlTestcase.Spec.Decode(&webSocketSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to unmarshal a yaml doc into the websocket testcase")
+			return nil, err
 		}
+		tc.Created = webSocketSpec.Created
+		tc.HTTPReq = webSocketSpec.Request
+		tc.HTTPResp = webSocketSpec.Response
+		tc.WebSocket = webSocketSpec.Messages
+		tc.Noise = decodeNoise(webSocketSpec.Assertions["noise"])
 	// unmarshal its mocks from yaml docs to go struct
 	case models.GRPC_EXPORT:
 		grpcSpec := models.GrpcSpec{}
@@ -265,11 +294,33 @@ func Decode(yamlTestcase *yaml.NetworkTrafficDoc, logger *zap.Logger) (*models.T
 			utils.LogError(logger, err, "failed to unmarshal a yaml doc into the gRPC testcase")
 			return nil, err
 		}
+		tc.Created = grpcSpec.Created
 		tc.GrpcReq = grpcSpec.GrpcReq
 		tc.GrpcResp = grpcSpec.GrpcResp
+		tc.Noise = decodeNoise(grpcSpec.Assertions["noise"])
 	default:
 		utils.LogError(logger, nil, "failed to unmarshal yaml doc of unknown type", zap.Any("type of yaml doc", tc.Kind))
 		return nil, errors.New("yaml doc of unknown type")
 	}
 	return &tc, nil
 }
+
+// decodeNoise reads the noise assertion of a testcase, which is either a map of the noisy fields
+// to the regexes of their values or a plain list of the noisy fields.
+func decodeNoise(assertion interface{}) map[string][]string {
+	noise := map[string][]string{}
+	switch reflect.ValueOf(assertion).Kind() {
+	case reflect.Map:
+		for k, v := range assertion.(map[string]interface{}) {
+			noise[k] = []string{}
+			for _, val := range v.([]interface{}) {
+				noise[k] = append(noise[k], val.(string))
+			}
+		}
+	case reflect.Slice:
+		for _, v := range assertion.([]interface{}) {
+			noise[v.(string)] = []string{}
+		}
+	}
+	return noise
+}
@@ -110,9 +110,10 @@ func ExtractURLPath(URL string) (string, string) {
 	return parsedURL.Path, parsedURL.Host
 }
 
-func GenerateHeader(header map[string]string) []models.Parameter {
+func GenerateHeader(header models.HTTPHeader) []models.Parameter {
 	var parameters []models.Parameter
-	for key, value := range header {
+	for key := range header {
+		value := header.Get(key)
 		parameters = append(parameters, models.Parameter{
 			Name:     key,
 			In:       "header",
@@ -0,0 +1,106 @@
+//go:build linux
+
+package record
+
+import (
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// mockQueue holds back the recorded mocks until the test cases recorded along with them have been processed.
+// The mocks of the dependency calls of a test case are captured before the test case, which is only sent once
+// its response is, while the test case must be known to process its mocks: the secrets of its request sent to
+// the dependencies are redacted in them, and they are dropped along with the test case if it is sampled out.
+// A mock is released once a test case whose request was captured after the request of the mock has been
+// processed, or once the recording stops. The mocks are released in their order.
+type mockQueue struct {
+	mocks   []*models.Mock
+	kept    []timeWindow
+	dropped []timeWindow
+}
+
+// timeWindow is the time between the request and the response of a test case.
+type timeWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+func (w timeWindow) contains(t time.Time) bool {
+	return !t.Before(w.start) && !t.After(w.end)
+}
+
+func (q *mockQueue) push(mock *models.Mock) {
+	q.mocks = append(q.mocks, mock)
+}
+
+// addTestCase records the time window of a processed test case, the mocks captured in the windows of the
+// dropped test cases alone being dropped along with them.
+func (q *mockQueue) addTestCase(tc *models.TestCase, kept bool) {
+	window := timeWindow{start: tc.RequestTimestamp(), end: tc.ResponseTimestamp()}
+	if kept {
+		q.kept = append(q.kept, window)
+	} else {
+		q.dropped = append(q.dropped, window)
+	}
+}
+
+// release returns the held mocks whose request was captured before the time, up to the first one which wasn't,
+// along with the number of those dropped.
+func (q *mockQueue) release(before time.Time) ([]*models.Mock, int) {
+	n := 0
+	for n < len(q.mocks) && q.mocks[n].Spec.ReqTimestampMock.Before(before) {
+		n++
+	}
+	released, dropped := q.filter(q.mocks[:n])
+	q.mocks = q.mocks[n:]
+
+	// the windows ended before the held mocks are of no use anymore
+	oldest := before
+	if len(q.mocks) > 0 && q.mocks[0].Spec.ReqTimestampMock.Before(oldest) {
+		oldest = q.mocks[0].Spec.ReqTimestampMock
+	}
+	q.kept = pruneWindows(q.kept, oldest)
+	q.dropped = pruneWindows(q.dropped, oldest)
+	return released, dropped
+}
+
+// drain returns all the held mocks along with the number of those dropped.
+func (q *mockQueue) drain() ([]*models.Mock, int) {
+	released, dropped := q.filter(q.mocks)
+	q.mocks, q.kept, q.dropped = nil, nil, nil
+	return released, dropped
+}
+
+// filter leaves out the mocks captured in the window of a dropped test case, unless they are in the window of
+// a kept one too, as the concurrent test cases may share a mock.
+func (q *mockQueue) filter(mocks []*models.Mock) ([]*models.Mock, int) {
+	released := make([]*models.Mock, 0, len(mocks))
+	for _, mock := range mocks {
+		ts := mock.Spec.ReqTimestampMock
+		if inWindow(q.dropped, ts) && !inWindow(q.kept, ts) {
+			continue
+		}
+		released = append(released, mock)
+	}
+	return released, len(mocks) - len(released)
+}
+
+func inWindow(windows []timeWindow, t time.Time) bool {
+	for _, w := range windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func pruneWindows(windows []timeWindow, before time.Time) []timeWindow {
+	kept := windows[:0]
+	for _, w := range windows {
+		if !w.end.Before(before) {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,63 @@
+//go:build linux
+
+package record
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func TestMockQueue(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(offset int) time.Time {
+		return start.Add(time.Duration(offset) * time.Second)
+	}
+	testCase := func(from, to int) *models.TestCase {
+		return &models.TestCase{Kind: models.HTTP, HTTPReq: models.HTTPReq{Timestamp: at(from)}, HTTPResp: models.HTTPResp{Timestamp: at(to)}}
+	}
+	names := func(mocks []*models.Mock) []string {
+		var n []string
+		for _, m := range mocks {
+			n = append(n, m.Name)
+		}
+		return n
+	}
+
+	var q mockQueue
+	// mock-3 was captured out of order
+	for i, offset := range []int{1, 3, 6, 4, 8, 11} {
+		q.push(&models.Mock{Name: fmt.Sprintf("mock-%d", i), Spec: models.MockSpec{ReqTimestampMock: at(offset)}})
+	}
+
+	tests := []struct {
+		name        string
+		testCase    *models.TestCase
+		kept        bool
+		wantMocks   []string
+		wantDropped int
+	}{
+		{name: "no mock before the test case", testCase: testCase(0, 2), kept: true},
+		{name: "mocks before the test case", testCase: testCase(3, 5), wantMocks: []string{"mock-0"}},
+		{name: "mocks of a dropped test case", testCase: testCase(7, 9), kept: true, wantMocks: []string{"mock-2"}, wantDropped: 2},
+		{name: "mock shared with a kept test case", testCase: testCase(8, 10)},
+	}
+	for _, tt := range tests {
+		q.addTestCase(tt.testCase, tt.kept)
+		mocks, dropped := q.release(tt.testCase.RequestTimestamp())
+		if !reflect.DeepEqual(names(mocks), tt.wantMocks) || dropped != tt.wantDropped {
+			t.Errorf("%s: release() = %v, %d dropped, want %v, %d dropped", tt.name, names(mocks), dropped, tt.wantMocks, tt.wantDropped)
+		}
+	}
+	q.addTestCase(testCase(11, 12), false)
+	mocks, dropped := q.drain()
+	if want := []string{"mock-4"}; !reflect.DeepEqual(names(mocks), want) || dropped != 1 {
+		t.Errorf("drain() = %v, %d dropped, want %v, 1 dropped", names(mocks), dropped, want)
+	}
+	if mocks, dropped := q.drain(); len(mocks) != 0 || dropped != 0 {
+		t.Errorf("drain() of an empty queue = %v, %d dropped, want none", names(mocks), dropped)
+	}
+}
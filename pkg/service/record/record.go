@@ -67,6 +67,7 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 	var newTestSetID string
 	var testCount = 0
 	var mockCountMap = make(map[string]int)
+	var testSampler = newSampler(r.config.Record)
 
 	// defering the stop function to stop keploy in case of any error in record or in case of context cancellation
 	defer func() {
@@ -94,6 +95,9 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 		if err != nil {
 			utils.LogError(r.logger, err, "failed to stop recording")
 		}
+		if testSampler != nil {
+			testSampler.report(r.logger)
+		}
 		r.telemetry.RecordedTestSuite(newTestSetID, testCount, mockCountMap)
 	}()
 
@@ -108,6 +112,13 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 		return fmt.Errorf(stopReason)
 	}
 
+	redactor, err := newRedactor(r.logger, r.config.Record.Redact, r.config.Path)
+	if err != nil {
+		stopReason = "failed to set up the redaction of the recorded data"
+		utils.LogError(r.logger, err, stopReason)
+		return fmt.Errorf(stopReason)
+	}
+
 	//checking for context cancellation as we don't want to start the instrumentation if the context is cancelled
 	select {
 	case <-ctx.Done():
@@ -136,36 +147,77 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 		return fmt.Errorf(stopReason)
 	}
 
-	errGrp.Go(func() error {
-		for testCase := range frames.Incoming {
-			err := r.testDB.InsertTestCase(ctx, testCase, newTestSetID)
-			if err != nil {
-				if ctx.Err() == context.Canceled {
-					continue
-				}
-				insertTestErrChan <- err
-			} else {
-
-				testCount++
-				r.telemetry.RecordedTestAndMocks()
+	insertMock := func(mock *models.Mock) {
+		if redactor != nil {
+			redactor.redactMock(mock)
+		}
+		err := r.mockDB.InsertMock(ctx, mock, newTestSetID)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				return
 			}
+			insertMockErrChan <- err
+			return
 		}
-		return nil
-	})
+		mockCountMap[mock.GetKind()]++
+		r.telemetry.RecordedTestCaseMock(mock.GetKind())
+	}
 
+	// the test cases and the mocks are processed in a single goroutine, the mocks being held back until the
+	// test cases recorded along with them are redacted or sampled (see mockQueue)
+	hold := redactor != nil || testSampler != nil
 	errGrp.Go(func() error {
-		for mock := range frames.Outgoing {
-			err := r.mockDB.InsertMock(ctx, mock, newTestSetID)
-			if err != nil {
-				if ctx.Err() == context.Canceled {
+		var held mockQueue
+		incoming, outgoing := frames.Incoming, frames.Outgoing
+		for incoming != nil || outgoing != nil {
+			select {
+			case mock, ok := <-outgoing:
+				if !ok {
+					outgoing = nil
 					continue
 				}
-				insertMockErrChan <- err
-			} else {
-				mockCountMap[mock.GetKind()]++
-				r.telemetry.RecordedTestCaseMock(mock.GetKind())
+				if !hold {
+					insertMock(mock)
+					continue
+				}
+				held.push(mock)
+			case testCase, ok := <-incoming:
+				if !ok {
+					incoming = nil
+					continue
+				}
+				kept := testSampler == nil || testSampler.keep(r.logger, testCase)
+				held.addTestCase(testCase, kept)
+				if kept {
+					if redactor != nil {
+						redactor.redactTestCase(testCase)
+					}
+					err := r.testDB.InsertTestCase(ctx, testCase, newTestSetID)
+					if err != nil {
+						if ctx.Err() != context.Canceled {
+							insertTestErrChan <- err
+						}
+					} else {
+						testCount++
+						r.telemetry.RecordedTestAndMocks()
+					}
+				}
+				mocks, dropped := held.release(testCase.RequestTimestamp())
+				for _, mock := range mocks {
+					insertMock(mock)
+				}
+				if testSampler != nil {
+					testSampler.droppedMocks += dropped
+				}
 			}
 		}
+		mocks, dropped := held.drain()
+		for _, mock := range mocks {
+			insertMock(mock)
+		}
+		if testSampler != nil {
+			testSampler.droppedMocks += dropped
+		}
 		return nil
 	})
 
@@ -267,6 +319,7 @@ func (r *Recorder) Instrument(ctx context.Context) (uint64, error) {
 func (r *Recorder) GetTestAndMockChans(ctx context.Context, appID uint64) (FrameChan, error) {
 	incomingOpts := models.IncomingOptions{
 		Filters: r.config.Record.Filters,
+		Proto:   r.config.Proto,
 	}
 	incomingChan, err := r.instrumentation.GetIncoming(ctx, appID, incomingOpts)
 	if err != nil {
@@ -277,6 +330,8 @@ func (r *Recorder) GetTestAndMockChans(ctx context.Context, appID uint64) (Frame
 		Rules:          r.config.BypassRules,
 		MongoPassword:  r.config.Test.MongoPassword,
 		FallBackOnMiss: r.config.Test.FallBackOnMiss,
+		Proto:          r.config.Proto,
+		ProtocolMap:    r.config.ProtocolMap,
 	}
 	outgoingChan, err := r.instrumentation.GetOutgoing(ctx, appID, outgoingOpts)
 	if err != nil {
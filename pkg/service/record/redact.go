@@ -0,0 +1,626 @@
+//go:build linux
+
+package record
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// redactionKeyFile is the file of the keploy directory holding the generated key of the placeholders.
+const redactionKeyFile = ".redaction-key"
+
+// minSecretLength is the length of the shortest value redacted by a header or a JSON path which is looked for
+// in the other texts, the shorter ones would be found in unrelated texts.
+const minSecretLength = 6
+
+// placeholderJWTHeader is the header of the placeholder JWTs, {"alg":"none","typ":"JWT"} encoded in base64url.
+const placeholderJWTHeader = "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0"
+
+var (
+	emailRegex = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	jwtRegex   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	// the card numbers start with the digit of their network (2 to 6), which leaves out the timestamps
+	panRegex = regexp.MustCompile(`\b[2-6](?:[ -]?\d){13,18}\b`)
+)
+
+type detector struct {
+	regex *regexp.Regexp
+	valid func(string) bool // validates the matches of the regex, if set
+}
+
+var detectors = map[string]detector{
+	"email": {regex: emailRegex},
+	"jwt":   {regex: jwtRegex},
+	"pan":   {regex: panRegex, valid: isPAN},
+}
+
+// redactor replaces the secrets and the personal data of the recorded test cases and mocks by placeholders.
+// A placeholder only depends on the value it replaces, so that a value shared by several test cases and mocks
+// is replaced by the same placeholder everywhere and the templates still line up on replay.
+//
+// The application is replayed with the placeholders, and sends them to the dependencies wherever it sent the
+// values, hence the values must be redacted in the same way in the mocks. The regexes and the detectors match
+// the values wherever they are, and the headers are redacted by their name in the mocks too. The values
+// redacted by a header or a JSON path are also kept in secrets and looked for in the texts redacted afterwards,
+// e.g. a token of a response sent back by the application in a header, or a password of the request of a
+// test case sent by the application to a dependency. The mocks are hence redacted after the test cases recorded
+// along with them (see mockQueue). The JSON paths aren't applied to the requests of the mocks, as the
+// application sends its own values of those fields to its dependencies on replay too.
+type redactor struct {
+	key       []byte
+	headers   map[string]bool
+	paths     [][]string
+	regexes   []*regexp.Regexp
+	detectors []detector
+	secrets   *secrets
+}
+
+// secrets holds the values redacted by the headers and the JSON paths.
+type secrets struct {
+	mu     sync.RWMutex
+	values map[string]bool
+}
+
+func (s *secrets) add(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	s.mu.Lock()
+	s.values[value] = true
+	s.mu.Unlock()
+}
+
+func (s *secrets) list() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	values := make([]string, 0, len(s.values))
+	for value := range s.values {
+		values = append(values, value)
+	}
+	return values
+}
+
+// newRedactor returns the redactor of the redaction config, nil if there is nothing to redact.
+func newRedactor(logger *zap.Logger, cfg config.Redact, keployPath string) (*redactor, error) {
+	if len(cfg.Headers) == 0 && len(cfg.JSONPaths) == 0 && len(cfg.Regexes) == 0 && len(cfg.Detectors) == 0 {
+		return nil, nil
+	}
+
+	rd := &redactor{headers: map[string]bool{}, secrets: &secrets{values: map[string]bool{}}}
+	for _, header := range cfg.Headers {
+		rd.headers[strings.ToLower(header)] = true
+	}
+	for _, path := range cfg.JSONPaths {
+		rd.paths = append(rd.paths, strings.Split(path, "."))
+	}
+	for _, expr := range cfg.Regexes {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction regex %q: %w", expr, err)
+		}
+		rd.regexes = append(rd.regexes, regex)
+	}
+	for _, name := range cfg.Detectors {
+		d, ok := detectors[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q, expected one of email, pan, jwt", name)
+		}
+		rd.detectors = append(rd.detectors, d)
+	}
+
+	key, err := redactionKey(logger, cfg.Key, keployPath)
+	if err != nil {
+		return nil, err
+	}
+	rd.key = key
+	return rd, nil
+}
+
+// redactionKey returns the key of the placeholders. Unless it is set in the config, the key is generated once
+// and kept in the keploy directory, out of git, so that the placeholders stay the same across the recordings.
+func redactionKey(logger *zap.Logger, key string, keployPath string) ([]byte, error) {
+	if key != "" {
+		return []byte(key), nil
+	}
+
+	path := filepath.Join(keployPath, redactionKeyFile)
+	data, err := os.ReadFile(path)
+	if err == nil && len(bytes.TrimSpace(data)) > 0 {
+		return bytes.TrimSpace(data), nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read the redaction key: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the redaction key: %w", err)
+	}
+	data = []byte(hex.EncodeToString(buf))
+	err = os.MkdirAll(keployPath, 0777)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the keploy directory: %w", err)
+	}
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the redaction key: %w", err)
+	}
+	err = utils.AddToGitIgnore(logger, keployPath, "/"+redactionKeyFile)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add the redaction key to .gitignore")
+	}
+	logger.Info("generated the key of the redaction placeholders", zap.String("path", path))
+	return data, nil
+}
+
+// requests returns the redactor of the requests of the mocks, which doesn't apply the JSON paths.
+func (rd *redactor) requests() *redactor {
+	req := *rd
+	req.paths = nil
+	return &req
+}
+
+// redactTestCase redacts a test case in place. The redacted fields of the response are added to the noise of
+// the test case, since the application keeps sending the real values on replay. The request is redacted first,
+// so that its values are looked for in the response.
+func (rd *redactor) redactTestCase(tc *models.TestCase) {
+	var headers, body, events []string
+	switch tc.Kind {
+	case models.HTTP:
+		rd.redactHTTPReq(&tc.HTTPReq)
+		headers = rd.redactHTTPHeaders(tc.HTTPResp.Header)
+		tc.HTTPResp.Body, body = rd.redactBody(tc.HTTPResp.Body)
+		// the redacted fields of the events of a stream are noisy for that event alone
+		for i := range tc.HTTPResp.Stream {
+			var fields []string
+			tc.HTTPResp.Stream[i].Data, fields = rd.redactBody(tc.HTTPResp.Stream[i].Data)
+			for _, field := range fields {
+				events = append(events, strings.TrimSuffix("stream."+strconv.Itoa(i)+".data."+field, "."))
+			}
+		}
+	case models.WebSocket:
+		rd.redactHTTPReq(&tc.HTTPReq)
+		headers = rd.redactHTTPHeaders(tc.HTTPResp.Header)
+		// the messages of the server are compared as the events of a stream, in their order
+		event := 0
+		for i, msg := range tc.WebSocket {
+			if msg.Type != models.WebSocketText {
+				if msg.Origin == models.FromServer {
+					event++
+				}
+				continue
+			}
+			if msg.Origin != models.FromServer {
+				tc.WebSocket[i].Data, _ = rd.redactBody(msg.Data)
+				continue
+			}
+			var fields []string
+			tc.WebSocket[i].Data, fields = rd.redactBody(msg.Data)
+			for _, field := range fields {
+				events = append(events, strings.TrimSuffix("stream."+strconv.Itoa(event)+".data."+field, "."))
+			}
+			event++
+		}
+	case models.GRPC_EXPORT:
+		rd.redactHeaders(tc.GrpcReq.Headers.OrdinaryHeaders)
+		rd.redactGrpcMessage(&tc.GrpcReq.Body)
+		for i := range tc.GrpcReq.Stream {
+			rd.redactGrpcMessage(&tc.GrpcReq.Stream[i].GrpcLengthPrefixedMessage)
+		}
+		headers = append(rd.redactHeaders(tc.GrpcResp.Headers.OrdinaryHeaders), rd.redactHeaders(tc.GrpcResp.Trailers.OrdinaryHeaders)...)
+		body = rd.redactGrpcMessage(&tc.GrpcResp.Body)
+		for i := range tc.GrpcResp.Stream {
+			body = append(body, rd.redactGrpcMessage(&tc.GrpcResp.Stream[i].GrpcLengthPrefixedMessage)...)
+		}
+	}
+
+	if len(headers) == 0 && len(body) == 0 && len(events) == 0 {
+		return
+	}
+	if tc.Noise == nil {
+		tc.Noise = map[string][]string{}
+	}
+	for _, header := range headers {
+		tc.Noise["header."+header] = []string{}
+	}
+	for _, field := range body {
+		if field == "" {
+			tc.Noise["body"] = []string{}
+			continue
+		}
+		tc.Noise["body."+field] = []string{}
+	}
+	for _, field := range events {
+		tc.Noise[field] = []string{}
+	}
+}
+
+// redactHTTPReq redacts the headers, the query, the form and the body of a request. The path is left as it
+// is since the mocks are looked up by their path.
+func (rd *redactor) redactHTTPReq(req *models.HTTPReq) {
+	rd.redactHTTPHeaders(req.Header)
+	req.Body, _ = rd.redactBody(req.Body)
+	if i := strings.IndexByte(req.URL, '?'); i >= 0 {
+		req.URL = req.URL[:i+1] + rd.redactQuery(req.URL[i+1:])
+	}
+	for key, value := range req.URLParams {
+		req.URLParams[key], _ = rd.redactField(key, value)
+	}
+	for i := range req.Form {
+		for j, value := range req.Form[i].Values {
+			req.Form[i].Values[j], _ = rd.redactField(req.Form[i].Key, value)
+		}
+	}
+}
+
+// redactHeaders redacts the values of the gRPC headers in place and returns the names of the redacted ones.
+func (rd *redactor) redactHeaders(headers map[string]string) []string {
+	var redacted []string
+	for name, value := range headers {
+		if text, ok := rd.redactHeader(name, value); ok {
+			headers[name] = text
+			redacted = append(redacted, name)
+		}
+	}
+	return redacted
+}
+
+// redactHTTPHeaders redacts the values of the http headers in place and returns the names of the redacted ones.
+func (rd *redactor) redactHTTPHeaders(headers models.HTTPHeader) []string {
+	var redacted []string
+	for name, values := range headers {
+		changed := false
+		for i, value := range values {
+			if text, ok := rd.redactHeader(name, value); ok {
+				values[i] = text
+				changed = true
+			}
+		}
+		if changed {
+			redacted = append(redacted, name)
+		}
+	}
+	return redacted
+}
+
+// redactHeader redacts the value of a header, the whole value if the header is listed in the config.
+func (rd *redactor) redactHeader(name string, value string) (string, bool) {
+	if rd.headers[strings.ToLower(name)] {
+		return rd.secret(value), true
+	}
+	return rd.redactText(value)
+}
+
+// redactGrpcMessage redacts a gRPC message in place and returns the flattened paths of its redacted fields.
+func (rd *redactor) redactGrpcMessage(msg *models.GrpcLengthPrefixedMessage) []string {
+	if msg.DecodedJSON != "" {
+		var redacted []string
+		msg.DecodedJSON, redacted = rd.redactBody(msg.DecodedJSON)
+		return redacted
+	}
+	// the messages decoded without their descriptor have no field names to add to the noise
+	msg.DecodedData, _ = rd.redactText(msg.DecodedData)
+	return nil
+}
+
+// redactQuery redacts the values of a raw query, keeping the order of its parameters.
+func (rd *redactor) redactQuery(query string) string {
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, value, found := strings.Cut(param, "=")
+		if !found {
+			continue
+		}
+		k, err := url.QueryUnescape(key)
+		if err != nil {
+			continue
+		}
+		v, err := url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		if redacted, ok := rd.redactField(k, v); ok {
+			params[i] = key + "=" + url.QueryEscape(redacted)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// redactField redacts the value of a query parameter or of a form field, whose name is matched against the
+// top level of the JSON paths.
+func (rd *redactor) redactField(name string, value string) (string, bool) {
+	if rd.matchesPath([]string{name}) {
+		return rd.secret(value), true
+	}
+	return rd.redactText(value)
+}
+
+// redactBody redacts a body, field by field if it is JSON. It returns the body along with the flattened paths of
+// its redacted fields, the empty path standing for a body which isn't JSON.
+func (rd *redactor) redactBody(body string) (string, []string) {
+	// the binary bodies are left as they are, a placeholder would corrupt them
+	if strings.TrimSpace(body) == "" || models.EncodingOf(body).IsBinary() {
+		return body, nil
+	}
+
+	if json.Valid([]byte(body)) {
+		decoder := json.NewDecoder(strings.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err == nil {
+			var redacted []string
+			value = rd.redactJSON(value, nil, nil, &redacted)
+			if len(redacted) == 0 {
+				return body, nil
+			}
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			err = encoder.Encode(value)
+			if err == nil {
+				return strings.TrimSuffix(buf.String(), "\n"), redacted
+			}
+		}
+	}
+
+	text, ok := rd.redactText(body)
+	if !ok {
+		return body, nil
+	}
+	return text, []string{""}
+}
+
+// redactJSON redacts the leaves of a decoded JSON value which are under a JSON path or which contain a match of
+// the regexes and the detectors. path holds the keys and the indices leading to the value, keys only the keys,
+// as in the flattened paths of the noise.
+func (rd *redactor) redactJSON(value interface{}, path []string, keys []string, redacted *[]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = rd.redactJSON(child, append(path[:len(path):len(path)], key), append(keys[:len(keys):len(keys)], key), redacted)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = rd.redactJSON(child, append(path[:len(path):len(path)], strconv.Itoa(i)), keys, redacted)
+		}
+		return v
+	}
+
+	var out interface{} = value
+	changed := false
+	switch v := value.(type) {
+	case string:
+		if rd.matchesPath(path) {
+			out, changed = rd.secret(v), true
+		} else {
+			out, changed = rd.redactText(v)
+		}
+	case json.Number:
+		// the numbers are replaced by numbers so that the body keeps its schema, which the mocks are matched on
+		if rd.matchesPath(path) {
+			out, changed = rd.numberPlaceholder(v), true
+		}
+	}
+	if changed {
+		*redacted = append(*redacted, strings.Join(keys, "."))
+	}
+	return out
+}
+
+// matchesPath tells whether a JSON path matches the path or one of its parents.
+func (rd *redactor) matchesPath(path []string) bool {
+	for _, p := range rd.paths {
+		if len(p) > len(path) {
+			continue
+		}
+		matched := true
+		for i, key := range p {
+			if key != "*" && key != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// redactText replaces the matches of the regexes, the detectors and the secrets in a text, returning whether
+// any was found. The regexes with capture groups only have their groups replaced.
+func (rd *redactor) redactText(text string) (string, bool) {
+	if text == "" {
+		return text, false
+	}
+
+	var spans [][]int
+	for _, value := range rd.secrets.list() {
+		for i := 0; ; {
+			j := strings.Index(text[i:], value)
+			if j < 0 {
+				break
+			}
+			spans = append(spans, []int{i + j, i + j + len(value)})
+			i += j + len(value)
+		}
+	}
+	for _, regex := range rd.regexes {
+		for _, m := range regex.FindAllStringSubmatchIndex(text, -1) {
+			if len(m) == 2 {
+				spans = append(spans, m)
+				continue
+			}
+			for i := 2; i+1 < len(m); i += 2 {
+				if m[i] >= 0 {
+					spans = append(spans, m[i:i+2])
+				}
+			}
+		}
+	}
+	for _, d := range rd.detectors {
+		for _, m := range d.regex.FindAllStringIndex(text, -1) {
+			if d.valid == nil || d.valid(text[m[0]:m[1]]) {
+				spans = append(spans, m)
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return text, false
+	}
+
+	// the matches are replaced from left to right, the longest one first, skipping the overlapping ones
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i][0] != spans[j][0] {
+			return spans[i][0] < spans[j][0]
+		}
+		return spans[i][1] > spans[j][1]
+	})
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span[0] < last || span[0] == span[1] {
+			continue
+		}
+		b.WriteString(text[last:span[0]])
+		b.WriteString(rd.placeholder(text[span[0]:span[1]]))
+		last = span[1]
+	}
+	if last == 0 {
+		return text, false
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
+}
+
+// sum returns the keyed hash of a value. Keying the hash keeps the short values, such as the card numbers, from
+// being recovered by hashing all the candidates.
+func (rd *redactor) sum(value string) []byte {
+	mac := hmac.New(sha256.New, rd.key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// secret returns the placeholder of a value redacted by a header or a JSON path, and keeps the value to look
+// for it in the other texts.
+func (rd *redactor) secret(value string) string {
+	rd.secrets.add(value)
+	return rd.placeholder(value)
+}
+
+// placeholder returns the placeholder of a value, keeping the format of the emails, the JWTs and the card numbers.
+func (rd *redactor) placeholder(value string) string {
+	sum := rd.sum(value)
+	id := hex.EncodeToString(sum)[:16]
+	switch {
+	case fullMatch(emailRegex, value):
+		return "redacted-" + id + "@redacted.invalid"
+	case fullMatch(jwtRegex, value):
+		payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"redacted-` + id + `"}`))
+		return placeholderJWTHeader + "." + payload + "." + base64.RawURLEncoding.EncodeToString(sum)
+	case fullMatch(panRegex, value) && isPAN(value):
+		return placeholderPAN(value, sum)
+	}
+	return "REDACTED-" + id
+}
+
+// numberPlaceholder returns the placeholder of a number, an integer with as many digits.
+func (rd *redactor) numberPlaceholder(value json.Number) json.Number {
+	sum := rd.sum(value.String())
+	n := 0
+	for _, c := range value.String() {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	digits := make([]byte, max(n, 1))
+	for i := range digits {
+		digits[i] = '0' + sum[i%len(sum)]%10
+	}
+	if len(digits) > 1 && digits[0] == '0' {
+		digits[0] = '1'
+	}
+	return json.Number(digits)
+}
+
+// placeholderPAN returns a card number which passes the Luhn check, keeping the network digit and the
+// separators of the card number.
+func placeholderPAN(pan string, sum []byte) string {
+	out := []byte(pan)
+	var positions []int
+	for i, c := range out {
+		if c >= '0' && c <= '9' {
+			positions = append(positions, i)
+		}
+	}
+	digits := make([]byte, len(positions))
+	digits[0] = out[positions[0]] - '0'
+	for i := 1; i < len(digits)-1; i++ {
+		digits[i] = sum[i%len(sum)] % 10
+	}
+	for check := byte(0); check < 10; check++ {
+		digits[len(digits)-1] = check
+		if luhn(digits) {
+			break
+		}
+	}
+	for i, pos := range positions {
+		out[pos] = '0' + digits[i]
+	}
+	return string(out)
+}
+
+// isPAN tells whether a match of the card number regex is a card number, i.e. passes the Luhn check.
+func isPAN(value string) bool {
+	var digits []byte
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, byte(c-'0'))
+		}
+	}
+	return len(digits) >= 14 && len(digits) <= 19 && luhn(digits)
+}
+
+func luhn(digits []byte) bool {
+	total := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i])
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		total += d
+	}
+	return total%10 == 0
+}
+
+func fullMatch(regex *regexp.Regexp, value string) bool {
+	loc := regex.FindStringIndex(value)
+	return loc != nil && loc[0] == 0 && loc[1] == len(value)
+}
@@ -0,0 +1,325 @@
+//go:build linux
+
+package record
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// redactMock redacts a mock in place. The payloads are only redacted where their format allows it, i.e. where
+// they are encoded again from their fields on replay. The raw payloads, such as the generic ones and the kafka
+// responses, are left as they are since a placeholder would corrupt them.
+func (rd *redactor) redactMock(mock *models.Mock) {
+	req := rd.requests()
+	switch mock.Kind {
+	case models.HTTP:
+		if mock.Spec.HTTPReq != nil {
+			req.redactHTTPReq(mock.Spec.HTTPReq)
+		}
+		if mock.Spec.HTTPResp != nil {
+			rd.redactHTTPHeaders(mock.Spec.HTTPResp.Header)
+			mock.Spec.HTTPResp.Body, _ = rd.redactBody(mock.Spec.HTTPResp.Body)
+		}
+	case models.WebSocket:
+		if mock.Spec.HTTPReq != nil {
+			req.redactHTTPHeaders(mock.Spec.HTTPReq.Header)
+		}
+		if mock.Spec.HTTPResp != nil {
+			rd.redactHTTPHeaders(mock.Spec.HTTPResp.Header)
+		}
+		for i, msg := range mock.Spec.WebSocketMessages {
+			if msg.Type != models.WebSocketText {
+				continue
+			}
+			if msg.Origin == models.FromServer {
+				mock.Spec.WebSocketMessages[i].Data, _ = rd.redactBody(msg.Data)
+			} else {
+				mock.Spec.WebSocketMessages[i].Data, _ = req.redactBody(msg.Data)
+			}
+		}
+	case models.GRPC_EXPORT:
+		if mock.Spec.GRPCReq != nil {
+			req.redactHeaders(mock.Spec.GRPCReq.Headers.OrdinaryHeaders)
+			req.redactGrpcMessage(&mock.Spec.GRPCReq.Body)
+			for i := range mock.Spec.GRPCReq.Stream {
+				req.redactGrpcMessage(&mock.Spec.GRPCReq.Stream[i].GrpcLengthPrefixedMessage)
+			}
+		}
+		if mock.Spec.GRPCResp != nil {
+			rd.redactHeaders(mock.Spec.GRPCResp.Headers.OrdinaryHeaders)
+			rd.redactHeaders(mock.Spec.GRPCResp.Trailers.OrdinaryHeaders)
+			rd.redactGrpcMessage(&mock.Spec.GRPCResp.Body)
+			for i := range mock.Spec.GRPCResp.Stream {
+				rd.redactGrpcMessage(&mock.Spec.GRPCResp.Stream[i].GrpcLengthPrefixedMessage)
+			}
+		}
+	case models.Postgres:
+		rd.redactPostgres(mock)
+	case models.MySQL:
+		rd.redactMySQL(mock)
+	case models.Mongo:
+		rd.redactMongo(mock)
+	case models.REDIS:
+		rd.redactRedis(mock)
+	case models.KAFKA:
+		// the responses are replayed from their raw frame, only the decoded requests are matched
+		if mock.Spec.KafkaRequest != nil && mock.Spec.KafkaRequest.Body != nil {
+			var redacted []string
+			req.redactJSON(mock.Spec.KafkaRequest.Body, nil, nil, &redacted)
+		}
+	}
+}
+
+// redactPostgres redacts the queries, the text parameters and the text values of the rows of a postgres mock.
+// The messages recorded with their raw payload, which couldn't be encoded again from their fields, are skipped.
+func (rd *redactor) redactPostgres(mock *models.Mock) {
+	for i := range mock.Spec.PostgresRequests {
+		req := &mock.Spec.PostgresRequests[i]
+		if req.Payload != "" {
+			continue
+		}
+		req.Query.String, _ = rd.redactText(req.Query.String)
+		for j := range req.Parses {
+			req.Parses[j].Query, _ = rd.redactText(req.Parses[j].Query)
+		}
+		for j := range req.Binds {
+			bind := &req.Binds[j]
+			for k := range bind.Parameters {
+				if pgFormat(bind.ParameterFormatCodes, k) == 0 {
+					bind.Parameters[k] = rd.redactBytes(bind.Parameters[k])
+				}
+			}
+		}
+	}
+	for i := range mock.Spec.PostgresResponses {
+		resp := &mock.Spec.PostgresResponses[i]
+		if resp.Payload != "" {
+			continue
+		}
+		fields := resp.RowDescription.Fields
+		for j := range resp.DataRows {
+			for k, value := range resp.DataRows[j].Values {
+				if k < len(fields) && fields[k].Format != 0 {
+					continue
+				}
+				resp.DataRows[j].Values[k] = rd.redactBytes(value)
+			}
+		}
+	}
+}
+
+// pgFormat returns the format of a parameter of a bind message: a single format code applies to all the
+// parameters, and no format code stands for the text format.
+func pgFormat(codes []int16, i int) int16 {
+	switch {
+	case len(codes) == 0:
+		return 0
+	case len(codes) == 1:
+		return codes[0]
+	case i < len(codes):
+		return codes[i]
+	}
+	return 0
+}
+
+// redactBytes redacts a value sent in the text format, the values which aren't text are left as they are.
+func (rd *redactor) redactBytes(value []byte) []byte {
+	if value == nil || !utf8.Valid(value) {
+		return value
+	}
+	if text, ok := rd.redactText(string(value)); ok {
+		return []byte(text)
+	}
+	return value
+}
+
+// redactMySQL redacts the queries and the string values of the rows of a mysql mock. The payload lengths of the
+// packets are replayed as recorded, hence they are updated along with the values.
+func (rd *redactor) redactMySQL(mock *models.Mock) {
+	for _, req := range mock.Spec.MySQLRequests {
+		switch msg := req.Message.(type) {
+		case *mysql.QueryPacket:
+			msg.Query = rd.redactMySQLQuery(msg.Query, req.Header)
+		case *mysql.StmtPreparePacket:
+			msg.Query = rd.redactMySQLQuery(msg.Query, req.Header)
+		}
+	}
+	for _, resp := range mock.Spec.MySQLResponses {
+		switch msg := resp.Message.(type) {
+		case *mysql.TextResultSet:
+			for _, row := range msg.Rows {
+				rd.redactMySQLRow(&row.Header, row.Values)
+			}
+		case *mysql.BinaryProtocolResultSet:
+			for _, row := range msg.Rows {
+				rd.redactMySQLRow(&row.Header, row.Values)
+			}
+		}
+	}
+}
+
+func (rd *redactor) redactMySQLQuery(query string, header *mysql.PacketInfo) string {
+	redacted, ok := rd.redactText(query)
+	if !ok || header == nil || header.Header == nil {
+		return query
+	}
+	header.Header.PayloadLength = uint32(int(header.Header.PayloadLength) + len(redacted) - len(query))
+	return redacted
+}
+
+// redactMySQLRow redacts the string values of a row, which are written as length encoded strings.
+func (rd *redactor) redactMySQLRow(header *mysql.Header, values []mysql.ColumnEntry) {
+	for i, entry := range values {
+		value, ok := entry.Value.(string)
+		if !ok || !isMySQLString(entry.Type) {
+			continue
+		}
+		redacted, ok := rd.redactText(value)
+		if !ok {
+			continue
+		}
+		values[i].Value = redacted
+		header.PayloadLength = uint32(int(header.PayloadLength) + lengthEncodedSize(redacted) - lengthEncodedSize(value))
+	}
+}
+
+func isMySQLString(fieldType mysql.FieldType) bool {
+	switch fieldType {
+	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeJSON:
+		return true
+	}
+	return false
+}
+
+// lengthEncodedSize returns the size of a mysql length encoded string.
+func lengthEncodedSize(s string) int {
+	switch n := len(s); {
+	case n <= 250:
+		return 1 + n
+	case n <= 0xFFFF:
+		return 3 + n
+	case n <= 0xFFFFFF:
+		return 4 + n
+	default:
+		return 9 + n
+	}
+}
+
+// redactMongo redacts the sections of the OP_MSG requests and responses of a mongo mock.
+func (rd *redactor) redactMongo(mock *models.Mock) {
+	for _, req := range mock.Spec.MongoRequests {
+		if msg, ok := req.Message.(*models.MongoOpMessage); ok {
+			for i, section := range msg.Sections {
+				msg.Sections[i] = rd.redactMongoSection(section)
+			}
+		}
+	}
+	for _, resp := range mock.Spec.MongoResponses {
+		if msg, ok := resp.Message.(*models.MongoOpMessage); ok {
+			for i, section := range msg.Sections {
+				msg.Sections[i] = rd.redactMongoSection(section)
+			}
+		}
+	}
+}
+
+// redactMongoSection redacts the documents of an OP_MSG section, which are encoded again from their extended
+// JSON on replay. The section is left as it is if the documents aren't valid JSON once redacted.
+func (rd *redactor) redactMongoSection(section string) string {
+	text, ok := rd.redactText(section)
+	if !ok {
+		return section
+	}
+	if doc, found := strings.CutPrefix(text, "{ SectionSingle msg: "); found {
+		if !json.Valid([]byte(strings.TrimSuffix(doc, " }"))) {
+			return section
+		}
+		return text
+	}
+	// { SectionSingle identifier: documents , msgs: [ {...}, {...} ] }
+	_, docs, found := strings.Cut(text, " , msgs: [ ")
+	if !found {
+		return section
+	}
+	for _, doc := range strings.Split(strings.TrimSuffix(docs, " ] }"), ", ") {
+		if !json.Valid([]byte(doc)) {
+			return section
+		}
+	}
+	return text
+}
+
+// redactRedis redacts the arguments of the commands and the string replies of a redis mock.
+func (rd *redactor) redactRedis(mock *models.Mock) {
+	for i := range mock.Spec.RedisCommands {
+		for j, arg := range mock.Spec.RedisCommands[i].Args {
+			mock.Spec.RedisCommands[i].Args[j], _ = rd.redactText(arg)
+		}
+	}
+	for i := range mock.Spec.RedisResponses {
+		for j, msg := range mock.Spec.RedisResponses[i].Message {
+			// the binary replies are left as they are
+			if msg.Type == models.String {
+				mock.Spec.RedisResponses[i].Message[j].Data = rd.redactRESP(msg.Data)
+			}
+		}
+	}
+}
+
+// redactRESP redacts the simple and the bulk strings of RESP replies, updating the lengths of the bulk strings.
+// The replies are left as they are if they can't be parsed.
+func (rd *redactor) redactRESP(data string) string {
+	var b strings.Builder
+	changed := false
+	for rest := data; rest != ""; {
+		end := strings.Index(rest, "\r\n")
+		if end < 0 {
+			return data
+		}
+		line := rest[:end]
+		rest = rest[end+2:]
+		if line == "" {
+			return data
+		}
+
+		switch line[0] {
+		case '+':
+			if text, ok := rd.redactText(line[1:]); ok {
+				line, changed = "+"+text, true
+			}
+			b.WriteString(line + "\r\n")
+		case '$', '=', '!':
+			n, err := strconv.Atoi(line[1:])
+			if err != nil {
+				return data
+			}
+			if n < 0 {
+				// null bulk string
+				b.WriteString(line + "\r\n")
+				continue
+			}
+			if len(rest) < n+2 || rest[n:n+2] != "\r\n" {
+				return data
+			}
+			value := rest[:n]
+			rest = rest[n+2:]
+			// the verbatim strings and the bulk errors are left as they are
+			if text, ok := rd.redactText(value); ok && line[0] == '$' {
+				line, value, changed = "$"+strconv.Itoa(len(text)), text, true
+			}
+			b.WriteString(line + "\r\n" + value + "\r\n")
+		default:
+			b.WriteString(line + "\r\n")
+		}
+	}
+	if !changed {
+		return data
+	}
+	return b.String()
+}
@@ -0,0 +1,243 @@
+//go:build linux
+
+package record
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+var (
+	uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	idRegex   = regexp.MustCompile(`^(?:\d+|[0-9a-fA-F]{16,})$`)
+)
+
+// endpointStats counts the testcases of an endpoint recorded and dropped by the sampler.
+type endpointStats struct {
+	recorded    int
+	duplicates  int
+	rateLimited int
+	sampledOut  int
+}
+
+func (s *endpointStats) dropped() int {
+	return s.duplicates + s.rateLimited + s.sampledOut
+}
+
+// rateWindow is the current minute of the rate limit of an endpoint.
+type rateWindow struct {
+	start time.Time
+	count uint
+}
+
+// sampler picks the testcases to record out of the incoming traffic: it drops the testcases with the shape of
+// an already recorded one, then the ones over the rate limit of their endpoint, then samples the rest. The
+// mocks captured while a dropped testcase was running are dropped along with it (see mockQueue).
+type sampler struct {
+	percentage float64
+	rateLimit  uint
+	dedup      bool
+
+	shapes    map[string]bool
+	windows   map[string]*rateWindow
+	endpoints map[string]*endpointStats
+	// droppedMocks counts the mocks dropped along with the testcases
+	droppedMocks int
+}
+
+// newSampler returns the sampler of the record config, nil if all the traffic is recorded.
+func newSampler(cfg config.Record) *sampler {
+	if (cfg.SamplePercentage <= 0 || cfg.SamplePercentage >= 100) && cfg.RateLimit == 0 && !cfg.Dedup {
+		return nil
+	}
+	return &sampler{
+		percentage: cfg.SamplePercentage,
+		rateLimit:  cfg.RateLimit,
+		dedup:      cfg.Dedup,
+		shapes:     map[string]bool{},
+		windows:    map[string]*rateWindow{},
+		endpoints:  map[string]*endpointStats{},
+	}
+}
+
+// keep tells whether a testcase should be recorded.
+func (s *sampler) keep(logger *zap.Logger, tc *models.TestCase) bool {
+	endpoint := endpointOf(tc)
+	stats, ok := s.endpoints[endpoint]
+	if !ok {
+		stats = &endpointStats{}
+		s.endpoints[endpoint] = stats
+	}
+
+	var shape string
+	if s.dedup {
+		shape = shapeOf(tc, endpoint)
+		if s.shapes[shape] {
+			stats.duplicates++
+			logger.Debug("dropping the testcase, its shape is already recorded", zap.String("endpoint", endpoint))
+			return false
+		}
+	}
+
+	window := s.windows[endpoint]
+	if s.rateLimit > 0 {
+		ts := tc.RequestTimestamp()
+		if window == nil || ts.Sub(window.start) >= time.Minute || ts.Before(window.start) {
+			window = &rateWindow{start: ts}
+			s.windows[endpoint] = window
+		}
+		if window.count >= s.rateLimit {
+			stats.rateLimited++
+			logger.Debug("dropping the testcase, its endpoint is over the rate limit", zap.String("endpoint", endpoint))
+			return false
+		}
+	}
+
+	if s.percentage > 0 && s.percentage < 100 && rand.Float64()*100 >= s.percentage {
+		stats.sampledOut++
+		logger.Debug("dropping the testcase, it is sampled out", zap.String("endpoint", endpoint))
+		return false
+	}
+
+	if window != nil {
+		window.count++
+	}
+	if s.dedup {
+		s.shapes[shape] = true
+	}
+	stats.recorded++
+	return true
+}
+
+// report prints the testcases recorded and dropped per endpoint, if any was dropped.
+func (s *sampler) report(logger *zap.Logger) {
+	var total endpointStats
+	endpoints := make([]string, 0, len(s.endpoints))
+	for endpoint, stats := range s.endpoints {
+		endpoints = append(endpoints, endpoint)
+		total.recorded += stats.recorded
+		total.duplicates += stats.duplicates
+		total.rateLimited += stats.rateLimited
+		total.sampledOut += stats.sampledOut
+	}
+	if total.dropped() == 0 {
+		return
+	}
+	sort.Strings(endpoints)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Endpoint", "Recorded", "Duplicates", "Rate limited", "Sampled out"})
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	for _, endpoint := range endpoints {
+		stats := s.endpoints[endpoint]
+		if stats.dropped() == 0 {
+			continue
+		}
+		table.Append([]string{endpoint, strconv.Itoa(stats.recorded), strconv.Itoa(stats.duplicates), strconv.Itoa(stats.rateLimited), strconv.Itoa(stats.sampledOut)})
+	}
+	table.Render()
+	logger.Info("dropped some of the recorded traffic",
+		zap.Int("recorded", total.recorded),
+		zap.Int("duplicates", total.duplicates),
+		zap.Int("rate limited", total.rateLimited),
+		zap.Int("sampled out", total.sampledOut),
+		zap.Int("dropped mocks", s.droppedMocks))
+}
+
+// endpointOf returns the endpoint of a testcase: the method and the path template of the http testcases, the
+// method of the gRPC ones.
+func endpointOf(tc *models.TestCase) string {
+	if tc.Kind == models.GRPC_EXPORT {
+		return "gRPC " + tc.GrpcReq.Headers.PseudoHeaders[":path"]
+	}
+	path := tc.HTTPReq.URL
+	if u, err := url.Parse(tc.HTTPReq.URL); err == nil {
+		path = u.Path
+	}
+	return string(tc.HTTPReq.Method) + " " + pathTemplate(path)
+}
+
+// pathTemplate replaces the segments of a path which look like identifiers by placeholders, so that
+// /users/42 and /users/43 are the same endpoint.
+func pathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case uuidRegex.MatchString(segment):
+			segments[i] = "{uuid}"
+		case idRegex.MatchString(segment):
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// shapeOf returns the shape of a testcase: its endpoint, its status code and the schemas of its bodies.
+func shapeOf(tc *models.TestCase, endpoint string) string {
+	if tc.Kind == models.GRPC_EXPORT {
+		return endpoint + " " + tc.GrpcResp.Headers.PseudoHeaders[":status"] + " " + tc.GrpcResp.Trailers.OrdinaryHeaders["grpc-status"] +
+			" " + bodySchema(tc.GrpcReq.Body.DecodedJSON) + " " + bodySchema(tc.GrpcResp.Body.DecodedJSON)
+	}
+	return endpoint + " " + strconv.Itoa(tc.HTTPResp.StatusCode) + " " + bodySchema(tc.HTTPReq.Body) + " " + bodySchema(tc.HTTPResp.Body)
+}
+
+// bodySchema returns the schema of a JSON body, the body being opaque text otherwise.
+func bodySchema(body string) string {
+	if strings.TrimSpace(body) == "" {
+		return "empty"
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return "text"
+	}
+	return jsonSchema(value)
+}
+
+// jsonSchema returns the schema of a decoded JSON value: the sorted keys of the objects along with the schemas
+// of their values, and the distinct schemas of the elements of the arrays.
+func jsonSchema(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			fields[i] = strconv.Quote(key) + ":" + jsonSchema(v[key])
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	case []interface{}:
+		seen := map[string]bool{}
+		var elems []string
+		for _, elem := range v {
+			schema := jsonSchema(elem)
+			if !seen[schema] {
+				seen[schema] = true
+				elems = append(elems, schema)
+			}
+		}
+		sort.Strings(elems)
+		return "[" + strings.Join(elems, "|") + "]"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	}
+	return "null"
+}
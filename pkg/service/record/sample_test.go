@@ -0,0 +1,160 @@
+//go:build linux
+
+package record
+
+import (
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func httpTestCase(method, url string, status int, reqBody, respBody string, ts time.Time) *models.TestCase {
+	return &models.TestCase{
+		Kind:     models.HTTP,
+		HTTPReq:  models.HTTPReq{Method: models.Method(method), URL: url, Body: reqBody, Timestamp: ts},
+		HTTPResp: models.HTTPResp{StatusCode: status, Body: respBody},
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Record
+		isNil bool
+	}{
+		{name: "everything recorded", cfg: config.Record{}, isNil: true},
+		{name: "full percentage", cfg: config.Record{SamplePercentage: 100}, isNil: true},
+		{name: "percentage", cfg: config.Record{SamplePercentage: 10}},
+		{name: "rate limit", cfg: config.Record{RateLimit: 5}},
+		{name: "dedup", cfg: config.Record{Dedup: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s := newSampler(tt.cfg); (s == nil) != tt.isNil {
+				t.Errorf("newSampler() = %v, want nil %v", s, tt.isNil)
+			}
+		})
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/users", want: "/users"},
+		{path: "/users/42", want: "/users/{id}"},
+		{path: "/users/42/orders/7", want: "/users/{id}/orders/{id}"},
+		{path: "/users/3fa85f64-5717-4562-b3fc-2c963f66afa6", want: "/users/{uuid}"},
+		{path: "/objects/507f1f77bcf86cd799439011", want: "/objects/{id}"},
+		{path: "/v2/users", want: "/v2/users"},
+		{path: "/files/cafe", want: "/files/cafe"},
+	}
+	for _, tt := range tests {
+		if got := pathTemplate(tt.path); got != tt.want {
+			t.Errorf("pathTemplate(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBodySchema(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "values", a: `{"id":1,"name":"a"}`, b: `{"name":"b","id":2}`, same: true},
+		{name: "keys", a: `{"id":1}`, b: `{"id":1,"name":"a"}`},
+		{name: "types", a: `{"id":1}`, b: `{"id":"1"}`},
+		{name: "array lengths", a: `[{"id":1}]`, b: `[{"id":1},{"id":2},{"id":3}]`, same: true},
+		{name: "array elements", a: `[1]`, b: `[1,"a"]`},
+		{name: "nested", a: `{"user":{"tags":["a"]}}`, b: `{"user":{"tags":[]}}`},
+		{name: "texts", a: "hello", b: "world", same: true},
+		{name: "empty and text", a: " ", b: "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := bodySchema(tt.a), bodySchema(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("bodySchema() = %s and %s, want the same %v", a, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestSamplerKeep(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		cfg  config.Record
+		tcs  []*models.TestCase
+		want []bool
+	}{
+		{
+			name: "duplicates",
+			cfg:  config.Record{Dedup: true},
+			tcs: []*models.TestCase{
+				httpTestCase("GET", "http://localhost/users/1", 200, "", `{"id":1}`, start),
+				httpTestCase("GET", "http://localhost/users/2?x=1", 200, "", `{"id":2}`, start),
+				httpTestCase("GET", "http://localhost/users/3", 404, "", `{"error":"not found"}`, start),
+				httpTestCase("POST", "http://localhost/users/3", 200, "", `{"id":3}`, start),
+				httpTestCase("GET", "http://localhost/users/4", 200, "", `{"id":4,"name":"a"}`, start),
+			},
+			want: []bool{true, false, true, true, true},
+		},
+		{
+			name: "rate limit",
+			cfg:  config.Record{RateLimit: 2},
+			tcs: []*models.TestCase{
+				httpTestCase("GET", "http://localhost/users/1", 200, "", "", start),
+				httpTestCase("GET", "http://localhost/users/2", 200, "", "", start.Add(time.Second)),
+				httpTestCase("GET", "http://localhost/users/3", 200, "", "", start.Add(2*time.Second)),
+				httpTestCase("GET", "http://localhost/orders", 200, "", "", start.Add(3*time.Second)),
+				httpTestCase("GET", "http://localhost/users/4", 200, "", "", start.Add(time.Minute)),
+			},
+			want: []bool{true, true, false, true, true},
+		},
+		{
+			// the testcases dropped as duplicates don't count in the rate limit
+			name: "duplicates and rate limit",
+			cfg:  config.Record{Dedup: true, RateLimit: 1},
+			tcs: []*models.TestCase{
+				httpTestCase("GET", "http://localhost/users/1", 200, "", `{"id":1}`, start),
+				httpTestCase("GET", "http://localhost/users/2", 200, "", `{"id":2}`, start),
+				httpTestCase("GET", "http://localhost/users/3", 500, "", "", start),
+			},
+			want: []bool{true, false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSampler(tt.cfg)
+			for i, tc := range tt.tcs {
+				if got := s.keep(zap.NewNop(), tc); got != tt.want[i] {
+					t.Errorf("keep() of testcase %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSamplerPercentage(t *testing.T) {
+	s := newSampler(config.Record{SamplePercentage: 20})
+	kept := 0
+	const total = 10000
+	for i := 0; i < total; i++ {
+		if s.keep(zap.NewNop(), httpTestCase("GET", "http://localhost/users", 200, "", "", time.Now())) {
+			kept++
+		}
+	}
+	if kept < total*15/100 || kept > total*25/100 {
+		t.Errorf("kept %d testcases out of %d, want about 20%%", kept, total)
+	}
+	stats := s.endpoints["GET /users"]
+	if stats == nil || stats.recorded != kept || stats.sampledOut != total-kept {
+		t.Errorf("stats = %+v, want %d recorded and %d sampled out", stats, kept, total-kept)
+	}
+}
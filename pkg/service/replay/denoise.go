@@ -0,0 +1,112 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.keploy.io/server/v2/pkg"
+	httpMatcher "go.keploy.io/server/v2/pkg/matcher/http"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// Denoise replays the test cases of the selected test sets twice, with their mocks, and adds the headers
+// and the json fields of the responses which differ between the two replays to the noise of the test cases.
+// This way the timestamps, uuids and the other values generated by the application don't fail the test runs.
+func (r *Replayer) Denoise(ctx context.Context) error {
+	r.denoise = true
+	r.config.Test.SkipCoverage = true
+	return r.Start(ctx)
+}
+
+// denoiseTestCase sends the request of the test case once more, and returns the noise of the fields whose
+// values differ between the two replayed responses. The recorded response isn't compared, since it differs
+// from the replayed ones on the fields which changed since the recording too, and not only on the noise.
+// The fields already in the noise of the test case are left out.
+func (r *Replayer) denoiseTestCase(ctx context.Context, appID uint64, testSetID string, tc *models.TestCase, resp *models.HTTPResp) (map[string][]string, error) {
+	// the mocks consumed by the first replay are set again for the second one
+	err := r.SetupOrUpdateMocks(ctx, appID, testSetID, tc.RequestTimestamp(), tc.ResponseTimestamp(), Update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update the mocks: %w", err)
+	}
+	resp2, err := HookImpl.SimulateRequest(ctx, appID, tc, testSetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate the request again: %w", err)
+	}
+	if r.instrument {
+		// the mocks consumed by the second replay aren't attributed to the next test case
+		if _, err := r.instrumentation.GetConsumedMocks(ctx, appID); err != nil {
+			utils.LogError(r.logger, err, "failed to get consumed filtered mocks")
+		}
+	}
+
+	first, err := flattenHTTPResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten the replayed response: %w", err)
+	}
+	second, err := flattenHTTPResponse(resp2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten the replayed response: %w", err)
+	}
+	noise := map[string][]string{}
+	for _, field := range differingFields(first, second) {
+		if _, ok := tc.Noise[field]; !ok {
+			noise[field] = []string{}
+		}
+	}
+	return noise, nil
+}
+
+// flattenHTTPResponse flattens the headers and the body of a response, or its events if it is streamed.
+func flattenHTTPResponse(resp *models.HTTPResp) (map[string][]string, error) {
+	flattened, err := httpMatcher.FlattenHTTPResponse(pkg.ToHTTPHeader(resp.Header), resp.Body)
+	if err != nil || len(resp.Stream) == 0 {
+		return flattened, err
+	}
+	events, err := httpMatcher.FlattenHTTPStream(resp.Stream)
+	for field, values := range events {
+		flattened[field] = values
+	}
+	return flattened, err
+}
+
+// differingFields returns the headers and the json fields whose values differ between the flattened
+// responses. The bodies which aren't json can't be told apart by field, so they are never noisy.
+func differingFields(first, second map[string][]string) []string {
+	var fields []string
+	for field, values := range first {
+		if field == "body" {
+			continue
+		}
+		if !reflect.DeepEqual(values, second[field]) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range second {
+		if _, ok := first[field]; !ok && field != "body" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// saveNoise adds the noise detected while denoising to the test cases of the test set.
+func (r *Replayer) saveNoise(ctx context.Context, testSetID string, noise map[string]map[string][]string) error {
+	if len(noise) == 0 {
+		return nil
+	}
+	var noiseParams []*models.NoiseParams
+	for testCaseID, assertion := range noise {
+		noiseParams = append(noiseParams, &models.NoiseParams{
+			TestCaseID: testCaseID,
+			EditedBy:   "denoise",
+			Assertion:  assertion,
+			Ops:        models.OpsAdd,
+		})
+		r.logger.Info("added the noisy fields to the test case", zap.String("testcase", testCaseID), zap.Any("noise", utils.Keys(assertion)))
+	}
+	_, err := r.DenoiseTestCases(ctx, testSetID, noiseParams)
+	return err
+}
@@ -0,0 +1,72 @@
+package replay
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func TestDifferingFields(t *testing.T) {
+	resp := func(date, body string, stream ...models.HTTPStreamEvent) *models.HTTPResp {
+		return &models.HTTPResp{
+			StatusCode: 200,
+			Header:     models.HTTPHeader{"Content-Type": {"application/json"}, "Date": {date}},
+			Body:       body,
+			Stream:     stream,
+		}
+	}
+	tests := []struct {
+		name   string
+		first  *models.HTTPResp
+		second *models.HTTPResp
+		want   []string
+	}{
+		{
+			name:   "same responses",
+			first:  resp("Mon", `{"id":1}`),
+			second: resp("Mon", `{"id":1}`),
+		},
+		{
+			name:   "header and json fields",
+			first:  resp("Mon", `{"id":1,"ts":"10:00","user":{"token":"a","name":"ann"}}`),
+			second: resp("Tue", `{"id":1,"ts":"10:01","user":{"token":"b","name":"ann"}}`),
+			want:   []string{"body.ts", "body.user.token", "header.Date"},
+		},
+		{
+			name:   "field missing from a response",
+			first:  resp("Mon", `{"id":1,"trace":"x"}`),
+			second: resp("Mon", `{"id":1,"span":"y"}`),
+			want:   []string{"body.span", "body.trace"},
+		},
+		{
+			name:   "text bodies",
+			first:  &models.HTTPResp{Header: models.HTTPHeader{"Content-Type": {"text/plain"}}, Body: "generated at 10:00"},
+			second: &models.HTTPResp{Header: models.HTTPHeader{"Content-Type": {"text/plain"}}, Body: "generated at 10:01"},
+		},
+		{
+			name:   "stream events",
+			first:  resp("Mon", "", models.HTTPStreamEvent{ID: "1", Data: `{"price":1,"at":"10:00"}`}, models.HTTPStreamEvent{ID: "2", Data: "ping"}),
+			second: resp("Mon", "", models.HTTPStreamEvent{ID: "1", Data: `{"price":1,"at":"10:01"}`}, models.HTTPStreamEvent{ID: "3", Data: "pong"}),
+			want:   []string{"stream.0.data.at", "stream.1.id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := flattenHTTPResponse(tt.first)
+			if err != nil {
+				t.Fatalf("flattenHTTPResponse() error = %v", err)
+			}
+			second, err := flattenHTTPResponse(tt.second)
+			if err != nil {
+				t.Fatalf("flattenHTTPResponse() error = %v", err)
+			}
+			got := differingFields(first, second)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("differingFields() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// depAsserter compares the dependency calls made during the replay of a test case with the ones recorded
+// during its recording. The calls are told apart by their dependency key (see depKey), and a test case
+// fails if a dependency is called fewer or more times than recorded, or if a call matches no mock.
+//
+// Only the mocks recorded during a test case are asserted: the mocks recorded outside of the test cases,
+// such as the ones of the connections opened at startup, can be consumed at any time. The dns mocks are
+// ignored too, as the answers are cached by the resolvers.
+type depAsserter struct {
+	mocks   map[string]*models.Mock
+	windows [][2]time.Time
+}
+
+func (r *Replayer) newDepAsserter(ctx context.Context, testSetID string, testCases []*models.TestCase) (*depAsserter, error) {
+	filtered, unfiltered, err := r.GetMocks(ctx, testSetID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	d := &depAsserter{mocks: map[string]*models.Mock{}}
+	for _, mock := range append(filtered, unfiltered...) {
+		d.mocks[mock.Name] = mock
+	}
+	for _, tc := range testCases {
+		after, before := tc.RequestTimestamp(), tc.ResponseTimestamp()
+		if after != (time.Time{}) && before != (time.Time{}) {
+			d.windows = append(d.windows, [2]time.Time{after, before})
+		}
+	}
+	return d, nil
+}
+
+// assert returns the dependency calls of the test case, and whether they match the recorded ones.
+// consumedMocks holds the mocks used while replaying the test case and their number of uses, along with
+// the calls which matched no mock.
+func (d *depAsserter) assert(tc *models.TestCase, consumedMocks []models.MockState) ([]models.DepResult, bool) {
+	type calls struct {
+		kind               models.Kind
+		expected, actual   int
+		unmatched          int
+		expMocks, actMocks []string
+	}
+	deps := map[string]*calls{}
+	get := func(key string, kind models.Kind) *calls {
+		if deps[key] == nil {
+			deps[key] = &calls{kind: kind}
+		}
+		return deps[key]
+	}
+
+	// the calls which matched no mock fail the test case, whether it can be told apart from the others or not
+	for _, consumed := range consumedMocks {
+		if !consumed.Unmatched {
+			continue
+		}
+		c := get(consumed.Name, consumed.Kind)
+		for i := 0; i < consumed.Usage; i++ {
+			c.unmatched++
+			c.actMocks = append(c.actMocks, "unmatched")
+		}
+	}
+
+	after, before := tc.RequestTimestamp(), tc.ResponseTimestamp()
+	if after != (time.Time{}) && before != (time.Time{}) {
+		for _, mock := range d.mocks {
+			if mock.Kind == models.DNS || !inWindow(mock, after, before) {
+				continue
+			}
+			c := get(depKey(mock), mock.Kind)
+			c.expected++
+			c.expMocks = append(c.expMocks, mock.Name)
+		}
+		for _, consumed := range consumedMocks {
+			mock, ok := d.mocks[consumed.Name]
+			if consumed.Unmatched || !ok || mock.Kind == models.DNS || !d.isTestCaseMock(mock) {
+				continue
+			}
+			c := get(depKey(mock), mock.Kind)
+			for i := 0; i < consumed.Usage; i++ {
+				c.actual++
+				c.actMocks = append(c.actMocks, mock.Name)
+			}
+		}
+	}
+	if len(deps) == 0 {
+		return nil, true
+	}
+
+	keys := make([]string, 0, len(deps))
+	for key := range deps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pass := true
+	results := make([]models.DepResult, 0, len(keys))
+	for _, key := range keys {
+		c := deps[key]
+		normal := c.expected == c.actual && c.unmatched == 0
+		pass = pass && normal
+		sort.Strings(c.expMocks)
+		res := models.DepResult{
+			Name: key,
+			Type: string(c.kind),
+			Meta: []models.DepMetaResult{
+				{Normal: normal, Key: "calls", Expected: strconv.Itoa(c.expected), Actual: strconv.Itoa(c.actual + c.unmatched)},
+				{Normal: normal, Key: "mocks", Expected: strings.Join(c.expMocks, ", "), Actual: strings.Join(c.actMocks, ", ")},
+			},
+		}
+		if c.unmatched > 0 {
+			res.Meta = append(res.Meta, models.DepMetaResult{Normal: false, Key: "unmatched", Expected: "0", Actual: strconv.Itoa(c.unmatched)})
+		}
+		results = append(results, res)
+	}
+	return results, pass
+}
+
+// isTestCaseMock tells whether the mock has been recorded during one of the test cases of the test set.
+func (d *depAsserter) isTestCaseMock(mock *models.Mock) bool {
+	for _, w := range d.windows {
+		if inWindow(mock, w[0], w[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func inWindow(mock *models.Mock, after, before time.Time) bool {
+	if mock.Spec.ReqTimestampMock == (time.Time{}) || mock.Spec.ResTimestampMock == (time.Time{}) {
+		return false
+	}
+	return mock.Spec.ReqTimestampMock.After(after) && mock.Spec.ResTimestampMock.Before(before)
+}
+
+// depKey identifies the dependency call of a mock, e.g. "Http POST api.stripe.com/v1/charges" or
+// "Postgres INSERT INTO orders ...". The calls of the kinds which can't be told apart are keyed by kind.
+func depKey(mock *models.Mock) string {
+	kind := string(mock.Kind)
+	switch mock.Kind {
+	case models.HTTP:
+		if req := mock.Spec.HTTPReq; req != nil {
+			if u, err := url.Parse(req.URL); err == nil {
+				return fmt.Sprintf("%s %s %s%s", kind, req.Method, u.Host, u.Path)
+			}
+		}
+	case models.WebSocket:
+		if req := mock.Spec.HTTPReq; req != nil {
+			if u, err := url.Parse(req.URL); err == nil {
+				return fmt.Sprintf("%s %s%s", kind, u.Host, u.Path)
+			}
+		}
+	case models.GRPC_EXPORT:
+		if req := mock.Spec.GRPCReq; req != nil && req.Headers.PseudoHeaders[":path"] != "" {
+			return kind + " " + req.Headers.PseudoHeaders[":path"]
+		}
+	case models.Postgres:
+		for _, req := range mock.Spec.PostgresRequests {
+			if req.Query.String != "" {
+				return kind + " " + req.Query.String
+			}
+			for _, parse := range req.Parses {
+				if parse.Query != "" {
+					return kind + " " + parse.Query
+				}
+			}
+		}
+	case models.MySQL:
+		for _, req := range mock.Spec.MySQLRequests {
+			switch msg := req.Message.(type) {
+			case *mysql.QueryPacket:
+				return kind + " " + msg.Query
+			case *mysql.StmtPreparePacket:
+				return kind + " " + msg.Query
+			}
+		}
+	case models.REDIS:
+		if len(mock.Spec.RedisCommands) > 0 {
+			return kind + " " + mock.Spec.RedisCommands[0].Name
+		}
+	}
+	return kind
+}
+
+// depMismatches describes the dependency calls which don't match the recorded ones.
+func depMismatches(results []models.DepResult) []string {
+	var mismatches []string
+	for _, res := range results {
+		calls := res.Meta[0]
+		if calls.Normal {
+			continue
+		}
+		mismatch := fmt.Sprintf("%s: expected %s call(s), got %s", res.Name, calls.Expected, calls.Actual)
+		if len(res.Meta) > 2 {
+			mismatch += fmt.Sprintf(", %s matching no mock", res.Meta[2].Actual)
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches
+}
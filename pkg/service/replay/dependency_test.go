@@ -0,0 +1,215 @@
+package replay
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+func TestDepKey(t *testing.T) {
+	tests := []struct {
+		name string
+		mock *models.Mock
+		want string
+	}{
+		{
+			name: "http",
+			mock: &models.Mock{Kind: models.HTTP, Spec: models.MockSpec{HTTPReq: &models.HTTPReq{Method: "POST", URL: "https://api.stripe.com/v1/charges?expand=all"}}},
+			want: "Http POST api.stripe.com/v1/charges",
+		},
+		{
+			name: "websocket",
+			mock: &models.Mock{Kind: models.WebSocket, Spec: models.MockSpec{HTTPReq: &models.HTTPReq{Method: "GET", URL: "wss://feed.example.com/prices"}}},
+			want: string(models.WebSocket) + " feed.example.com/prices",
+		},
+		{
+			name: "grpc",
+			mock: &models.Mock{Kind: models.GRPC_EXPORT, Spec: models.MockSpec{GRPCReq: &models.GrpcReq{Headers: models.GrpcHeaders{PseudoHeaders: map[string]string{":path": "/users.Users/Get"}}}}},
+			want: string(models.GRPC_EXPORT) + " /users.Users/Get",
+		},
+		{
+			name: "postgres query",
+			mock: &models.Mock{Kind: models.Postgres, Spec: models.MockSpec{PostgresRequests: []models.Backend{{Query: pgproto3.Query{String: "SELECT 1"}}}}},
+			want: "Postgres SELECT 1",
+		},
+		{
+			name: "postgres prepared statement",
+			mock: &models.Mock{Kind: models.Postgres, Spec: models.MockSpec{PostgresRequests: []models.Backend{{}, {Parses: []pgproto3.Parse{{Query: "INSERT INTO orders VALUES ($1)"}}}}}},
+			want: "Postgres INSERT INTO orders VALUES ($1)",
+		},
+		{
+			name: "mysql",
+			mock: &models.Mock{Kind: models.MySQL, Spec: models.MockSpec{MySQLRequests: []mysql.Request{{PacketBundle: mysql.PacketBundle{Message: &mysql.QueryPacket{Query: "SELECT 1"}}}}}},
+			want: "MySQL SELECT 1",
+		},
+		{
+			name: "redis",
+			mock: &models.Mock{Kind: models.REDIS, Spec: models.MockSpec{RedisCommands: []models.RedisCommand{{Name: "GET", Args: []string{"k"}}}}},
+			want: string(models.REDIS) + " GET",
+		},
+		{
+			name: "postgres handshake",
+			mock: &models.Mock{Kind: models.Postgres, Spec: models.MockSpec{PostgresRequests: []models.Backend{{}}}},
+			want: "Postgres",
+		},
+		{name: "generic", mock: &models.Mock{Kind: models.GENERIC}, want: string(models.GENERIC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := depKey(tt.mock); got != tt.want {
+				t.Errorf("depKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepAsserterAssert(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+	httpMock := func(name, path string, req int) *models.Mock {
+		return &models.Mock{Name: name, Kind: models.HTTP, Spec: models.MockSpec{
+			HTTPReq:          &models.HTTPReq{Method: "GET", URL: "http://users" + path},
+			ReqTimestampMock: at(req),
+			ResTimestampMock: at(req + 1),
+		}}
+	}
+	d := &depAsserter{
+		mocks: map[string]*models.Mock{},
+		windows: [][2]time.Time{
+			{at(10), at(20)},
+			{at(30), at(40)},
+		},
+	}
+	for _, mock := range []*models.Mock{
+		httpMock("mock-0", "/config", 1), // recorded at startup
+		httpMock("mock-1", "/users/1", 11),
+		httpMock("mock-2", "/users/1", 13),
+		httpMock("mock-3", "/orders", 15),
+		httpMock("mock-4", "/users/1", 31), // recorded during another test case
+		{Name: "mock-5", Kind: models.DNS, Spec: models.MockSpec{ReqTimestampMock: at(12), ResTimestampMock: at(12)}},
+	} {
+		d.mocks[mock.Name] = mock
+	}
+	tc := &models.TestCase{Kind: models.HTTP, HTTPReq: models.HTTPReq{Timestamp: at(10)}, HTTPResp: models.HTTPResp{Timestamp: at(20)}}
+
+	type dep struct {
+		name, calls, mocks string
+		normal             bool
+		unmatched          string
+	}
+	tests := []struct {
+		name     string
+		tc       *models.TestCase
+		consumed []models.MockState
+		want     []dep
+		wantPass bool
+	}{
+		{
+			name:     "same calls",
+			tc:       tc,
+			consumed: []models.MockState{{Name: "mock-0"}, {Name: "mock-1", Usage: 1}, {Name: "mock-2", Usage: 1}, {Name: "mock-3", Usage: 1}, {Name: "mock-5", Usage: 1}},
+			want: []dep{
+				{name: "Http GET users/orders", calls: "1/1", mocks: "mock-3/mock-3", normal: true},
+				{name: "Http GET users/users/1", calls: "2/2", mocks: "mock-1, mock-2/mock-1, mock-2", normal: true},
+			},
+			wantPass: true,
+		},
+		{
+			name:     "fewer calls",
+			tc:       tc,
+			consumed: []models.MockState{{Name: "mock-1", Usage: 1}, {Name: "mock-3", Usage: 1}},
+			want: []dep{
+				{name: "Http GET users/orders", calls: "1/1", mocks: "mock-3/mock-3", normal: true},
+				{name: "Http GET users/users/1", calls: "2/1", mocks: "mock-1, mock-2/mock-1"},
+			},
+		},
+		{
+			name:     "more calls through the mock of another test case",
+			tc:       tc,
+			consumed: []models.MockState{{Name: "mock-1", Usage: 1}, {Name: "mock-2", Usage: 1}, {Name: "mock-3", Usage: 1}, {Name: "mock-4", Usage: 1}},
+			want: []dep{
+				{name: "Http GET users/orders", calls: "1/1", mocks: "mock-3/mock-3", normal: true},
+				{name: "Http GET users/users/1", calls: "2/3", mocks: "mock-1, mock-2/mock-1, mock-2, mock-4"},
+			},
+		},
+		{
+			name:     "mock used twice",
+			tc:       tc,
+			consumed: []models.MockState{{Name: "mock-1", Usage: 2}, {Name: "mock-3", Usage: 1}},
+			want: []dep{
+				{name: "Http GET users/orders", calls: "1/1", mocks: "mock-3/mock-3", normal: true},
+				{name: "Http GET users/users/1", calls: "2/2", mocks: "mock-1, mock-2/mock-1, mock-1", normal: true},
+			},
+			wantPass: true,
+		},
+		{
+			name: "unmatched call",
+			tc:   tc,
+			consumed: []models.MockState{
+				{Name: "mock-1", Usage: 1}, {Name: "mock-2", Usage: 1}, {Name: "mock-3", Usage: 1},
+				{Name: "Http DELETE /users/1", Kind: models.HTTP, Usage: 2, Unmatched: true},
+			},
+			want: []dep{
+				{name: "Http DELETE /users/1", calls: "0/2", mocks: "/unmatched, unmatched", unmatched: "2"},
+				{name: "Http GET users/orders", calls: "1/1", mocks: "mock-3/mock-3", normal: true},
+				{name: "Http GET users/users/1", calls: "2/2", mocks: "mock-1, mock-2/mock-1, mock-2", normal: true},
+			},
+		},
+		{
+			name:     "test case without timestamps",
+			tc:       &models.TestCase{Kind: models.HTTP},
+			consumed: []models.MockState{{Name: "mock-1", Usage: 1}},
+			wantPass: true,
+		},
+		{
+			name:     "unmatched call of a test case without timestamps",
+			tc:       &models.TestCase{Kind: models.HTTP},
+			consumed: []models.MockState{{Name: "Redis GET", Kind: models.REDIS, Usage: 1, Unmatched: true}},
+			want:     []dep{{name: "Redis GET", calls: "0/1", mocks: "/unmatched", unmatched: "1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, pass := d.assert(tt.tc, tt.consumed)
+			if pass != tt.wantPass {
+				t.Errorf("assert() pass = %v, want %v", pass, tt.wantPass)
+			}
+			var got []dep
+			for _, res := range results {
+				calls, mocks := res.Meta[0], res.Meta[1]
+				g := dep{
+					name:   res.Name,
+					calls:  calls.Expected + "/" + calls.Actual,
+					mocks:  mocks.Expected + "/" + mocks.Actual,
+					normal: calls.Normal,
+				}
+				if len(res.Meta) > 2 {
+					g.unmatched = res.Meta[2].Actual
+				}
+				got = append(got, g)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assert() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepMismatches(t *testing.T) {
+	results := []models.DepResult{
+		{Name: "Http GET users/orders", Meta: []models.DepMetaResult{{Normal: true, Expected: "1", Actual: "1"}, {Normal: true}}},
+		{Name: "Http GET users/users/1", Meta: []models.DepMetaResult{{Expected: "2", Actual: "1"}, {}}},
+		{Name: "Redis GET", Meta: []models.DepMetaResult{{Expected: "0", Actual: "1"}, {}, {Key: "unmatched", Expected: "0", Actual: "1"}}},
+	}
+	want := []string{
+		"Http GET users/users/1: expected 2 call(s), got 1",
+		"Redis GET: expected 0 call(s), got 1, 1 matching no mock",
+	}
+	if got := depMismatches(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("depMismatches() = %q, want %q", got, want)
+	}
+}
@@ -24,6 +24,7 @@ type Hooks struct {
 	tsConfigDB TestSetConfig
 	storage    Storage
 	auth       service.Auth
+	protos     pkg.ProtoRegistryCache
 }
 
 func NewHooks(logger *zap.Logger, cfg *config.Config, tsConfigDB TestSetConfig, storage Storage, auth service.Auth) TestHooks {
@@ -43,10 +44,22 @@ func (h *Hooks) SimulateRequest(ctx context.Context, _ uint64, tc *models.TestCa
 		resp, err := pkg.SimulateHTTP(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout)
 		h.logger.Debug("After simulating the request", zap.Any("test case id", tc.Name))
 		return resp, err
+	case models.WebSocket:
+		h.logger.Debug("Before simulating the websocket session", zap.Any("Test case", tc))
+		resp, err := pkg.SimulateWebSocket(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout)
+		h.logger.Debug("After simulating the websocket session", zap.Any("test case id", tc.Name))
+		return resp, err
 	}
 	return nil, nil
 }
 
+func (h *Hooks) SimulateGrpcRequest(ctx context.Context, _ uint64, tc *models.TestCase, testSetID string) (*models.GrpcResp, error) {
+	h.logger.Debug("Before simulating the grpc request", zap.Any("Test case", tc))
+	resp, err := pkg.SimulateGRPC(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout, h.protos.Get(ctx, h.logger, h.cfg.Proto))
+	h.logger.Debug("After simulating the grpc request", zap.Any("test case id", tc.Name))
+	return resp, err
+}
+
 func (h *Hooks) AfterTestSetRun(ctx context.Context, testSetID string, status bool) error {
 
 	if h.cfg.Test.DisableMockUpload {
@@ -0,0 +1,156 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// templateMu keeps the test sets using templates from running along with other test sets, as the
+// templatized values of the running test set are global (see utils.TemplatizedValues).
+var templateMu sync.RWMutex
+
+var (
+	dockerNameFlag    = regexp.MustCompile(`--name[\s=]+[^\s]+`)
+	dockerPublishFlag = regexp.MustCompile(`\s(?:-p|--publish)[\s=]+[^\s]+`)
+)
+
+// runsInParallel tells whether the test sets are run on several instances of the application.
+//
+// Only the applications started with docker run can be run in parallel: the eBPF hooks tell the
+// connections of the containers apart by their client id, while the native applications share the
+// network namespace of keploy, and the docker compose services can't be renamed per instance.
+func (r *Replayer) runsInParallel(testSets int) bool {
+	if r.config.Test.Parallel <= 1 || testSets <= 1 || !r.instrument {
+		return false
+	}
+	if utils.CmdType(r.config.CommandType) != utils.DockerRun {
+		r.logger.Warn("the testsets can only be run in parallel for the applications started with docker run, running them one at a time", zap.Int("parallel", r.config.Test.Parallel), zap.String("command type", r.config.CommandType))
+		return false
+	}
+	return true
+}
+
+// runTestSetsInParallel runs the test sets on r.config.Test.Parallel instances of the application. Each
+// instance is hooked with its own app id, and so gets its own session and mock manager in the proxy. The
+// first instance is the one already instrumented by Start, and the test sets are handed out to the
+// instances as they become free. The results of all the instances are written to the same test run.
+func (r *Replayer) runTestSetsInParallel(ctx context.Context, testSets []string, testRunID string, first *InstrumentState) (testRunResult bool, abortTestRun bool, userAbort bool, err error) {
+	appIDs := []uint64{first.AppID}
+	for i := 1; i < r.config.Test.Parallel && i < len(testSets); i++ {
+		cmd, container := instanceCommand(r.config.Command, r.config.ContainerName, i)
+		inst, err := r.instrumentApp(ctx, cmd, models.SetupOptions{Container: container, DockerNetwork: r.config.NetworkName, DockerDelay: r.config.BuildDelay})
+		if err != nil {
+			return false, false, false, fmt.Errorf("failed to instrument the instance %d of the application: %w", i, err)
+		}
+		defer inst.HookCancel()
+		appIDs = append(appIDs, inst.AppID)
+	}
+	r.logger.Info("running the testsets in parallel", zap.Int("instances", len(appIDs)), zap.Int("testsets", len(testSets)))
+
+	pending := make(chan string, len(testSets))
+	for _, testSet := range testSets {
+		pending <- testSet
+	}
+	close(pending)
+
+	var mu sync.Mutex
+	testRunResult = true
+	g, gctx := errgroup.WithContext(ctx)
+	for _, appID := range appIDs {
+		appID := appID
+		g.Go(func() error {
+			defer utils.Recover(r.logger)
+			for testSet := range pending {
+				mu.Lock()
+				stop := abortTestRun || userAbort
+				mu.Unlock()
+				if stop {
+					return nil
+				}
+
+				testSetStatus, err := r.runTestSetOn(gctx, testSet, testRunID, appID)
+				if err != nil {
+					return err
+				}
+
+				mu.Lock()
+				if testSetStatus == models.TestSetStatusUserAbort {
+					userAbort = true
+				} else if testSetStatus != models.TestSetStatusIgnored {
+					passed, abort := testSetVerdict(testSetStatus)
+					testRunResult = testRunResult && passed
+					abortTestRun = abortTestRun || abort
+				}
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	err = g.Wait()
+	return testRunResult, abortTestRun, userAbort, err
+}
+
+// runTestSetOn runs a test set on an instance of the application, along with the test set hooks.
+func (r *Replayer) runTestSetOn(ctx context.Context, testSetID string, testRunID string, appID uint64) (models.TestSetStatus, error) {
+	err := HookImpl.BeforeTestSetRun(ctx, testSetID)
+	if err != nil {
+		return models.TestSetStatusFailed, fmt.Errorf("failed to run before test hook: %w", err)
+	}
+
+	if r.usesTemplate(ctx, testSetID) {
+		templateMu.Lock()
+		defer func() {
+			// the test sets run next without a template must not see the values of this one
+			utils.TemplatizedValues = nil
+			templateMu.Unlock()
+		}()
+	} else {
+		templateMu.RLock()
+		defer templateMu.RUnlock()
+	}
+	testSetStatus, err := r.RunTestSet(ctx, testSetID, testRunID, appID, false)
+	if err != nil {
+		return testSetStatus, fmt.Errorf("failed to run test set %s: %w", testSetID, err)
+	}
+
+	passed, abort := testSetVerdict(testSetStatus)
+	if testSetStatus == models.TestSetStatusUserAbort || abort {
+		return testSetStatus, nil
+	}
+	err = HookImpl.AfterTestSetRun(ctx, testSetID, passed)
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to execute after test set run hook", zap.Any("testSet", testSetID))
+	}
+	return testSetStatus, nil
+}
+
+// usesTemplate tells whether the test set reads or writes templatized values.
+func (r *Replayer) usesTemplate(ctx context.Context, testSetID string) bool {
+	if r.config.Test.UpdateTemplate {
+		return true
+	}
+	conf, err := r.testSetConf.Read(ctx, testSetID)
+	return err == nil && conf != nil && len(conf.Template) > 0
+}
+
+// instanceCommand returns the docker run command of the i-th instance of the application, along with the
+// name of its container. The instances share the network of the application, as the eBPF hooks redirect
+// all of them to the same proxy address, but their containers are named after the instance, and they
+// don't publish any port, as the ports are already published by the first instance. The test cases are
+// sent to the ip of the container of each instance.
+func instanceCommand(cmd, container string, i int) (string, string) {
+	if i == 0 {
+		return cmd, container
+	}
+	name := fmt.Sprintf("%s-%d", container, i)
+	cmd = dockerNameFlag.ReplaceAllString(cmd, "--name "+name)
+	cmd = dockerPublishFlag.ReplaceAllString(cmd, "")
+	return cmd, name
+}
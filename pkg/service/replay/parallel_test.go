@@ -0,0 +1,132 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func TestInstanceCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		i        int
+		wantCmd  string
+		wantName string
+	}{
+		{
+			name:     "first instance",
+			cmd:      "docker run -p 8080:8080 --name app --network keploy app:latest",
+			i:        0,
+			wantCmd:  "docker run -p 8080:8080 --name app --network keploy app:latest",
+			wantName: "app",
+		},
+		{
+			name:     "other instance",
+			cmd:      "docker run -p 8080:8080 --name app --network keploy app:latest",
+			i:        2,
+			wantCmd:  "docker run --name app-2 --network keploy app:latest",
+			wantName: "app-2",
+		},
+		{
+			name:     "long flags with values after an equal sign",
+			cmd:      "docker run --publish=8080:8080 --publish 9090:9090 --name=app -e PORT=8080 app:latest",
+			i:        1,
+			wantCmd:  "docker run --name app-1 -e PORT=8080 app:latest",
+			wantName: "app-1",
+		},
+		{
+			name:     "image name containing the flags",
+			cmd:      "docker run --name app registry/app-publish:latest",
+			i:        1,
+			wantCmd:  "docker run --name app-1 registry/app-publish:latest",
+			wantName: "app-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, name := instanceCommand(tt.cmd, "app", tt.i)
+			if cmd != tt.wantCmd || name != tt.wantName {
+				t.Errorf("instanceCommand() = %q, %q, want %q, %q", cmd, name, tt.wantCmd, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestRunsInParallel(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallel    int
+		testSets    int
+		commandType utils.CmdType
+		instrument  bool
+		want        bool
+	}{
+		{name: "docker run", parallel: 3, testSets: 4, commandType: utils.DockerRun, instrument: true, want: true},
+		{name: "parallel not set", parallel: 0, testSets: 4, commandType: utils.DockerRun, instrument: true},
+		{name: "single instance", parallel: 1, testSets: 4, commandType: utils.DockerRun, instrument: true},
+		{name: "single test set", parallel: 3, testSets: 1, commandType: utils.DockerRun, instrument: true},
+		{name: "not instrumented", parallel: 3, testSets: 4, commandType: utils.DockerRun},
+		{name: "native", parallel: 3, testSets: 4, commandType: utils.Native, instrument: true},
+		{name: "docker compose", parallel: 3, testSets: 4, commandType: utils.DockerCompose, instrument: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CommandType: string(tt.commandType)}
+			cfg.Test.Parallel = tt.parallel
+			r := &Replayer{config: cfg, instrument: tt.instrument, logger: zap.NewNop()}
+			if got := r.runsInParallel(tt.testSets); got != tt.want {
+				t.Errorf("runsInParallel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testSetConf returns the configs of the test sets, the ones missing failing to be read.
+type testSetConf map[string]*models.TestSet
+
+func (c testSetConf) Read(_ context.Context, testSetID string) (*models.TestSet, error) {
+	conf, ok := c[testSetID]
+	if !ok {
+		return nil, errors.New("no config")
+	}
+	return conf, nil
+}
+
+func (c testSetConf) Write(_ context.Context, testSetID string, testSet *models.TestSet) error {
+	c[testSetID] = testSet
+	return nil
+}
+
+func TestUsesTemplate(t *testing.T) {
+	conf := testSetConf{
+		"test-set-0": {Template: map[string]interface{}{"token": "abc"}},
+		"test-set-1": {PreScript: "echo start"},
+	}
+	tests := []struct {
+		name           string
+		testSetID      string
+		updateTemplate bool
+		want           bool
+	}{
+		{name: "templated", testSetID: "test-set-0", want: true},
+		{name: "not templated", testSetID: "test-set-1"},
+		{name: "no config", testSetID: "test-set-2"},
+		{name: "templates updated", testSetID: "test-set-1", updateTemplate: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Test.UpdateTemplate = tt.updateTemplate
+			r := &Replayer{config: cfg, testSetConf: conf, logger: zap.NewNop()}
+			if got := r.usesTemplate(context.Background(), tt.testSetID); got != tt.want {
+				t.Errorf("usesTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
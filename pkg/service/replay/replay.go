@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 
 	"time"
@@ -20,6 +21,7 @@ import (
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg"
 	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	grpcMatcher "go.keploy.io/server/v2/pkg/matcher/grpc"
 	httpMatcher "go.keploy.io/server/v2/pkg/matcher/http"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/platform/coverage"
@@ -27,7 +29,9 @@ import (
 	"go.keploy.io/server/v2/pkg/platform/coverage/java"
 	"go.keploy.io/server/v2/pkg/platform/coverage/javascript"
 	"go.keploy.io/server/v2/pkg/platform/coverage/python"
+	"go.keploy.io/server/v2/pkg/platform/metrics"
 	"go.keploy.io/server/v2/pkg/service"
+	"go.keploy.io/server/v2/pkg/service/report"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -39,6 +43,9 @@ var totalTestPassed int
 var totalTestFailed int
 var totalTestIgnored int
 var totalTestTimeTaken time.Duration
+
+// reportMu guards the totals above, which are updated by the test sets running in parallel.
+var reportMu sync.Mutex
 var HookImpl TestHooks
 
 type Replayer struct {
@@ -51,6 +58,7 @@ type Replayer struct {
 	instrumentation Instrumentation
 	config          *config.Config
 	instrument      bool
+	denoise         bool
 }
 
 func NewReplayer(logger *zap.Logger, testDB TestDB, mockDB MockDB, reportDB ReportDB, testSetConf TestSetConfig, telemetry Telemetry, instrumentation Instrumentation, auth service.Auth, storage Storage, config *config.Config) Service {
@@ -200,77 +208,78 @@ func (r *Replayer) Start(ctx context.Context) error {
 
 	// Sort the testsets.
 	natsort.Sort(testSets)
-	for i, testSet := range testSets {
-		testSetResult = false
-		err := HookImpl.BeforeTestSetRun(ctx, testSet)
+	if r.runsInParallel(len(testSets)) {
+		var userAbort bool
+		testRunResult, abortTestRun, userAbort, err = r.runTestSetsInParallel(ctx, testSets, testRunID, inst)
 		if err != nil {
-			stopReason = fmt.Sprintf("failed to run before test hook: %v", err)
+			stopReason = fmt.Sprintf("failed to run the test sets in parallel: %v", err)
 			utils.LogError(r.logger, err, stopReason)
 			if ctx.Err() == context.Canceled {
 				return err
 			}
 			return fmt.Errorf(stopReason)
 		}
-
-		if !r.config.Test.SkipCoverage {
-			err = os.Setenv("TESTSETID", testSet) // related to java coverage calculation
+		if userAbort {
+			return nil
+		}
+	} else {
+		for i, testSet := range testSets {
+			testSetResult = false
+			err := HookImpl.BeforeTestSetRun(ctx, testSet)
 			if err != nil {
-				r.config.Test.SkipCoverage = true
-				r.logger.Warn("failed to set TESTSETID env variable, skipping coverage caluclation", zap.Error(err))
+				stopReason = fmt.Sprintf("failed to run before test hook: %v", err)
+				utils.LogError(r.logger, err, stopReason)
+				if ctx.Err() == context.Canceled {
+					return err
+				}
+				return fmt.Errorf(stopReason)
 			}
-		}
 
-		testSetStatus, err := r.RunTestSet(ctx, testSet, testRunID, inst.AppID, false)
-		if err != nil {
-			stopReason = fmt.Sprintf("failed to run test set: %v", err)
-			utils.LogError(r.logger, err, stopReason)
-			if ctx.Err() == context.Canceled {
-				return err
+			if !r.config.Test.SkipCoverage {
+				err = os.Setenv("TESTSETID", testSet) // related to java coverage calculation
+				if err != nil {
+					r.config.Test.SkipCoverage = true
+					r.logger.Warn("failed to set TESTSETID env variable, skipping coverage caluclation", zap.Error(err))
+				}
 			}
-			return fmt.Errorf(stopReason)
-		}
-		switch testSetStatus {
-		case models.TestSetStatusAppHalted:
-			testSetResult = false
-			abortTestRun = true
-		case models.TestSetStatusInternalErr:
-			testSetResult = false
-			abortTestRun = true
-		case models.TestSetStatusFaultUserApp:
-			testSetResult = false
-			abortTestRun = true
-		case models.TestSetStatusUserAbort:
-			return nil
-		case models.TestSetStatusFailed:
-			testSetResult = false
-		case models.TestSetStatusPassed:
-			testSetResult = true
-		case models.TestSetStatusIgnored:
-			testSetResult = false
-		}
 
-		if testSetStatus != models.TestSetStatusIgnored {
-			testRunResult = testRunResult && testSetResult
-			if abortTestRun {
-				break
+			testSetStatus, err := r.RunTestSet(ctx, testSet, testRunID, inst.AppID, false)
+			if err != nil {
+				stopReason = fmt.Sprintf("failed to run test set: %v", err)
+				utils.LogError(r.logger, err, stopReason)
+				if ctx.Err() == context.Canceled {
+					return err
+				}
+				return fmt.Errorf(stopReason)
 			}
-		}
+			if testSetStatus == models.TestSetStatusUserAbort {
+				return nil
+			}
+			testSetResult, abortTestRun = testSetVerdict(testSetStatus)
 
-		err = HookImpl.AfterTestSetRun(ctx, testSet, testSetResult)
-		if err != nil {
-			utils.LogError(r.logger, err, "failed to execute after test set run hook", zap.Any("testSet", testSet))
-		}
+			if testSetStatus != models.TestSetStatusIgnored {
+				testRunResult = testRunResult && testSetResult
+				if abortTestRun {
+					break
+				}
+			}
 
-		if i == 0 && !r.config.Test.SkipCoverage {
-			err = os.Setenv("CLEAN", "false") // related to javascript coverage calculation
+			err = HookImpl.AfterTestSetRun(ctx, testSet, testSetResult)
 			if err != nil {
-				r.config.Test.SkipCoverage = true
-				r.logger.Warn("failed to set CLEAN env variable, skipping coverage caluclation.", zap.Error(err))
+				utils.LogError(r.logger, err, "failed to execute after test set run hook", zap.Any("testSet", testSet))
 			}
-			err = os.Setenv("APPEND", "--append") // related to python coverage calculation
-			if err != nil {
-				r.config.Test.SkipCoverage = true
-				r.logger.Warn("failed to set APPEND env variable, skipping coverage caluclation.", zap.Error(err))
+
+			if i == 0 && !r.config.Test.SkipCoverage {
+				err = os.Setenv("CLEAN", "false") // related to javascript coverage calculation
+				if err != nil {
+					r.config.Test.SkipCoverage = true
+					r.logger.Warn("failed to set CLEAN env variable, skipping coverage caluclation.", zap.Error(err))
+				}
+				err = os.Setenv("APPEND", "--append") // related to python coverage calculation
+				if err != nil {
+					r.config.Test.SkipCoverage = true
+					r.logger.Warn("failed to set APPEND env variable, skipping coverage caluclation.", zap.Error(err))
+				}
 			}
 		}
 	}
@@ -318,6 +327,8 @@ func (r *Replayer) Start(ctx context.Context) error {
 		}
 	}
 
+	r.exportTestRun(ctx, testRunID, testSets)
+
 	// return non-zero error code so that pipeline processes
 	// know that there is a failure in tests
 	if !testRunResult {
@@ -331,14 +342,23 @@ func (r *Replayer) Instrument(ctx context.Context) (*InstrumentState, error) {
 		r.logger.Info("Keploy will not mock the outgoing calls when base path is provided", zap.Any("base path", r.config.Test.BasePath))
 		return &InstrumentState{}, nil
 	}
-	appID, err := r.instrumentation.Setup(ctx, r.config.Command, models.SetupOptions{Container: r.config.ContainerName, DockerNetwork: r.config.NetworkName, DockerDelay: r.config.BuildDelay})
+	inst, err := r.instrumentApp(ctx, r.config.Command, models.SetupOptions{Container: r.config.ContainerName, DockerNetwork: r.config.NetworkName, DockerDelay: r.config.BuildDelay})
+	if err != nil {
+		return inst, err
+	}
+	r.config.AppID = inst.AppID
+	return inst, nil
+}
+
+// instrumentApp sets up the app of the command, and loads the hooks and starts the proxy for it.
+func (r *Replayer) instrumentApp(ctx context.Context, cmd string, opts models.SetupOptions) (*InstrumentState, error) {
+	appID, err := r.instrumentation.Setup(ctx, cmd, opts)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			return &InstrumentState{}, err
 		}
 		return &InstrumentState{}, fmt.Errorf("failed to setup instrumentation: %w", err)
 	}
-	r.config.AppID = appID
 
 	var cancel context.CancelFunc
 	// starting the hooks and proxy
@@ -360,6 +380,43 @@ func (r *Replayer) Instrument(ctx context.Context) (*InstrumentState, error) {
 	return &InstrumentState{AppID: appID, HookCancel: cancel}, nil
 }
 
+// exportTestRun writes the test run in the report formats of the config, next to its yaml reports.
+func (r *Replayer) exportTestRun(ctx context.Context, testRunID string, testSets []string) {
+	if len(r.config.Test.ReportFormats) == 0 {
+		return
+	}
+	var reports []*models.TestReport
+	for _, testSetID := range testSets {
+		testReport, err := r.reportDB.GetReport(ctx, testRunID, testSetID)
+		if err != nil {
+			// the test sets without test cases have no report
+			r.logger.Debug("no report found for the test set", zap.String("testSet", testSetID), zap.Error(err))
+			continue
+		}
+		reports = append(reports, testReport)
+	}
+	for _, format := range r.config.Test.ReportFormats {
+		path := filepath.Join(r.config.Path, "reports", testRunID, report.FileName(format))
+		err := report.Write(path, format, testRunID, reports)
+		if err != nil {
+			utils.LogError(r.logger, err, "failed to export the test run", zap.String("format", format))
+			continue
+		}
+		r.logger.Info("exported the test run", zap.String("format", format), zap.String("path", path))
+	}
+}
+
+// testSetVerdict tells whether the test set passed, and whether the test run is aborted after it.
+func testSetVerdict(status models.TestSetStatus) (passed bool, abort bool) {
+	switch status {
+	case models.TestSetStatusAppHalted, models.TestSetStatusInternalErr, models.TestSetStatusFaultUserApp:
+		return false, true
+	case models.TestSetStatusPassed:
+		return true, false
+	}
+	return false, false
+}
+
 func (r *Replayer) GetNextTestRunID(ctx context.Context) (string, error) {
 	testRunIDs, err := r.reportDB.GetAllTestRunIDs(ctx)
 	if err != nil {
@@ -396,6 +453,8 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 			utils.LogError(r.logger, err, "error in testLoopErrGrp")
 		}
 		close(exitLoopChan)
+		// the mocks of the test set are parsed again if it is run again
+		r.mockDB.ReleaseMocks(testSetID)
 	}()
 
 	testCases, err := r.testDB.GetTestCases(runTestSetCtx, testSetID)
@@ -431,9 +490,11 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 			duration: time.Duration(0),
 		}
 
+		reportMu.Lock()
 		completeTestReport[testSetID] = verdict
 		totalTests += testReport.Total
 		totalTestIgnored += testReport.Ignored
+		reportMu.Unlock()
 
 		return models.TestSetStatusIgnored, nil
 	}
@@ -467,6 +528,17 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 	var ignored int
 	var totalConsumedMocks = map[string]bool{}
 
+	// noise detected for the test cases of the test set, when denoising
+	var detectedNoise = map[string]map[string][]string{}
+
+	var deps *depAsserter
+	if r.instrument && r.config.Test.AssertDependencies {
+		deps, err = r.newDepAsserter(runTestSetCtx, testSetID, testCases)
+		if err != nil {
+			return models.TestSetStatusFailed, fmt.Errorf("failed to get the mocks to assert the dependency calls: %w", err)
+		}
+	}
+
 	testSetStatus := models.TestSetStatusPassed
 	testSetStatusByErrChan := models.TestSetStatusRunning
 
@@ -562,7 +634,11 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 	var exitLoop bool
 	// var to store the error in the loop
 	var loopErr error
-	utils.TemplatizedValues = conf.Template
+	// The test sets without a template leave the templatized values alone once they are empty, as the
+	// test sets run in parallel share them (see runTestSetOn).
+	if len(conf.Template) > 0 || len(utils.TemplatizedValues) > 0 {
+		utils.TemplatizedValues = conf.Template
+	}
 
 	for _, testCase := range testCases {
 
@@ -572,7 +648,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 
 		if _, ok := ignoredTests[testCase.Name]; ok {
 			testCaseResult := &models.TestResult{
-				Kind:         models.HTTP,
+				Kind:         testCase.Kind,
 				Name:         testSetID,
 				Status:       models.TestStatusIgnored,
 				TestCaseID:   testCase.Name,
@@ -585,11 +661,12 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 				break
 			}
 			ignored++
+			metrics.TestCase(testSetID, models.TestStatusIgnored, 0)
 			continue
 		}
 
 		// replace the request URL's BasePath/origin if provided
-		if r.config.Test.BasePath != "" {
+		if r.config.Test.BasePath != "" && (testCase.Kind == models.HTTP || testCase.Kind == models.WebSocket) {
 			newURL, err := ReplaceBaseURL(r.config.Test.BasePath, testCase.HTTPReq.URL)
 			if err != nil {
 				r.logger.Warn("failed to replace the request basePath", zap.String("testcase", testCase.Name), zap.String("basePath", r.config.Test.BasePath), zap.Error(err))
@@ -617,24 +694,25 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		var loopErr error
 
 		//No need to handle mocking when basepath is provided
-		err := r.SetupOrUpdateMocks(runTestSetCtx, appID, testSetID, testCase.HTTPReq.Timestamp, testCase.HTTPResp.Timestamp, Update)
+		err := r.SetupOrUpdateMocks(runTestSetCtx, appID, testSetID, testCase.RequestTimestamp(), testCase.ResponseTimestamp(), Update)
 		if err != nil {
 			utils.LogError(r.logger, err, "failed to update mocks")
 			break
 		}
 
+		reqURL := testCaseURL(testCase)
 		if utils.IsDockerCmd(cmdType) {
-			testCase.HTTPReq.URL, err = utils.ReplaceHost(testCase.HTTPReq.URL, userIP)
+			reqURL, err = utils.ReplaceHost(reqURL, userIP)
 			if err != nil {
 				utils.LogError(r.logger, err, "failed to replace host to docker container's IP")
 				break
 			}
-			r.logger.Debug("", zap.Any("replaced URL in case of docker env", testCase.HTTPReq.URL))
+			r.logger.Debug("", zap.Any("replaced URL in case of docker env", reqURL))
 		}
 
 		// send the flag replace-host instead of sending the IP
 		if r.config.Test.Host != "" {
-			testCase.HTTPReq.URL, err = utils.ReplaceHost(testCase.HTTPReq.URL, r.config.Test.Host)
+			reqURL, err = utils.ReplaceHost(reqURL, r.config.Test.Host)
 			if err != nil {
 				utils.LogError(r.logger, err, "failed to replace host to provided host by the user")
 				break
@@ -642,31 +720,85 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 
 		if r.config.Test.Port != 0 {
-			testCase.HTTPReq.URL, err = utils.ReplacePort(testCase.HTTPReq.URL, strconv.Itoa(int(r.config.Test.Port)))
+			reqURL, err = utils.ReplacePort(reqURL, strconv.Itoa(int(r.config.Test.Port)))
 		}
 
+		err = setTestCaseURL(testCase, reqURL)
+		if err != nil {
+			utils.LogError(r.logger, err, "failed to update the request URL of the testcase")
+			break
+		}
+
+		var (
+			resp     *models.HTTPResp
+			grpcResp *models.GrpcResp
+		)
 		started := time.Now().UTC()
-		resp, loopErr := HookImpl.SimulateRequest(runTestSetCtx, appID, testCase, testSetID)
+		if testCase.Kind == models.GRPC_EXPORT {
+			grpcResp, loopErr = HookImpl.SimulateGrpcRequest(runTestSetCtx, appID, testCase, testSetID)
+		} else {
+			resp, loopErr = HookImpl.SimulateRequest(runTestSetCtx, appID, testCase, testSetID)
+		}
 		if loopErr != nil {
 			utils.LogError(r.logger, err, "failed to simulate request")
 			failure++
+			metrics.TestCase(testSetID, models.TestStatusFailed, 0)
 			continue
 		}
 
-		var consumedMocks []string
+		var consumedMocks []models.MockState
 		if r.instrument {
 			consumedMocks, err = r.instrumentation.GetConsumedMocks(runTestSetCtx, appID)
 			if err != nil {
 				utils.LogError(r.logger, err, "failed to get consumed filtered mocks")
 			}
-			if r.config.Test.RemoveUnusedMocks {
-				for _, mockName := range consumedMocks {
-					totalConsumedMocks[mockName] = true
+			for _, mock := range consumedMocks {
+				if !mock.Unmatched {
+					totalConsumedMocks[mock.Name] = true
 				}
 			}
 		}
 
-		testPass, testResult = r.compareResp(testCase, resp, testSetID)
+		if r.denoise && (testCase.Kind == models.HTTP || testCase.Kind == models.WebSocket) {
+			noise, err := r.denoiseTestCase(runTestSetCtx, appID, testSetID, testCase, resp)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to denoise the test case", zap.Any("testcase", testCase.Name))
+			} else if len(noise) > 0 {
+				detectedNoise[testCase.Name] = noise
+				testCase.Noise = mergeMaps(testCase.Noise, noise)
+			}
+		}
+
+		if testCase.Kind == models.GRPC_EXPORT {
+			testPass, testResult = r.compareGrpcResp(testCase, grpcResp, testSetID)
+		} else {
+			testPass, testResult = r.compareResp(testCase, resp, testSetID)
+		}
+		if deps != nil && testResult != nil {
+			depResult, depPass := deps.assert(testCase, consumedMocks)
+			testResult.DepResult = depResult
+			if !depPass {
+				testPass = false
+				r.logger.Info("dependency calls don't match the recorded ones", zap.Any("testcase id", testCase.Name), zap.Strings("mismatches", depMismatches(depResult)))
+			}
+		}
+
+		// rerun the failed test case to tell the flaky failures from the consistent ones
+		flaky := false
+		if !testPass && testResult != nil && r.config.Test.RerunFailures > 0 && !r.denoise {
+			rerun, err := r.rerunTestCase(runTestSetCtx, appID, testSetID, testCase, deps)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to rerun the testcase", zap.Any("testcase id", testCase.Name))
+			} else if rerun.pass {
+				flaky = true
+				testPass, testResult, resp, grpcResp = true, rerun.result, rerun.resp, rerun.grpcResp
+				for _, mock := range rerun.consumedMocks {
+					if !mock.Unmatched {
+						totalConsumedMocks[mock.Name] = true
+					}
+				}
+			}
+		}
 		if !testPass {
 			// log the consumed mocks during the test run of the test case for test set
 			r.logger.Info("result", zap.Any("testcase id", models.HighlightFailingString(testCase.Name)), zap.Any("testset id", models.HighlightFailingString(testSetID)), zap.Any("passed", models.HighlightFailingString(testPass)))
@@ -676,22 +808,35 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 		if testPass {
 			testStatus = models.TestStatusPassed
+			if flaky {
+				testStatus = models.TestStatusFlaky
+			}
 			success++
 		} else {
 			testStatus = models.TestStatusFailed
 			failure++
 			testSetStatus = models.TestSetStatusFailed
 		}
+		metrics.TestCase(testSetID, testStatus, time.Since(started))
 
 		if testResult != nil {
 			testCaseResult := &models.TestResult{
-				Kind:       models.HTTP,
-				Name:       testSetID,
-				Status:     testStatus,
-				Started:    started.Unix(),
-				Completed:  time.Now().UTC().Unix(),
-				TestCaseID: testCase.Name,
-				Req: models.HTTPReq{
+				Kind:         testCase.Kind,
+				Name:         testSetID,
+				Status:       testStatus,
+				Started:      started.Unix(),
+				Completed:    time.Now().UTC().Unix(),
+				TestCaseID:   testCase.Name,
+				TestCasePath: filepath.Join(r.config.Path, testSetID),
+				MockPath:     filepath.Join(r.config.Path, testSetID, "mocks.yaml"),
+				Noise:        testCase.Noise,
+				Result:       *testResult,
+			}
+			if testCase.Kind == models.GRPC_EXPORT {
+				testCaseResult.GrpcReq = testCase.GrpcReq
+				testCaseResult.GrpcRes = *grpcResp
+			} else {
+				testCaseResult.Req = models.HTTPReq{
 					Method:     testCase.HTTPReq.Method,
 					ProtoMajor: testCase.HTTPReq.ProtoMajor,
 					ProtoMinor: testCase.HTTPReq.ProtoMinor,
@@ -702,12 +847,8 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 					Binary:     testCase.HTTPReq.Binary,
 					Form:       testCase.HTTPReq.Form,
 					Timestamp:  testCase.HTTPReq.Timestamp,
-				},
-				Res:          *resp,
-				TestCasePath: filepath.Join(r.config.Path, testSetID),
-				MockPath:     filepath.Join(r.config.Path, testSetID, "mocks.yaml"),
-				Noise:        testCase.Noise,
-				Result:       *testResult,
+				}
+				testCaseResult.Res = *resp
 			}
 			loopErr = r.reportDB.InsertTestCaseResult(runTestSetCtx, testRunID, testSetID, testCaseResult)
 			if loopErr != nil {
@@ -726,6 +867,13 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 	}
 
+	if r.denoise {
+		err = r.saveNoise(runTestSetCtx, testSetID, detectedNoise)
+		if err != nil {
+			utils.LogError(r.logger, err, "failed to save the noise of the test cases", zap.String("test-set", testSetID))
+		}
+	}
+
 	if conf.PostScript != "" {
 		//Execute the Post-script after each test-set if provided
 		r.logger.Info("Running Post-script", zap.String("script", conf.PostScript), zap.String("test-set", testSetID))
@@ -792,6 +940,11 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 	}
 
+	metrics.TestSet(testSetStatus, timeTaken)
+	if r.instrument {
+		metrics.TestSetMocksConsumed(testSetID, len(totalConsumedMocks))
+	}
+
 	// TODO Need to decide on whether to use global variable or not
 	verdict := TestReportVerdict{
 		total:    testReport.Total,
@@ -802,6 +955,9 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		duration: timeTaken,
 	}
 
+	// the summary is printed under the lock too, so that the summaries of the test sets running in
+	// parallel aren't interleaved
+	reportMu.Lock()
 	completeTestReport[testSetID] = verdict
 	totalTests += testReport.Total
 	totalTestPassed += testReport.Success
@@ -827,6 +983,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 			}
 		}
 	}
+	reportMu.Unlock()
 
 	r.telemetry.TestSetRun(testReport.Success, testReport.Failure, testSetID, string(testSetStatus))
 
@@ -876,12 +1033,18 @@ func (r *Replayer) SetupOrUpdateMocks(ctx context.Context, appID uint64, testSet
 	}
 
 	if action == Start {
+		metrics.TestSetMocksLoaded(testSetID, len(filteredMocks)+len(unfilteredMocks))
 		err = r.instrumentation.MockOutgoing(ctx, appID, models.OutgoingOptions{
-			Rules:          r.config.BypassRules,
-			MongoPassword:  r.config.Test.MongoPassword,
-			SQLDelay:       time.Duration(r.config.Test.Delay),
-			FallBackOnMiss: r.config.Test.FallBackOnMiss,
-			Mocking:        r.config.Test.Mocking,
+			Rules:            r.config.BypassRules,
+			MongoPassword:    r.config.Test.MongoPassword,
+			PostgresPassword: r.config.Test.PostgresPassword,
+			SQLDelay:         time.Duration(r.config.Test.Delay),
+			FallBackOnMiss:   r.config.Test.FallBackOnMiss,
+			Mocking:          r.config.Test.Mocking,
+			Proto:            r.config.Proto,
+			Redis:            r.config.Redis,
+			ProtocolMap:      r.config.ProtocolMap,
+			Noise:            r.mockNoise(testSetID),
 		})
 		if err != nil {
 			utils.LogError(r.logger, err, "failed to mock outgoing")
@@ -897,6 +1060,18 @@ func (r *Replayer) SetupOrUpdateMocks(ctx context.Context, appID uint64, testSet
 	return nil
 }
 
+// mockNoise returns the body noise of the test set, by the lower-cased path of the field, which is also
+// ignored in the messages of the gRPC mocks.
+func (r *Replayer) mockNoise(testSetID string) map[string][]string {
+	noise := map[string][]string{}
+	for _, noiseConfig := range []config.GlobalNoise{r.config.Test.GlobalNoise.Global, r.config.Test.GlobalNoise.Testsets[testSetID]} {
+		for field, regexArr := range noiseConfig["body"] {
+			noise[strings.ToLower(field)] = regexArr
+		}
+	}
+	return noise
+}
+
 func (r *Replayer) GetTestSetStatus(ctx context.Context, testRunID string, testSetID string) (models.TestSetStatus, error) {
 	testReport, err := r.reportDB.GetReport(ctx, testRunID, testSetID)
 	if err != nil {
@@ -915,9 +1090,21 @@ func (r *Replayer) compareResp(tc *models.TestCase, actualResponse *models.HTTPR
 	if tsNoise, ok := r.config.Test.GlobalNoise.Testsets[testSetID]; ok {
 		noiseConfig = LeftJoinNoise(r.config.Test.GlobalNoise.Global, tsNoise)
 	}
+	if tc.Kind == models.WebSocket {
+		return httpMatcher.MatchWebSocket(tc, actualResponse, noiseConfig, r.config.Test.IgnoreOrdering, r.logger)
+	}
 	return httpMatcher.Match(tc, actualResponse, noiseConfig, r.config.Test.IgnoreOrdering, r.logger)
 }
 
+func (r *Replayer) compareGrpcResp(tc *models.TestCase, actualResponse *models.GrpcResp, testSetID string) (bool, *models.Result) {
+
+	noiseConfig := r.config.Test.GlobalNoise.Global
+	if tsNoise, ok := r.config.Test.GlobalNoise.Testsets[testSetID]; ok {
+		noiseConfig = LeftJoinNoise(r.config.Test.GlobalNoise.Global, tsNoise)
+	}
+	return grpcMatcher.Match(tc, actualResponse, noiseConfig, r.config.Test.IgnoreOrdering, r.logger)
+}
+
 func (r *Replayer) printSummary(_ context.Context, _ bool) {
 	if totalTests > 0 {
 		testSuiteNames := make([]string, 0, len(completeTestReport))
@@ -1099,10 +1286,15 @@ func (r *Replayer) NormalizeTestCases(ctx context.Context, testRun string, testS
 			r.logger.Info("test case not found in the test report", zap.String("test-case-id", testCase.Name), zap.String("test-set-id", testSetID))
 			continue
 		}
-		if testCaseResultMap[testCase.Name].Status == models.TestStatusPassed {
+		// the flaky test cases passed on rerun, so their recorded response is kept
+		if status := testCaseResultMap[testCase.Name].Status; status == models.TestStatusPassed || status == models.TestStatusFlaky {
 			continue
 		}
-		testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+		if testCase.Kind == models.GRPC_EXPORT {
+			testCase.GrpcResp = testCaseResultMap[testCase.Name].GrpcRes
+		} else {
+			testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+		}
 		err = r.testDB.UpdateTestCase(ctx, testCase, testSetID)
 		if err != nil {
 			return fmt.Errorf("failed to update test case: %w", err)
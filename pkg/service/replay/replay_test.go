@@ -0,0 +1,36 @@
+package replay
+
+import (
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/config"
+)
+
+func TestMockNoise(t *testing.T) {
+	tests := []struct {
+		name  string
+		noise config.Globalnoise
+		want  map[string][]string
+	}{
+		{name: "no noise", want: map[string][]string{}},
+		{
+			name: "global and test set body noise",
+			noise: config.Globalnoise{
+				Global:   config.GlobalNoise{"body": {"Data.TS": {}}, "header": {"date": {}}},
+				Testsets: config.TestsetNoise{"test-set-0": {"body": {"id": {"[0-9]+"}}}, "test-set-1": {"body": {"other": {}}}},
+			},
+			want: map[string][]string{"data.ts": {}, "id": {"[0-9]+"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Test.GlobalNoise = tt.noise
+			r := &Replayer{config: cfg}
+			if got := r.mockNoise("test-set-0"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mockNoise() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
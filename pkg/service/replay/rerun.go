@@ -0,0 +1,70 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// rerunOutcome is the outcome of a rerun of a failed test case.
+type rerunOutcome struct {
+	pass          bool
+	result        *models.Result
+	resp          *models.HTTPResp
+	grpcResp      *models.GrpcResp
+	consumedMocks []models.MockState
+}
+
+// rerunTestCase replays a failed test case up to the number of reruns of the test config, against the
+// mocks of its window, and returns the outcome of the first rerun which passes. A test case which fails
+// and then passes on rerun is flaky.
+func (r *Replayer) rerunTestCase(ctx context.Context, appID uint64, testSetID string, testCase *models.TestCase, deps *depAsserter) (rerunOutcome, error) {
+	for attempt := 1; attempt <= r.config.Test.RerunFailures; attempt++ {
+		r.logger.Info("rerunning the failed testcase", zap.Any("testcase id", testCase.Name), zap.Any("testset id", testSetID), zap.Int("attempt", attempt))
+
+		err := r.SetupOrUpdateMocks(ctx, appID, testSetID, testCase.RequestTimestamp(), testCase.ResponseTimestamp(), Update)
+		if err != nil {
+			return rerunOutcome{}, fmt.Errorf("failed to update mocks: %w", err)
+		}
+
+		var out rerunOutcome
+		if testCase.Kind == models.GRPC_EXPORT {
+			out.grpcResp, err = HookImpl.SimulateGrpcRequest(ctx, appID, testCase, testSetID)
+		} else {
+			out.resp, err = HookImpl.SimulateRequest(ctx, appID, testCase, testSetID)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return rerunOutcome{}, ctx.Err()
+			}
+			utils.LogError(r.logger, err, "failed to simulate request", zap.Any("testcase id", testCase.Name))
+			continue
+		}
+
+		if r.instrument {
+			out.consumedMocks, err = r.instrumentation.GetConsumedMocks(ctx, appID)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to get consumed filtered mocks")
+			}
+		}
+
+		if testCase.Kind == models.GRPC_EXPORT {
+			out.pass, out.result = r.compareGrpcResp(testCase, out.grpcResp, testSetID)
+		} else {
+			out.pass, out.result = r.compareResp(testCase, out.resp, testSetID)
+		}
+		if deps != nil && out.result != nil {
+			depResult, depPass := deps.assert(testCase, out.consumedMocks)
+			out.result.DepResult = depResult
+			out.pass = out.pass && depPass
+		}
+		if out.pass && out.result != nil {
+			r.logger.Warn("the testcase passed on rerun, marking it as flaky", zap.Any("testcase id", testCase.Name), zap.Any("testset id", testSetID), zap.Int("attempt", attempt))
+			return out, nil
+		}
+	}
+	return rerunOutcome{}, nil
+}
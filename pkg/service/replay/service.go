@@ -16,8 +16,9 @@ type Instrumentation interface {
 	MockOutgoing(ctx context.Context, id uint64, opts models.OutgoingOptions) error
 	// SetMocks Allows for setting mocks between test runs for better filtering and matching
 	SetMocks(ctx context.Context, id uint64, filtered []*models.Mock, unFiltered []*models.Mock) error
-	// GetConsumedMocks to log the names of the mocks that were consumed during the test run of failed test cases
-	GetConsumedMocks(ctx context.Context, id uint64) ([]string, error)
+	// GetConsumedMocks returns the mocks consumed since its last call and their number of uses, to log them for
+	// the failed test cases and to assert the dependency calls of the test cases
+	GetConsumedMocks(ctx context.Context, id uint64) ([]models.MockState, error)
 	// Run is blocking call and will execute until error
 	Run(ctx context.Context, id uint64, opts models.RunOptions) models.AppError
 
@@ -37,6 +38,7 @@ type Service interface {
 	Normalize(ctx context.Context) error
 	Templatize(ctx context.Context) error
 	DenoiseTestCases(ctx context.Context, testSetID string, noiseParams []*models.NoiseParams) ([]*models.NoiseParams, error)
+	Denoise(ctx context.Context) error
 	NormalizeTestCases(ctx context.Context, testRun string, testSetID string, selectedTestCaseIDs []string, testResult []models.TestResult) error
 	DeleteTests(ctx context.Context, testSetID string, testCaseIDs []string) error
 	DeleteTestSet(ctx context.Context, testSetID string) error
@@ -54,6 +56,7 @@ type MockDB interface {
 	GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
 	GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
 	UpdateMocks(ctx context.Context, testSetID string, mockNames map[string]bool) error
+	ReleaseMocks(testSetID string)
 }
 
 type ReportDB interface {
@@ -78,6 +81,7 @@ type Telemetry interface {
 
 type TestHooks interface {
 	SimulateRequest(ctx context.Context, appID uint64, tc *models.TestCase, testSetID string) (*models.HTTPResp, error)
+	SimulateGrpcRequest(ctx context.Context, appID uint64, tc *models.TestCase, testSetID string) (*models.GrpcResp, error)
 	BeforeTestSetRun(ctx context.Context, testSetID string) error
 	AfterTestSetRun(ctx context.Context, testSetID string, status bool) error
 	AfterTestRun(ctx context.Context, testRunID string, testSetIDs []string, coverage models.TestCoverage) error // hook executed after running all the test-sets
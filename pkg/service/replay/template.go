@@ -263,6 +263,18 @@ func addTemplates(logger *zap.Logger, interface1 interface{}, interface2 *interf
 			// we change the current value also in the interface1
 			v[key] = val
 		}
+	case models.HTTPHeader:
+		// only the headers with a single value are templatized
+		single := map[string]string{}
+		for key, values := range v {
+			if len(values) == 1 {
+				single[key] = values[0]
+			}
+		}
+		addTemplates(logger, single, interface2)
+		for key, val := range single {
+			v.Set(key, val)
+		}
 	case map[string]string:
 		for key, val := range v {
 			val1, err := renderIfTemplatized(val)
@@ -533,8 +545,13 @@ func render(val string) (interface{}, error) {
 }
 
 // Compare the headers of 2 requests and add the templates.
-func compareReqHeaders(logger *zap.Logger, req1 map[string]string, req2 map[string]string) {
-	for key, val1 := range req1 {
+func compareReqHeaders(logger *zap.Logger, req1 models.HTTPHeader, req2 models.HTTPHeader) {
+	for key, values1 := range req1 {
+		// the headers with several values aren't templatized
+		if len(values1) != 1 || len(req2[key]) != 1 {
+			continue
+		}
+		val1 := values1[0]
 		// Check if the value is already present in the templatized values.
 		tempVal, err := renderIfTemplatized(val1)
 		if err != nil {
@@ -546,7 +563,7 @@ func compareReqHeaders(logger *zap.Logger, req1 map[string]string, req2 map[stri
 			continue
 		}
 		val1 = val
-		if val2, ok := req2[key]; ok {
+		if val2 := req2.Get(key); val2 != "" {
 			tempVal, err := renderIfTemplatized(val2)
 			if err != nil {
 				utils.LogError(logger, err, "failed to render for template")
@@ -564,8 +581,8 @@ func compareReqHeaders(logger *zap.Logger, req1 map[string]string, req2 map[stri
 				if newKey == "" {
 					newKey = key
 				}
-				req2[key] = fmt.Sprintf("{{%s .%v }}", getType(val2), newKey)
-				req1[key] = fmt.Sprintf("{{%s .%v }}", getType(val2), newKey)
+				req2.Set(key, fmt.Sprintf("{{%s .%v }}", getType(val2), newKey))
+				req1.Set(key, fmt.Sprintf("{{%s .%v }}", getType(val2), newKey))
 			}
 		}
 	}
@@ -7,6 +7,7 @@ import (
 
 	// "encoding/json"
 	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
 )
 
 type TestReportVerdict struct {
@@ -18,8 +19,16 @@ type TestReportVerdict struct {
 	duration time.Duration
 }
 
+// LeftJoinNoise returns the global noise overridden by the noise of the test set. The global noise is
+// left untouched, as it is shared by all the test sets.
 func LeftJoinNoise(globalNoise config.GlobalNoise, tsNoise config.GlobalNoise) config.GlobalNoise {
-	noise := globalNoise
+	noise := config.GlobalNoise{}
+	for key, fields := range globalNoise {
+		noise[key] = make(map[string][]string, len(fields))
+		for field, regexArr := range fields {
+			noise[key][field] = regexArr
+		}
+	}
 
 	if _, ok := noise["body"]; !ok {
 		noise["body"] = make(map[string][]string)
@@ -75,6 +84,9 @@ func ReplaceBaseURL(newURL, oldURL string) (string, error) {
 }
 
 func mergeMaps(map1, map2 map[string][]string) map[string][]string {
+	if map1 == nil {
+		map1 = make(map[string][]string, len(map2))
+	}
 	for key, values := range map2 {
 		if _, exists := map1[key]; exists {
 			map1[key] = append(map1[key], values...)
@@ -102,3 +114,33 @@ func timeWithUnits(duration time.Duration) string {
 	}
 	return fmt.Sprintf("%.2f hr", duration.Hours())
 }
+
+// testCaseURL returns the url the request of the testcase is sent to. The url of a gRPC call is
+// made up of its :scheme and :authority pseudo headers.
+func testCaseURL(tc *models.TestCase) string {
+	if tc.Kind != models.GRPC_EXPORT {
+		return tc.HTTPReq.URL
+	}
+	scheme := tc.GrpcReq.Headers.PseudoHeaders[":scheme"]
+	if scheme == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, tc.GrpcReq.Headers.PseudoHeaders[":authority"])
+}
+
+// setTestCaseURL updates the request of the testcase to be sent to the given url.
+func setTestCaseURL(tc *models.TestCase, rawURL string) error {
+	if tc.Kind != models.GRPC_EXPORT {
+		tc.HTTPReq.URL = rawURL
+		return nil
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse the grpc URL: %v", err)
+	}
+	if tc.GrpcReq.Headers.PseudoHeaders == nil {
+		tc.GrpcReq.Headers.PseudoHeaders = map[string]string{}
+	}
+	tc.GrpcReq.Headers.PseudoHeaders[":authority"] = parsedURL.Host
+	return nil
+}
@@ -0,0 +1,46 @@
+package replay
+
+import (
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/config"
+)
+
+func TestLeftJoinNoise(t *testing.T) {
+	tests := []struct {
+		name   string
+		global config.GlobalNoise
+		ts     config.GlobalNoise
+		want   config.GlobalNoise
+	}{
+		{
+			name: "no noise",
+			want: config.GlobalNoise{"body": {}, "header": {}},
+		},
+		{
+			name:   "test set noise overrides the global noise",
+			global: config.GlobalNoise{"body": {"id": {}, "ts": {}}, "header": {"date": {}}},
+			ts:     config.GlobalNoise{"body": {"ts": {"[0-9]+"}}, "header": {"etag": {}}},
+			want:   config.GlobalNoise{"body": {"id": {}, "ts": {"[0-9]+"}}, "header": {"date": {}, "etag": {}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global := config.GlobalNoise{}
+			for key, fields := range tt.global {
+				global[key] = map[string][]string{}
+				for field, regexArr := range fields {
+					global[key][field] = regexArr
+				}
+			}
+			got := LeftJoinNoise(tt.global, tt.ts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LeftJoinNoise() = %v, want %v", got, tt.want)
+			}
+			if len(global) > 0 && !reflect.DeepEqual(tt.global, global) {
+				t.Errorf("global noise = %v, want it left untouched as %v", tt.global, global)
+			}
+		})
+	}
+}
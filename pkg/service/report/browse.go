@@ -0,0 +1,236 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"facette.io/natsort"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	failedColor = color.New(color.FgHiRed).SprintFunc()
+	passedColor = color.New(color.FgHiGreen).SprintFunc()
+	flakyColor  = color.New(color.FgHiYellow).SprintFunc()
+)
+
+// List prints the summary of each test run.
+func (r *Reporter) List(ctx context.Context) error {
+	testRunIDs, err := r.reportDB.GetAllTestRunIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the test runs: %w", err)
+	}
+	if len(testRunIDs) == 0 {
+		r.logger.Info("no test run found, please run the testcases using keploy test")
+		return nil
+	}
+	natsort.Sort(testRunIDs)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Test run", "Status", "Test sets", "Total", "Passed", "Failed", "Ignored", "Flaky"})
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	for _, testRunID := range testRunIDs {
+		reports, err := r.getReports(ctx, testRunID)
+		if err != nil {
+			r.logger.Warn("failed to read the reports of the test run", zap.String("test-run", testRunID), zap.Error(err))
+			continue
+		}
+		summary := Summarize(testRunID, reports)
+		table.Append([]string{
+			testRunID,
+			statusColor(summary.Status),
+			strconv.Itoa(len(summary.TestSets)),
+			strconv.Itoa(summary.Total),
+			passedColor(summary.Passed),
+			failedColor(summary.Failed),
+			strconv.Itoa(summary.Ignored),
+			flakyColor(summary.Flaky),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+// Show prints the summary of each test set of the test run, along with the failed test cases.
+func (r *Reporter) Show(ctx context.Context, testRunID string) error {
+	testRunID, err := r.testRunID(ctx, testRunID)
+	if err != nil {
+		return err
+	}
+	reports, err := r.getReports(ctx, testRunID)
+	if err != nil {
+		return err
+	}
+	summary := Summarize(testRunID, reports)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Test set", "Status", "Total", "Passed", "Failed", "Ignored", "Flaky"})
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetFooter([]string{testRunID, statusColor(summary.Status), strconv.Itoa(summary.Total), strconv.Itoa(summary.Passed), strconv.Itoa(summary.Failed), strconv.Itoa(summary.Ignored), strconv.Itoa(summary.Flaky)})
+	for _, set := range summary.TestSets {
+		table.Append([]string{set.Name, statusColor(set.Status), strconv.Itoa(set.Total), passedColor(set.Passed), failedColor(set.Failed), strconv.Itoa(set.Ignored), flakyColor(set.Flaky)})
+	}
+	table.Render()
+
+	for _, set := range summary.TestSets {
+		for _, test := range set.FailedTests {
+			fmt.Printf("%s %s/%s: %s\n", failedColor("FAILED"), set.Name, test.TestCaseID, strings.SplitN(test.Failures[0], "\n", 2)[0])
+		}
+	}
+	if summary.Failed > 0 {
+		fmt.Printf("\nUse %s to see the diffs of a failed testcase\n", models.HighlightGrayString("keploy report diff <test-set> <testcase> --test-run "+testRunID))
+	}
+	return nil
+}
+
+// Diff prints the diffs of the test cases of a test set in the test run, the same way they are printed
+// by keploy test. The diffs of all the failed test cases of the test set are printed if no test case is
+// given.
+func (r *Reporter) Diff(ctx context.Context, testSetID string, testCaseIDs []string) error {
+	testRunID, err := r.testRunID(ctx, r.config.Report.TestRun)
+	if err != nil {
+		return err
+	}
+	report, err := r.reportDB.GetReport(ctx, testRunID, testSetID)
+	if err != nil {
+		return fmt.Errorf("failed to get the report of the test set %s in %s: %w", testSetID, testRunID, err)
+	}
+
+	found := false
+	for _, result := range report.Tests {
+		if len(testCaseIDs) != 0 && !slices.Contains(testCaseIDs, result.TestCaseID) {
+			continue
+		}
+		if len(testCaseIDs) == 0 && result.Status != models.TestStatusFailed {
+			continue
+		}
+		found = true
+		if result.Status != models.TestStatusFailed {
+			r.logger.Info("the testcase didn't fail", zap.String("testcase", result.TestCaseID), zap.String("status", string(result.Status)))
+			continue
+		}
+		err := renderDiff(result)
+		if err != nil {
+			utils.LogError(r.logger, err, "failed to render the diffs", zap.String("testcase", result.TestCaseID))
+		}
+	}
+	if !found {
+		if len(testCaseIDs) == 0 {
+			r.logger.Info("no failed testcase in the test set", zap.String("test-set", testSetID), zap.String("test-run", testRunID))
+			return nil
+		}
+		return fmt.Errorf("testcases %s not found in the test set %s of %s", strings.Join(testCaseIDs, ", "), testSetID, testRunID)
+	}
+	return nil
+}
+
+// renderDiff prints the diffs of a failed test case with the DiffsPrinter, followed by the dependency
+// calls which don't match the recorded ones.
+func renderDiff(result models.TestResult) error {
+	bodyNoise, headerNoise := matcherUtils.SplitNoise(result.Noise, nil)
+
+	logDiffs := matcherUtils.NewDiffsPrinter(result.TestCaseID)
+	if !result.Result.StatusCode.Normal {
+		logDiffs.PushStatusDiff(fmt.Sprint(result.Result.StatusCode.Expected), fmt.Sprint(result.Result.StatusCode.Actual))
+	}
+	for _, header := range result.Result.HeadersResult {
+		if !header.Normal {
+			logDiffs.PushHeaderDiff(fmt.Sprint(header.Expected.Value), fmt.Sprint(header.Actual.Value), header.Expected.Key, headerNoise)
+		}
+	}
+	for _, body := range result.Result.BodyResult {
+		if !body.Normal {
+			logDiffs.PushBodyDiff(body.Expected, body.Actual, bodyNoise)
+		}
+	}
+	err := logDiffs.Render()
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range result.Result.DepResult {
+		if len(dep.Meta) == 0 || dep.Meta[0].Normal {
+			continue
+		}
+		fmt.Printf("%s %s: expected %s call(s), got %s\n", failedColor("dependency"), dep.Name, dep.Meta[0].Expected, dep.Meta[0].Actual)
+	}
+	return nil
+}
+
+// Compare prints the test cases which fail in the head test run but passed in the base one, the ones
+// which pass in the head test run but failed in the base one, and the flaky ones in the test runs from
+// base to head.
+func (r *Reporter) Compare(ctx context.Context, base, head string) error {
+	testRunIDs, err := r.reportDB.GetAllTestRunIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the test runs: %w", err)
+	}
+	natsort.Sort(testRunIDs)
+	from, to := slices.Index(testRunIDs, base), slices.Index(testRunIDs, head)
+	if from == -1 || to == -1 {
+		return errors.New("test run not found, use keploy report list to see the test runs")
+	}
+	if from > to {
+		from, to = to, from
+	}
+
+	window := testRunIDs[from : to+1]
+	tests, statuses, err := r.history(ctx, window)
+	if err != nil {
+		return err
+	}
+
+	newlyFailing, newlyPassing, flaky := compareTests(tests, statuses, slices.Index(window, base), slices.Index(window, head))
+	fmt.Printf("Comparing %s with %s\n\n", base, head)
+	printTests(failedColor("Newly failing"), newlyFailing)
+	printTests(passedColor("Newly passing"), newlyPassing)
+	printTests(flakyColor("Flaky"), flaky)
+	return nil
+}
+
+// compareTests splits the test cases into the ones failing in the head test run but not in the base one,
+// the ones passing in the head test run but failing in the base one, and the flaky ones.
+func compareTests(tests []string, statuses map[string][]models.TestStatus, baseIdx, headIdx int) (newlyFailing, newlyPassing, flaky []string) {
+	for _, test := range tests {
+		history := statuses[test]
+		baseStatus, headStatus := history[baseIdx], history[headIdx]
+		switch {
+		case headStatus == models.TestStatusFailed && baseStatus != models.TestStatusFailed:
+			newlyFailing = append(newlyFailing, test)
+		case headStatus == models.TestStatusPassed && baseStatus == models.TestStatusFailed:
+			newlyPassing = append(newlyPassing, test)
+		}
+		if isFlaky(history) {
+			flaky = append(flaky, test)
+		}
+	}
+	return newlyFailing, newlyPassing, flaky
+}
+
+func printTests(title string, tests []string) {
+	fmt.Printf("%s (%d)\n", title, len(tests))
+	for _, test := range tests {
+		fmt.Printf("  %s\n", test)
+	}
+	fmt.Println()
+}
+
+func statusColor(status string) string {
+	switch status {
+	case string(models.TestSetStatusPassed):
+		return passedColor(status)
+	case string(models.TestSetStatusIgnored):
+		return status
+	}
+	return failedColor(status)
+}
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// reportDB keeps the statuses of the test cases by test run, test set and test case.
+type reportDB map[string]map[string]map[string]models.TestStatus
+
+func (db reportDB) GetAllTestRunIDs(_ context.Context) ([]string, error) {
+	var ids []string
+	for id := range db {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (db reportDB) GetAllTestSetIDs(_ context.Context, testRunID string) ([]string, error) {
+	run, ok := db[testRunID]
+	if !ok {
+		return nil, fmt.Errorf("test run %s not found", testRunID)
+	}
+	var ids []string
+	for id := range run {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (db reportDB) GetReport(_ context.Context, testRunID string, testSetID string) (*models.TestReport, error) {
+	set, ok := db[testRunID][testSetID]
+	if !ok {
+		return nil, errors.New("report not found")
+	}
+	report := &models.TestReport{}
+	for id, status := range set {
+		report.Tests = append(report.Tests, models.TestResult{TestCaseID: id, Status: status, Result: models.Result{StatusCode: models.IntResult{Normal: status != fail}}})
+	}
+	return report, nil
+}
+
+func newReporter(db ReportDB, cfg *config.Config) *Reporter {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	return &Reporter{logger: zap.NewNop(), reportDB: db, config: cfg}
+}
+
+func TestHistory(t *testing.T) {
+	db := reportDB{
+		"test-run-1":  {"test-set-0": {"test-1": pass, "test-2": fail}},
+		"test-run-2":  {"test-set-0": {"test-1": fail}, "test-set-1": {"test-1": pass}},
+		"test-run-10": {"test-set-0": {"test-1": pass, "test-2": flaky}},
+	}
+	tests, statuses, err := newReporter(db, nil).history(context.Background(), []string{"test-run-1", "test-run-2", "test-run-10"})
+	if err != nil {
+		t.Fatalf("history() error = %v", err)
+	}
+	wantTests := []string{"test-set-0/test-1", "test-set-0/test-2", "test-set-1/test-1"}
+	if !reflect.DeepEqual(tests, wantTests) {
+		t.Errorf("tests = %v, want %v", tests, wantTests)
+	}
+	wantStatuses := map[string][]models.TestStatus{
+		"test-set-0/test-1": {pass, fail, pass},
+		"test-set-0/test-2": {fail, "", flaky},
+		"test-set-1/test-1": {"", pass, ""},
+	}
+	if !reflect.DeepEqual(statuses, wantStatuses) {
+		t.Errorf("statuses = %v, want %v", statuses, wantStatuses)
+	}
+
+	if _, _, err := newReporter(db, nil).history(context.Background(), []string{"test-run-3"}); err == nil {
+		t.Error("history() of a missing test run succeeded, want an error")
+	}
+}
+
+func TestTestRunID(t *testing.T) {
+	tests := []struct {
+		name    string
+		db      reportDB
+		given   string
+		want    string
+		wantErr bool
+	}{
+		{name: "given", db: reportDB{"test-run-1": {}}, given: "test-run-0", want: "test-run-0"},
+		{name: "latest", db: reportDB{"test-run-2": {}, "test-run-10": {}, "test-run-9": {}}, want: "test-run-10"},
+		{name: "no test run", db: reportDB{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newReporter(tt.db, nil).testRunID(context.Background(), tt.given)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("testRunID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("testRunID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReports(t *testing.T) {
+	db := reportDB{"test-run-0": {"test-set-10": {}, "test-set-2": {}, "test-set-1": {}}}
+	reports, err := newReporter(db, nil).getReports(context.Background(), "test-run-0")
+	if err != nil {
+		t.Fatalf("getReports() error = %v", err)
+	}
+	var got []string
+	for _, report := range reports {
+		got = append(got, report.TestSet)
+	}
+	if want := []string{"test-set-1", "test-set-2", "test-set-10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("test sets = %v, want %v, named after their ids and sorted", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	db := reportDB{"test-run-0": {"test-set-0": {"test-1": pass, "test-2": fail}}}
+	tests := []struct {
+		name        string
+		testSetID   string
+		testCaseIDs []string
+		wantErr     bool
+	}{
+		{name: "failed testcases", testSetID: "test-set-0"},
+		{name: "passed testcase", testSetID: "test-set-0", testCaseIDs: []string{"test-1"}},
+		{name: "missing testcase", testSetID: "test-set-0", testCaseIDs: []string{"test-3"}, wantErr: true},
+		{name: "missing test set", testSetID: "test-set-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newReporter(db, nil).Diff(context.Background(), tt.testSetID, tt.testCaseIDs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Diff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	db := reportDB{
+		"test-run-0": {"test-set-0": {"test-1": pass}},
+		"test-run-1": {"test-set-0": {"test-1": fail}},
+	}
+	tests := []struct {
+		name       string
+		base, head string
+		wantErr    bool
+	}{
+		{name: "base before head", base: "test-run-0", head: "test-run-1"},
+		{name: "head before base", base: "test-run-1", head: "test-run-0"},
+		{name: "missing test run", base: "test-run-0", head: "test-run-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newReporter(db, nil).Compare(context.Background(), tt.base, tt.head)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
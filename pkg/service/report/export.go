@@ -0,0 +1,106 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// The formats the test runs can be exported to.
+const (
+	FormatJUnit = "junit"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// Formats lists the formats the test runs can be exported to.
+var Formats = []string{FormatJUnit, FormatJSON, FormatSARIF}
+
+// FileName returns the name of the file a test run is exported to, in the directory of the test run.
+func FileName(format string) string {
+	switch format {
+	case FormatJUnit:
+		return "junit.xml"
+	case FormatJSON:
+		return "summary.json"
+	case FormatSARIF:
+		return "keploy.sarif"
+	}
+	return format
+}
+
+// Encode encodes the reports of the test sets of a test run in the format.
+func Encode(format, testRunID string, reports []*models.TestReport) ([]byte, error) {
+	switch format {
+	case FormatJUnit:
+		return encodeJUnit(testRunID, reports)
+	case FormatJSON:
+		return encodeSummary(testRunID, reports)
+	case FormatSARIF:
+		return encodeSARIF(reports)
+	}
+	return nil, fmt.Errorf("unknown report format %q, must be one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Write encodes the reports of the test sets of a test run in the format, and writes them to the file.
+func Write(path, format, testRunID string, reports []*models.TestReport) error {
+	data, err := Encode(format, testRunID, reports)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+		return fmt.Errorf("failed to create the directory of the report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write the report: %w", err)
+	}
+	return nil
+}
+
+// failures describes why a test case failed: its status code, headers, body and dependency calls which
+// don't match the recorded ones.
+func failures(result models.TestResult) []string {
+	var msgs []string
+	if !result.Result.StatusCode.Normal {
+		msgs = append(msgs, fmt.Sprintf("status code: expected %d, got %d", result.Result.StatusCode.Expected, result.Result.StatusCode.Actual))
+	}
+	for _, header := range result.Result.HeadersResult {
+		if header.Normal {
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("header %s: expected %q, got %q", header.Expected.Key, strings.Join(header.Expected.Value, ", "), strings.Join(header.Actual.Value, ", ")))
+	}
+	for _, body := range result.Result.BodyResult {
+		if body.Normal {
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("body (%s):\n  expected: %s\n  actual: %s", body.Type, body.Expected, body.Actual))
+	}
+	for _, dep := range result.Result.DepResult {
+		if len(dep.Meta) == 0 || dep.Meta[0].Normal {
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("dependency %s: expected %s call(s), got %s", dep.Name, dep.Meta[0].Expected, dep.Meta[0].Actual))
+	}
+	if len(msgs) == 0 && result.Status == models.TestStatusFailed {
+		msgs = append(msgs, "the response doesn't match the recorded one")
+	}
+	return msgs
+}
+
+// testCaseFile returns the path of the file of the test case.
+func testCaseFile(result models.TestResult) string {
+	return filepath.Join(result.TestCasePath, "tests", result.TestCaseID+".yaml")
+}
+
+// isHalted tells whether the test set was stopped before all its test cases ran.
+func isHalted(report *models.TestReport) bool {
+	switch models.TestSetStatus(report.Status) {
+	case models.TestSetStatusAppHalted, models.TestSetStatusFaultUserApp, models.TestSetStatusInternalErr, models.TestSetStatusUserAbort, models.TestSetStatusFaultScript:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+var normalStatus = models.IntResult{Normal: true, Expected: 200, Actual: 200}
+
+// exportReports returns a passed test set, a failed one and one stopped by the application.
+func exportReports() []*models.TestReport {
+	return []*models.TestReport{
+		{
+			TestSet: "test-set-0",
+			Status:  string(models.TestSetStatusPassed),
+			Total:   2,
+			Success: 2,
+			Tests: []models.TestResult{
+				{TestCaseID: "test-1", TestCasePath: "keploy/test-set-0", Status: pass, Started: 100, Completed: 102, Result: models.Result{StatusCode: normalStatus}},
+				{TestCaseID: "test-2", TestCasePath: "keploy/test-set-0", Status: flaky, Started: 102, Completed: 105, Result: models.Result{StatusCode: normalStatus}},
+			},
+		},
+		{
+			TestSet: "test-set-1",
+			Status:  string(models.TestSetStatusFailed),
+			Total:   2,
+			Failure: 1,
+			Ignored: 1,
+			Tests: []models.TestResult{
+				{
+					TestCaseID: "test-1", TestCasePath: "keploy/test-set-1", Status: fail, Started: 200, Completed: 201,
+					Result: models.Result{
+						StatusCode: normalStatus,
+						BodyResult: []models.BodyResult{{Type: models.BodyTypeJSON, Expected: `{"id":1}`, Actual: `{"id":2}`}},
+					},
+				},
+				{TestCaseID: "test-2", TestCasePath: "keploy/test-set-1", Status: models.TestStatusIgnored},
+			},
+		},
+		{
+			TestSet: "test-set-2",
+			Status:  string(models.TestSetStatusAppHalted),
+			Total:   1,
+			Failure: 1,
+			Tests: []models.TestResult{
+				{TestCaseID: "test-1", TestCasePath: "keploy/test-set-2", Status: fail, Started: 300, Completed: 299, Result: models.Result{StatusCode: models.IntResult{Expected: 200, Actual: 500}}},
+			},
+		},
+	}
+}
+
+func TestFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		result models.TestResult
+		want   []string
+	}{
+		{
+			name:   "passed",
+			result: models.TestResult{Status: pass, Result: models.Result{StatusCode: normalStatus}},
+		},
+		{
+			name: "status code, header, body and dependency",
+			result: models.TestResult{Status: fail, Result: models.Result{
+				StatusCode: models.IntResult{Expected: 200, Actual: 404},
+				HeadersResult: []models.HeaderResult{
+					{Normal: true, Expected: models.Header{Key: "Date"}},
+					{Expected: models.Header{Key: "Accept", Value: []string{"text/plain", "text/html"}}, Actual: models.Header{Key: "Accept", Value: []string{"*/*"}}},
+				},
+				BodyResult: []models.BodyResult{{Type: models.BodyTypePlain, Expected: "ok", Actual: "not found"}},
+				DepResult: []models.DepResult{
+					{Name: "postgres", Meta: []models.DepMetaResult{{Normal: true, Expected: "1", Actual: "1"}}},
+					{Name: "redis", Meta: []models.DepMetaResult{{Expected: "2", Actual: "1"}}},
+					{Name: "mongo"},
+				},
+			}},
+			want: []string{
+				"status code: expected 200, got 404",
+				`header Accept: expected "text/plain, text/html", got "*/*"`,
+				"body (PLAIN):\n  expected: ok\n  actual: not found",
+				"dependency redis: expected 2 call(s), got 1",
+			},
+		},
+		{
+			name:   "failed without a mismatch",
+			result: models.TestResult{Status: fail, Result: models.Result{StatusCode: normalStatus}},
+			want:   []string{"the response doesn't match the recorded one"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failures(tt.result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failures() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHalted(t *testing.T) {
+	tests := []struct {
+		status models.TestSetStatus
+		want   bool
+	}{
+		{status: models.TestSetStatusPassed},
+		{status: models.TestSetStatusFailed},
+		{status: models.TestSetStatusIgnored},
+		{status: models.TestSetStatusAppHalted, want: true},
+		{status: models.TestSetStatusFaultUserApp, want: true},
+		{status: models.TestSetStatusInternalErr, want: true},
+		{status: models.TestSetStatusUserAbort, want: true},
+		{status: models.TestSetStatusFaultScript, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := isHalted(&models.TestReport{Status: string(tt.status)}); got != tt.want {
+				t.Errorf("isHalted(%s) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		format   string
+		fileName string
+		prefix   string
+		wantErr  bool
+	}{
+		{format: FormatJUnit, fileName: "junit.xml", prefix: xml.Header},
+		{format: FormatJSON, fileName: "summary.json", prefix: "{"},
+		{format: FormatSARIF, fileName: "keploy.sarif", prefix: "{"},
+		{format: "html", fileName: "html", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := FileName(tt.format); got != tt.fileName {
+				t.Errorf("FileName(%q) = %q, want %q", tt.format, got, tt.fileName)
+			}
+			data, err := Encode(tt.format, "test-run-0", exportReports())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+			if !strings.HasPrefix(string(data), tt.prefix) {
+				t.Errorf("Encode(%q) = %q, want it to start with %q", tt.format, data, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestEncodeJUnit(t *testing.T) {
+	data, err := encodeJUnit("test-run-0", exportReports())
+	if err != nil {
+		t.Fatalf("encodeJUnit() error = %v", err)
+	}
+	var got junitTestSuites
+	if err := xml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed to parse the JUnit report: %v", err)
+	}
+
+	if got.Name != "test-run-0" || got.Tests != 6 || got.Failures != 2 || got.Errors != 1 || got.Skipped != 1 || got.Time != "5" {
+		t.Errorf("testsuites = {name: %s, tests: %d, failures: %d, errors: %d, skipped: %d, time: %s}, want {name: test-run-0, tests: 6, failures: 2, errors: 1, skipped: 1, time: 5}",
+			got.Name, got.Tests, got.Failures, got.Errors, got.Skipped, got.Time)
+	}
+	if len(got.Suites) != 3 {
+		t.Fatalf("got %d test suites, want 3", len(got.Suites))
+	}
+
+	passed, failed, halted := got.Suites[0], got.Suites[1], got.Suites[2]
+	if passed.Tests != 2 || passed.Failures != 0 || passed.Time != "5" || passed.Timestamp != "1970-01-01T00:01:40Z" {
+		t.Errorf("passed test suite = %+v", passed)
+	}
+	if file := passed.Cases[0].File; file != filepath.Join("keploy", "test-set-0", "tests", "test-1.yaml") {
+		t.Errorf("test case file = %q", file)
+	}
+
+	if failed.Failures != 1 || failed.Skipped != 1 {
+		t.Errorf("failed test suite has %d failures and %d skipped, want 1 and 1", failed.Failures, failed.Skipped)
+	}
+	if f := failed.Cases[0].Failure; f == nil || f.Message != "body (JSON):" || !strings.Contains(f.Text, `actual: {"id":2}`) {
+		t.Errorf("failure = %+v, want the first line of the body mismatch as its message", f)
+	}
+	if s := failed.Cases[1].Skipped; s == nil || s.Message != "ignored" {
+		t.Errorf("skipped = %+v, want the ignored message", s)
+	}
+
+	if halted.Tests != 2 || halted.Errors != 1 {
+		t.Errorf("halted test suite has %d tests and %d errors, want 2 and 1", halted.Tests, halted.Errors)
+	}
+	if c := halted.Cases[0]; c.Time != "0" || c.Failure == nil || c.Failure.Message != "status code: expected 200, got 500" {
+		t.Errorf("halted test case = %+v", c)
+	}
+	if c := halted.Cases[1]; c.Name != "test-set-2" || c.Error == nil || c.Error.Message != string(models.TestSetStatusAppHalted) {
+		t.Errorf("halted test set case = %+v, want an error named after the test set", c)
+	}
+}
+
+func TestEncodeSARIF(t *testing.T) {
+	data, err := encodeSARIF(exportReports())
+	if err != nil {
+		t.Fatalf("encodeSARIF() error = %v", err)
+	}
+	var got sarifLog
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed to parse the SARIF report: %v", err)
+	}
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("got the version %s with %d runs, want 2.1.0 with 1", got.Version, len(got.Runs))
+	}
+	run := got.Runs[0]
+	if rules := run.Tool.Driver.Rules; len(rules) != 2 || rules[0].ID != sarifTestFailure || rules[1].ID != sarifTestSetHalt {
+		t.Errorf("rules = %+v", rules)
+	}
+
+	want := []struct {
+		ruleID  string
+		message string
+		uri     string
+	}{
+		{ruleID: sarifTestFailure, message: "test-set-1/test-1 failed:\nbody (JSON):\n  expected: {\"id\":1}\n  actual: {\"id\":2}", uri: "keploy/test-set-1/tests/test-1.yaml"},
+		{ruleID: sarifTestFailure, message: "test-set-2/test-1 failed:\nstatus code: expected 200, got 500", uri: "keploy/test-set-2/tests/test-1.yaml"},
+		{ruleID: sarifTestSetHalt, message: "test-set-2 stopped with the status APP_HALTED"},
+	}
+	if len(run.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(want))
+	}
+	for i, w := range want {
+		r := run.Results[i]
+		if r.RuleID != w.ruleID || r.Level != "error" || r.Message.Text != w.message {
+			t.Errorf("result %d = {%s %s %q}, want {%s error %q}", i, r.RuleID, r.Level, r.Message.Text, w.ruleID, w.message)
+		}
+		var uri string
+		if len(r.Locations) > 0 {
+			uri = r.Locations[0].PhysicalLocation.ArtifactLocation.URI
+		}
+		if uri != w.uri {
+			t.Errorf("result %d location = %q, want %q", i, uri, w.uri)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "relative", path: "keploy/test-set-0/tests/test-1.yaml", want: "keploy/test-set-0/tests/test-1.yaml"},
+		{name: "under the working directory", path: filepath.Join(wd, "keploy", "test-1.yaml"), want: "keploy/test-1.yaml"},
+		{name: "outside the working directory", path: "/tmp/keploy/test-1.yaml", want: "file:///tmp/keploy/test-1.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := location(tt.path).PhysicalLocation.ArtifactLocation.URI; got != tt.want {
+				t.Errorf("location(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	got := Summarize("test-run-0", exportReports())
+	if got.Status != string(models.TestSetStatusFailed) || got.Total != 5 || got.Passed != 2 || got.Failed != 2 || got.Ignored != 1 || got.Flaky != 1 {
+		t.Errorf("summary = %+v", got)
+	}
+	if len(got.TestSets) != 3 {
+		t.Fatalf("got %d test sets, want 3", len(got.TestSets))
+	}
+	if got.TestSets[0].Flaky != 1 || len(got.TestSets[0].FailedTests) != 0 {
+		t.Errorf("passed test set = %+v", got.TestSets[0])
+	}
+	wantFailed := []FailedTest{{
+		TestCaseID: "test-1",
+		Path:       filepath.Join("keploy", "test-set-1", "tests", "test-1.yaml"),
+		Failures:   []string{"body (JSON):\n  expected: {\"id\":1}\n  actual: {\"id\":2}"},
+	}}
+	if !reflect.DeepEqual(got.TestSets[1].FailedTests, wantFailed) {
+		t.Errorf("failed tests = %+v, want %+v", got.TestSets[1].FailedTests, wantFailed)
+	}
+
+	passed := Summarize("test-run-1", []*models.TestReport{
+		{TestSet: "test-set-0", Status: string(models.TestSetStatusPassed)},
+		{TestSet: "test-set-1", Status: string(models.TestSetStatusIgnored)},
+	})
+	if passed.Status != string(models.TestSetStatusPassed) {
+		t.Errorf("status of a run with passed and ignored test sets = %s, want %s", passed.Status, models.TestSetStatusPassed)
+	}
+}
@@ -0,0 +1,234 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"facette.io/natsort"
+	"github.com/olekukonko/tablewriter"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// FlakyTest is a test case whose status isn't stable across the test runs.
+type FlakyTest struct {
+	TestSet    string
+	TestCaseID string
+	Runs       int // test runs in which the test case ran
+	Failed     int // test runs in which the test case failed
+	Flaky      int // test runs in which the test case failed, then passed when rerun
+}
+
+// reason tells why the test case is flaky.
+func (ft FlakyTest) reason() string {
+	var reasons []string
+	if ft.Failed > 0 {
+		reasons = append(reasons, fmt.Sprintf("failed in %d", ft.Failed))
+	}
+	if ft.Flaky > 0 {
+		reasons = append(reasons, fmt.Sprintf("passed on rerun in %d", ft.Flaky))
+	}
+	return "flaky, " + strings.Join(reasons, " and ") + fmt.Sprintf(" of the last %d runs", ft.Runs)
+}
+
+// history returns the test cases of the test runs, as test-set/testcase, along with the status of each
+// test case in each test run. The status is empty for the test runs which didn't run the test case.
+func (r *Reporter) history(ctx context.Context, testRunIDs []string) ([]string, map[string][]models.TestStatus, error) {
+	statuses := map[string][]models.TestStatus{}
+	var tests []string
+	for i, testRunID := range testRunIDs {
+		reports, err := r.getReports(ctx, testRunID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, report := range reports {
+			for _, result := range report.Tests {
+				key := report.TestSet + "/" + result.TestCaseID
+				if _, ok := statuses[key]; !ok {
+					statuses[key] = make([]models.TestStatus, len(testRunIDs))
+					tests = append(tests, key)
+				}
+				statuses[key][i] = result.Status
+			}
+		}
+	}
+	natsort.Sort(tests)
+	return tests, statuses, nil
+}
+
+// isFlaky tells whether a test case passed on rerun, or whether it passed, then failed, then passed again
+// across the test runs. A single flip between passed and failed is a change of the test case, not flakiness.
+func isFlaky(history []models.TestStatus) bool {
+	passed, failedAfterPass := false, false
+	for _, status := range history {
+		switch status {
+		case models.TestStatusFlaky:
+			return true
+		case models.TestStatusPassed:
+			if failedAfterPass {
+				return true
+			}
+			passed = true
+		case models.TestStatusFailed:
+			failedAfterPass = failedAfterPass || passed
+		}
+	}
+	return false
+}
+
+// FlakyTests returns the flaky test cases of the latest test runs, all of them if runs isn't positive.
+func (r *Reporter) FlakyTests(ctx context.Context, runs int) ([]FlakyTest, int, error) {
+	testRunIDs, err := r.reportDB.GetAllTestRunIDs(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get the test runs: %w", err)
+	}
+	natsort.Sort(testRunIDs)
+	if runs > 0 && len(testRunIDs) > runs {
+		testRunIDs = testRunIDs[len(testRunIDs)-runs:]
+	}
+
+	tests, statuses, err := r.history(ctx, testRunIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	var flaky []FlakyTest
+	for _, test := range tests {
+		history := statuses[test]
+		if !isFlaky(history) {
+			continue
+		}
+		testSet, testCaseID := splitTest(test)
+		ft := FlakyTest{TestSet: testSet, TestCaseID: testCaseID}
+		for _, status := range history {
+			switch status {
+			case models.TestStatusPassed:
+				ft.Runs++
+			case models.TestStatusFailed:
+				ft.Runs++
+				ft.Failed++
+			case models.TestStatusFlaky:
+				ft.Runs++
+				ft.Flaky++
+			}
+		}
+		flaky = append(flaky, ft)
+	}
+	return flaky, len(testRunIDs), nil
+}
+
+// Quarantine adds the flaky test cases of the latest test runs to the ignored tests of the config file,
+// each one with a comment giving the reason. The test sets which are already ignored are left as they are.
+func (r *Reporter) Quarantine(ctx context.Context) error {
+	flaky, runs, err := r.FlakyTests(ctx, r.config.Report.Runs)
+	if err != nil {
+		return err
+	}
+	if len(flaky) == 0 {
+		r.logger.Info("no flaky testcase found", zap.Int("test runs", runs))
+		return nil
+	}
+
+	path := filepath.Join(r.config.ConfigPath, "keploy.yml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("config file not found at %s, generate it using keploy config --generate", path)
+		}
+		return fmt.Errorf("failed to read the config file: %w", err)
+	}
+	var doc yamlLib.Node
+	err = yamlLib.Unmarshal(data, &doc)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal the config file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yamlLib.MappingNode {
+		return fmt.Errorf("the config file %s isn't a yaml mapping", path)
+	}
+	ignored := mappingValue(mappingValue(doc.Content[0], "test"), "ignoredTests")
+
+	date := time.Now().Format(time.DateOnly)
+	var quarantined []FlakyTest
+	for _, ft := range flaky {
+		tests := mappingValue(ignored, ft.TestSet)
+		switch {
+		case tests.Kind == 0:
+			tests.Kind, tests.Tag = yamlLib.SequenceNode, "!!seq"
+		case tests.Kind != yamlLib.SequenceNode || len(tests.Content) == 0:
+			// an empty list ignores the whole test set
+			continue
+		}
+		if containsScalar(tests, ft.TestCaseID) {
+			continue
+		}
+		tests.Style = 0
+		tests.Content = append(tests.Content, &yamlLib.Node{
+			Kind:        yamlLib.ScalarNode,
+			Tag:         "!!str",
+			Value:       ft.TestCaseID,
+			LineComment: "# quarantined on " + date + ": " + ft.reason(),
+		})
+		quarantined = append(quarantined, ft)
+	}
+	if len(quarantined) == 0 {
+		r.logger.Info("the flaky testcases are already ignored", zap.Int("flaky testcases", len(flaky)))
+		return nil
+	}
+
+	data, err = yamlLib.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the config file: %w", err)
+	}
+	err = os.WriteFile(path, data, 0777)
+	if err != nil {
+		return fmt.Errorf("failed to write the config file: %w", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Test set", "Testcase", "Runs", "Failed", "Passed on rerun"})
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	for _, ft := range quarantined {
+		table.Append([]string{ft.TestSet, flakyColor(ft.TestCaseID), strconv.Itoa(ft.Runs), strconv.Itoa(ft.Failed), strconv.Itoa(ft.Flaky)})
+	}
+	table.Render()
+	r.logger.Info("added the flaky testcases to the ignored tests of the config file", zap.String("config", path), zap.Int("quarantined", len(quarantined)))
+	return nil
+}
+
+// mappingValue returns the value of a key of a yaml mapping, adding the key if needed. The mapping is
+// switched to the block style so that the comments of its values can be written.
+func mappingValue(mapping *yamlLib.Node, key string) *yamlLib.Node {
+	if mapping.Kind != yamlLib.MappingNode {
+		mapping.Kind, mapping.Tag, mapping.Value, mapping.Content = yamlLib.MappingNode, "!!map", "", nil
+	}
+	mapping.Style = 0
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	value := &yamlLib.Node{}
+	mapping.Content = append(mapping.Content, &yamlLib.Node{Kind: yamlLib.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
+
+func containsScalar(seq *yamlLib.Node, value string) bool {
+	for _, n := range seq.Content {
+		if n.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTest splits a test-set/testcase key of the history.
+func splitTest(test string) (string, string) {
+	i := strings.LastIndex(test, "/")
+	return test[:i], test[i+1:]
+}
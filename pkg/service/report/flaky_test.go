@@ -0,0 +1,292 @@
+package report
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"facette.io/natsort"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+const (
+	pass  = models.TestStatusPassed
+	fail  = models.TestStatusFailed
+	flaky = models.TestStatusFlaky
+)
+
+func TestIsFlaky(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []models.TestStatus
+		want    bool
+	}{
+		{name: "always passing", history: []models.TestStatus{pass, pass, pass}},
+		{name: "always failing", history: []models.TestStatus{fail, fail}},
+		{name: "started failing", history: []models.TestStatus{pass, fail}},
+		{name: "fixed", history: []models.TestStatus{fail, pass}},
+		{name: "failing again after a fix", history: []models.TestStatus{fail, pass, fail}},
+		{name: "passed, failed and passed again", history: []models.TestStatus{pass, fail, pass}, want: true},
+		{name: "runs without the test case in between", history: []models.TestStatus{pass, "", fail, models.TestStatusIgnored, pass}, want: true},
+		{name: "passed on rerun", history: []models.TestStatus{pass, flaky}, want: true},
+		{name: "no runs", history: []models.TestStatus{"", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFlaky(tt.history); got != tt.want {
+				t.Errorf("isFlaky(%v) = %v, want %v", tt.history, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareTests(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         map[string][]models.TestStatus
+		baseIdx, headIdx int
+		wantFailing      []string
+		wantPassing      []string
+		wantFlaky        []string
+	}{
+		{
+			name:        "two test runs",
+			statuses:    map[string][]models.TestStatus{"set/a": {pass, fail}, "set/b": {fail, pass}, "set/c": {pass, pass}, "set/d": {"", fail}},
+			headIdx:     1,
+			wantFailing: []string{"set/a", "set/d"},
+			wantPassing: []string{"set/b"},
+		},
+		{
+			name:        "flaky in between",
+			statuses:    map[string][]models.TestStatus{"set/a": {pass, fail, pass}, "set/b": {pass, pass, fail}},
+			headIdx:     2,
+			wantFailing: []string{"set/b"},
+			wantFlaky:   []string{"set/a"},
+		},
+		{
+			name:        "head before base",
+			statuses:    map[string][]models.TestStatus{"set/a": {fail, pass}},
+			baseIdx:     1,
+			wantFailing: []string{"set/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for name := range tt.statuses {
+				names = append(names, name)
+			}
+			natsort.Sort(names)
+			failing, passing, flakyTests := compareTests(names, tt.statuses, tt.baseIdx, tt.headIdx)
+			if !reflect.DeepEqual(failing, tt.wantFailing) {
+				t.Errorf("newly failing = %v, want %v", failing, tt.wantFailing)
+			}
+			if !reflect.DeepEqual(passing, tt.wantPassing) {
+				t.Errorf("newly passing = %v, want %v", passing, tt.wantPassing)
+			}
+			if !reflect.DeepEqual(flakyTests, tt.wantFlaky) {
+				t.Errorf("flaky = %v, want %v", flakyTests, tt.wantFlaky)
+			}
+		})
+	}
+}
+
+func TestFlakyTestReason(t *testing.T) {
+	tests := []struct {
+		ft   FlakyTest
+		want string
+	}{
+		{ft: FlakyTest{Runs: 3, Failed: 1}, want: "flaky, failed in 1 of the last 3 runs"},
+		{ft: FlakyTest{Runs: 2, Flaky: 2}, want: "flaky, passed on rerun in 2 of the last 2 runs"},
+		{ft: FlakyTest{Runs: 5, Failed: 2, Flaky: 1}, want: "flaky, failed in 2 and passed on rerun in 1 of the last 5 runs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.ft.reason(); got != tt.want {
+				t.Errorf("reason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTest(t *testing.T) {
+	tests := []struct {
+		test                string
+		testSet, testCaseID string
+	}{
+		{test: "test-set-0/test-1", testSet: "test-set-0", testCaseID: "test-1"},
+		{test: "apps/test-set-0/test-1", testSet: "apps/test-set-0", testCaseID: "test-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.test, func(t *testing.T) {
+			testSet, testCaseID := splitTest(tt.test)
+			if testSet != tt.testSet || testCaseID != tt.testCaseID {
+				t.Errorf("splitTest(%q) = %q, %q, want %q, %q", tt.test, testSet, testCaseID, tt.testSet, tt.testCaseID)
+			}
+		})
+	}
+}
+
+func TestMappingValue(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		key  string
+		want string
+	}{
+		{name: "existing key", yaml: "a: 1\nb: 2\n", key: "b", want: "a: 1\nb: 2\n"},
+		{name: "missing key", yaml: "a: 1\n", key: "b", want: "a: 1\nb: 3\n"},
+		{name: "flow mapping", yaml: "{a: 1}\n", key: "b", want: "a: 1\nb: 3\n"},
+		{name: "not a mapping", yaml: "null\n", key: "b", want: "b: 3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yamlLib.Node
+			if err := yamlLib.Unmarshal([]byte(tt.yaml), &doc); err != nil {
+				t.Fatal(err)
+			}
+			value := mappingValue(doc.Content[0], tt.key)
+			if value.Kind == 0 {
+				value.Kind, value.Tag, value.Value = yamlLib.ScalarNode, "!!int", "3"
+			}
+			got, err := yamlLib.Marshal(&doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsScalar(t *testing.T) {
+	var seq yamlLib.Node
+	if err := yamlLib.Unmarshal([]byte("[test-1, test-2]"), &seq); err != nil {
+		t.Fatal(err)
+	}
+	for value, want := range map[string]bool{"test-1": true, "test-2": true, "test-3": false} {
+		if got := containsScalar(seq.Content[0], value); got != want {
+			t.Errorf("containsScalar(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+// flakyDB has test-set-0/test-1 flipping across the test runs, test-set-1/test-1 passing on rerun, and
+// test-set-1/test-2 failing from the second test run on.
+var flakyDB = reportDB{
+	"test-run-1": {"test-set-0": {"test-1": pass}, "test-set-1": {"test-1": pass, "test-2": pass}},
+	"test-run-2": {"test-set-0": {"test-1": fail}, "test-set-1": {"test-1": flaky, "test-2": fail}},
+	"test-run-3": {"test-set-0": {"test-1": pass}, "test-set-1": {"test-1": pass, "test-2": fail}},
+}
+
+func TestFlakyTests(t *testing.T) {
+	tests := []struct {
+		name     string
+		runs     int
+		want     []FlakyTest
+		wantRuns int
+	}{
+		{
+			name: "all the test runs",
+			want: []FlakyTest{
+				{TestSet: "test-set-0", TestCaseID: "test-1", Runs: 3, Failed: 1},
+				{TestSet: "test-set-1", TestCaseID: "test-1", Runs: 3, Flaky: 1},
+			},
+			wantRuns: 3,
+		},
+		{
+			name:     "the latest test runs",
+			runs:     2,
+			want:     []FlakyTest{{TestSet: "test-set-1", TestCaseID: "test-1", Runs: 2, Flaky: 1}},
+			wantRuns: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, runs, err := newReporter(flakyDB, nil).FlakyTests(context.Background(), tt.runs)
+			if err != nil {
+				t.Fatalf("FlakyTests() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || runs != tt.wantRuns {
+				t.Errorf("FlakyTests() = %+v, %d, want %+v, %d", got, runs, tt.want, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		want        map[string][]string
+		wantComment bool
+		wantErr     bool
+	}{
+		{
+			name:        "no ignored tests",
+			config:      "path: \"\"\ntest:\n  delay: 5\n",
+			want:        map[string][]string{"test-set-0": {"test-1"}, "test-set-1": {"test-1"}},
+			wantComment: true,
+		},
+		{
+			name:   "ignored tests",
+			config: "test:\n  ignoredTests: {test-set-1: [test-3]}\n",
+			want:   map[string][]string{"test-set-0": {"test-1"}, "test-set-1": {"test-3", "test-1"}},
+		},
+		{
+			name:   "ignored test set",
+			config: "test:\n  ignoredTests:\n    test-set-0: []\n",
+			want:   map[string][]string{"test-set-0": {}, "test-set-1": {"test-1"}},
+		},
+		{
+			name:   "already ignored",
+			config: "test:\n  ignoredTests:\n    test-set-0: [test-1]\n    test-set-1: [test-1]\n",
+			want:   map[string][]string{"test-set-0": {"test-1"}, "test-set-1": {"test-1"}},
+		},
+		{name: "not a mapping", config: "- test\n", wantErr: true},
+		{name: "no config file", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "keploy.yml")
+			if tt.config != "" {
+				if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := newReporter(flakyDB, &config.Config{ConfigPath: dir}).Quarantine(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Quarantine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got struct {
+				Test struct {
+					IgnoredTests map[string][]string `yaml:"ignoredTests"`
+				} `yaml:"test"`
+			}
+			if err := yamlLib.Unmarshal(data, &got); err != nil {
+				t.Fatalf("failed to unmarshal the config file: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got.Test.IgnoredTests, tt.want) {
+				t.Errorf("ignored tests = %v, want %v", got.Test.IgnoredTests, tt.want)
+			}
+			if tt.wantComment && !strings.Contains(string(data), "test-1 # quarantined on ") {
+				t.Errorf("the quarantined testcases have no comment:\n%s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package report
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// The JUnit XML elements understood by the CI servers: a testsuite per test set, and a testcase per test
+// case of the test set.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func encodeJUnit(testRunID string, reports []*models.TestReport) ([]byte, error) {
+	suites := junitTestSuites{Name: testRunID}
+	var total int64
+	for _, report := range reports {
+		suite := junitTestSuite{Name: report.TestSet}
+		var started, completed int64
+		for _, result := range report.Tests {
+			tc := junitTestCase{
+				Name:      result.TestCaseID,
+				ClassName: report.TestSet,
+				File:      testCaseFile(result),
+				Time:      seconds(result.Completed - result.Started),
+			}
+			switch result.Status {
+			case models.TestStatusFailed:
+				msgs := failures(result)
+				tc.Failure = &junitMessage{Message: strings.SplitN(msgs[0], "\n", 2)[0], Type: string(result.Status), Text: strings.Join(msgs, "\n")}
+				suite.Failures++
+			case models.TestStatusIgnored:
+				tc.Skipped = &junitMessage{Message: "ignored"}
+				suite.Skipped++
+			}
+			if started == 0 || (result.Started != 0 && result.Started < started) {
+				started = result.Started
+			}
+			if result.Completed > completed {
+				completed = result.Completed
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		// the test cases which didn't run because the application stopped are reported as an error of the test set
+		if isHalted(report) {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      report.TestSet,
+				ClassName: report.TestSet,
+				Time:      seconds(0),
+				Error:     &junitMessage{Message: report.Status, Type: report.Status, Text: "the test set stopped with the status " + report.Status},
+			})
+			suite.Errors++
+		}
+		suite.Tests = len(suite.Cases)
+		suite.Time = seconds(completed - started)
+		if started != 0 {
+			suite.Timestamp = time.Unix(started, 0).UTC().Format(time.RFC3339)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		total += completed - started
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = seconds(total)
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// seconds formats a duration in seconds, as the JUnit time attributes are.
+func seconds(s int64) string {
+	if s < 0 {
+		s = 0
+	}
+	return strconv.FormatInt(s, 10)
+}
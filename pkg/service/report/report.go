@@ -0,0 +1,87 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"facette.io/natsort"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+type Reporter struct {
+	logger   *zap.Logger
+	reportDB ReportDB
+	config   *config.Config
+}
+
+func New(logger *zap.Logger, reportDB ReportDB, config *config.Config) Service {
+	return &Reporter{
+		logger:   logger,
+		reportDB: reportDB,
+		config:   config,
+	}
+}
+
+func (r *Reporter) Export(ctx context.Context) error {
+	testRunID, err := r.testRunID(ctx, r.config.Report.TestRun)
+	if err != nil {
+		return err
+	}
+	reports, err := r.getReports(ctx, testRunID)
+	if err != nil {
+		return err
+	}
+	output := r.config.Report.Output
+	if output == "" {
+		output = filepath.Join(r.config.Path, "reports", testRunID, FileName(r.config.Report.Format))
+	}
+	err = Write(output, r.config.Report.Format, testRunID, reports)
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to export the test run", zap.String("test-run", testRunID))
+		return err
+	}
+	r.logger.Info("exported the test run", zap.String("test-run", testRunID), zap.String("format", r.config.Report.Format), zap.String("path", output))
+	return nil
+}
+
+// testRunID returns the given test run, or the latest one if none is given.
+func (r *Reporter) testRunID(ctx context.Context, testRunID string) (string, error) {
+	if testRunID != "" {
+		return testRunID, nil
+	}
+	testRunIDs, err := r.reportDB.GetAllTestRunIDs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the test runs: %w", err)
+	}
+	if len(testRunIDs) == 0 {
+		return "", errors.New("no test run found, please run the testcases using keploy test")
+	}
+	natsort.Sort(testRunIDs)
+	return testRunIDs[len(testRunIDs)-1], nil
+}
+
+// getReports returns the reports of the test sets of the test run, sorted by test set.
+func (r *Reporter) getReports(ctx context.Context, testRunID string) ([]*models.TestReport, error) {
+	testSetIDs, err := r.reportDB.GetAllTestSetIDs(ctx, testRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the test sets of the test run: %w", err)
+	}
+	natsort.Sort(testSetIDs)
+	reports := make([]*models.TestReport, 0, len(testSetIDs))
+	for _, testSetID := range testSetIDs {
+		report, err := r.reportDB.GetReport(ctx, testRunID, testSetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the report of the test set %s: %w", testSetID, err)
+		}
+		if report.TestSet == "" {
+			report.TestSet = testSetID
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
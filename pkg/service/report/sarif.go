@@ -0,0 +1,126 @@
+package report
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+// The subset of SARIF 2.1.0 needed to report the failed test cases as results located in their files.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+const (
+	sarifTestFailure = "keploy/test-failure"
+	sarifTestSetHalt = "keploy/test-set-halted"
+)
+
+func encodeSARIF(reports []*models.TestReport) ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "keploy",
+			Version:        utils.Version,
+			InformationURI: "https://keploy.io",
+			Rules: []sarifRule{
+				{ID: sarifTestFailure, ShortDescription: sarifMessage{Text: "The response of the test case doesn't match the recorded one"}},
+				{ID: sarifTestSetHalt, ShortDescription: sarifMessage{Text: "The test set stopped before all its test cases ran"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, report := range reports {
+		for _, result := range report.Tests {
+			if result.Status != models.TestStatusFailed {
+				continue
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    sarifTestFailure,
+				Level:     "error",
+				Message:   sarifMessage{Text: report.TestSet + "/" + result.TestCaseID + " failed:\n" + strings.Join(failures(result), "\n")},
+				Locations: []sarifLocation{location(testCaseFile(result))},
+			})
+		}
+		if isHalted(report) {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  sarifTestSetHalt,
+				Level:   "error",
+				Message: sarifMessage{Text: report.TestSet + " stopped with the status " + report.Status},
+			})
+		}
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// location returns the location of a file, relative to the working directory when possible, as the
+// code scanning tools resolve the locations against the root of the repository.
+func location(path string) sarifLocation {
+	uri := filepath.ToSlash(path)
+	if wd, err := filepath.Abs("."); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			uri = filepath.ToSlash(rel)
+		}
+	}
+	if filepath.IsAbs(path) && strings.HasPrefix(uri, "/") {
+		uri = "file://" + uri
+	}
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}}}
+}
@@ -0,0 +1,29 @@
+// Package report provides the service to browse and export the reports of the test runs.
+package report
+
+import (
+	"context"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type Service interface {
+	// List prints the summary of each test run.
+	List(ctx context.Context) error
+	// Show prints the summary of each test set of a test run, the latest one if none is given.
+	Show(ctx context.Context, testRunID string) error
+	// Diff prints the diffs of the test cases of a test set, in the test run of the report config.
+	Diff(ctx context.Context, testSetID string, testCaseIDs []string) error
+	// Compare prints the test cases which started failing, started passing or are flaky between two test runs.
+	Compare(ctx context.Context, base, head string) error
+	// Quarantine adds the flaky test cases of the latest test runs to the ignored tests of the config file.
+	Quarantine(ctx context.Context) error
+	// Export writes the reports of a test run in the format of the report config.
+	Export(ctx context.Context) error
+}
+
+type ReportDB interface {
+	GetAllTestRunIDs(ctx context.Context) ([]string, error)
+	GetAllTestSetIDs(ctx context.Context, testRunID string) ([]string, error)
+	GetReport(ctx context.Context, testRunID string, testSetID string) (*models.TestReport, error)
+}
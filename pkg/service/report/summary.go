@@ -0,0 +1,83 @@
+package report
+
+import (
+	"encoding/json"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Summary is the machine-readable summary of a test run.
+type Summary struct {
+	TestRunID string           `json:"testRunId"`
+	Status    string           `json:"status"`
+	Total     int              `json:"total"`
+	Passed    int              `json:"passed"`
+	Failed    int              `json:"failed"`
+	Ignored   int              `json:"ignored"`
+	Flaky     int              `json:"flaky"` // passed on rerun, counted in passed too
+	TestSets  []TestSetSummary `json:"testSets"`
+}
+
+type TestSetSummary struct {
+	Name        string       `json:"name"`
+	Status      string       `json:"status"`
+	Total       int          `json:"total"`
+	Passed      int          `json:"passed"`
+	Failed      int          `json:"failed"`
+	Ignored     int          `json:"ignored"`
+	Flaky       int          `json:"flaky"`
+	FailedTests []FailedTest `json:"failedTests,omitempty"`
+}
+
+type FailedTest struct {
+	TestCaseID string   `json:"testCaseId"`
+	Path       string   `json:"path"`
+	Failures   []string `json:"failures"`
+}
+
+// Summarize returns the summary of the reports of the test sets of a test run. The test run passes if all
+// its test sets either passed or were ignored.
+func Summarize(testRunID string, reports []*models.TestReport) Summary {
+	summary := Summary{TestRunID: testRunID, Status: string(models.TestSetStatusPassed), TestSets: []TestSetSummary{}}
+	for _, report := range reports {
+		set := TestSetSummary{
+			Name:    report.TestSet,
+			Status:  report.Status,
+			Total:   report.Total,
+			Passed:  report.Success,
+			Failed:  report.Failure,
+			Ignored: report.Ignored,
+		}
+		for _, result := range report.Tests {
+			if result.Status == models.TestStatusFlaky {
+				set.Flaky++
+			}
+			if result.Status != models.TestStatusFailed {
+				continue
+			}
+			set.FailedTests = append(set.FailedTests, FailedTest{
+				TestCaseID: result.TestCaseID,
+				Path:       testCaseFile(result),
+				Failures:   failures(result),
+			})
+		}
+		summary.Total += report.Total
+		summary.Passed += report.Success
+		summary.Failed += report.Failure
+		summary.Ignored += report.Ignored
+		summary.Flaky += set.Flaky
+		if report.Status != string(models.TestSetStatusPassed) && report.Status != string(models.TestSetStatusIgnored) {
+			summary.Status = string(models.TestSetStatusFailed)
+		}
+		summary.TestSets = append(summary.TestSets, set)
+	}
+	return summary
+}
+
+func encodeSummary(testRunID string, reports []*models.TestReport) ([]byte, error) {
+	data, err := json.MarshalIndent(Summarize(testRunID, reports), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// streamContentTypes are the content types of the streamed responses, along with whether their events are
+// server-sent events or lines.
+var streamContentTypes = map[string]bool{
+	"text/event-stream":       true,
+	"application/x-ndjson":    false,
+	"application/ndjson":      false,
+	"application/jsonl":       false,
+	"application/jsonlines":   false,
+	"application/stream+json": false,
+}
+
+// IsHTTPStream tells whether a response is streamed, i.e. whether it is made of server-sent events or of
+// newline-delimited JSON. The other chunked responses are kept as a single body, as their chunks only depend
+// on the buffering of the server. The compressed streams are kept as a single body as well.
+func IsHTTPStream(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" && !strings.EqualFold(header.Get("Content-Encoding"), "identity") {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	_, ok := streamContentTypes[mediaType]
+	return ok
+}
+
+// HTTPStreamParser splits the body of a streamed response into events as it is received.
+type HTTPStreamParser struct {
+	sse     bool
+	pending []byte
+	event   models.HTTPStreamEvent
+	data    []string
+	started bool
+}
+
+// NewHTTPStreamParser returns the parser of a streamed response, from its headers.
+func NewHTTPStreamParser(header http.Header) *HTTPStreamParser {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return &HTTPStreamParser{sse: streamContentTypes[mediaType]}
+}
+
+// Feed parses the next piece of the body, received after offset, and returns the events it completes.
+func (p *HTTPStreamParser) Feed(data []byte, offset time.Duration) []models.HTTPStreamEvent {
+	p.pending = append(p.pending, data...)
+	var events []models.HTTPStreamEvent
+	for {
+		i := bytes.IndexByte(p.pending, '\n')
+		if i < 0 {
+			return events
+		}
+		line := strings.TrimSuffix(string(p.pending[:i]), "\r")
+		p.pending = p.pending[i+1:]
+		if event, ok := p.line(line, offset); ok {
+			events = append(events, event)
+		}
+	}
+}
+
+// Close returns the last event, which the end of the body completes.
+func (p *HTTPStreamParser) Close(offset time.Duration) []models.HTTPStreamEvent {
+	var events []models.HTTPStreamEvent
+	if len(p.pending) > 0 {
+		line := strings.TrimSuffix(string(p.pending), "\r")
+		p.pending = nil
+		if event, ok := p.line(line, offset); ok {
+			events = append(events, event)
+		}
+	}
+	if event, ok := p.line("", offset); ok {
+		events = append(events, event)
+	}
+	return events
+}
+
+// line parses a line of the body and returns the event it completes, if any.
+func (p *HTTPStreamParser) line(line string, offset time.Duration) (models.HTTPStreamEvent, bool) {
+	if !p.sse {
+		if strings.TrimSpace(line) == "" {
+			return models.HTTPStreamEvent{}, false
+		}
+		return models.HTTPStreamEvent{Offset: offset, Data: line}, true
+	}
+
+	// an empty line dispatches the event, the lines starting with a colon are comments such as heartbeats
+	if line == "" {
+		if !p.started {
+			return models.HTTPStreamEvent{}, false
+		}
+		event := p.event
+		event.Offset = offset
+		event.Data = strings.Join(p.data, "\n")
+		p.event, p.data, p.started = models.HTTPStreamEvent{}, nil, false
+		return event, true
+	}
+	if strings.HasPrefix(line, ":") {
+		return models.HTTPStreamEvent{}, false
+	}
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "data":
+		p.data = append(p.data, value)
+	case "event":
+		p.event.Event = value
+	case "id":
+		p.event.ID = value
+	case "retry":
+		p.event.Retry = value
+	default:
+		// the unknown fields are ignored by the clients
+		return models.HTTPStreamEvent{}, false
+	}
+	p.started = true
+	return models.HTTPStreamEvent{}, false
+}
+
+// consumeHTTPStream reads a streamed response as a client of the stream would, and returns its events timed
+// from start, until the stream ends or the timeout of the client expires. The reading stops once maxEvents
+// events were read, if maxEvents is positive, which is only the case of the streams cut off by their client
+// while being recorded, as the application doesn't end them. The events read before an error are returned
+// along with it.
+func consumeHTTPStream(logger *zap.Logger, resp *http.Response, start time.Time, maxEvents int) ([]models.HTTPStreamEvent, error) {
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			utils.LogError(logger, err, "failed to close the streamed response body")
+		}
+	}()
+	parser := NewHTTPStreamParser(resp.Header)
+	var events []models.HTTPStreamEvent
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			events = append(events, parser.Feed(buf[:n], time.Since(start))...)
+		}
+		if maxEvents > 0 && len(events) >= maxEvents {
+			return events[:maxEvents], nil
+		}
+		if err == io.EOF {
+			return append(events, parser.Close(time.Since(start))...), nil
+		}
+		if err != nil {
+			return append(events, parser.Close(time.Since(start))...), err
+		}
+	}
+}
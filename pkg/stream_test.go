@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestIsHTTPStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{name: "server-sent events", header: http.Header{"Content-Type": {"text/event-stream; charset=utf-8"}}, want: true},
+		{name: "ndjson", header: http.Header{"Content-Type": {"application/x-ndjson"}}, want: true},
+		{name: "identity encoding", header: http.Header{"Content-Type": {"text/event-stream"}, "Content-Encoding": {"identity"}}, want: true},
+		{name: "compressed", header: http.Header{"Content-Type": {"text/event-stream"}, "Content-Encoding": {"gzip"}}},
+		{name: "json", header: http.Header{"Content-Type": {"application/json"}}},
+		{name: "no content type", header: http.Header{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsHTTPStream(tt.header); got != tt.want {
+				t.Errorf("IsHTTPStream() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPStreamParser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		chunks      []string
+		want        []models.HTTPStreamEvent
+	}{
+		{
+			name:        "server-sent events",
+			contentType: "text/event-stream",
+			chunks:      []string{"event: update\nid: 1\nretry: 1000\ndata: a\ndata: b\n\n", ": heartbeat\n\n", "data: c\r\n\r\n"},
+			want: []models.HTTPStreamEvent{
+				{Event: "update", ID: "1", Retry: "1000", Data: "a\nb"},
+				{Data: "c"},
+			},
+		},
+		{
+			name:        "events split across chunks",
+			contentType: "text/event-stream",
+			chunks:      []string{"da", "ta: hel", "lo\n", "\ndata:world\n\n"},
+			want:        []models.HTTPStreamEvent{{Data: "hello"}, {Data: "world"}},
+		},
+		{
+			name:        "unknown fields",
+			contentType: "text/event-stream",
+			chunks:      []string{"foo: bar\n\ndata: a\nfoo: bar\n\n"},
+			want:        []models.HTTPStreamEvent{{Data: "a"}},
+		},
+		{
+			name:        "event ended by the body",
+			contentType: "text/event-stream",
+			chunks:      []string{"data: a\n\ndata: b"},
+			want:        []models.HTTPStreamEvent{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			chunks:      []string{"{\"id\":1}\n\n{\"id\"", ":2}\r\n{\"id\":3}"},
+			want:        []models.HTTPStreamEvent{{Data: `{"id":1}`}, {Data: `{"id":2}`}, {Data: `{"id":3}`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewHTTPStreamParser(http.Header{"Content-Type": {tt.contentType}})
+			var got []models.HTTPStreamEvent
+			for _, chunk := range tt.chunks {
+				got = append(got, p.Feed([]byte(chunk), 0)...)
+			}
+			got = append(got, p.Close(0)...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPStreamEventString(t *testing.T) {
+	tests := []struct {
+		event models.HTTPStreamEvent
+		want  string
+	}{
+		{event: models.HTTPStreamEvent{Data: `{"id":1}`}, want: `{"id":1}`},
+		{event: models.HTTPStreamEvent{Event: "update", ID: "1", Data: "a\nb"}, want: "event: update\nid: 1\ndata: a\ndata: b"},
+	}
+	for _, tt := range tests {
+		if got := tt.event.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// chunkedBody returns the chunks one by one, then the error, io.EOF if it is nil.
+type chunkedBody struct {
+	chunks []string
+	err    error
+}
+
+func (b *chunkedBody) Read(p []byte) (int, error) {
+	if len(b.chunks) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, b.chunks[0])
+	b.chunks = b.chunks[1:]
+	return n, nil
+}
+
+func (b *chunkedBody) Close() error { return nil }
+
+func TestConsumeHTTPStream(t *testing.T) {
+	errReset := errors.New("connection reset by peer")
+	tests := []struct {
+		name      string
+		body      *chunkedBody
+		maxEvents int
+		want      []string
+		wantErr   bool
+	}{
+		{name: "whole stream", body: &chunkedBody{chunks: []string{"data: a\n\n", "data: b\n\n"}}, want: []string{"a", "b"}},
+		{name: "stopped after the recorded events", body: &chunkedBody{chunks: []string{"data: a\n\n", "data: b\n\ndata: c\n\n"}, err: errReset}, maxEvents: 2, want: []string{"a", "b"}},
+		{name: "more events than recorded", body: &chunkedBody{chunks: []string{"data: a\n\n", "data: b\n\n"}}, maxEvents: 5, want: []string{"a", "b"}},
+		{name: "events read before an error", body: &chunkedBody{chunks: []string{"data: a\n\n", "data: b"}, err: errReset}, want: []string{"a", "b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{"Content-Type": {"text/event-stream"}}, Body: tt.body}
+			events, err := consumeHTTPStream(zap.NewNop(), resp, time.Now(), tt.maxEvents)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("consumeHTTPStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, event := range events {
+				got = append(got, event.Data)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("consumeHTTPStream() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
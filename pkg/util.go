@@ -40,25 +40,19 @@ func URLParams(r *http.Request) map[string]string {
 	return result
 }
 
-// ToYamlHTTPHeader converts the http header into yaml format
-func ToYamlHTTPHeader(httpHeader http.Header) map[string]string {
-	header := map[string]string{}
+// ToYamlHTTPHeader converts the http header into yaml format, keeping each value of the headers sent on several lines
+func ToYamlHTTPHeader(httpHeader http.Header) models.HTTPHeader {
+	header := models.HTTPHeader{}
 	for i, j := range httpHeader {
-		header[i] = strings.Join(j, ",")
+		header[i] = append([]string(nil), j...)
 	}
 	return header
 }
 
-func ToHTTPHeader(mockHeader map[string]string) http.Header {
+func ToHTTPHeader(mockHeader models.HTTPHeader) http.Header {
 	header := http.Header{}
 	for i, j := range mockHeader {
-		match := IsTime(j)
-		if match {
-			//Values like "Tue, 17 Jan 2023 16:34:58 IST" should be considered as single element
-			header[i] = []string{j}
-			continue
-		}
-		header[i] = strings.Split(j, ",")
+		header[i] = append([]string(nil), j...)
 	}
 	return header
 }
@@ -84,16 +78,16 @@ func IsTime(stringDate string) bool {
 	return false
 }
 
-func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.HTTPResp, error) {
-	var resp *models.HTTPResp
-
+// renderTemplatizedValues replaces the template placeholders of the testcase with the values
+// extracted from the previous responses.
+func renderTemplatizedValues(logger *zap.Logger, tc *models.TestCase, testSet string) error {
 	//TODO: adjust this logic in the render function in order to remove the redundant code
 	// convert testcase to string and render the template values.
 	if len(utils.TemplatizedValues) > 0 {
 		testCaseStr, err := json.Marshal(tc)
 		if err != nil {
 			utils.LogError(logger, err, "failed to marshal the testcase")
-			return nil, err
+			return err
 		}
 		funcMap := template.FuncMap{
 			"int":    utils.ToInt,
@@ -103,22 +97,31 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 		tmpl, err := template.New("template").Funcs(funcMap).Parse(string(testCaseStr))
 		if err != nil || tmpl == nil {
 			utils.LogError(logger, err, "failed to parse the template", zap.Any("TestCaseString", string(testCaseStr)), zap.Any("TestCase", tc.Name), zap.Any("TestSet", testSet))
-			return nil, err
+			return err
 		}
 
 		var output bytes.Buffer
 		err = tmpl.Execute(&output, utils.TemplatizedValues)
 		if err != nil {
 			utils.LogError(logger, err, "failed to execute the template")
-			return nil, err
+			return err
 		}
 		testCaseStr = output.Bytes()
 		err = json.Unmarshal([]byte(testCaseStr), &tc)
 		if err != nil {
 			utils.LogError(logger, err, "failed to unmarshal the testcase")
-			return nil, err
+			return err
 		}
 	}
+	return nil
+}
+
+func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.HTTPResp, error) {
+	var resp *models.HTTPResp
+
+	if err := renderTemplatizedValues(logger, tc, testSet); err != nil {
+		return nil, err
+	}
 
 	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
 	req, err := http.NewRequestWithContext(ctx, string(tc.HTTPReq.Method), tc.HTTPReq.URL, bytes.NewBufferString(tc.HTTPReq.Body))
@@ -134,12 +137,18 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	logger.Debug(fmt.Sprintf("Sending request to user app:%v", req))
 
 	// override host header if present in the request
-	hostHeader := tc.HTTPReq.Header["Host"]
+	hostHeader := tc.HTTPReq.Header.Get("Host")
 	if hostHeader != "" {
 		logger.Debug("overriding host header", zap.String("host", hostHeader))
 		req.Host = hostHeader
 	}
 
+	// the streams are consumed as long as they were recorded, on top of the timeout of the api
+	timeout := time.Second * time.Duration(apiTimeout)
+	if n := len(tc.HTTPResp.Stream); n > 0 {
+		timeout += tc.HTTPResp.Stream[n-1].Offset
+	}
+
 	// Creating the client and disabling redirects
 	var client *http.Client
 
@@ -150,7 +159,7 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	if ok && strings.EqualFold(keepAlive[0], "keep-alive") {
 		logger.Debug("simulating request with conn:keep-alive")
 		client = &http.Client{
-			Timeout: time.Second * time.Duration(apiTimeout),
+			Timeout: timeout,
 			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
@@ -161,7 +170,7 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	} else if ok && strings.EqualFold(keepAlive[0], "close") {
 		logger.Debug("simulating request with conn:close")
 		client = &http.Client{
-			Timeout: time.Second * time.Duration(apiTimeout),
+			Timeout: timeout,
 			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
@@ -173,7 +182,7 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	} else {
 		logger.Debug("simulating request with conn:keep-alive (maxIdleConn=1)")
 		client = &http.Client{
-			Timeout: time.Second * time.Duration(apiTimeout),
+			Timeout: timeout,
 			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
@@ -185,12 +194,31 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 		}
 	}
 
+	start := time.Now()
 	httpResp, errHTTPReq := client.Do(req)
 	if errHTTPReq != nil {
 		utils.LogError(logger, errHTTPReq, "failed to send testcase request to app")
 		return nil, errHTTPReq
 	}
 
+	if IsHTTPStream(httpResp.Header) {
+		// the stream is read till its end, or up to the events recorded if it was cut off by its client while
+		// being recorded. The events read before an error are still compared.
+		maxEvents := 0
+		if tc.HTTPResp.StreamCutOff {
+			maxEvents = len(tc.HTTPResp.Stream)
+		}
+		stream, err := consumeHTTPStream(logger, httpResp, start, maxEvents)
+		if err != nil {
+			logger.Warn("failed to read the streamed response till its end", zap.Int("events", len(stream)), zap.Error(err))
+		}
+		return &models.HTTPResp{
+			StatusCode: httpResp.StatusCode,
+			Header:     ToYamlHTTPHeader(httpResp.Header),
+			Stream:     stream,
+		}, nil
+	}
+
 	respBody, errReadRespBody := io.ReadAll(httpResp.Body)
 	if errReadRespBody != nil {
 		utils.LogError(logger, errReadRespBody, "failed reading response body")
@@ -198,9 +226,10 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	}
 
 	resp = &models.HTTPResp{
-		StatusCode: httpResp.StatusCode,
-		Body:       string(respBody),
-		Header:     ToYamlHTTPHeader(httpResp.Header),
+		StatusCode:   httpResp.StatusCode,
+		Body:         string(respBody),
+		BodyEncoding: models.EncodingOf(string(respBody)),
+		Header:       ToYamlHTTPHeader(httpResp.Header),
 	}
 
 	return resp, errHTTPReq
@@ -230,10 +259,11 @@ func ParseHTTPResponse(data []byte, request *http.Request) (*http.Response, erro
 func MakeCurlCommand(tc models.HTTPReq) string {
 	curl := fmt.Sprintf("curl --request %s \\\n", string(tc.Method))
 	curl = curl + fmt.Sprintf("  --url %s \\\n", tc.URL)
-	header := ToHTTPHeader(tc.Header)
-
-	for k, v := range ToYamlHTTPHeader(header) {
-		if k != "Content-Length" {
+	for k, values := range tc.Header {
+		if k == "Content-Length" {
+			continue
+		}
+		for _, v := range values {
 			curl = curl + fmt.Sprintf("  --header '%s: %s' \\\n", k, v)
 		}
 	}
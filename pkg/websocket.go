@@ -0,0 +1,541 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// websocketGUID is appended to the key of the client to compute the accept key of the handshake.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// the opcodes of the websocket frames
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// maxWebSocketMessage is the largest payload accepted for a frame, and for a message made of several frames.
+const maxWebSocketMessage = 32 << 20
+
+// deflateTail is removed from the end of the compressed messages by the permessage-deflate extension.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// IsWebSocketUpgrade tells whether the headers of a request or of a response are the ones of a websocket handshake.
+func IsWebSocketUpgrade(header http.Header) bool {
+	if !strings.EqualFold(header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsWebSocketHandshake tells whether a buffer starts with the handshake request of a websocket session.
+func IsWebSocketHandshake(buf []byte) bool {
+	if !bytes.HasPrefix(buf, []byte("GET ")) {
+		return false
+	}
+	i := bytes.Index(buf, []byte("\r\n\r\n"))
+	if i < 0 {
+		return false
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:i+4])))
+	if err != nil {
+		return false
+	}
+	return IsWebSocketUpgrade(req.Header)
+}
+
+// WebSocketAccept returns the accept key of the handshake response for the key of the client.
+func WebSocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// IsWebSocketDeflate tells whether the permessage-deflate extension was negotiated, from the
+// Sec-WebSocket-Extensions header of the handshake response.
+func IsWebSocketDeflate(extensions string) bool {
+	for _, ext := range strings.Split(extensions, ",") {
+		name, _, _ := strings.Cut(ext, ";")
+		if strings.EqualFold(strings.TrimSpace(name), "permessage-deflate") {
+			return true
+		}
+	}
+	return false
+}
+
+// WebSocketParser splits the frames sent by one of the parties of a websocket session into messages as they are
+// received.
+type WebSocketParser struct {
+	origin  models.OriginType
+	deflate bool
+	pending []byte
+
+	// the fragments of the message being received
+	fragments  []byte
+	opcode     byte
+	compressed bool
+	fragmented bool
+
+	// window holds the last decompressed bytes, which the compressed messages refer to when the context is
+	// taken over from a message to the next one.
+	window []byte
+}
+
+// NewWebSocketParser returns the parser of the frames sent by origin, deflate telling whether the
+// permessage-deflate extension was negotiated.
+func NewWebSocketParser(origin models.OriginType, deflate bool) *WebSocketParser {
+	return &WebSocketParser{origin: origin, deflate: deflate}
+}
+
+// Feed parses the next bytes sent by the party, received after offset, and returns the messages they complete.
+// The control messages are returned between the fragments of a message as they are received.
+func (p *WebSocketParser) Feed(data []byte, offset time.Duration) ([]models.WebSocketMessage, error) {
+	p.pending = append(p.pending, data...)
+	var msgs []models.WebSocketMessage
+	for {
+		n, fin, rsv1, opcode, payload, ok, err := parseWebSocketFrame(p.pending)
+		if err != nil {
+			return msgs, err
+		}
+		if !ok {
+			return msgs, nil
+		}
+		p.pending = p.pending[n:]
+
+		if opcode >= wsClose {
+			msg, err := p.message(opcode, payload, false, offset)
+			if err != nil {
+				return msgs, err
+			}
+			msgs = append(msgs, msg)
+			continue
+		}
+		if opcode != wsContinuation {
+			if p.fragmented {
+				return msgs, errors.New("a new websocket message started before the end of the previous one")
+			}
+			p.opcode, p.compressed, p.fragments = opcode, rsv1, nil
+		} else if !p.fragmented {
+			return msgs, errors.New("a websocket continuation frame was received outside of a message")
+		}
+		if len(p.fragments)+len(payload) > maxWebSocketMessage {
+			return msgs, fmt.Errorf("a websocket message is larger than %d bytes", maxWebSocketMessage)
+		}
+		p.fragments = append(p.fragments, payload...)
+		p.fragmented = !fin
+		if !fin {
+			continue
+		}
+		msg, err := p.message(p.opcode, p.fragments, p.compressed, offset)
+		if err != nil {
+			return msgs, err
+		}
+		p.fragments = nil
+		msgs = append(msgs, msg)
+	}
+}
+
+// message builds a message from its whole payload.
+func (p *WebSocketParser) message(opcode byte, payload []byte, compressed bool, offset time.Duration) (models.WebSocketMessage, error) {
+	if compressed && p.deflate {
+		var err error
+		payload, err = p.inflate(payload)
+		if err != nil {
+			return models.WebSocketMessage{}, err
+		}
+	}
+	msg := models.WebSocketMessage{Origin: p.origin, Offset: offset}
+	switch opcode {
+	case wsText:
+		msg.Type, msg.Data = models.WebSocketText, string(payload)
+	case wsBinary:
+		msg.Type, msg.Data = models.WebSocketBinary, base64.StdEncoding.EncodeToString(payload)
+	case wsClose:
+		msg.Type = models.WebSocketClose
+		if len(payload) >= 2 {
+			msg.CloseCode = int(binary.BigEndian.Uint16(payload))
+			msg.Data = string(payload[2:])
+		}
+	case wsPing:
+		msg.Type, msg.Data = models.WebSocketPing, base64.StdEncoding.EncodeToString(payload)
+	case wsPong:
+		msg.Type, msg.Data = models.WebSocketPong, base64.StdEncoding.EncodeToString(payload)
+	default:
+		return msg, fmt.Errorf("unknown websocket opcode %#x", opcode)
+	}
+	return msg, nil
+}
+
+// inflate decompresses a message compressed by the permessage-deflate extension.
+func (p *WebSocketParser) inflate(payload []byte) ([]byte, error) {
+	r := flate.NewReaderDict(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail)), p.window)
+	out, err := io.ReadAll(r)
+	// the compressed messages don't end the deflate stream, so the reader runs out of input after the message
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to decompress the websocket message: %w", err)
+	}
+	p.window = append(p.window, out...)
+	if len(p.window) > 32*1024 {
+		p.window = p.window[len(p.window)-32*1024:]
+	}
+	return out, nil
+}
+
+// parseWebSocketFrame parses the frame at the start of buf, ok being false if it isn't whole yet. The payload
+// is unmasked. The frames whose length is invalid or larger than maxWebSocketMessage are rejected with an error.
+func parseWebSocketFrame(buf []byte) (n int, fin, rsv1 bool, opcode byte, payload []byte, ok bool, err error) {
+	if len(buf) < 2 {
+		return 0, false, false, 0, nil, false, nil
+	}
+	fin, rsv1, opcode = buf[0]&0x80 != 0, buf[0]&0x40 != 0, buf[0]&0x0f
+	masked := buf[1]&0x80 != 0
+	length := uint64(buf[1] & 0x7f)
+	n = 2
+	switch length {
+	case 126:
+		if len(buf) < n+2 {
+			return 0, false, false, 0, nil, false, nil
+		}
+		length = uint64(binary.BigEndian.Uint16(buf[n:]))
+		n += 2
+	case 127:
+		if len(buf) < n+8 {
+			return 0, false, false, 0, nil, false, nil
+		}
+		length = binary.BigEndian.Uint64(buf[n:])
+		n += 8
+		// the most significant bit of a 64-bit length must be 0
+		if length>>63 != 0 {
+			return 0, false, false, 0, nil, false, errors.New("the length of a websocket frame has its most significant bit set")
+		}
+	}
+	if length > maxWebSocketMessage {
+		return 0, false, false, 0, nil, false, fmt.Errorf("a websocket frame of %d bytes is larger than %d bytes", length, maxWebSocketMessage)
+	}
+	var mask []byte
+	if masked {
+		if len(buf) < n+4 {
+			return 0, false, false, 0, nil, false, nil
+		}
+		mask = buf[n : n+4]
+		n += 4
+	}
+	if uint64(len(buf)-n) < length {
+		return 0, false, false, 0, nil, false, nil
+	}
+	payload = make([]byte, length)
+	copy(payload, buf[n:n+int(length)])
+	for i := range mask {
+		for j := i; j < len(payload); j += 4 {
+			payload[j] ^= mask[i]
+		}
+	}
+	return n + int(length), fin, rsv1, opcode, payload, true, nil
+}
+
+// EncodeWebSocketFrame encodes a message as a single uncompressed frame, masked when it is sent by a client.
+func EncodeWebSocketFrame(msg models.WebSocketMessage, masked bool) ([]byte, error) {
+	var (
+		opcode  byte
+		payload []byte
+		err     error
+	)
+	switch msg.Type {
+	case models.WebSocketText:
+		opcode, payload = wsText, []byte(msg.Data)
+	case models.WebSocketClose:
+		opcode = wsClose
+		if msg.CloseCode != 0 {
+			payload = binary.BigEndian.AppendUint16(nil, uint16(msg.CloseCode))
+			payload = append(payload, msg.Data...)
+		}
+	case models.WebSocketBinary:
+		opcode = wsBinary
+	case models.WebSocketPing:
+		opcode = wsPing
+	case models.WebSocketPong:
+		opcode = wsPong
+	default:
+		return nil, fmt.Errorf("unknown websocket message type %q", msg.Type)
+	}
+	if opcode == wsBinary || opcode == wsPing || opcode == wsPong {
+		payload, err = base64.StdEncoding.DecodeString(msg.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the payload of the websocket message: %w", err)
+		}
+	}
+
+	frame := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	if !masked {
+		return append(frame, payload...), nil
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return nil, err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame, nil
+}
+
+// SimulateWebSocket replays the session of a websocket testcase against the application. The messages of the
+// client are sent in their recorded order, each of them once the messages the server had sent before it are
+// received, or once the api timeout is over. The messages of the server are returned as the events of the
+// response, so that they are compared the way the events of a stream are.
+func SimulateWebSocket(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.HTTPResp, error) {
+	if err := renderTemplatizedValues(logger, tc, testSet); err != nil {
+		return nil, err
+	}
+
+	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
+	req, err := http.NewRequestWithContext(ctx, string(tc.HTTPReq.Method), tc.HTTPReq.URL, nil)
+	if err != nil {
+		utils.LogError(logger, err, "failed to create a websocket handshake request from the yaml document")
+		return nil, err
+	}
+	req.Header = ToHTTPHeader(tc.HTTPReq.Header)
+	// the messages are compared uncompressed, so no extension is offered
+	req.Header.Del("Sec-WebSocket-Extensions")
+	req.Header.Set("KEPLOY-TEST-ID", tc.Name)
+	req.Header.Set("KEPLOY-TEST-SET-ID", testSet)
+	if hostHeader := tc.HTTPReq.Header.Get("Host"); hostHeader != "" {
+		req.Host = hostHeader
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &http.Transport{DisableCompression: true},
+	}
+	httpResp, err := client.Do(req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to send the websocket handshake to app")
+		return nil, err
+	}
+	defer func() {
+		if err := httpResp.Body.Close(); err != nil {
+			logger.Debug("failed to close the websocket connection", zap.Error(err))
+		}
+	}()
+	resp := &models.HTTPResp{
+		StatusCode: httpResp.StatusCode,
+		Header:     ToYamlHTTPHeader(httpResp.Header),
+	}
+	if httpResp.StatusCode != http.StatusSwitchingProtocols {
+		body, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			utils.LogError(logger, err, "failed reading the response body of the websocket handshake")
+			return nil, err
+		}
+		resp.Body, resp.BodyEncoding = string(body), models.EncodingOf(string(body))
+		return resp, nil
+	}
+	conn, ok := httpResp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return nil, errors.New("the websocket connection can't be written to")
+	}
+
+	replay := &webSocketReplay{logger: logger, conn: conn, start: time.Now(), received: make(chan struct{}, 1)}
+	go replay.read(NewWebSocketParser(models.FromServer, IsWebSocketDeflate(httpResp.Header.Get("Sec-WebSocket-Extensions"))))
+
+	// a message of the server is awaited as long after the handshake as it was recorded, on top of the api timeout
+	timeout := time.Second * time.Duration(apiTimeout)
+	var (
+		expected int
+		deadline = replay.start.Add(timeout)
+	)
+	for _, msg := range tc.WebSocket {
+		if msg.Origin == models.FromServer {
+			expected++
+			deadline = replay.start.Add(msg.Offset + timeout)
+			continue
+		}
+		replay.wait(ctx, expected, deadline)
+		if replay.isClosed() {
+			break
+		}
+		err := replay.send(msg)
+		if err != nil {
+			utils.LogError(logger, err, "failed to send the websocket message to app")
+			return nil, err
+		}
+	}
+	replay.wait(ctx, expected, deadline)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	resp.Stream = replay.events()
+
+	// the session is closed properly if the recorded one wasn't closed, the answer of the app being left out
+	if !replay.isClosed() {
+		if err := replay.send(models.WebSocketMessage{Type: models.WebSocketClose, CloseCode: 1000}); err != nil {
+			logger.Debug("failed to close the websocket session", zap.Error(err))
+		}
+	}
+	return resp, nil
+}
+
+// webSocketReplay holds the messages received from the application while a session is replayed.
+type webSocketReplay struct {
+	logger   *zap.Logger
+	conn     io.ReadWriteCloser
+	start    time.Time
+	received chan struct{}
+
+	mu       sync.Mutex
+	messages []models.WebSocketMessage
+	closed   bool
+	sentEnd  bool
+}
+
+// read collects the messages of the server until the connection is closed, answering its pings.
+func (r *webSocketReplay) read(parser *WebSocketParser) {
+	defer func() {
+		r.mu.Lock()
+		r.closed = true
+		r.mu.Unlock()
+		r.notify()
+	}()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.conn.Read(buf)
+		if n > 0 {
+			msgs, perr := parser.Feed(buf[:n], time.Since(r.start))
+			for _, msg := range msgs {
+				switch msg.Type {
+				case models.WebSocketPing:
+					if err := r.send(models.WebSocketMessage{Type: models.WebSocketPong, Data: msg.Data}); err != nil {
+						r.logger.Debug("failed to answer the ping of the app", zap.Error(err))
+					}
+					continue
+				case models.WebSocketPong:
+					continue
+				}
+				r.mu.Lock()
+				r.messages = append(r.messages, msg)
+				r.mu.Unlock()
+				r.notify()
+				if msg.Type == models.WebSocketClose {
+					// the close message of the server is answered before the connection is left
+					if err := r.send(models.WebSocketMessage{Type: models.WebSocketClose, CloseCode: msg.CloseCode}); err != nil {
+						r.logger.Debug("failed to answer the close message of the app", zap.Error(err))
+					}
+					return
+				}
+			}
+			if perr != nil {
+				utils.LogError(r.logger, perr, "failed to parse the websocket messages of the app")
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (r *webSocketReplay) notify() {
+	select {
+	case r.received <- struct{}{}:
+	default:
+	}
+}
+
+// wait waits until count messages have been received from the server, the connection is closed or the deadline
+// is over.
+func (r *webSocketReplay) wait(ctx context.Context, count int, deadline time.Time) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		done := len(r.messages) >= count || r.closed
+		r.mu.Unlock()
+		if done {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case <-r.received:
+		}
+	}
+}
+
+func (r *webSocketReplay) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// send writes a message to the application, once the session is closed by either side nothing is sent anymore.
+func (r *webSocketReplay) send(msg models.WebSocketMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sentEnd {
+		return nil
+	}
+	frame, err := EncodeWebSocketFrame(msg, true)
+	if err != nil {
+		return err
+	}
+	r.sentEnd = msg.Type == models.WebSocketClose
+	_, err = r.conn.Write(frame)
+	return err
+}
+
+// events returns the messages of the server as the events of a streamed response.
+func (r *webSocketReplay) events() []models.HTTPStreamEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]models.HTTPStreamEvent, 0, len(r.messages))
+	for _, msg := range r.messages {
+		events = append(events, msg.StreamEvent())
+	}
+	return events
+}
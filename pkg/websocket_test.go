@@ -0,0 +1,285 @@
+package pkg
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// frame builds a websocket frame, masked with a fixed key when masked is set.
+func frame(fin, rsv1 bool, opcode byte, payload []byte, masked bool) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	out := []byte{b0}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		out = append(out, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		out = append(out, maskBit|126)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(payload)))
+	default:
+		out = append(out, maskBit|127)
+		out = binary.BigEndian.AppendUint64(out, uint64(len(payload)))
+	}
+	if !masked {
+		return append(out, payload...)
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	out = append(out, mask...)
+	for i, b := range payload {
+		out = append(out, b^mask[i%4])
+	}
+	return out
+}
+
+// deflater compresses the messages the way the permessage-deflate extension does, the context being taken over
+// from a message to the next one.
+type deflater struct {
+	buf bytes.Buffer
+	w   *flate.Writer
+}
+
+func newDeflater(t *testing.T) *deflater {
+	t.Helper()
+	d := &deflater{}
+	w, err := flate.NewWriter(&d.buf, flate.BestSpeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.w = w
+	return d
+}
+
+func (d *deflater) compress(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	d.buf.Reset()
+	if _, err := d.w.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Clone(bytes.TrimSuffix(d.buf.Bytes(), deflateTail))
+}
+
+func TestParseWebSocketFrame(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 300)
+	tests := []struct {
+		name    string
+		buf     []byte
+		n       int
+		fin     bool
+		opcode  byte
+		payload []byte
+		ok      bool
+		wantErr bool
+	}{
+		{name: "empty", buf: nil},
+		{name: "partial header", buf: []byte{0x81}},
+		{name: "partial payload", buf: frame(true, false, wsText, []byte("hello"), false)[:5]},
+		{name: "text", buf: frame(true, false, wsText, []byte("hello"), false), n: 7, fin: true, opcode: wsText, payload: []byte("hello"), ok: true},
+		{name: "masked", buf: frame(true, false, wsText, []byte("hello"), true), n: 11, fin: true, opcode: wsText, payload: []byte("hello"), ok: true},
+		{name: "fragment", buf: frame(false, false, wsBinary, []byte{1, 2}, false), n: 4, opcode: wsBinary, payload: []byte{1, 2}, ok: true},
+		{name: "16-bit length", buf: frame(true, false, wsText, long, false), n: 304, fin: true, opcode: wsText, payload: long, ok: true},
+		{name: "partial 16-bit length", buf: []byte{0x81, 126, 0x01}},
+		{name: "partial 64-bit length", buf: []byte{0x81, 127, 0, 0, 0}},
+		{
+			name:    "64-bit length with its most significant bit set",
+			buf:     append([]byte{0x82, 127}, binary.BigEndian.AppendUint64(nil, 1<<63|5)...),
+			wantErr: true,
+		},
+		{
+			name:    "frame larger than the maximum",
+			buf:     append([]byte{0x82, 127}, binary.BigEndian.AppendUint64(nil, maxWebSocketMessage+1)...),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, fin, _, opcode, payload, ok, err := parseWebSocketFrame(tt.buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWebSocketFrame() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.ok {
+				t.Fatalf("parseWebSocketFrame() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if n != tt.n || fin != tt.fin || opcode != tt.opcode || !bytes.Equal(payload, tt.payload) {
+				t.Errorf("parseWebSocketFrame() = %d, %v, %#x, %q, want %d, %v, %#x, %q", n, fin, opcode, payload, tt.n, tt.fin, tt.opcode, tt.payload)
+			}
+		})
+	}
+}
+
+func TestWebSocketParserFeed(t *testing.T) {
+	closePayload := append(binary.BigEndian.AppendUint16(nil, 1000), "bye"...)
+	tests := []struct {
+		name    string
+		chunks  [][]byte
+		want    []models.WebSocketMessage
+		wantErr bool
+	}{
+		{
+			name:   "text message",
+			chunks: [][]byte{frame(true, false, wsText, []byte("hello"), true)},
+			want:   []models.WebSocketMessage{{Type: models.WebSocketText, Data: "hello"}},
+		},
+		{
+			name: "message split across reads",
+			chunks: func() [][]byte {
+				f := frame(true, false, wsText, []byte("hello"), false)
+				return [][]byte{f[:3], f[3:]}
+			}(),
+			want: []models.WebSocketMessage{{Type: models.WebSocketText, Data: "hello"}},
+		},
+		{
+			name: "fragmented message with a ping in between",
+			chunks: [][]byte{
+				frame(false, false, wsText, []byte("hel"), false),
+				frame(true, false, wsPing, []byte{7}, false),
+				frame(true, false, wsContinuation, []byte("lo"), false),
+			},
+			want: []models.WebSocketMessage{
+				{Type: models.WebSocketPing, Data: base64.StdEncoding.EncodeToString([]byte{7})},
+				{Type: models.WebSocketText, Data: "hello"},
+			},
+		},
+		{
+			name:   "binary message",
+			chunks: [][]byte{frame(true, false, wsBinary, []byte{0, 1, 2}, false)},
+			want:   []models.WebSocketMessage{{Type: models.WebSocketBinary, Data: base64.StdEncoding.EncodeToString([]byte{0, 1, 2})}},
+		},
+		{
+			name:   "close message",
+			chunks: [][]byte{frame(true, false, wsClose, closePayload, false)},
+			want:   []models.WebSocketMessage{{Type: models.WebSocketClose, CloseCode: 1000, Data: "bye"}},
+		},
+		{
+			name:    "continuation outside of a message",
+			chunks:  [][]byte{frame(true, false, wsContinuation, []byte("lo"), false)},
+			wantErr: true,
+		},
+		{
+			name: "new message before the end of the previous one",
+			chunks: [][]byte{
+				frame(false, false, wsText, []byte("hel"), false),
+				frame(true, false, wsText, []byte("lo"), false),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "oversized frame",
+			chunks:  [][]byte{append([]byte{0x82, 127}, binary.BigEndian.AppendUint64(nil, maxWebSocketMessage+1)...)},
+			wantErr: true,
+		},
+		{
+			name:    "unknown opcode",
+			chunks:  [][]byte{frame(true, false, 0x3, []byte("x"), false)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewWebSocketParser(models.FromClient, false)
+			var (
+				got []models.WebSocketMessage
+				err error
+			)
+			for _, chunk := range tt.chunks {
+				var msgs []models.WebSocketMessage
+				msgs, err = p.Feed(chunk, 0)
+				got = append(got, msgs...)
+				if err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Feed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			assertMessages(t, got, tt.want)
+		})
+	}
+}
+
+func TestWebSocketParserDeflate(t *testing.T) {
+	p := NewWebSocketParser(models.FromServer, true)
+	d := newDeflater(t)
+	var got []models.WebSocketMessage
+	// the second message refers to the first one through the context taken over
+	for _, text := range []string{"hello websocket", "hello websocket again"} {
+		msgs, err := p.Feed(frame(true, true, wsText, d.compress(t, []byte(text)), false), 0)
+		if err != nil {
+			t.Fatalf("Feed() error = %v", err)
+		}
+		got = append(got, msgs...)
+	}
+	assertMessages(t, got, []models.WebSocketMessage{
+		{Type: models.WebSocketText, Data: "hello websocket"},
+		{Type: models.WebSocketText, Data: "hello websocket again"},
+	})
+}
+
+func TestEncodeWebSocketFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    models.WebSocketMessage
+		masked bool
+	}{
+		{name: "text", msg: models.WebSocketMessage{Type: models.WebSocketText, Data: "hello"}},
+		{name: "masked text", msg: models.WebSocketMessage{Type: models.WebSocketText, Data: "hello"}, masked: true},
+		{name: "long text", msg: models.WebSocketMessage{Type: models.WebSocketText, Data: string(bytes.Repeat([]byte("a"), 70000))}},
+		{name: "binary", msg: models.WebSocketMessage{Type: models.WebSocketBinary, Data: base64.StdEncoding.EncodeToString([]byte{0, 1, 2})}},
+		{name: "close", msg: models.WebSocketMessage{Type: models.WebSocketClose, CloseCode: 1001, Data: "going away"}, masked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeWebSocketFrame(tt.msg, tt.masked)
+			if err != nil {
+				t.Fatalf("EncodeWebSocketFrame() error = %v", err)
+			}
+			if masked := encoded[1]&0x80 != 0; masked != tt.masked {
+				t.Errorf("EncodeWebSocketFrame() masked = %v, want %v", masked, tt.masked)
+			}
+			msgs, err := NewWebSocketParser(models.FromClient, false).Feed(encoded, 0)
+			if err != nil {
+				t.Fatalf("Feed() error = %v", err)
+			}
+			tt.msg.Origin = models.FromClient
+			assertMessages(t, msgs, []models.WebSocketMessage{tt.msg})
+		})
+	}
+
+	if _, err := EncodeWebSocketFrame(models.WebSocketMessage{Type: models.WebSocketBinary, Data: "not base64!"}, false); err == nil {
+		t.Error("EncodeWebSocketFrame() of an invalid binary payload didn't fail")
+	}
+}
+
+func assertMessages(t *testing.T, got, want []models.WebSocketMessage) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d messages %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].Data != want[i].Data || got[i].CloseCode != want[i].CloseCode {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}